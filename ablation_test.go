@@ -223,111 +223,6 @@ func leftOnlyDeltaDecompressU16(in []uint16, width, height int) []uint16 {
 	return out
 }
 
-// paethPredict returns the Paeth predictor value (PNG spec).
-// a = left, b = top, c = top-left.  Always returns one of {a, b, c}.
-func paethPredict(a, b, c int32) int32 {
-	p := a + b - c
-	pa := p - a
-	if pa < 0 {
-		pa = -pa
-	}
-	pb := p - b
-	if pb < 0 {
-		pb = -pb
-	}
-	pc := p - c
-	if pc < 0 {
-		pc = -pc
-	}
-	if pa <= pb && pa <= pc {
-		return a
-	}
-	if pb <= pc {
-		return b
-	}
-	return c
-}
-
-// paethDeltaCompressU16 applies the Paeth predictor to 16-bit pixel data.
-func paethDeltaCompressU16(in []uint16, width, height int, maxValue uint16) ([]uint16, error) {
-	pixelDepth := bits.Len16(maxValue)
-	deltaThreshold := uint16((1 << (pixelDepth - 1)) - 1)
-	delimiterForOverflow := uint16((1 << pixelDepth) - 1)
-	out := make([]uint16, 0, width*height*2)
-	out = append(out, maxValue)
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			index := y*width + x
-
-			var predicted int32
-			if x == 0 && y == 0 {
-				predicted = 0
-			} else if y == 0 {
-				predicted = int32(in[index-1]) // left only
-			} else if x == 0 {
-				predicted = int32(in[index-width]) // top only
-			} else {
-				a := int32(in[index-1])
-				b := int32(in[index-width])
-				c := int32(in[index-width-1])
-				predicted = paethPredict(a, b, c)
-			}
-
-			inputVal := in[index]
-			diff := int32(inputVal) - predicted
-
-			if uint16(abs(diff)) >= deltaThreshold {
-				out = append(out, delimiterForOverflow)
-				out = append(out, inputVal)
-			} else {
-				out = append(out, uint16(int32(deltaThreshold)+diff))
-			}
-		}
-	}
-	return out, nil
-}
-
-// paethDeltaDecompressU16 inverts paethDeltaCompressU16.
-func paethDeltaDecompressU16(in []uint16, width, height int) []uint16 {
-	maxValue := in[0]
-	pixelDepth := bits.Len16(maxValue)
-	deltaThreshold := uint16((1 << (pixelDepth - 1)) - 1)
-	delimiterForOverflow := uint16((1 << pixelDepth) - 1)
-	out := make([]uint16, width*height)
-	ic := 1
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			index := y*width + x
-
-			var predicted int32
-			if x == 0 && y == 0 {
-				predicted = 0
-			} else if y == 0 {
-				predicted = int32(out[index-1])
-			} else if x == 0 {
-				predicted = int32(out[index-width])
-			} else {
-				a := int32(out[index-1])
-				b := int32(out[index-width])
-				c := int32(out[index-width-1])
-				predicted = paethPredict(a, b, c)
-			}
-
-			v := in[ic]
-			ic++
-			if v == delimiterForOverflow {
-				out[index] = in[ic]
-				ic++
-			} else {
-				out[index] = uint16(int32(v) - int32(deltaThreshold) + predicted)
-			}
-		}
-	}
-	return out
-}
-
 // compressPredictorPipeline runs Delta(predictor)+RLE+FSE and returns (ratio, compSize).
 func compressPredictorPipeline(deltaStream []uint16, rawBytes int) (float64, int) {
 	var rle RleCompressU16
@@ -392,12 +287,12 @@ func TestPredictorAblation(t *testing.T) {
 			}
 
 			// ── Paeth ──
-			paethDelta, err := paethDeltaCompressU16(shortData, cols, rows, maxShort)
+			paethDelta, err := PaethDeltaCompressU16(shortData, cols, rows, maxShort)
 			if err != nil {
 				t.Fatalf("paeth delta: %v", err)
 			}
 			// Verify roundtrip
-			paethOut := paethDeltaDecompressU16(paethDelta, cols, rows)
+			paethOut := PaethDeltaDecompressU16(paethDelta, cols, rows)
 			for i, v := range shortData {
 				if paethOut[i] != v {
 					t.Fatalf("paeth roundtrip fail at %d: want %d got %d", i, v, paethOut[i])
@@ -479,7 +374,7 @@ func TestPredictorAblationDecompSpeed(t *testing.T) {
 			avgComp, _ := FSECompressU16(avgRle, &s1)
 			avgRleBytes := len(avgRle) * 2
 
-			paethDelta, _ := paethDeltaCompressU16(shortData, cols, rows, maxShort)
+			paethDelta, _ := PaethDeltaCompressU16(shortData, cols, rows, maxShort)
 			paethCR := buildComp(paethDelta)
 
 			medDelta, _ := MEDDeltaCompressU16(shortData, cols, rows, maxShort)
@@ -0,0 +1,260 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Archive pipeline.
+//
+// CompressArchive is the high-ratio mode for storage where decode speed
+// matters less than size. It models each pixel as JPEG-LS does: the MED
+// prediction is corrected by the running bias of the pixel's context, one of
+// 365 contexts from the quantised gradients NE-N, N-NW and NW-W, with the
+// sign of the gradients folded so that mirrored textures share statistics.
+// The corrected residuals, reduced modulo 2^depth and ZigZag mapped, are
+// then coded with ArithCompressU16 in raster order using the image width as
+// stride, so the arithmetic coder conditions every residual on the error
+// energy of its causal neighbours, as CALIC does.
+//
+// Nothing is stored besides the coded residuals: the gradient thresholds
+// follow from the max value as in JPEG-LS, and the decoder rebuilds the bias
+// state as it goes. The result is a MIC1 container with pipeline
+// PipelineArchive whose payload is one entropy stream, read through
+// FSEDecompressU16Auto like every other coder.
+
+const (
+	archiveContexts = 9 * 9 * 9 // quantised gradient triples, before sign folding
+	archiveReset    = 64        // JPEG-LS RESET: halve the statistics this often
+	archiveMinBias  = -128
+	archiveMaxBias  = 127
+)
+
+// archiveModel holds the bias-correction state shared by the archive
+// encoder and decoder.
+type archiveModel struct {
+	t1, t2, t3 int32
+	bias       [archiveContexts]int32 // C: correction added to the prediction
+	sum        [archiveContexts]int32 // B: accumulated corrected error
+	count      [archiveContexts]int32 // N: occurrences since the last reset
+}
+
+// newArchiveModel returns a model with the default JPEG-LS thresholds for
+// maxValue.
+func newArchiveModel(maxValue uint16) *archiveModel {
+	m := new(archiveModel)
+	mv := int(maxValue)
+	var t1, t2, t3 int
+	if mv >= 128 {
+		f := (min(mv, 4095) + 128) >> 8
+		t1, t2, t3 = f*1+2, f*4+3, f*17+4
+	} else {
+		f := 256 / (mv + 1)
+		t1 = max(2, 3/f)
+		t2 = max(t1, 7/f)
+		t3 = max(t2, 21/f)
+	}
+	m.t1, m.t2, m.t3 = int32(min(t1, mv)), int32(min(t2, mv)), int32(min(t3, mv))
+	for i := range m.count {
+		m.count[i] = 1
+	}
+	return m
+}
+
+// quantise maps a gradient to -4..4.
+func (m *archiveModel) quantise(d int32) int {
+	switch {
+	case d <= -m.t3:
+		return -4
+	case d <= -m.t2:
+		return -3
+	case d <= -m.t1:
+		return -2
+	case d < 0:
+		return -1
+	case d == 0:
+		return 0
+	case d < m.t1:
+		return 1
+	case d < m.t2:
+		return 2
+	case d < m.t3:
+		return 3
+	}
+	return 4
+}
+
+// predict returns the bias-corrected prediction, clamped to [0, maxValue],
+// with the context and the sign (+1 or -1) that the residual is multiplied
+// by.
+func (m *archiveModel) predict(w, n, nw, ne, maxValue int32) (int32, int, int32) {
+	q1, q2, q3 := m.quantise(ne-n), m.quantise(n-nw), m.quantise(nw-w)
+	sign := int32(1)
+	if q1 < 0 || (q1 == 0 && (q2 < 0 || (q2 == 0 && q3 < 0))) {
+		q1, q2, q3, sign = -q1, -q2, -q3, -1
+	}
+	ctx := ((q1+4)*9+q2+4)*9 + q3 + 4
+
+	pred := medPredict(w, n, nw) + sign*m.bias[ctx]
+	if pred < 0 {
+		pred = 0
+	} else if pred > maxValue {
+		pred = maxValue
+	}
+	return pred, ctx, sign
+}
+
+// update records the sign-corrected residual e of context ctx.
+func (m *archiveModel) update(ctx int, e int32) {
+	b, n := m.sum[ctx]+e, m.count[ctx]
+	if n == archiveReset {
+		b >>= 1
+		n >>= 1
+	}
+	n++
+	if b <= -n {
+		if m.bias[ctx] > archiveMinBias {
+			m.bias[ctx]--
+		}
+		b += n
+		if b <= -n {
+			b = -n + 1
+		}
+	} else if b > 0 {
+		if m.bias[ctx] < archiveMaxBias {
+			m.bias[ctx]++
+		}
+		b -= n
+		if b > 0 {
+			b = 0
+		}
+	}
+	m.sum[ctx], m.count[ctx] = b, n
+}
+
+// archiveNeighbours returns the causal neighbours of pixel (x, y) at index i
+// of img. Missing neighbours repeat the nearest one that exists, so every
+// pixel goes through the same model; the first pixel predicts from zeros.
+func archiveNeighbours(img []uint16, i, x, y, width int) (w, n, nw, ne int32) {
+	switch {
+	case y == 0 && x == 0:
+		return 0, 0, 0, 0
+	case y == 0:
+		w = int32(img[i-1])
+		return w, w, w, w
+	case x == 0:
+		n = int32(img[i-width])
+		ne = n
+		if x+1 < width {
+			ne = int32(img[i-width+1])
+		}
+		return n, n, n, ne
+	}
+	w, n, nw = int32(img[i-1]), int32(img[i-width]), int32(img[i-width-1])
+	ne = n
+	if x+1 < width {
+		ne = int32(img[i-width+1])
+	}
+	return w, n, nw, ne
+}
+
+// CompressArchive compresses a single 16-bit frame with the archive
+// pipeline. maxValue 0 derives it from the pixels. Decompress and Decode
+// read the result.
+func CompressArchive(pixels []uint16, width, height int, maxValue uint16) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("archive: invalid dimensions %dx%d", width, height)
+	}
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("archive: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if maxValue == 0 {
+		for _, v := range pixels {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+		if maxValue == 0 {
+			maxValue = 1
+		}
+	}
+	for i, v := range pixels {
+		if v > maxValue {
+			return nil, fmt.Errorf("archive: pixel %d value %d exceeds max value %d", i, v, maxValue)
+		}
+	}
+
+	m := newArchiveModel(maxValue)
+	depth := bits.Len16(maxValue)
+	mask, half := int32(1)<<depth-1, int32(1)<<(depth-1)
+	mv := int32(maxValue)
+	symbols := make([]uint16, len(pixels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			w, n, nw, ne := archiveNeighbours(pixels, i, x, y, width)
+			pred, ctx, sign := m.predict(w, n, nw, ne, mv)
+			e := (sign*(int32(pixels[i])-pred)+half)&mask - half
+			m.update(ctx, e)
+			symbols[i] = uint16((e << 1) ^ (e >> 31))
+		}
+	}
+
+	payload, err := ArithCompressU16(symbols, width)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	hdr := MIC1Header{Width: width, Height: height, MaxValue: maxValue, Pipeline: PipelineArchive}
+	if err := WriteMIC1(&buf, hdr, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressArchive decodes a PipelineArchive payload into dst, or into a
+// new slice if dst is nil.
+func (d *Decoder) decompressArchive(dst []uint16, payload []byte, width, height int, maxValue uint16) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	if maxValue == 0 {
+		return nil, errors.New("archive: zero max value")
+	}
+	n := width * height
+	s := ScratchU16{DecompressLimit: symbolLimit(n)}
+	symbols, err := FSEDecompressU16Auto(payload, &s)
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	if len(symbols) != n {
+		return nil, fmt.Errorf("archive: %d residuals for %d pixels", len(symbols), n)
+	}
+
+	out := dst
+	if out == nil {
+		out = make([]uint16, n)
+	}
+	out = out[:n]
+	m := newArchiveModel(maxValue)
+	mask := int32(1)<<bits.Len16(maxValue) - 1
+	mv := int32(maxValue)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			w, nn, nw, ne := archiveNeighbours(out, i, x, y, width)
+			pred, ctx, sign := m.predict(w, nn, nw, ne, mv)
+			z := int32(symbols[i])
+			e := z>>1 ^ -(z & 1)
+			m.update(ctx, e)
+			out[i] = uint16((pred + sign*e) & mask)
+		}
+	}
+	return out, nil
+}
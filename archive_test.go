@@ -0,0 +1,224 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"testing"
+)
+
+// TestArithRoundtrip verifies the arithmetic coder directly and through
+// FSEDecompressU16Auto, with and without a stride.
+func TestArithRoundtrip(t *testing.T) {
+	skewed := make([]uint16, 5000)
+	for i := range skewed {
+		skewed[i] = uint16(i*i) % 7
+	}
+	full := make([]uint16, 1<<16)
+	for i := range full {
+		full[i] = uint16(i * 40503)
+	}
+	for _, tc := range []struct {
+		name    string
+		symbols []uint16
+		stride  int
+	}{
+		{"empty", nil, 0},
+		{"single", []uint16{65535}, 0},
+		{"zeros", make([]uint16, 10000), 100},
+		{"long_zeros", make([]uint16, 1<<20), 0}, // near the most symbols per byte
+		{"skewed", skewed, 0},
+		{"full", full, 256},
+		{"stride1", skewed, 1},
+		{"delta", fuzzSymbols(), 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := ArithCompressU16(tc.symbols, tc.stride)
+			if err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			got, err := FSEDecompressU16Auto(b, nil)
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			assertPixelsEqual(t, tc.symbols, got, tc.name)
+
+			if len(tc.symbols) > 1 { // a zero limit selects the default
+				if _, err := ArithDecompressU16(b, &ScratchU16{DecompressLimit: len(tc.symbols) - 1}); err == nil {
+					t.Error("expected error above DecompressLimit")
+				}
+			}
+		})
+	}
+
+	b, err := ArithCompressU16(full, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ArithDecompressU16(b[:len(b)/2], nil); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+	// A short stream declaring billions of symbols must fail before
+	// allocating them.
+	huge := []byte("\xff\x10\xa6\xd6\x02m\x96\x14\xe4 (\xc7\x0e\x01\x87\x1d\xa1\xba\xd3")
+	if _, err := FSEDecompressU16Auto(huge, nil); err == nil {
+		t.Error("expected error for a count the input cannot hold")
+	}
+	if _, err := ArithCompressU16(full, -1); err == nil {
+		t.Error("expected error for negative stride")
+	}
+
+	// Compress selects the coder like any other.
+	pixels, _ := fuzzPixels()
+	blob, err := Compress(pixels, fuzzWidth, fuzzHeight, CompressOptions{Coder: CoderArith, Strips: 2})
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	img, err := Decode(blob)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	assertPixelsEqual(t, pixels, img.Pixels, "CoderArith")
+}
+
+// TestArchiveRoundtrip verifies pixel-exact roundtrip of the archive pipeline
+// on MR, CT and edge-case images.
+func TestArchiveRoundtrip(t *testing.T) {
+	for _, td := range testFiles[:2] { // MR 256x256, CT 512x512
+		_, pixels, maxVal, width, height := SetupTests(td)
+		blob, err := CompressArchive(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: compress: %v", td.name, err)
+		}
+		img, err := Decode(blob)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", td.name, err)
+		}
+		assertPixelsEqual(t, pixels, img.Pixels, td.name)
+		t.Logf("%s archive %6.2fx", td.name, float64(len(pixels)*2)/float64(len(blob)))
+	}
+
+	ramp := make([]uint16, 17*5)
+	for i := range ramp {
+		ramp[i] = uint16(i * 771)
+	}
+	for _, tc := range []struct {
+		name          string
+		pixels        []uint16
+		width, height int
+	}{
+		{"flat", make([]uint16, 64*64), 64, 64},
+		{"single", []uint16{42}, 1, 1},
+		{"row", []uint16{1, 5, 2, 9, 9, 0}, 6, 1},
+		{"column", []uint16{1, 5, 2, 9, 9, 0}, 1, 6},
+		{"binary", []uint16{0, 1, 1, 0, 1, 0, 0, 1, 1}, 3, 3},
+		{"ramp16", ramp, 17, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			blob, err := CompressArchive(tc.pixels, tc.width, tc.height, 0)
+			if err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			got, _, _, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			assertPixelsEqual(t, tc.pixels, got, tc.name)
+		})
+	}
+
+	pixels, _ := fuzzPixels()
+	blob, err := CompressArchive(pixels, fuzzWidth, fuzzHeight, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]uint16, fuzzWidth*fuzzHeight)
+	if _, _, err := DecompressInto(dst, blob); err != nil {
+		t.Fatal(err)
+	}
+	assertPixelsEqual(t, pixels, dst, "DecompressInto")
+	if _, _, _, err := Decompress(blob[:len(blob)-8]); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+	if _, err := CompressArchive(pixels, fuzzWidth, fuzzHeight, 1); err == nil {
+		t.Error("expected error for pixels above max value")
+	}
+}
+
+// jplsRatios are the CharLS ratios recorded by TestJPEGLSComparison in
+// ojph/ (docs/jpegls-comparison.md), which needs cgo and CharLS to rerun.
+var jplsRatios = map[string]float64{
+	"MR": 2.38, "CT": 2.31, "CR": 3.63, "XR": 1.73,
+	"MG1": 8.69, "MG2": 8.68, "MG3": 2.36, "MG4": 3.42,
+}
+
+// TestArchiveComparisonTable prints the archive pipeline's ratio against
+// MIC, context-bucketed FSE and the recorded JPEG-LS ratios.
+func TestArchiveComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== Compression ratio: archive pipeline vs JPEG-LS ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %7s  %7s  %7s  %7s  %8s\n", "Image", "MIC", "CTX-8", "Archive", "JPEG-LS", "vs JPLS")
+	fmt.Println("------  -------  -------  -------  -------  --------")
+
+	logSum, logJPLS, n := 0.0, 0.0, 0
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		if len(pixels) == 0 {
+			continue
+		}
+		orig := float64(width * height * 2)
+
+		mic, err := CompressSingleFrame(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: MIC: %v", td.name, err)
+		}
+		ctx, err := CompressContextFSE(pixels, width, height, maxVal, 0)
+		if err != nil {
+			t.Fatalf("%s: context FSE: %v", td.name, err)
+		}
+		archive, err := CompressArchive(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: archive: %v", td.name, err)
+		}
+		ratio := orig / float64(len(archive))
+		line := fmt.Sprintf("%-6s  %6.2fx  %6.2fx  %6.2fx", td.name,
+			orig/float64(len(mic)), orig/float64(len(ctx)), ratio)
+		if jpls, ok := jplsRatios[td.name]; ok {
+			line += fmt.Sprintf("  %6.2fx  %+7.1f%%", jpls, 100*(ratio/jpls-1))
+			logSum += math.Log(ratio)
+			logJPLS += math.Log(jpls)
+			n++
+		}
+		fmt.Println(line)
+	}
+	if n > 0 {
+		a, j := math.Exp(logSum/float64(n)), math.Exp(logJPLS/float64(n))
+		fmt.Printf("%-6s  %7s  %7s  %6.2fx  %6.2fx  %+7.1f%%\n", "geo", "", "", a, j, 100*(a/j-1))
+	}
+	fmt.Println()
+}
+
+func BenchmarkArchiveDecompress(b *testing.B) {
+	_, pixels, maxVal, width, height := SetupTests(testFiles[1]) // CT
+	blob, err := CompressArchive(pixels, width, height, maxVal)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dst := make([]uint16, width*height)
+	b.SetBytes(int64(width * height * 2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := DecompressInto(dst, blob); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
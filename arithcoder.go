@@ -0,0 +1,354 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Adaptive binary arithmetic coding of uint16 symbols.
+//
+// FSE and rANS code every symbol with one static table written up front.
+// ArithCompressU16 trades speed for ratio: it binarises each symbol and
+// codes the bits with an LZMA-style range coder whose probabilities adapt as
+// the stream is coded, so no table is stored and the statistics can drift
+// across the image.
+//
+// A symbol z is coded as its bit length k = bits.Len16(z) in unary, then
+// the k-1 bits below its leading one. The unary bits and the two leading
+// mantissa bits are conditioned on the error energy of the neighbouring
+// symbols, as CALIC conditions its residuals:
+//
+//	E = 2*W + 2*N + NW + NE
+//
+// where N, NW and NE are the symbols one row (stride) back. Without a stride
+// they are the three symbols before W. E is quantised logarithmically into
+// arithEnergyContexts buckets. The lowest bit, which carries the sign of a
+// ZigZag residual, is conditioned on the parity of W and N. Every
+// probability is the average of a fast- and a slow-adapting estimate.
+//
+// Output format: [0xFF][0x10][count uint32 LE][stride uint32 LE][range-coded bytes]
+//
+// FSEDecompressU16Auto recognises the magic.
+
+const (
+	arithMagic0 = 0xFF
+	arithMagic1 = 0x10
+
+	arithHeaderSize = 10 // magic + count + stride
+
+	arithEnergyContexts = 40 // 2 per octave of E, which stays below 2^20
+	arithSignContexts   = 9  // zero / odd / even for W and N
+	arithMaxLen         = 16 // bits.Len16 of the largest symbol
+
+	arithProbBits  = 16
+	arithFastShift = 4
+	arithSlowShift = 7
+	arithTop       = 1 << 24
+
+	// arithMaxSymbolsPerByte bounds the symbols one byte of range-coded
+	// data can hold. The probability estimates saturate at 65465/65536, so
+	// every binary decision costs at least 1/5116 of a byte, and every
+	// symbol takes at least one.
+	arithMaxSymbolsPerByte = 5120
+)
+
+// arithProb estimates the probability of a 0 bit, scaled to 1<<arithProbBits.
+type arithProb struct {
+	fast, slow uint16
+}
+
+func (p *arithProb) p0() uint32 {
+	return (uint32(p.fast) + uint32(p.slow)) >> 1
+}
+
+func (p *arithProb) update(bit uint32) {
+	if bit == 0 {
+		p.fast += uint16((1<<arithProbBits - uint32(p.fast)) >> arithFastShift)
+		p.slow += uint16((1<<arithProbBits - uint32(p.slow)) >> arithSlowShift)
+	} else {
+		p.fast -= p.fast >> arithFastShift
+		p.slow -= p.slow >> arithSlowShift
+	}
+}
+
+// arithModel holds every adaptive probability of one stream.
+type arithModel struct {
+	prefix [arithEnergyContexts][arithMaxLen]arithProb
+	mant   [arithEnergyContexts][arithMaxLen + 1][4]arithProb // two-level bit tree
+	low    [arithMaxLen + 1][arithMaxLen]arithProb
+	sign   [arithMaxLen + 1][arithSignContexts]arithProb
+}
+
+func newArithModel() *arithModel {
+	m := new(arithModel)
+	half := arithProb{1 << (arithProbBits - 1), 1 << (arithProbBits - 1)}
+	for i := range m.prefix {
+		for j := range m.prefix[i] {
+			m.prefix[i][j] = half
+		}
+		for j := range m.mant[i] {
+			for n := range m.mant[i][j] {
+				m.mant[i][j][n] = half
+			}
+		}
+	}
+	for k := range m.low {
+		for j := range m.low[k] {
+			m.low[k][j] = half
+		}
+		for j := range m.sign[k] {
+			m.sign[k][j] = half
+		}
+	}
+	return m
+}
+
+// arithContexts returns the energy and sign contexts of symbol i of syms,
+// whose predecessors are final.
+func arithContexts(syms []uint16, i, stride int) (energy, sign int) {
+	at := func(j int) uint32 {
+		if j < 0 || j >= i {
+			return 0
+		}
+		return uint32(syms[j])
+	}
+	var w, n, nw, ne uint32
+	if stride > 0 {
+		w, n, nw, ne = at(i-1), at(i-stride), at(i-stride-1), at(i-stride+1)
+	} else {
+		w, n, nw, ne = at(i-1), at(i-2), at(i-3), at(i-4)
+	}
+
+	if e := 2*w + 2*n + nw + ne; e != 0 {
+		l := bits.Len32(e)
+		energy = 2*l - 1
+		if l >= 2 {
+			energy += int(e>>(l-2)) & 1
+		}
+		energy = min(energy, arithEnergyContexts-1)
+	}
+
+	parity := func(v uint32) int {
+		if v == 0 {
+			return 0
+		}
+		return 1 + int(v&1)
+	}
+	return energy, 3*parity(w) + parity(n)
+}
+
+// arithEncoder is an LZMA-style range encoder with carry propagation.
+type arithEncoder struct {
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize int
+	out       []byte
+}
+
+func (e *arithEncoder) init(out []byte) {
+	e.low, e.rng, e.cache, e.cacheSize, e.out = 0, 0xFFFFFFFF, 0, 1, out
+}
+
+func (e *arithEncoder) encode(p *arithProb, bit uint32) {
+	bound := (e.rng >> arithProbBits) * p.p0()
+	if bit == 0 {
+		e.rng = bound
+	} else {
+		e.low += uint64(bound)
+		e.rng -= bound
+	}
+	p.update(bit)
+	for e.rng < arithTop {
+		e.rng <<= 8
+		e.shiftLow()
+	}
+}
+
+func (e *arithEncoder) shiftLow() {
+	if uint32(e.low) < 0xFF000000 || e.low>>32 != 0 {
+		carry := byte(e.low >> 32)
+		temp := e.cache
+		for {
+			e.out = append(e.out, temp+carry)
+			temp = 0xFF
+			e.cacheSize--
+			if e.cacheSize == 0 {
+				break
+			}
+		}
+		e.cache = byte(e.low >> 24)
+	}
+	e.cacheSize++
+	e.low = (e.low & 0x00FFFFFF) << 8
+}
+
+func (e *arithEncoder) flush() []byte {
+	for range 5 {
+		e.shiftLow()
+	}
+	return e.out
+}
+
+// arithDecoder reverses arithEncoder.
+type arithDecoder struct {
+	code, rng uint32
+	in        []byte
+	pos       int
+	overrun   bool
+}
+
+func (d *arithDecoder) init(in []byte) {
+	d.in, d.pos, d.rng, d.code = in, 0, 0xFFFFFFFF, 0
+	for range 5 {
+		d.code = d.code<<8 | uint32(d.next())
+	}
+}
+
+func (d *arithDecoder) next() byte {
+	if d.pos >= len(d.in) {
+		d.overrun = true
+		return 0
+	}
+	b := d.in[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *arithDecoder) decode(p *arithProb) uint32 {
+	bound := (d.rng >> arithProbBits) * p.p0()
+	var bit uint32
+	if d.code < bound {
+		d.rng = bound
+	} else {
+		d.code -= bound
+		d.rng -= bound
+		bit = 1
+	}
+	p.update(bit)
+	for d.rng < arithTop {
+		d.rng <<= 8
+		d.code = d.code<<8 | uint32(d.next())
+	}
+	return bit
+}
+
+// ArithCompressU16 codes in with the adaptive binary arithmetic coder.
+// stride is the row length when in holds one symbol per pixel in raster
+// order, so that the contexts see the symbols above; 0 uses only the
+// preceding symbols.
+func ArithCompressU16(in []uint16, stride int) ([]byte, error) {
+	if stride < 0 || uint64(stride) > 1<<32-1 {
+		return nil, fmt.Errorf("arith: invalid stride %d", stride)
+	}
+	if uint64(len(in)) > 1<<32-1 {
+		return nil, fmt.Errorf("arith: %d symbols exceed the stream limit", len(in))
+	}
+	out := make([]byte, arithHeaderSize, arithHeaderSize+len(in))
+	out[0], out[1] = arithMagic0, arithMagic1
+	binary.LittleEndian.PutUint32(out[2:6], uint32(len(in)))
+	binary.LittleEndian.PutUint32(out[6:10], uint32(stride))
+
+	m := newArithModel()
+	var e arithEncoder
+	e.init(out)
+	for i, z := range in {
+		energy, sign := arithContexts(in, i, stride)
+		k := bits.Len16(z)
+		for j := 0; j < arithMaxLen; j++ {
+			if k > j {
+				e.encode(&m.prefix[energy][j], 1)
+				continue
+			}
+			e.encode(&m.prefix[energy][j], 0)
+			break
+		}
+		if k < 2 {
+			continue
+		}
+		nb := k - 1
+		node := 1
+		for j := nb - 1; j >= 0; j-- {
+			bit := uint32(z>>j) & 1
+			switch {
+			case j >= nb-2:
+				e.encode(&m.mant[energy][k][node], bit)
+				node = node<<1 | int(bit)
+			case j == 0:
+				e.encode(&m.sign[k][sign], bit)
+			default:
+				e.encode(&m.low[k][j], bit)
+			}
+		}
+	}
+	return e.flush(), nil
+}
+
+// ArithDecompressU16 decompresses a stream produced by ArithCompressU16. It
+// honours s.DecompressLimit when s is non-nil.
+func ArithDecompressU16(b []byte, s *ScratchU16) ([]uint16, error) {
+	if len(b) < arithHeaderSize || b[0] != arithMagic0 || b[1] != arithMagic1 {
+		return nil, errors.New("arith: missing magic bytes")
+	}
+	count := int(binary.LittleEndian.Uint32(b[2:6]))
+	stride := int(binary.LittleEndian.Uint32(b[6:10]))
+	s, err := s.prepare(nil, b)
+	if err != nil {
+		return nil, err
+	}
+	if count > s.DecompressLimit {
+		return nil, fmt.Errorf("output size (%d) > DecompressLimit (%d)", count, s.DecompressLimit)
+	}
+	if uint64(count) > uint64(len(b)-arithHeaderSize)*arithMaxSymbolsPerByte {
+		return nil, fmt.Errorf("arith: %d symbols too many for %d bytes of input", count, len(b)-arithHeaderSize)
+	}
+
+	out := make([]uint16, count)
+	m := newArithModel()
+	var d arithDecoder
+	d.init(b[arithHeaderSize:])
+	for i := range out {
+		energy, sign := arithContexts(out, i, stride)
+		k := 0
+		for k < arithMaxLen && d.decode(&m.prefix[energy][k]) == 1 {
+			k++
+		}
+		if d.overrun {
+			return nil, errors.New("arith: stream truncated")
+		}
+		if k < 2 {
+			out[i] = uint16(k)
+			continue
+		}
+		nb := k - 1
+		z := uint32(1)
+		node := 1
+		for j := nb - 1; j >= 0; j-- {
+			var bit uint32
+			switch {
+			case j >= nb-2:
+				bit = d.decode(&m.mant[energy][k][node])
+				node = node<<1 | int(bit)
+			case j == 0:
+				bit = d.decode(&m.sign[k][sign])
+			default:
+				bit = d.decode(&m.low[k][j])
+			}
+			z = z<<1 | bit
+		}
+		out[i] = uint16(z)
+	}
+	if d.overrun {
+		return nil, errors.New("arith: stream truncated")
+	}
+	if s != nil {
+		s.OutU16 = out
+	}
+	return out, nil
+}
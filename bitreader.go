@@ -20,6 +20,7 @@ type bitReader struct {
 	off      uint // next byte to read is at in[off - 1]
 	value    uint64
 	bitsRead uint8
+	overread int // reads that wanted bits after the stream was exhausted
 }
 
 // init initializes and resets the bit reader.
@@ -36,6 +37,7 @@ func (b *bitReader) init(in []byte) error {
 	}
 	b.bitsRead = 64
 	b.value = 0
+	b.overread = 0
 	if len(in) >= 8 {
 		b.fillFastStart()
 	} else {
@@ -48,6 +50,9 @@ func (b *bitReader) init(in []byte) error {
 
 func (b *bitReader) getBits32(n uint8) uint32 {
 	if n == 0 || b.bitsRead >= 64 {
+		if n != 0 {
+			b.overread++
+		}
 		return 0
 	}
 	return b.getBitsFast32(n)
@@ -109,6 +114,14 @@ func (b *bitReader) finished() bool {
 	return b.bitsRead >= 64 && b.off == 0
 }
 
+// exhausted reports whether more than states reads have asked for bits past
+// the end of the stream. A multi-state decoder legitimately does so once per
+// state, for the final transition of each state; anything beyond that means
+// the symbol count in the header does not match the stream.
+func (b *bitReader) exhausted(states int) bool {
+	return b.overread > states
+}
+
 // close the bitstream and returns an error if out-of-buffer reads occurred.
 func (b *bitReader) close() error {
 	// Release reference.
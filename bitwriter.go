@@ -14,6 +14,7 @@ type bitWriter struct {
 	bitContainer uint64
 	nBits        uint8
 	out          []byte
+	err          error // sticky error from flush, reported by close
 }
 
 // bitMask16 is bitmasks. Has extra to avoid bounds check.
@@ -127,7 +128,10 @@ func (b *bitWriter) flush() {
 			byte(b.bitContainer>>56),
 		)
 	default:
-		panic(fmt.Errorf("bits (%d) > 64", b.nBits))
+		if b.err == nil {
+			b.err = fmt.Errorf("bits (%d) > 64", b.nBits)
+		}
+		v = 8
 	}
 	b.bitContainer >>= v << 3
 	b.nBits &= 7
@@ -158,13 +162,13 @@ func (b *bitWriter) flushAlign() {
 }
 
 // close will write the alignment bit and write the final byte(s)
-// to the output.
+// to the output. It returns any error recorded while writing.
 func (b *bitWriter) close() error {
 	// End mark
 	b.addBits16Clean(1, 1)
 	// flush until next byte.
 	b.flushAlign()
-	return nil
+	return b.err
 }
 
 // reset and continue writing by appending to out.
@@ -172,4 +176,5 @@ func (b *bitWriter) reset(out []byte) {
 	b.bitContainer = 0
 	b.nBits = 0
 	b.out = out
+	b.err = nil
 }
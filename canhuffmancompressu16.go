@@ -4,6 +4,7 @@
 package mic
 
 import (
+	"errors"
 	"math/bits"
 	"sort"
 )
@@ -35,6 +36,10 @@ type CanHuffmanCompressU16 struct {
 	allSymbols                     []SymbolLenDelimiter
 	delimiterCode                  uint32
 	delimiterCodeLength            uint32
+
+	// codeLengthLimit caps the code length OptimizeSymbolCount allows before
+	// the delimiter is added; 0 means 14.
+	codeLengthLimit uint8
 }
 
 type SymbolLenDelimiter struct {
@@ -49,7 +54,7 @@ func (c *CanHuffmanCompressU16) Init(in []uint16) {
 	c.bw.reset(c.Out)
 }
 
-func (c *CanHuffmanCompressU16) Compress() {
+func (c *CanHuffmanCompressU16) Compress() error {
 	c.GenerateFrequencies()
 	c.OptimizeSymbolCount()
 	c.AddDelimiterToSymbolList()
@@ -59,7 +64,7 @@ func (c *CanHuffmanCompressU16) Compress() {
 	c.GenerateAllSymbolTable()
 
 	if c.pixelDepth+c.maxCodeLength > 32 {
-		panic("PixelLength + MaxCodelength is greater than 32 bits")
+		return errors.New("huffman: pixel depth + max code length is greater than 32 bits")
 	}
 
 	for i := 0; i < len(c.in); i++ {
@@ -78,6 +83,7 @@ func (c *CanHuffmanCompressU16) Compress() {
 	c.bw.addBits32(0, uint8(c.maxCodeLength+c.pixelDepth))
 	c.bw.flushAlign()
 	c.Out = c.bw.out
+	return nil
 }
 
 func (c *CanHuffmanCompressU16) GenerateAllSymbolTable() {
@@ -166,9 +172,14 @@ func (c *CanHuffmanCompressU16) GenerateFrequencies() {
 }
 
 func (c *CanHuffmanCompressU16) OptimizeSymbolCount() {
-	// Binary search for the maximum number of symbols that yield a max code length <= 14.
+	// Binary search for the maximum number of symbols that yield a max code length <= 14
+	// (or codeLengthLimit).
 	// As we increase the symbol count the code length is monotonically non-decreasing,
 	// so binary search finds the upper bound in O(log N) probes instead of O(N).
+	limit := uint32(14)
+	if c.codeLengthLimit != 0 {
+		limit = uint32(c.codeLengthLimit)
+	}
 	n := len(c.symbolsOfInterestList)
 	lo, hi := 0, n
 	for lo < hi {
@@ -176,7 +187,7 @@ func (c *CanHuffmanCompressU16) OptimizeSymbolCount() {
 		tempList := make([]SymbFreq, mid)
 		copy(tempList, c.symbolsOfInterestList)
 		codeLen := c.CalculateCodeLengthForGivenSlice(tempList)
-		if codeLen <= 14 {
+		if codeLen <= limit {
 			lo = mid
 		} else {
 			hi = mid - 1
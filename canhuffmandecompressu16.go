@@ -4,9 +4,26 @@
 package mic
 
 import (
+	"errors"
+	"fmt"
 	"math/bits"
 )
 
+const (
+	// huffMaxCodeLength bounds the code length accepted from a stream header;
+	// the encoder never exceeds 15 and the lookup table has 1<<maxCodeLength
+	// entries.
+	huffMaxCodeLength = 16
+
+	// huffMinHeaderSize is the size of the fixed part of the table header:
+	// length (32 bits), maxValue (16), max code length (8) and symbol count (16).
+	huffMinHeaderSize = 9
+
+	// maxHuffmanSamples caps the decompressed length of a single stream so a
+	// corrupt header cannot request an unbounded allocation.
+	maxHuffmanSamples = 1 << 28
+)
+
 type CanHuffmanDecompressU16 struct {
 	c                           CanHuffmanCompressU16
 	in                          []byte
@@ -33,27 +50,67 @@ func (d *CanHuffmanDecompressU16) Init(in []byte) {
 	d.br.initFwd(d.in)
 }
 
-func (d *CanHuffmanDecompressU16) ReadTable() {
-	decompLength := d.br.getBits32NFillFwd(32)
+// ReadTable parses the stream header and builds the decoding table. It
+// returns an error if the header is truncated or describes an invalid code.
+func (d *CanHuffmanDecompressU16) ReadTable() error {
+	decompLength, err := d.readTable()
+	if err != nil {
+		return err
+	}
 	d.Out = make([]uint16, decompLength)
+	return nil
+}
+
+// readTable is ReadTable without allocating Out; it returns the number of
+// samples the header declares.
+func (d *CanHuffmanDecompressU16) readTable() (uint32, error) {
+	if len(d.in) < huffMinHeaderSize {
+		return 0, errors.New("huffman: input too small")
+	}
+	decompLength := d.br.getBits32NFillFwd(32)
 	d.c.maxValue = d.br.getBitsNFillFwd(16)
 	d.c.pixelDepth = uint8(bits.Len16(d.c.maxValue))
 	d.pixelDepthMask = 0xffffffff >> (32 - d.c.pixelDepth)
 	d.c.delimiterForCompressDecompress = uint16((1 << (d.c.pixelDepth)) - 1)
 
 	d.c.maxCodeLength = uint8(d.br.getBitsNFillFwd(8))
+	if d.c.maxCodeLength > huffMaxCodeLength {
+		return 0, fmt.Errorf("huffman: max code length (%d) > %d", d.c.maxCodeLength, huffMaxCodeLength)
+	}
 	maxCodeLenBits := bits.Len8(d.c.maxCodeLength)
 	d.maxCodeLengthMask = 0xffffffff >> (32 - d.c.maxCodeLength)
 	d.maxCodeLengthAndPixelDepth = uint8(d.c.maxCodeLength) + uint8(d.c.pixelDepth)
 	d.maxCodePlusPixelDepthMask = 0xffffffff >> (32 - d.maxCodeLengthAndPixelDepth)
 
 	numOfSymbolsOfInterest := d.br.getBitsNFillFwd(16)
+	if numOfSymbolsOfInterest == 0 {
+		return 0, errors.New("huffman: empty symbol table")
+	}
+	// The symbol list and code lengths must fit in what remains of the input.
+	tableBits := uint64(numOfSymbolsOfInterest) * uint64(uint(d.c.pixelDepth)+uint(maxCodeLenBits))
+	if tableBits > 8*uint64(len(d.in)) {
+		return 0, errors.New("huffman: symbol table extends beyond input")
+	}
 	d.c.symbolsOfInterestList = make([]SymbFreq, numOfSymbolsOfInterest)
 	for i := uint16(0); i < numOfSymbolsOfInterest; i++ {
 		d.c.symbolsOfInterestList[i].symbol = d.br.getBitsNFillFwd(uint8(d.c.pixelDepth))
 	}
+	minCodeLength := uint32(d.c.maxCodeLength)
 	for i := uint16(0); i < numOfSymbolsOfInterest; i++ {
-		d.c.symbolsOfInterestList[i].freq = d.br.getBits32NFillFwd(uint8(maxCodeLenBits))
+		codeLen := d.br.getBits32NFillFwd(uint8(maxCodeLenBits))
+		if codeLen > uint32(d.c.maxCodeLength) || (codeLen == 0 && numOfSymbolsOfInterest > 1) {
+			return 0, fmt.Errorf("huffman: invalid code length %d for symbol %d", codeLen, i)
+		}
+		d.c.symbolsOfInterestList[i].freq = codeLen
+		if codeLen < minCodeLength {
+			minCodeLength = codeLen
+		}
+	}
+
+	// Every code except the degenerate single-symbol one consumes at least
+	// one bit, which bounds how many samples the input can hold.
+	if decompLength > maxHuffmanSamples || (minCodeLength > 0 && uint64(decompLength) > 8*uint64(len(d.in))) {
+		return 0, fmt.Errorf("huffman: decompressed length (%d) too large for input", decompLength)
 	}
 
 	d.c.CalculateSymbolsPerCodeLength()
@@ -65,8 +122,12 @@ func (d *CanHuffmanDecompressU16) ReadTable() {
 	// Populate the code to symbol table
 	for j := uint16(0); j < numOfSymbolsOfInterest; j++ {
 		symbLen := d.c.symbolsOfInterestList[j]
+		span := uint32(1) << (d.c.maxCodeLength - uint8(symbLen.freq))
 		leftShitedCode := d.c.canHuffmanTable[j] << (d.c.maxCodeLength - uint8(symbLen.freq))
-		for i := uint32(0); i < 1<<(d.c.maxCodeLength-uint8(symbLen.freq)); i++ {
+		if uint64(leftShitedCode)+uint64(span) > uint64(len(d.codeToSymbolTable)) {
+			return 0, errors.New("huffman: code lengths do not form a prefix code")
+		}
+		for i := uint32(0); i < span; i++ {
 			d.codeToSymbolTable[leftShitedCode+i].symbol = symbLen.symbol
 			d.codeToSymbolTable[leftShitedCode+i].codeLen = uint8(symbLen.freq)
 			d.codeToSymbolTable[leftShitedCode+i].isDelimiter = symbLen.symbol == d.c.delimiterForCompressDecompress
@@ -76,20 +137,26 @@ func (d *CanHuffmanDecompressU16) ReadTable() {
 		}
 	}
 
+	return decompLength, nil
 }
 
-func (d *CanHuffmanDecompressU16) DecompressInit() {
+func (d *CanHuffmanDecompressU16) DecompressInit() error {
 	if d.c.pixelDepth+d.c.maxCodeLength > 32 {
-		panic("PixelLength + MaxCodelength is creater than 32 bits")
+		return errors.New("huffman: pixel depth + max code length is greater than 32 bits")
 	}
 	d.maxCodeLengthBits = d.br.getBits32NFillFwd(d.maxCodeLengthAndPixelDepth)
+	return nil
 }
 
-func (d *CanHuffmanDecompressU16) Decompress() {
-	d.DecompressInit()
+func (d *CanHuffmanDecompressU16) Decompress() error {
+	if err := d.DecompressInit(); err != nil {
+		return err
+	}
 
+	// The fast path refills 4 bytes at a time without bounds checks; four
+	// symbols consume at most 16 bytes, so stop it that far from the end.
 	outCounter := 0
-	for outCounter < (len(d.Out) - 4) {
+	for outCounter < (len(d.Out)-4) && d.br.fwd+16 <= d.br.inLen {
 		d.Out[outCounter] = d.DecodeNextFast()
 		outCounter++
 		d.Out[outCounter] = d.DecodeNextFast()
@@ -104,7 +171,7 @@ func (d *CanHuffmanDecompressU16) Decompress() {
 		d.Out[outCounter] = d.DecodeNext()
 		outCounter++
 	}
-
+	return nil
 }
 
 func (d *CanHuffmanDecompressU16) DecodeNextFast() uint16 {
@@ -0,0 +1,210 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// Integrity checksums for MIC2, MIC3 and PICS containers.
+//
+// Each container has an optional checksummed revision that stores a CRC32C
+// (Castagnoli) of every compressed unit — frame, tile or strip — next to its
+// offset-table entry, plus a whole-file CRC32C computed with the checksum
+// field itself taken as zero. The revisions are chosen so that readers
+// predating them reject the file instead of misparsing it:
+//
+//	MIC2 → magic "MC2C", 24-byte header, 12-byte frame entries
+//	PICS → magic "PICC", 24-byte header, 12-byte strip entries
+//	PICP → flags bit0, 12-byte strip entries
+//	MIC3 → version 2, 24-byte tile entries
+//
+// Checksummed files are produced by setting MIC2Header.Checksums or
+// WSIOptions.Checksums, or by converting an existing file with AddChecksums.
+// ExtractFrame, ExtractTileBlob and DecompressParallelStrips verify the
+// per-unit checksums; VerifyChecksums additionally checks the whole file.
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// crc32c returns the CRC32C (Castagnoli) checksum of b.
+func crc32c(b []byte) uint32 {
+	return crc32.Checksum(b, crc32cTable)
+}
+
+// fileCRC32C returns the CRC32C of data with the four bytes at crcOff
+// treated as zero.
+func fileCRC32C(data []byte, crcOff int) uint32 {
+	var zero [4]byte
+	crc := crc32.Update(0, crc32cTable, data[:crcOff])
+	crc = crc32.Update(crc, crc32cTable, zero[:])
+	return crc32.Update(crc, crc32cTable, data[crcOff+4:])
+}
+
+// ErrChecksumMismatch is returned when a stored CRC32C does not match the
+// data it covers. Use errors.As to recover which unit failed.
+type ErrChecksumMismatch struct {
+	Container string // "MIC2", "MIC3" or "PICS"
+	Unit      string // "frame", "tile", "strip" or "file"
+	Index     int    // unit index; -1 for the whole file
+	Stored    uint32
+	Computed  uint32
+}
+
+func (e *ErrChecksumMismatch) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: %s checksum mismatch (stored %08x, computed %08x)",
+			e.Container, e.Unit, e.Stored, e.Computed)
+	}
+	return fmt.Sprintf("%s: %s %d checksum mismatch (stored %08x, computed %08x)",
+		e.Container, e.Unit, e.Index, e.Stored, e.Computed)
+}
+
+// verifyUnit checks blob against its stored checksum.
+func verifyUnit(container, unit string, idx int, blob []byte, stored uint32) error {
+	if got := crc32c(blob); got != stored {
+		return &ErrChecksumMismatch{Container: container, Unit: unit, Index: idx, Stored: stored, Computed: got}
+	}
+	return nil
+}
+
+// verifyFile checks the whole-file checksum stored at crcOff.
+func verifyFile(container string, data []byte, crcOff int, stored uint32) error {
+	if got := fileCRC32C(data, crcOff); got != stored {
+		return &ErrChecksumMismatch{Container: container, Unit: "file", Index: -1, Stored: stored, Computed: got}
+	}
+	return nil
+}
+
+// VerifyChecksums checks the whole-file checksum and every per-unit checksum
+// of a checksummed MIC2, MIC3 or PICS container. It returns an
+// *ErrChecksumMismatch for the first failure, and an error if data is not a
+// checksummed container.
+func VerifyChecksums(data []byte) error {
+	if len(data) < 4 {
+		return ErrUnknownFormat
+	}
+	switch string(data[0:4]) {
+	case mic2CRCMagic:
+		_, entries, dataOffset, err := ReadMIC2Header(data)
+		if err != nil {
+			return err
+		}
+		if err := verifyFile(mic2Magic, data, mic2CRCFileOff, binary.LittleEndian.Uint32(data[mic2CRCFileOff:])); err != nil {
+			return err
+		}
+		for i := range entries {
+			if _, err := ExtractFrame(data, entries, dataOffset, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case mic3Magic:
+		hdr, entries, dataOffset, err := ReadMIC3Header(data)
+		if err != nil {
+			return err
+		}
+		if !hdr.Checksums {
+			return fmt.Errorf("MIC3: file has no checksums")
+		}
+		if err := verifyFile(mic3Magic, data, mic3CRCFileOff, binary.LittleEndian.Uint32(data[mic3CRCFileOff:])); err != nil {
+			return err
+		}
+		for i := range entries {
+			if _, err := ExtractTileBlob(data, entries, dataOffset, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case picsCRCMagic, picpMagic:
+		p, err := readPICSHeader(data)
+		if err != nil {
+			return err
+		}
+		if !p.checksums {
+			return fmt.Errorf("%s: file has no checksums", data[0:4])
+		}
+		if err := verifyFile(picsMagic, data, p.crcOff, binary.LittleEndian.Uint32(data[p.crcOff:])); err != nil {
+			return err
+		}
+		for i := range p.strips {
+			if _, err := p.stripBlob(data, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case mic2Magic, mic2SignedMagic, picsMagic:
+		return fmt.Errorf("%s: file has no checksums", data[0:4])
+	}
+	return ErrUnknownFormat
+}
+
+// AddChecksums rewrites a MIC2, MIC3 or PICS container as its checksummed
+// revision. The compressed units are copied unchanged, so existing archives
+// can be upgraded without re-encoding. Files that already carry checksums
+// are returned as-is.
+func AddChecksums(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrUnknownFormat
+	}
+	var buf bytes.Buffer
+	switch string(data[0:4]) {
+	case mic2CRCMagic, picsCRCMagic:
+		return data, nil
+	case mic2Magic, mic2SignedMagic:
+		hdr, entries, dataOffset, err := ReadMIC2Header(data)
+		if err != nil {
+			return nil, err
+		}
+		frames := make([][]byte, len(entries))
+		for i := range entries {
+			if frames[i], err = ExtractFrame(data, entries, dataOffset, i); err != nil {
+				return nil, err
+			}
+		}
+		hdr.Checksums = true
+		if err := WriteMIC2(&buf, hdr, frames); err != nil {
+			return nil, err
+		}
+	case mic3Magic:
+		hdr, entries, dataOffset, err := ReadMIC3Header(data)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Checksums {
+			return data, nil
+		}
+		tiles := make([][]byte, len(entries))
+		for i := range entries {
+			if tiles[i], err = ExtractTileBlob(data, entries, dataOffset, i); err != nil {
+				return nil, err
+			}
+		}
+		hdr.Checksums = true
+		if err := WriteMIC3(&buf, hdr, tiles); err != nil {
+			return nil, err
+		}
+	case picsMagic, picpMagic:
+		p, err := readPICSHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		if p.checksums {
+			return data, nil
+		}
+		strips := make([][]byte, len(p.strips))
+		for i := range p.strips {
+			if strips[i], err = p.stripBlob(data, i); err != nil {
+				return nil, err
+			}
+		}
+		return buildPICS(strips, p.width, p.height, p.stripH, p.predictor, true), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"errors"
+	"testing"
+)
+
+// checksummedFixtures returns one checksummed container of each kind, keyed
+// by container name.
+func checksummedFixtures(t *testing.T) map[string][]byte {
+	t.Helper()
+	width, height := 96, 64
+	frames, maxValue := makeSmoothFrames(width, height, 3, 21)
+
+	mic2, err := CompressMultiFrame(frames, width, height, maxValue, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pics, err := CompressParallelStrips(frames[0], width, height, maxValue, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgb := makeWSITestImage(300, 200, 8)
+	mic3, err := CompressWSI(rgb, 300, 200, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128, Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := map[string][]byte{"MIC3": mic3}
+	for name, data := range map[string][]byte{"MIC2": mic2, "PICS": pics} {
+		if err := VerifyChecksums(data); err == nil {
+			t.Fatalf("%s: expected error verifying a file without checksums", name)
+		}
+		c, err := AddChecksums(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		out[name] = c
+	}
+	return out
+}
+
+func TestChecksumRoundTrip(t *testing.T) {
+	for name, data := range checksummedFixtures(t) {
+		if err := VerifyChecksums(data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		img, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if img.Format != name {
+			t.Fatalf("%s: decoded as %s", name, img.Format)
+		}
+		again, err := AddChecksums(data)
+		if err != nil || len(again) != len(data) {
+			t.Fatalf("%s: AddChecksums on a checksummed file: %v", name, err)
+		}
+	}
+
+	// Checksummed revisions decode to the same pixels as the originals.
+	width, height := 96, 64
+	frames, maxValue := makeSmoothFrames(width, height, 3, 21)
+	plain, _ := CompressMultiFrame(frames, width, height, maxValue, true)
+	checked, err := AddChecksums(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, hdr, err := DecompressMultiFrame(checked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hdr.Checksums || !hdr.Temporal {
+		t.Fatalf("unexpected header %+v", hdr)
+	}
+	for i := range frames {
+		assertPixelsEqual(t, frames[i], got[i], "frame "+itoa(i))
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	fixtures := checksummedFixtures(t)
+
+	cases := []struct {
+		container, unit string
+		decode          func([]byte) error
+		unitStart       func([]byte) int
+	}{
+		{"MIC2", "frame", func(d []byte) error { _, _, err := DecompressFrame(d, 1); return err },
+			func(d []byte) int {
+				_, entries, off, _ := ReadMIC2Header(d)
+				return off + int(entries[1].Offset)
+			}},
+		{"PICS", "strip", func(d []byte) error { _, _, _, err := DecompressParallelStrips(d); return err },
+			func(d []byte) int {
+				p, _ := readPICSHeader(d)
+				return p.dataOffset + p.strips[1].offset
+			}},
+		{"MIC3", "tile", func(d []byte) error { _, err := DecompressWSITile(d, 0, 1, 0); return err },
+			func(d []byte) int {
+				_, entries, off, _ := ReadMIC3Header(d)
+				return off + int(entries[1].Offset)
+			}},
+	}
+	for _, c := range cases {
+		t.Run(c.container, func(t *testing.T) {
+			data := append([]byte(nil), fixtures[c.container]...)
+			data[c.unitStart(data)+3] ^= 0x10
+
+			var mismatch *ErrChecksumMismatch
+			if err := c.decode(data); !errors.As(err, &mismatch) {
+				t.Fatalf("got %v, want *ErrChecksumMismatch", err)
+			}
+			if mismatch.Container != c.container || mismatch.Unit != c.unit || mismatch.Index != 1 {
+				t.Fatalf("mismatch reported for %s %s %d", mismatch.Container, mismatch.Unit, mismatch.Index)
+			}
+			if err := VerifyChecksums(data); !errors.As(err, &mismatch) {
+				t.Fatalf("VerifyChecksums: got %v, want *ErrChecksumMismatch", err)
+			}
+
+			// Header damage is caught by the whole-file checksum.
+			data = append([]byte(nil), fixtures[c.container]...)
+			data[18] ^= 0x01
+			if err := VerifyChecksums(data); !errors.As(err, &mismatch) || mismatch.Unit != "file" {
+				t.Fatalf("got %v, want whole-file mismatch", err)
+			}
+		})
+	}
+}
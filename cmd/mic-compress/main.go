@@ -23,49 +23,24 @@ import (
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
-// .mic container format (MIC1 - single frame):
-//
-//	Bytes 0-3:   Magic "MIC1" (0x4D 0x49 0x43 0x31)
-//	Bytes 4-7:   Width  (uint32 LE)
-//	Bytes 8-11:  Height (uint32 LE)
-//	Bytes 12-15: Pipeline type (uint32 LE): 1=Delta+RLE+FSE
-//	Bytes 16-19: Compressed data length (uint32 LE)
-//	Bytes 20+:   FSE compressed data
-func writeMicFile(filename string, width, height int, compressed []byte) error {
+// writeMicFile writes a MIC1 single-frame container file (see mic.WriteMIC1).
+func writeMicFile(filename string, width, height int, maxValue uint16, compressed []byte) error {
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	header := make([]byte, 20)
-	// Magic "MIC1"
-	header[0] = 'M'
-	header[1] = 'I'
-	header[2] = 'C'
-	header[3] = '1'
-	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
-	binary.LittleEndian.PutUint32(header[8:12], uint32(height))
-	binary.LittleEndian.PutUint32(header[12:16], 1) // pipeline: Delta+RLE+FSE
-	binary.LittleEndian.PutUint32(header[16:20], uint32(len(compressed)))
-
-	if _, err := f.Write(header); err != nil {
-		return err
+	hdr := mic.MIC1Header{
+		Width:    width,
+		Height:   height,
+		Pipeline: mic.PipelineDeltaRLEFSE,
+		MaxValue: maxValue,
 	}
-	if _, err := f.Write(compressed); err != nil {
-		return err
-	}
-	return nil
+	return mic.WriteMIC1(f, hdr, compressed)
 }
 
-// writeMICRFile writes a MICR single-frame RGB container file.
-//
-// MICR format:
-//
-//	Bytes 0-3:   Magic "MICR" (0x5243494D)
-//	Bytes 4-7:   Width  (uint32 LE)
-//	Bytes 8-11:  Height (uint32 LE)
-//	Bytes 12+:   CompressRGB blob ([Y_len][Co_len][Cg_len][Y_data][Co_data][Cg_data])
+// writeMICRFile writes a MICR single-frame RGB container file (see mic.WriteMICR).
 func writeMICRFile(filename string, width, height int, blob []byte) error {
 	f, err := os.Create(filename)
 	if err != nil {
@@ -73,21 +48,73 @@ func writeMICRFile(filename string, width, height int, blob []byte) error {
 	}
 	defer f.Close()
 
-	header := make([]byte, 12)
-	header[0] = 'M'
-	header[1] = 'I'
-	header[2] = 'C'
-	header[3] = 'R'
-	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
-	binary.LittleEndian.PutUint32(header[8:12], uint32(height))
+	hdr := mic.MICRHeader{
+		Width:    width,
+		Height:   height,
+		Pipeline: mic.PipelineDeltaRLEFSE,
+	}
+	return mic.WriteMICR(f, hdr, blob)
+}
 
-	if _, err := f.Write(header); err != nil {
-		return err
+// writeSignedMicFile compresses signed pixels and writes them as a MIC1
+// container with the signed flag and offset set. It returns the payload size.
+func writeSignedMicFile(filename string, pixels []int16, width, height int) (int, error) {
+	compressed, minValue, err := mic.CompressSingleFrameS16(pixels, width, height)
+	if err != nil {
+		return 0, err
 	}
-	if _, err := f.Write(blob); err != nil {
-		return err
+	var maxStored uint16
+	for _, v := range mic.S16ToU16(pixels, minValue) {
+		maxStored = max(maxStored, v)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	hdr := mic.MIC1Header{
+		Width:    width,
+		Height:   height,
+		Pipeline: mic.PipelineDeltaRLEFSE,
+		Signed:   true,
+		MinValue: minValue,
+		MaxValue: maxStored,
+	}
+	return len(compressed), mic.WriteMIC1(f, hdr, compressed)
+}
+
+// compressMultiFrame compresses DICOM frames as MIC2, using the signed
+// pipeline when the samples are signed (PixelRepresentation = 1).
+func compressMultiFrame(frames [][]uint16, width, height int, maxValue uint16, signed, temporal bool) ([]byte, error) {
+	if signed {
+		return mic.CompressMultiFrameS16(toS16(frames), width, height, temporal)
+	}
+	return mic.CompressMultiFrame(frames, width, height, maxValue, temporal)
+}
+
+// toS16 reinterprets raw 16-bit DICOM samples as two's complement int16.
+func toS16(frames [][]uint16) [][]int16 {
+	out := make([][]int16, len(frames))
+	for f, frame := range frames {
+		out[f] = make([]int16, len(frame))
+		for i, v := range frame {
+			out[f][i] = int16(v)
+		}
+	}
+	return out
+}
+
+// isSigned reports whether a dataset stores signed samples
+// (PixelRepresentation = 1).
+func isSigned(dataset dicom.Dataset) bool {
+	el, err := dataset.FindElementByTag(tag.PixelRepresentation)
+	if err != nil {
+		return false
 	}
-	return nil
+	v, ok := el.Value.GetValue().([]int)
+	return ok && len(v) > 0 && v[0] == 1
 }
 
 func compressImage(shortData []uint16, width, height int, maxValue uint16) ([]byte, error) {
@@ -102,37 +129,39 @@ func compressImage8State(shortData []uint16, width, height int, maxValue uint16)
 	return mic.CompressSingleFrame8State(shortData, width, height, maxValue)
 }
 
-// readDicomMultiFrame reads all frames from a multiframe DICOM file.
-func readDicomMultiFrame(fileName string) ([][]uint16, int, int, uint16, error) {
+// readDicomMultiFrame reads all frames from a multiframe DICOM file. Samples
+// are returned as raw 16-bit values; signed reports PixelRepresentation = 1,
+// in which case they are two's complement int16 and maxValue is meaningless.
+func readDicomMultiFrame(fileName string) (frames [][]uint16, width, height int, maxValue uint16, signed bool, err error) {
 	dataset, err := dicom.ParseFile(fileName, nil)
 	if err != nil {
-		return nil, 0, 0, 0, fmt.Errorf("parse DICOM: %w", err)
+		return nil, 0, 0, 0, false, fmt.Errorf("parse DICOM: %w", err)
 	}
+	signed = isSigned(dataset)
 
 	pixelDataElement, err := dataset.FindElementByTag(tag.PixelData)
 	if err != nil {
-		return nil, 0, 0, 0, fmt.Errorf("find pixel data: %w", err)
+		return nil, 0, 0, 0, false, fmt.Errorf("find pixel data: %w", err)
 	}
 
 	pixelDataInfo := dicom.MustGetPixelDataInfo(pixelDataElement.Value)
 	if len(pixelDataInfo.Frames) == 0 {
-		return nil, 0, 0, 0, fmt.Errorf("no frames in DICOM file")
+		return nil, 0, 0, 0, false, fmt.Errorf("no frames in DICOM file")
 	}
 
 	firstFrame, err := pixelDataInfo.Frames[0].GetNativeFrame()
 	if err != nil {
-		return nil, 0, 0, 0, fmt.Errorf("get first frame: %w", err)
+		return nil, 0, 0, 0, false, fmt.Errorf("get first frame: %w", err)
 	}
-	width := firstFrame.Cols
-	height := firstFrame.Rows
+	width = firstFrame.Cols
+	height = firstFrame.Rows
 
-	var maxValue uint16
-	frames := make([][]uint16, len(pixelDataInfo.Frames))
+	frames = make([][]uint16, len(pixelDataInfo.Frames))
 
 	for f, fr := range pixelDataInfo.Frames {
 		nativeFrame, err := fr.GetNativeFrame()
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("get frame %d: %w", f, err)
+			return nil, 0, 0, 0, false, fmt.Errorf("get frame %d: %w", f, err)
 		}
 		pixels := make([]uint16, width*height)
 		for j := 0; j < len(nativeFrame.Data); j++ {
@@ -145,15 +174,16 @@ func readDicomMultiFrame(fileName string) ([][]uint16, int, int, uint16, error)
 		frames[f] = pixels
 	}
 
-	return frames, width, height, maxValue, nil
+	return frames, width, height, maxValue, signed, nil
 }
 
 // readDicomSeries reads all single-frame DICOM files from a directory,
-// orders them by InstanceNumber, and returns the assembled frames.
-func readDicomSeries(seriesDir string) ([][]uint16, int, int, uint16, error) {
+// orders them by InstanceNumber, and returns the assembled frames. Samples
+// are returned as for readDicomMultiFrame.
+func readDicomSeries(seriesDir string) (frames [][]uint16, width, height int, maxValue uint16, signed bool, err error) {
 	entries, err := os.ReadDir(seriesDir)
 	if err != nil {
-		return nil, 0, 0, 0, fmt.Errorf("read directory: %w", err)
+		return nil, 0, 0, 0, false, fmt.Errorf("read directory: %w", err)
 	}
 
 	type dicomEntry struct {
@@ -169,51 +199,50 @@ func readDicomSeries(seriesDir string) ([][]uint16, int, int, uint16, error) {
 		fpath := filepath.Join(seriesDir, e.Name())
 		dataset, err := dicom.ParseFile(fpath, nil)
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("parse %s: %w", e.Name(), err)
+			return nil, 0, 0, 0, false, fmt.Errorf("parse %s: %w", e.Name(), err)
 		}
 		el, err := dataset.FindElementByTag(tag.InstanceNumber)
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("no InstanceNumber in %s: %w", e.Name(), err)
+			return nil, 0, 0, 0, false, fmt.Errorf("no InstanceNumber in %s: %w", e.Name(), err)
 		}
 		instNum, err := strconv.Atoi(fmt.Sprintf("%v", el.Value.GetValue().([]string)[0]))
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("parse InstanceNumber in %s: %w", e.Name(), err)
+			return nil, 0, 0, 0, false, fmt.Errorf("parse InstanceNumber in %s: %w", e.Name(), err)
 		}
 		dcmFiles = append(dcmFiles, dicomEntry{path: fpath, instanceNumber: instNum})
 	}
 
 	if len(dcmFiles) == 0 {
-		return nil, 0, 0, 0, fmt.Errorf("no .dcm files in %s", seriesDir)
+		return nil, 0, 0, 0, false, fmt.Errorf("no .dcm files in %s", seriesDir)
 	}
 
 	sort.Slice(dcmFiles, func(i, j int) bool {
 		return dcmFiles[i].instanceNumber < dcmFiles[j].instanceNumber
 	})
 
-	var width, height int
-	var maxValue uint16
-	frames := make([][]uint16, len(dcmFiles))
+	frames = make([][]uint16, len(dcmFiles))
 
 	for f, de := range dcmFiles {
 		dataset, err := dicom.ParseFile(de.path, nil)
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("parse frame %d: %w", f, err)
+			return nil, 0, 0, 0, false, fmt.Errorf("parse frame %d: %w", f, err)
 		}
 		pixelDataElement, err := dataset.FindElementByTag(tag.PixelData)
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("no pixel data in frame %d: %w", f, err)
+			return nil, 0, 0, 0, false, fmt.Errorf("no pixel data in frame %d: %w", f, err)
 		}
 		pixelDataInfo := dicom.MustGetPixelDataInfo(pixelDataElement.Value)
 		nativeFrame, err := pixelDataInfo.Frames[0].GetNativeFrame()
 		if err != nil {
-			return nil, 0, 0, 0, fmt.Errorf("get native frame %d: %w", f, err)
+			return nil, 0, 0, 0, false, fmt.Errorf("get native frame %d: %w", f, err)
 		}
 
 		if f == 0 {
 			width = nativeFrame.Cols
 			height = nativeFrame.Rows
+			signed = isSigned(dataset)
 		} else if nativeFrame.Cols != width || nativeFrame.Rows != height {
-			return nil, 0, 0, 0, fmt.Errorf("frame %d dimension mismatch: %dx%d vs %dx%d",
+			return nil, 0, 0, 0, false, fmt.Errorf("frame %d dimension mismatch: %dx%d vs %dx%d",
 				f, nativeFrame.Cols, nativeFrame.Rows, width, height)
 		}
 
@@ -228,7 +257,7 @@ func readDicomSeries(seriesDir string) ([][]uint16, int, int, uint16, error) {
 		frames[f] = pixels
 	}
 
-	return frames, width, height, maxValue, nil
+	return frames, width, height, maxValue, signed, nil
 }
 
 type testImage struct {
@@ -447,7 +476,7 @@ func main() {
 			}
 
 			outPath := filepath.Join(outDir, img.name+".mic")
-			if err := writeMicFile(outPath, img.cols, img.rows, compressed); err != nil {
+			if err := writeMicFile(outPath, img.cols, img.rows, maxValue, compressed); err != nil {
 				fmt.Fprintf(os.Stderr, "  error writing %s: %v\n", outPath, err)
 				continue
 			}
@@ -484,7 +513,7 @@ func main() {
 			}
 
 			outPath := filepath.Join(outDir, img.name+"_4s.mic")
-			if err := writeMicFile(outPath, img.cols, img.rows, compressed); err != nil {
+			if err := writeMicFile(outPath, img.cols, img.rows, maxValue, compressed); err != nil {
 				fmt.Fprintf(os.Stderr, "  error writing %s: %v\n", outPath, err)
 				continue
 			}
@@ -521,7 +550,7 @@ func main() {
 			}
 
 			outPath := filepath.Join(outDir, img.name+"_8s.mic")
-			if err := writeMicFile(outPath, img.cols, img.rows, compressed); err != nil {
+			if err := writeMicFile(outPath, img.cols, img.rows, maxValue, compressed); err != nil {
 				fmt.Fprintf(os.Stderr, "  error writing %s: %v\n", outPath, err)
 				continue
 			}
@@ -628,7 +657,7 @@ func main() {
 				continue
 			}
 
-			frames, w, h, maxVal, err := readDicomMultiFrame(img.file)
+			frames, w, h, maxVal, signed, err := readDicomMultiFrame(img.file)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "  error reading %s: %v\n", img.name, err)
 				continue
@@ -639,7 +668,7 @@ func main() {
 				len(frames), w, h, maxVal, rawSize)
 
 			// Compress independently (MIC2)
-			compressed, err := mic.CompressMultiFrame(frames, w, h, maxVal, false)
+			compressed, err := compressMultiFrame(frames, w, h, maxVal, signed, false)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "  error compressing %s: %v\n", img.name, err)
 				continue
@@ -665,7 +694,7 @@ func main() {
 				continue
 			}
 
-			frames, w, h, maxVal, err := readDicomSeries(img.dir)
+			frames, w, h, maxVal, signed, err := readDicomSeries(img.dir)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "  error reading %s: %v\n", img.name, err)
 				continue
@@ -675,7 +704,7 @@ func main() {
 			fmt.Printf("  %d frames, %dx%d, maxValue=%d, raw=%d bytes\n",
 				len(frames), w, h, maxVal, rawSize)
 
-			compressed, err := mic.CompressMultiFrame(frames, w, h, maxVal, false)
+			compressed, err := compressMultiFrame(frames, w, h, maxVal, signed, false)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "  error compressing %s: %v\n", img.name, err)
 				continue
@@ -756,23 +785,37 @@ func main() {
 			os.Exit(1)
 		}
 
-		frames, w, h, maxVal, err := readDicomMultiFrame(*dicomFile)
+		frames, w, h, maxVal, signed, err := readDicomMultiFrame(*dicomFile)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading DICOM: %v\n", err)
 			os.Exit(1)
 		}
 
 		rawSize := len(frames) * w * h * 2
-		fmt.Printf("Read %d frames, %dx%d, maxValue=%d\n", len(frames), w, h, maxVal)
+		if signed {
+			fmt.Printf("Read %d frames, %dx%d, signed samples\n", len(frames), w, h)
+		} else {
+			fmt.Printf("Read %d frames, %dx%d, maxValue=%d\n", len(frames), w, h, maxVal)
+		}
 
-		if len(frames) == 1 {
+		if len(frames) == 1 && signed {
+			// Single signed frame: write MIC1 with the signed flag
+			n, err := writeSignedMicFile(*outputFile, toS16(frames)[0], w, h)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Compression error: %v\n", err)
+				os.Exit(1)
+			}
+			ratio := float64(rawSize) / float64(n)
+			fmt.Printf("Compressed: %d bytes -> %d bytes (%.2f:1) -> %s\n",
+				rawSize, n, ratio, *outputFile)
+		} else if len(frames) == 1 {
 			// Single frame: write MIC1
 			compressed, err := compressImage(frames[0], w, h, maxVal)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Compression error: %v\n", err)
 				os.Exit(1)
 			}
-			if err := writeMicFile(*outputFile, w, h, compressed); err != nil {
+			if err := writeMicFile(*outputFile, w, h, maxVal, compressed); err != nil {
 				fmt.Fprintf(os.Stderr, "Error writing: %v\n", err)
 				os.Exit(1)
 			}
@@ -787,7 +830,7 @@ func main() {
 			}
 			fmt.Printf("Compressing %d frames (%s mode)...\n", len(frames), mode)
 
-			compressed, err := mic.CompressMultiFrame(frames, w, h, maxVal, *temporal)
+			compressed, err := compressMultiFrame(frames, w, h, maxVal, signed, *temporal)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Compression error: %v\n", err)
 				os.Exit(1)
@@ -841,7 +884,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := writeMicFile(*outputFile, *width, *height, compressed); err != nil {
+	if err := writeMicFile(*outputFile, *width, *height, maxValue, compressed); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *outputFile, err)
 		os.Exit(1)
 	}
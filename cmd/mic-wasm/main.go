@@ -12,7 +12,8 @@ import (
 	"syscall/js"
 )
 
-// decodeDeltaRleFSE decodes FSE-compressed Delta+RLE data to original pixels.
+// decodeDeltaRleFSE decodes entropy-coded Delta+RLE data to original pixels.
+// Any stream FSEDecompressU16Auto recognises is accepted (FSE, rANS or Huffman).
 // Args: compressedBytes (Uint8Array), width (number), height (number)
 // Returns: Uint16Array of decoded pixel data
 func decodeDeltaRleFSE(_ js.Value, args []js.Value) interface{} {
@@ -29,9 +30,9 @@ func decodeDeltaRleFSE(_ js.Value, args []js.Value) interface{} {
 	compressed := make([]byte, length)
 	js.CopyBytesToGo(compressed, jsBytes)
 
-	// FSE decompress
+	// FSE, rANS or Huffman decompress
 	var s mic.ScratchU16
-	rleSymbols, err := mic.FSEDecompressU16(compressed, &s)
+	rleSymbols, err := mic.FSEDecompressU16Auto(compressed, &s)
 	if err != nil {
 		return jsError("FSE decompress: " + err.Error())
 	}
@@ -49,12 +50,15 @@ func decodeDeltaRleFSE(_ js.Value, args []js.Value) interface{} {
 	return result
 }
 
-// decodeMicFile decodes a .mic container file (MIC1 or MIC2).
+// decodeMicFile decodes a .mic container file (MIC1, MIC2, MIC3, PICS, or any
+// other format recognised by mic.Decode).
 // Args: fileBytes (Uint8Array)
 // Returns: {pixels: Uint16Array, width: number, height: number}
 //
 //	For MIC2: also includes frameCount, temporal, isMIC2 fields.
 //	Returns first frame pixels for MIC2.
+//	For MIC1 and MIC2: signed, minValue — pixels of a signed file are stored
+//	values; the original int16 sample is pixel + minValue.
 func decodeMicFile(_ js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
 		return jsError("decodeMicFile requires 1 arg: fileBytes")
@@ -71,7 +75,7 @@ func decodeMicFile(_ js.Value, args []js.Value) interface{} {
 
 	magic := string(data[0:4])
 
-	if magic == "PICS" {
+	if magic == "PICS" || magic == "PICC" || magic == "PICP" {
 		pixels, width, height, err := mic.DecompressParallelStrips(data)
 		if err != nil {
 			return jsError("PICS decompress: " + err.Error())
@@ -90,30 +94,22 @@ func decodeMicFile(_ js.Value, args []js.Value) interface{} {
 		return decodeMIC3FileImpl(data)
 	}
 
-	if magic == "MIC2" {
+	if magic == "MIC2" || magic == "MC2S" || magic == "MC2C" {
 		return decodeMIC2FileImpl(data)
 	}
 
 	if magic != "MIC1" {
-		return jsError("invalid .mic magic: " + magic)
+		// PICA, MICR and wavelet streams go through the library's generic decoder.
+		return decodeGenericImpl(data)
 	}
 
-	if length < 20 {
-		return jsError("MIC1 file too small")
-	}
-
-	width := int(binary.LittleEndian.Uint32(data[4:8]))
-	height := int(binary.LittleEndian.Uint32(data[8:12]))
-	pipeline := binary.LittleEndian.Uint32(data[12:16])
-	compLen := binary.LittleEndian.Uint32(data[16:20])
-
-	if pipeline != 1 {
-		return jsError("unsupported pipeline type")
+	hdr, _, err := mic.ReadMIC1Header(data)
+	if err != nil {
+		return jsError(err.Error())
 	}
-
-	compressed := data[20 : 20+compLen]
-
-	pixels, err := mic.DecompressSingleFrame(compressed, width, height)
+	// Decompress returns the stored samples of any MIC1 pipeline. mic.Decode
+	// would add minValue itself, and the viewer adds it again.
+	pixels, width, height, err := mic.Decompress(data)
 	if err != nil {
 		return jsError("decompress: " + err.Error())
 	}
@@ -122,6 +118,33 @@ func decodeMicFile(_ js.Value, args []js.Value) interface{} {
 	result.Set("pixels", uint16SliceToJS(pixels))
 	result.Set("width", width)
 	result.Set("height", height)
+	result.Set("signed", hdr.Signed)
+	result.Set("minValue", int(hdr.MinValue))
+	result.Set("isMIC2", false)
+	return result
+}
+
+// decodeGenericImpl decodes any other container via mic.Decode.
+func decodeGenericImpl(data []byte) interface{} {
+	img, err := mic.Decode(data)
+	if err != nil {
+		return jsError("decode: " + err.Error())
+	}
+
+	result := js.Global().Get("Object").New()
+	if img.Channels == 3 {
+		jsRGB := js.Global().Get("Uint8Array").New(len(img.RGB))
+		js.CopyBytesToJS(jsRGB, img.RGB)
+		result.Set("rgb", jsRGB)
+	} else {
+		result.Set("pixels", uint16SliceToJS(img.Frame(0)))
+	}
+	result.Set("width", img.Width)
+	result.Set("height", img.Height)
+	result.Set("channels", img.Channels)
+	result.Set("bitDepth", img.BitDepth)
+	result.Set("frameCount", img.FrameCount)
+	result.Set("format", img.Format)
 	result.Set("isMIC2", false)
 	return result
 }
@@ -145,13 +168,15 @@ func decodeMIC2FileImpl(data []byte) interface{} {
 	result.Set("height", hdr.Height)
 	result.Set("frameCount", hdr.FrameCount)
 	result.Set("temporal", hdr.Temporal)
+	result.Set("signed", hdr.Signed)
+	result.Set("minValue", int(hdr.MinValue))
 	result.Set("isMIC2", true)
 	return result
 }
 
 // parseMIC2Header parses header metadata without decompressing.
 // Args: fileBytes (Uint8Array)
-// Returns: {width, height, frameCount, temporal}
+// Returns: {width, height, frameCount, temporal, signed, minValue}
 func parseMIC2Header(_ js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
 		return jsError("parseMIC2Header requires 1 arg: fileBytes")
@@ -171,6 +196,8 @@ func parseMIC2Header(_ js.Value, args []js.Value) interface{} {
 	result.Set("height", hdr.Height)
 	result.Set("frameCount", hdr.FrameCount)
 	result.Set("temporal", hdr.Temporal)
+	result.Set("signed", hdr.Signed)
+	result.Set("minValue", int(hdr.MinValue))
 	return result
 }
 
@@ -228,7 +255,8 @@ func decodePICSFile(_ js.Value, args []js.Value) interface{} {
 	return result
 }
 
-// fseDecompress performs only the FSE decompression step.
+// fseDecompress performs only the entropy decoding step, detecting the
+// stream format as FSEDecompressU16Auto does.
 // Args: compressedBytes (Uint8Array)
 // Returns: Uint16Array
 func fseDecompress(_ js.Value, args []js.Value) interface{} {
@@ -241,7 +269,7 @@ func fseDecompress(_ js.Value, args []js.Value) interface{} {
 	js.CopyBytesToGo(compressed, jsBytes)
 
 	var s mic.ScratchU16
-	symbols, err := mic.FSEDecompressU16(compressed, &s)
+	symbols, err := mic.FSEDecompressU16Auto(compressed, &s)
 	if err != nil {
 		return jsError("FSE decompress: " + err.Error())
 	}
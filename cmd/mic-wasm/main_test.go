@@ -0,0 +1,74 @@
+//go:build js && wasm
+
+// Run: GOOS=js GOARCH=wasm go test -exec "$(go env GOROOT)/lib/wasm/go_js_wasm_exec" ./cmd/mic-wasm/
+package main
+
+import (
+	"bytes"
+	"mic"
+	"syscall/js"
+	"testing"
+)
+
+// TestDecodeMicFileSigned checks that decodeMicFile returns the stored
+// samples and minValue of signed MIC1 files, whatever their pipeline, so
+// that pixel + minValue gives back the original samples.
+func TestDecodeMicFileSigned(t *testing.T) {
+	const width, height = 64, 48
+	pixels := make([]int16, width*height)
+	for i := range pixels {
+		x, y := i%width, i/width
+		pixels[i] = int16(-1024 + 17*x + 23*y + (x*y)%13)
+	}
+	minValue, maxValue := pixels[0], pixels[0]
+	for _, v := range pixels {
+		minValue, maxValue = min(minValue, v), max(maxValue, v)
+	}
+	stored := mic.S16ToU16(pixels, minValue)
+	storedMax := uint16(maxValue - minValue)
+
+	single, err := mic.CompressSingleFrame(stored, width, height, storedMax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, err := mic.Compress(stored, width, height, mic.CompressOptions{MaxValue: storedMax, Predictor: mic.PredictorMED})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, optionsPayload, err := mic.ReadMIC1Header(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		pipeline uint8
+		payload  []byte
+	}{
+		{"DeltaRLEFSE", mic.PipelineDeltaRLEFSE, single},
+		{"Options", mic.PipelineOptions, optionsPayload},
+	} {
+		var buf bytes.Buffer
+		hdr := mic.MIC1Header{Width: width, Height: height, Pipeline: tc.pipeline, Signed: true, MinValue: minValue, MaxValue: storedMax}
+		if err := mic.WriteMIC1(&buf, hdr, tc.payload); err != nil {
+			t.Fatal(err)
+		}
+		file := js.Global().Get("Uint8Array").New(buf.Len())
+		js.CopyBytesToJS(file, buf.Bytes())
+
+		result := decodeMicFile(js.Undefined(), []js.Value{file}).(js.Value)
+		if result.InstanceOf(js.Global().Get("Error")) {
+			t.Fatalf("%s: %s", tc.name, result.Get("message").String())
+		}
+		if !result.Get("signed").Bool() || result.Get("minValue").Int() != int(minValue) {
+			t.Fatalf("%s: signed %v, minValue %v; want true, %d", tc.name, result.Get("signed"), result.Get("minValue"), minValue)
+		}
+		got := result.Get("pixels")
+		offset := result.Get("minValue").Int()
+		for i, want := range pixels {
+			if v := got.Index(i).Int() + offset; v != int(want) {
+				t.Fatalf("%s: pixel %d is %d, want %d", tc.name, i, v, want)
+			}
+		}
+	}
+}
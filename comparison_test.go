@@ -222,7 +222,7 @@ func BenchmarkMEDPredictor(b *testing.B) {
 					fseOut, _ := FSEDecompressU16(medComp, &s)
 					var rleD RleDecompressU16
 					rleD.Init(fseOut)
-					rleDecomp := rleD.Decompress()
+					rleDecomp, _ := rleD.Decompress()
 					MEDDeltaDecompressU16(rleDecomp, cols, rows)
 				}
 			})
@@ -255,7 +255,7 @@ func benchmarkPipeline(data []uint16, cols, rows int, maxShort uint16, useMED bo
 		fseOut, _ := FSEDecompressU16(comp, &s2)
 		var rleD RleDecompressU16
 		rleD.Init(fseOut)
-		rleDecomp := rleD.Decompress()
+		rleDecomp, _ := rleD.Decompress()
 		MEDDeltaDecompressU16(rleDecomp, cols, rows)
 		decompTime = time.Since(start)
 	} else {
@@ -0,0 +1,593 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+	"sync"
+)
+
+// Configurable single-frame compression.
+//
+// Compress builds the pipeline from CompressOptions instead of a dedicated
+// function per variant: optional value packing remaps the pixels onto a
+// dense range, a modelling stage (any registered Predictor, or a 5/3
+// wavelet) turns each strip into a uint16 symbol stream, optional gap
+// removal compacts its alphabet, and the selected entropy coder writes it.
+// The result is a MIC1 container with pipeline PipelineOptions whose payload
+// records the configuration, so Decompress and Decode need no options:
+//
+//	Byte  0:     PredictorID
+//	Byte  1:     Coder
+//	Byte  2:     Flags: bit0=gap removal, bit1=value packing
+//	Byte  3:     Wavelet levels (0 = spatial predictor)
+//	Bytes 4-7:   StripHeight (uint32 LE) — rows per strip; last strip may be shorter
+//	Bytes 8..:   Value map, only with value packing (see valuepacking.go)
+//	Then:        Length table (NumStrips × uint32 LE), NumStrips = ceil(height/StripHeight)
+//	After table: Concatenated strip blobs
+//
+// Each strip blob is the gap removal map header (see removeGaps; a single
+// zero byte when gap removal is off or does not pay) followed by the coded
+// symbols. The per-variant functions (CompressSingleFrame4State,
+// CompressParallelStrips, ...) remain for callers that need their bare
+// stream formats.
+
+// Coder selects the entropy coder.
+type Coder uint8
+
+const (
+	CoderFSE2      Coder = iota // two-state FSE, as CompressSingleFrame
+	CoderFSE1                   // single-state FSE
+	CoderFSE4                   // four-state FSE
+	CoderFSE8                   // eight-state FSE
+	CoderRANS                   // eight-state rANS
+	CoderHuffman                // four-stream canonical Huffman, see HuffCompressU16Streams
+	CoderArith                  // adaptive binary arithmetic coding, see ArithCompressU16
+	CoderFSEBlocks              // FSE with a table per block, see FSECompressU16Blocks
+)
+
+// CompressOptions configures Compress. The zero value is the
+// CompressSingleFrame pipeline: avg predictor, two-state FSE, one strip.
+type CompressOptions struct {
+	// Predictor selects the spatial predictor of the Delta+RLE stage; any
+	// registered ID is accepted (see RegisterPredictor).
+	Predictor PredictorID
+	Coder     Coder
+
+	// Strips splits the image into horizontal bands that are compressed
+	// and decompressed concurrently, as in PICS. 0 or 1 = a single strip.
+	Strips int
+
+	// GapRemoval remaps each strip's symbols onto a dense alphabet when the
+	// map pays for itself (see CompressSingleFrameGapRemoval).
+	GapRemoval bool
+
+	// ValuePacking remaps the pixel values onto a dense range before the
+	// modelling stage when the image uses a sparse subset of them: a common
+	// shift or divisor, or a table of the distinct values (see packValues).
+	ValuePacking bool
+
+	// WaveletLevels > 0 replaces the spatial predictor with that many
+	// levels (at most 8) of the 5/3 integer wavelet.
+	WaveletLevels int
+
+	// MaxValue is the largest sample. 0 derives it from the pixels.
+	MaxValue uint16
+
+	// Dictionary, if set, codes each strip with its best pre-trained table
+	// when that beats the strip's own table (see TrainDictionary). The
+	// decoder must have the dictionary registered.
+	Dictionary *Dictionary
+}
+
+const optionsHeaderSize = 8 // predictor + coder + flags + levels + stripH
+
+const (
+	optionsFlagGapRemoval   = 0x01
+	optionsFlagValuePacking = 0x02
+)
+
+// fseLadder lists the FSE encoders from most to fewest states. An encoder
+// that rejects its input falls back to the next entry, as the fixed
+// CompressSingleFrame* variants do; FSEDecompressU16Auto reads them all.
+var fseLadder = []struct {
+	coder    Coder
+	compress func([]uint16, *ScratchU16) ([]byte, error)
+}{
+	{CoderFSE8, FSECompressU16EightState},
+	{CoderFSE4, FSECompressU16FourState},
+	{CoderFSE2, FSECompressU16TwoState},
+	{CoderFSE1, FSECompressU16},
+}
+
+// validate reports options Compress cannot honour.
+func (o *CompressOptions) validate() error {
+	if _, ok := LookupPredictor(o.Predictor); !ok {
+		return fmt.Errorf("unknown predictor %d", o.Predictor)
+	}
+	if o.Coder > CoderFSEBlocks {
+		return fmt.Errorf("unknown coder %d", o.Coder)
+	}
+	if o.WaveletLevels < 0 || o.WaveletLevels > 8 {
+		return fmt.Errorf("wavelet levels %d out of range [0, 8]", o.WaveletLevels)
+	}
+	if o.WaveletLevels > 0 && o.Predictor != PredictorAvg {
+		return errors.New("the wavelet replaces the spatial predictor; leave Predictor unset")
+	}
+	if o.Strips < 0 {
+		return fmt.Errorf("negative strip count %d", o.Strips)
+	}
+	return nil
+}
+
+// Compress compresses a single 16-bit frame with the pipeline selected by
+// opts and returns a MIC1 container that Decompress or Decode reads back.
+func Compress(pixels []uint16, width, height int, opts CompressOptions) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("compress: invalid dimensions %dx%d", width, height)
+	}
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("compress: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if err := opts.validate(); err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+
+	maxValue := opts.MaxValue
+	if maxValue == 0 {
+		for _, v := range pixels {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+	if maxValue == 0 {
+		maxValue = 1 // the Delta+RLE coder needs at least one bit
+	}
+
+	// The header keeps maxValue; the strips code the packed samples.
+	var vm valueMap
+	packed, packedMax := pixels, maxValue
+	if opts.ValuePacking {
+		if vm, packed = packValues(pixels, width); vm.kind != valueMapNone {
+			if packedMax = slices.Max(packed); packedMax == 0 {
+				packedMax = 1
+			}
+		}
+	}
+
+	numStrips := opts.Strips
+	if numStrips < 1 {
+		numStrips = 1
+	}
+	if numStrips > height {
+		numStrips = height
+	}
+	stripH := (height + numStrips - 1) / numStrips
+	actual := (height + stripH - 1) / stripH
+
+	results := make([][]byte, actual)
+	errs := make([]error, actual)
+
+	var wg sync.WaitGroup
+	for s := 0; s < actual; s++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			y0 := idx * stripH
+			y1 := min(y0+stripH, height)
+			results[idx], errs[idx] = opts.compressStrip(packed[y0*width:y1*width], width, y1-y0, packedMax)
+		}(s)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("compress: strip %d: %w", i, err)
+		}
+	}
+
+	var valueMapBytes []byte
+	if opts.ValuePacking {
+		valueMapBytes = vm.marshal()
+	}
+	tableStart := optionsHeaderSize + len(valueMapBytes)
+	payloadSize := tableStart + 4*actual
+	for _, r := range results {
+		payloadSize += len(r)
+	}
+	payload := make([]byte, optionsHeaderSize, payloadSize)
+	payload[0] = byte(opts.Predictor)
+	payload[1] = byte(opts.Coder)
+	if opts.GapRemoval {
+		payload[2] |= optionsFlagGapRemoval
+	}
+	if opts.ValuePacking {
+		payload[2] |= optionsFlagValuePacking
+	}
+	payload[3] = byte(opts.WaveletLevels)
+	binary.LittleEndian.PutUint32(payload[4:8], uint32(stripH))
+	payload = append(payload, valueMapBytes...)
+	payload = payload[:tableStart+4*actual]
+	for i, r := range results {
+		binary.LittleEndian.PutUint32(payload[tableStart+4*i:], uint32(len(r)))
+		payload = append(payload, r...)
+	}
+
+	var buf bytes.Buffer
+	hdr := MIC1Header{Width: width, Height: height, Pipeline: PipelineOptions, MaxValue: maxValue}
+	if err := WriteMIC1(&buf, hdr, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressStrip runs the modelling, gap removal and entropy stages on one strip.
+func (o *CompressOptions) compressStrip(pixels []uint16, width, height int, maxValue uint16) ([]byte, error) {
+	var symbols []uint16
+	var err error
+	if o.WaveletLevels > 0 {
+		symbols, _ = waveletSymbols(pixels, height, width, o.WaveletLevels)
+	} else {
+		symbols, err = predictorSymbols(o.Predictor, pixels, width, height, maxValue)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("delta+RLE compress: %w", err)
+	}
+
+	header := []byte{gapModeNone}
+	if o.GapRemoval {
+		header, symbols = removeGaps(symbols)
+	}
+
+	coded, err := o.Coder.encodeDict(symbols, o.Dictionary)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, coded...), nil
+}
+
+// checkStreamCoder reports whether c can code the streams of PICS, MIC2 and
+// MIC3, whose decoders do not record the coder but detect it from the
+// stream.
+func checkStreamCoder(c Coder) error {
+	if c > CoderFSEBlocks {
+		return fmt.Errorf("unknown coder %d", c)
+	}
+	return nil
+}
+
+// encode entropy-codes symbols with c. FSE and rANS fall back to fewer
+// states when the requested coder rejects the input.
+func (c Coder) encode(symbols []uint16) ([]byte, error) {
+	if c == CoderHuffman {
+		return HuffCompressU16Streams(symbols, 4)
+	}
+
+	if c == CoderArith {
+		return ArithCompressU16(symbols, 0)
+	}
+	if c == CoderFSEBlocks {
+		return FSECompressU16Blocks(symbols, 0, nil)
+	}
+	if c == CoderRANS {
+		var s ScratchU16
+		if out, err := RANSCompressU16EightState(symbols, &s); err == nil {
+			return out, nil
+		}
+		c = CoderFSE2
+	}
+	var err error
+	started := false
+	for _, step := range fseLadder {
+		if step.coder == c {
+			started = true
+		}
+		if !started {
+			continue
+		}
+		var s ScratchU16
+		var out []byte
+		if out, err = step.compress(symbols, &s); err == nil {
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("FSE compress: %w", err)
+}
+
+// encodeDict is encode, replaced by the FSECompressU16Dict stream of d when
+// that is smaller or encode fails. A nil d is plain encode.
+func (c Coder) encodeDict(symbols []uint16, d *Dictionary) ([]byte, error) {
+	out, err := c.encode(symbols)
+	if d == nil {
+		return out, err
+	}
+	if dict, derr := FSECompressU16Dict(symbols, d, nil); derr == nil && (err != nil || len(dict) < len(out)) {
+		return dict, nil
+	}
+	return out, err
+}
+
+// decode reverses encode, producing at most limit symbols.
+func (c Coder) decode(data []byte, limit int) ([]uint16, error) {
+	s := ScratchU16{DecompressLimit: limit}
+	symbols, err := FSEDecompressU16Auto(data, &s)
+	if err != nil {
+		return nil, fmt.Errorf("FSE decompress: %w", err)
+	}
+	return symbols, nil
+}
+
+// Decompress decompresses a MIC1 container written by Compress or WriteMIC1
+// and returns the stored samples with the image dimensions.
+func Decompress(data []byte) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.Decompress(data)
+}
+
+// Decompress is Decompress under d's limits.
+func (d *Decoder) Decompress(data []byte) (pixels []uint16, width, height int, err error) {
+	hdr, payload, err := ReadMIC1Header(data)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	pixels, err = d.decompressMIC1Payload(nil, hdr, payload)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, hdr.Width, hdr.Height, nil
+}
+
+// DecompressInto is Decompress writing the samples into dst[:width*height]
+// instead of a new slice. It returns an error wrapping io.ErrShortBuffer if
+// dst holds fewer samples than the image.
+func DecompressInto(dst []uint16, src []byte) (width, height int, err error) {
+	var d Decoder
+	return d.DecompressInto(dst, src)
+}
+
+// DecompressInto is DecompressInto under d's limits. For CompressSingleFrame
+// containers it allocates nothing once d has decoded an image of this size;
+// Compress containers still allocate per-strip buffers.
+func (d *Decoder) DecompressInto(dst []uint16, src []byte) (width, height int, err error) {
+	hdr, payload, err := ReadMIC1Header(src)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := checkDst(dst, hdr.Width, hdr.Height); err != nil {
+		return 0, 0, fmt.Errorf("MIC1: %w", err)
+	}
+	if _, err := d.decompressMIC1Payload(dst, hdr, payload); err != nil {
+		return 0, 0, err
+	}
+	return hdr.Width, hdr.Height, nil
+}
+
+// checkDst reports whether dst can take a width x height image.
+func checkDst(dst []uint16, width, height int) error {
+	if width > 0 && height > 0 && len(dst) < width*height {
+		return fmt.Errorf("destination holds %d samples, image needs %dx%d: %w", len(dst), width, height, io.ErrShortBuffer)
+	}
+	return nil
+}
+
+// ReadCompressOptions returns the configuration a MIC1 container was written
+// with. Pipeline 1 containers report the zero options, which select the same
+// CompressSingleFrame pipeline. MaxValue is taken from the header.
+func ReadCompressOptions(data []byte) (CompressOptions, error) {
+	hdr, payload, err := ReadMIC1Header(data)
+	if err != nil {
+		return CompressOptions{}, err
+	}
+	opts := CompressOptions{MaxValue: hdr.MaxValue}
+	switch hdr.Pipeline {
+	case PipelineDeltaRLEFSE:
+		return opts, nil
+	case PipelineOptions:
+	default:
+		return CompressOptions{}, fmt.Errorf("MIC1: unsupported pipeline type %d", hdr.Pipeline)
+	}
+	stripH, err := opts.readOptionsHeader(payload, hdr.Height)
+	if err != nil {
+		return CompressOptions{}, fmt.Errorf("MIC1: %w", err)
+	}
+	opts.Strips = (hdr.Height + stripH - 1) / stripH
+	return opts, nil
+}
+
+// decompressMIC1Payload decodes a MIC1 payload according to its pipeline,
+// into dst if it is non-nil and into a new slice otherwise.
+func (d *Decoder) decompressMIC1Payload(dst []uint16, hdr MIC1Header, payload []byte) ([]uint16, error) {
+	var pixels []uint16
+	var err error
+	switch hdr.Pipeline {
+	case PipelineDeltaRLEFSE:
+		pixels, err = d.decompressSingleFrame(dst, payload, hdr.Width, hdr.Height, streamRefs{})
+	case PipelineOptions:
+		pixels, err = d.decompressOptions(dst, payload, hdr.Width, hdr.Height)
+	case PipelineContextFSE:
+		pixels, err = d.decompressContextFSE(dst, payload, hdr.Width, hdr.Height, hdr.MaxValue)
+	case PipelineArchive:
+		pixels, err = d.decompressArchive(dst, payload, hdr.Width, hdr.Height, hdr.MaxValue)
+	case PipelineSplitStream:
+		pixels, err = d.decompressSplitStream(dst, payload, hdr.Width, hdr.Height, hdr.MaxValue)
+	case PipelineLossyWavelet:
+		pixels, err = d.decompressLossy(payload, hdr.Width, hdr.Height, hdr.MaxValue)
+		if err == nil && dst != nil {
+			pixels = dst[:copy(dst, pixels)]
+		}
+	default:
+		return nil, fmt.Errorf("MIC1: unsupported pipeline type %d", hdr.Pipeline)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("MIC1: %w", err)
+	}
+	return pixels, nil
+}
+
+// decompressOptions decodes a PipelineOptions payload into dst, or into a
+// new slice if dst is nil.
+func (d *Decoder) decompressOptions(dst []uint16, payload []byte, width, height int) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	var opts CompressOptions
+	stripH, err := opts.readOptionsHeader(payload, height)
+	if err != nil {
+		return nil, err
+	}
+	tableStart := optionsHeaderSize
+	var vm valueMap
+	if opts.ValuePacking {
+		var n int
+		if vm, n, err = readValueMap(payload[optionsHeaderSize:]); err != nil {
+			return nil, err
+		}
+		tableStart += n
+	}
+	numStrips := (height + stripH - 1) / stripH
+	tableEnd := tableStart + 4*numStrips
+	if len(payload) < tableEnd {
+		return nil, errors.New("strip table truncated")
+	}
+
+	blobs := make([][]byte, numStrips)
+	off := tableEnd
+	for i := range blobs {
+		n := int(binary.LittleEndian.Uint32(payload[tableStart+4*i:]))
+		if n > len(payload)-off {
+			return nil, fmt.Errorf("strip %d: data out of bounds", i)
+		}
+		blobs[i] = payload[off : off+n]
+		off += n
+	}
+
+	out := dst
+	if out == nil {
+		out = make([]uint16, width*height)
+	}
+	out = out[:width*height]
+	errs := make([]error, numStrips)
+
+	var wg sync.WaitGroup
+	for s := 0; s < numStrips; s++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			y0 := idx * stripH
+			y1 := min(y0+stripH, height)
+			pixels, err := opts.decompressStrip(blobs[idx], width, y1-y0)
+			if err == nil {
+				err = vm.unpack(pixels)
+			}
+			if err != nil {
+				errs[idx] = err
+				return
+			}
+			copy(out[y0*width:y1*width], pixels)
+		}(s)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("strip %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// readOptionsHeader fills o from the configuration block at the start of a
+// PipelineOptions payload and returns the strip height.
+func (o *CompressOptions) readOptionsHeader(payload []byte, height int) (stripH int, err error) {
+	if len(payload) < optionsHeaderSize {
+		return 0, errors.New("options header too short")
+	}
+	o.Predictor = PredictorID(payload[0])
+	o.Coder = Coder(payload[1])
+	o.GapRemoval = payload[2]&optionsFlagGapRemoval != 0
+	o.ValuePacking = payload[2]&optionsFlagValuePacking != 0
+	o.WaveletLevels = int(payload[3])
+	if err := o.validate(); err != nil {
+		return 0, err
+	}
+	stripH = int(binary.LittleEndian.Uint32(payload[4:8]))
+	if stripH < 1 || stripH > height {
+		return 0, fmt.Errorf("invalid strip height %d", stripH)
+	}
+	return stripH, nil
+}
+
+// decompressStrip reverses compressStrip.
+func (o *CompressOptions) decompressStrip(blob []byte, width, height int) ([]uint16, error) {
+	expandMap, coded, err := readGapMap(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := symbolLimit(width * height)
+	if o.WaveletLevels > 0 {
+		limit = symbolLimit(2 * width * height)
+	}
+	symbols, err := o.Coder.decode(coded, limit)
+	if err != nil {
+		return nil, err
+	}
+	if expandMap != nil {
+		if symbols, err = expandGaps(symbols, expandMap); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.WaveletLevels > 0 {
+		pixels, err := waveletPixels(symbols, height, width, waveletLevels(height, width, o.WaveletLevels))
+		if err != nil {
+			return nil, fmt.Errorf("wavelet: %w", err)
+		}
+		return pixels, nil
+	}
+	return predictorPixels(o.Predictor, symbols, width, height)
+}
+
+// deltaRLE run-length codes the output of a standalone delta predictor
+// (MEDDeltaCompressU16, PaethDeltaCompressU16, PredictorDeltaCompressU16),
+// whose first element is the max value.
+func deltaRLE(delta []uint16, err error) ([]uint16, error) {
+	if err != nil {
+		return nil, err
+	}
+	var rle RleCompressU16
+	rle.Init(len(delta), 1, delta[0])
+	return rle.Compress(delta), nil
+}
+
+// checkDeltaStream verifies that a standalone delta stream holds exactly n
+// pixels, so the unchecked standalone decoders cannot run off its end.
+func checkDeltaStream(delta []uint16, n int) error {
+	if len(delta) < 1 || delta[0] == 0 {
+		return errors.New("delta stream: missing max value")
+	}
+	delimiter := uint16(1)<<bits.Len16(delta[0]) - 1
+	i := 1
+	for p := 0; p < n; p++ {
+		if i >= len(delta) {
+			return fmt.Errorf("delta stream: truncated at pixel %d", p)
+		}
+		if delta[i] == delimiter {
+			i++
+		}
+		i++
+	}
+	if i != len(delta) {
+		return fmt.Errorf("delta stream: %d symbols for %d pixels", len(delta), n)
+	}
+	return nil
+}
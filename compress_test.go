@@ -0,0 +1,107 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestCompressOptions(t *testing.T) {
+	width, height := 160, 120
+	frames, maxValue := makeSmoothFrames(width, height, 1, 5)
+	pixels := frames[0]
+
+	var cases []CompressOptions
+	for _, coder := range []Coder{CoderFSE1, CoderFSE2, CoderFSE4, CoderFSE8, CoderRANS, CoderHuffman} {
+		for _, strips := range []int{0, 3} {
+			for _, gap := range []bool{false, true} {
+				cases = append(cases,
+					CompressOptions{Predictor: PredictorAvg, Coder: coder, Strips: strips, GapRemoval: gap},
+					CompressOptions{Predictor: PredictorGrad, Coder: coder, Strips: strips, GapRemoval: gap},
+					CompressOptions{Predictor: PredictorMED, Coder: coder, Strips: strips, GapRemoval: gap},
+					CompressOptions{Predictor: PredictorPaeth, Coder: coder, Strips: strips, GapRemoval: gap},
+					CompressOptions{Coder: coder, Strips: strips, GapRemoval: gap, WaveletLevels: 3},
+				)
+			}
+		}
+	}
+	cases = append(cases, CompressOptions{MaxValue: maxValue, Strips: height})
+
+	for _, opts := range cases {
+		name := fmt.Sprintf("%+v", opts)
+		data, err := Compress(pixels, width, height, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, w, h, err := Decompress(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if w != width || h != height {
+			t.Fatalf("%s: dimensions %dx%d, want %dx%d", name, w, h, width, height)
+		}
+		for i := range pixels {
+			if got[i] != pixels[i] {
+				t.Fatalf("%s: pixel %d mismatch: got %d, want %d", name, i, got[i], pixels[i])
+			}
+		}
+
+		img, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", name, err)
+		}
+		if img.Format != FormatMIC1 || len(img.Pixels) != len(pixels) {
+			t.Fatalf("%s: Decode returned %s with %d pixels", name, img.Format, len(img.Pixels))
+		}
+	}
+
+	data, err := Compress(pixels, width, height, CompressOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, _, err := ReadMIC1Header(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Pipeline != PipelineOptions || hdr.MaxValue != maxValue {
+		t.Fatalf("MIC1 header: pipeline %d maxValue %d", hdr.Pipeline, hdr.MaxValue)
+	}
+
+	// Pipeline 1 containers go through the same entry point.
+	stream, err := CompressSingleFrame(pixels, width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteMIC1(&buf, MIC1Header{Width: width, Height: height, MaxValue: maxValue}, stream); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _, err := Decompress(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pixels {
+		if got[i] != pixels[i] {
+			t.Fatalf("pipeline 1: pixel %d mismatch: got %d, want %d", i, got[i], pixels[i])
+		}
+	}
+}
+
+func TestCompressOptionsInvalid(t *testing.T) {
+	pixels := make([]uint16, 64*64)
+	for _, opts := range []CompressOptions{
+		{Predictor: 7},
+		{Coder: 42},
+		{WaveletLevels: 9},
+		{WaveletLevels: 2, Predictor: PredictorGrad},
+		{Strips: -1},
+	} {
+		if _, err := Compress(pixels, 64, 64, opts); err == nil {
+			t.Fatalf("%+v: expected an error", opts)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Automatic pipeline selection.
+//
+// No single pipeline wins on every modality: the ablations have avg ahead on
+// MG, MED and Paeth on CT and MR, the wavelet on CR and XR, and gap removal
+// helping CT. CompressAuto extends what PICA does per strip (avg vs grad) to
+// every lossless pipeline the package has: each modelling stage Compress
+// offers, the archive, context-FSE and split-stream pipelines, and every
+// entropy coder. Each candidate is first compressed on a sample of evenly
+// spaced row bands; the candidates are then compressed in full, best sample
+// first, for as long as the time budget allows, and the smallest output
+// wins. Every candidate writes a MIC1 container, so the choice is recorded
+// in its header and Decompress needs nothing extra.
+//
+// The entropy coder changes the output far less than the modelling does (a
+// few percent at most on the test images, against up to a quarter between
+// the pipelines), so rather than multiply every candidate by every coder, the
+// other coders are tried only with the best Compress candidate on the
+// sample. CompressLossy and CompressSingleFrameNear are not candidates:
+// CompressAuto is lossless.
+
+const (
+	autoSampleBands = 8  // row bands drawn from the image
+	autoBandRows    = 16 // rows per band
+)
+
+// autoCandidate is one pipeline CompressAuto chooses between.
+type autoCandidate struct {
+	pipeline uint8           // MIC1 pipeline ID of the output
+	opts     CompressOptions // PipelineOptions: the Compress options; else only MaxValue
+}
+
+// compress runs c on pixels.
+func (c autoCandidate) compress(pixels []uint16, width, height int) ([]byte, error) {
+	switch c.pipeline {
+	case PipelineArchive:
+		return CompressArchive(pixels, width, height, c.opts.MaxValue)
+	case PipelineContextFSE:
+		return CompressContextFSE(pixels, width, height, c.opts.MaxValue, 0)
+	case PipelineSplitStream:
+		return CompressSplitStream(pixels, width, height, c.opts.MaxValue)
+	}
+	return Compress(pixels, width, height, c.opts)
+}
+
+// autoCandidates returns the pipelines CompressAuto ranks for pixels, rows
+// of width samples: Compress with every registered predictor and with the
+// wavelet, each with and without gap removal, and each of those again with
+// value packing when packValues finds a map for the image; then the
+// archive, context-FSE and split-stream pipelines. The Compress candidates
+// use two-state FSE; autoCoders are tried afterwards.
+func autoCandidates(pixels []uint16, width int, maxValue uint16) []autoCandidate {
+	var base []CompressOptions
+	for _, id := range Predictors() {
+		base = append(base, CompressOptions{Predictor: id})
+	}
+	base = append(base, CompressOptions{WaveletLevels: 5})
+
+	packing := []bool{false}
+	if m, _ := packValues(pixels, width); m.kind != valueMapNone {
+		packing = append(packing, true)
+	}
+	var candidates []autoCandidate
+	for _, pack := range packing {
+		for _, gap := range []bool{false, true} {
+			for _, opts := range base {
+				opts.MaxValue, opts.GapRemoval, opts.ValuePacking = maxValue, gap, pack
+				candidates = append(candidates, autoCandidate{pipeline: PipelineOptions, opts: opts})
+			}
+		}
+	}
+	for _, p := range []uint8{PipelineArchive, PipelineContextFSE, PipelineSplitStream} {
+		candidates = append(candidates, autoCandidate{pipeline: p, opts: CompressOptions{MaxValue: maxValue}})
+	}
+	return candidates
+}
+
+// autoCoders returns the entropy coders tried with the best Compress
+// candidate: all but two-state FSE, which the candidates already use.
+func autoCoders() []Coder {
+	var coders []Coder
+	for c := CoderFSE1; c <= CoderFSEBlocks; c++ {
+		coders = append(coders, c)
+	}
+	return coders
+}
+
+// CompressAuto compresses a single 16-bit frame with whichever candidate
+// pipeline gives the smallest output, and returns a MIC1 container that
+// Decompress reads back; ReadMIC1Header reports the pipeline chosen and,
+// for Compress output, ReadCompressOptions its options.
+//
+// budget bounds the encode time. The best-ranked candidate on the row
+// sample is always compressed in full; further candidates are compressed
+// only while the time spent plus the cost of one more full encode stays
+// within budget. A budget of 0 therefore trusts the sample, and a generous
+// budget tries every candidate on the whole image. Images no taller than
+// the sample are their own sample, so every trial is a full encode: they
+// are tried in autoCandidates order, the first always and the rest under
+// the same budget rule, and a budget of 0 gives Compress with the default
+// options.
+func CompressAuto(pixels []uint16, width, height int, maxValue uint16, budget time.Duration) ([]byte, error) {
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("compress auto: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	start := time.Now()
+	candidates := autoCandidates(pixels, width, maxValue)
+
+	sample, sampleH := pixels, height
+	whole := height <= autoSampleBands*autoBandRows
+	if !whole {
+		sample, sampleH = sampleRowBands(pixels, width, height)
+	}
+
+	// Rank the candidates on the sample; failures sort last. On a whole
+	// image the trials stop once the budget would be exceeded.
+	var outs [][]byte
+	var lastErr error
+	var trialTime time.Duration
+	trial := func(c autoCandidate) bool {
+		if whole && len(outs) > 0 && time.Since(start)+trialTime > budget {
+			return false
+		}
+		t := time.Now()
+		out, err := c.compress(sample, width, sampleH)
+		trialTime = time.Since(t)
+		if err != nil {
+			lastErr = err
+		}
+		outs = append(outs, out)
+		return true
+	}
+	for _, c := range candidates {
+		if !trial(c) {
+			break
+		}
+	}
+	candidates = candidates[:len(outs)]
+
+	bestOpts := -1
+	for i, c := range candidates {
+		if c.pipeline == PipelineOptions && outs[i] != nil && (bestOpts < 0 || len(outs[i]) < len(outs[bestOpts])) {
+			bestOpts = i
+		}
+	}
+	if bestOpts >= 0 {
+		for _, coder := range autoCoders() {
+			c := candidates[bestOpts]
+			c.opts.Coder = coder
+			if !trial(c) {
+				break
+			}
+			candidates = append(candidates, c)
+		}
+	}
+
+	order := make([]int, 0, len(candidates))
+	for i := range candidates {
+		if outs[i] != nil {
+			order = append(order, i)
+		}
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("compress auto: %w", lastErr)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(outs[order[a]]) < len(outs[order[b]]) })
+	if whole {
+		return outs[order[0]], nil
+	}
+
+	var best []byte
+	var encodeTime time.Duration
+	for _, i := range order {
+		if best != nil && time.Since(start)+encodeTime > budget {
+			break
+		}
+		t := time.Now()
+		out, err := candidates[i].compress(pixels, width, height)
+		encodeTime = time.Since(t)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if best == nil || len(out) < len(best) {
+			best = out
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("compress auto: %w", lastErr)
+	}
+	return best, nil
+}
+
+// sampleRowBands copies autoSampleBands evenly spaced bands of autoBandRows
+// rows into one image. The caller guarantees height exceeds the sample.
+func sampleRowBands(pixels []uint16, width, height int) ([]uint16, int) {
+	sampleH := autoSampleBands * autoBandRows
+	sample := make([]uint16, 0, width*sampleH)
+	for b := 0; b < autoSampleBands; b++ {
+		y0 := b * (height - autoBandRows) / (autoSampleBands - 1)
+		sample = append(sample, pixels[y0*width:(y0+autoBandRows)*width]...)
+	}
+	return sample, sampleH
+}
@@ -0,0 +1,136 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompressAuto(t *testing.T) {
+	for _, size := range []struct{ width, height int }{
+		{96, 64},  // evaluated in full
+		{96, 400}, // ranked on a row sample
+	} {
+		frames, maxValue := makeSmoothFrames(size.width, size.height, 1, 9)
+		pixels := frames[0]
+		whole := size.height <= autoSampleBands*autoBandRows
+
+		candidates := autoCandidates(pixels, size.width, maxValue)
+		outs := make([][]byte, len(candidates))
+		smallest, bestOpts := 0, -1
+		for i, c := range candidates {
+			out, err := c.compress(pixels, size.width, size.height)
+			if err != nil {
+				t.Fatalf("%dx%d %+v: %v", size.width, size.height, c, err)
+			}
+			outs[i] = out
+			if smallest == 0 || len(out) < smallest {
+				smallest = len(out)
+			}
+			if c.pipeline == PipelineOptions && (bestOpts < 0 || len(out) < len(outs[bestOpts])) {
+				bestOpts = i
+			}
+		}
+		if whole {
+			// The other coders are tried with the best Compress candidate.
+			for _, coder := range autoCoders() {
+				c := candidates[bestOpts]
+				c.opts.Coder = coder
+				if out, err := c.compress(pixels, size.width, size.height); err == nil && len(out) < smallest {
+					smallest = len(out)
+				}
+			}
+		}
+
+		for _, budget := range []time.Duration{0, time.Hour} {
+			data, err := CompressAuto(pixels, size.width, size.height, maxValue, budget)
+			if err != nil {
+				t.Fatalf("%dx%d budget %v: %v", size.width, size.height, budget, err)
+			}
+			switch {
+			case budget == 0 && whole && !bytes.Equal(data, outs[0]):
+				t.Fatalf("%dx%d: budget 0 gave %d bytes, not the first candidate", size.width, size.height, len(data))
+			case budget == time.Hour && whole && len(data) != smallest,
+				budget == time.Hour && len(data) > smallest:
+				t.Fatalf("%dx%d: full search gave %d bytes, smallest candidate is %d", size.width, size.height, len(data), smallest)
+			}
+
+			hdr, _, err := ReadMIC1Header(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chosen := autoCandidate{pipeline: hdr.Pipeline, opts: CompressOptions{MaxValue: maxValue}}
+			if hdr.Pipeline == PipelineOptions {
+				if chosen.opts, err = ReadCompressOptions(data); err != nil {
+					t.Fatal(err)
+				}
+				chosen.opts.Coder, chosen.opts.Strips = CoderFSE2, 0
+			}
+			if !slices.Contains(candidates, chosen) {
+				t.Fatalf("chose %+v, which is not a candidate", chosen)
+			}
+
+			got, _, _, err := Decompress(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range pixels {
+				if got[i] != pixels[i] {
+					t.Fatalf("%dx%d budget %v (%+v): pixel %d mismatch: got %d, want %d",
+						size.width, size.height, budget, chosen, i, got[i], pixels[i])
+				}
+			}
+		}
+	}
+}
+
+// TestCompressAutoCandidates checks that the candidates follow the predictor
+// registry, add value packing only when the image has a value map, and
+// include the pipelines Compress does not cover.
+func TestCompressAutoCandidates(t *testing.T) {
+	const width, height = 96, 64
+	registerTestPredictor(t)
+	frames, maxValue := makeSmoothFrames(width, height, 1, 9)
+	dense := frames[0]
+	lo := slices.Min(dense)
+	for i := range dense {
+		dense[i] -= lo // start the values at zero, leaving no affine offset
+	}
+	sparse := sparseVariants(dense, maxValue, 9)["shifted"]
+
+	for _, tc := range []struct {
+		name   string
+		pixels []uint16
+		pack   bool
+	}{
+		{"dense", dense, false},
+		{"shifted", sparse, true},
+	} {
+		candidates := autoCandidates(tc.pixels, width, maxValue)
+		for _, id := range Predictors() {
+			for _, pack := range []bool{false, true} {
+				want := autoCandidate{pipeline: PipelineOptions, opts: CompressOptions{Predictor: id, MaxValue: maxValue, GapRemoval: true, ValuePacking: pack}}
+				if got := slices.Contains(candidates, want); got != (!pack || tc.pack) {
+					t.Errorf("%s: candidate %+v present = %v", tc.name, want, got)
+				}
+			}
+		}
+		for _, p := range []uint8{PipelineArchive, PipelineContextFSE, PipelineSplitStream} {
+			if want := (autoCandidate{pipeline: p, opts: CompressOptions{MaxValue: maxValue}}); !slices.Contains(candidates, want) {
+				t.Errorf("%s: pipeline %d is not a candidate", tc.name, p)
+			}
+		}
+		want := 2 * (len(Predictors()) + 1) // the wavelet, with and without gap removal
+		if tc.pack {
+			want *= 2
+		}
+		if want += 3; len(candidates) != want {
+			t.Errorf("%s: %d candidates, want %d", tc.name, len(candidates), want)
+		}
+	}
+}
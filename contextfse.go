@@ -0,0 +1,313 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Context-bucketed FSE.
+//
+// One FSE table per image has to serve flat regions, where residuals
+// cluster around zero, and edges, where they spread over hundreds of
+// levels. CompressContextFSE instead codes each pixel's MED residual under
+// one of K contexts chosen from the local activity
+//
+//	a = |N - NW| + |W - NW| + |NE - N|
+//
+// which the decoder computes from pixels it has already reconstructed, as
+// JPEG-LS does. Every context gets its own normalised table and its own
+// four-state FSE stream, so decoding stays table-driven: the K streams are
+// decoded up front and the reconstruction loop only picks the next symbol
+// of the context each pixel falls in.
+//
+// Residuals are reduced modulo 2^depth (depth = bits of the max value) and
+// ZigZag mapped, so no escapes are needed. Pixels in the first row or
+// column use the border predictions of PredictorDeltaCompressU16 and the
+// last context. The encoder places the K-1 activity thresholds at quantiles
+// of the image's own activity histogram.
+//
+// The result is a MIC1 container with pipeline PipelineContextFSE:
+//
+//	Byte  0:    Context count K (1-MaxContexts)
+//	Bytes 1..:  K-1 activity thresholds (uint16 LE, strictly increasing);
+//	            context c holds activities in (threshold[c-1], threshold[c]]
+//	Then:       K x {coding u8, length u32 LE}, coding as in packSymbols
+//	After:      Concatenated context streams
+
+// MaxContexts is the largest context count of CompressContextFSE.
+const MaxContexts = 16
+
+// DefaultContexts is the context count CompressContextFSE uses for 0.
+const DefaultContexts = 8
+
+const contextEntrySize = 5 // coding + length
+
+// CompressContextFSE compresses a single 16-bit frame with the MED predictor
+// and one FSE table per activity context. contexts selects K (0 =
+// DefaultContexts); flat images may use fewer. maxValue 0 derives it from
+// the pixels. Decompress and Decode read the result.
+func CompressContextFSE(pixels []uint16, width, height int, maxValue uint16, contexts int) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("context FSE: invalid dimensions %dx%d", width, height)
+	}
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("context FSE: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if contexts == 0 {
+		contexts = DefaultContexts
+	}
+	if contexts < 1 || contexts > MaxContexts {
+		return nil, fmt.Errorf("context FSE: %d contexts outside 1..%d", contexts, MaxContexts)
+	}
+	if maxValue == 0 {
+		for _, v := range pixels {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+		if maxValue == 0 {
+			maxValue = 1
+		}
+	}
+	depth := bits.Len16(maxValue)
+	for i, v := range pixels {
+		if bits.Len16(v) > depth {
+			return nil, fmt.Errorf("context FSE: pixel %d value %d exceeds max value %d", i, v, maxValue)
+		}
+	}
+
+	thresholds := contextThresholds(pixels, width, height, contexts)
+	lut := contextLUT(thresholds)
+	k := len(thresholds) + 1
+
+	streams := make([][]uint16, k)
+	mask, half := int32(1)<<depth-1, int32(1)<<(depth-1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := y*width + x
+			pred, ctx := contextPredict(pixels, index, x, y, width, lut, k)
+			e := (int32(pixels[index])-pred+half)&mask - half
+			streams[ctx] = append(streams[ctx], uint16((e<<1)^(e>>31)))
+		}
+	}
+
+	payload := make([]byte, 1, 1+2*len(thresholds)+k*contextEntrySize)
+	payload[0] = byte(k)
+	for _, t := range thresholds {
+		payload = binary.LittleEndian.AppendUint16(payload, t)
+	}
+	var data []byte
+	for _, s := range streams {
+		coding, coded := byte(symbolsRaw), []byte(nil)
+		if len(s) > 0 {
+			coding, coded = packSymbols(s)
+		}
+		payload = append(payload, coding)
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(len(coded)))
+		data = append(data, coded...)
+	}
+	payload = append(payload, data...)
+
+	var buf bytes.Buffer
+	hdr := MIC1Header{Width: width, Height: height, MaxValue: maxValue, Pipeline: PipelineContextFSE}
+	if err := WriteMIC1(&buf, hdr, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// contextThresholds returns up to contexts-1 strictly increasing activity
+// thresholds at equal-count quantiles of the interior pixels' activity.
+func contextThresholds(pixels []uint16, width, height, contexts int) []uint16 {
+	if contexts == 1 || width < 2 || height < 2 {
+		return nil
+	}
+	hist := make([]int, 1<<16)
+	n := 0
+	for y := 1; y < height; y++ {
+		for x := 1; x < width; x++ {
+			i := y*width + x
+			hist[contextActivity(pixels, i, x, width)]++
+			n++
+		}
+	}
+
+	thresholds := make([]uint16, 0, contexts-1)
+	sum, next := 0, 1
+	for a, c := range hist {
+		sum += c
+		// Every quantile this activity reaches closes one context.
+		reached := false
+		for next < contexts && sum*contexts >= next*n {
+			next++
+			reached = true
+		}
+		if reached && a < len(hist)-1 {
+			thresholds = append(thresholds, uint16(a))
+		}
+		if next == contexts {
+			break
+		}
+	}
+	return thresholds
+}
+
+// contextActivity returns the clamped activity of interior pixel (x, y) at
+// index i.
+func contextActivity(img []uint16, i, x, width int) int32 {
+	n, w, nw := int32(img[i-width]), int32(img[i-1]), int32(img[i-width-1])
+	ne := nw
+	if x+1 < width {
+		ne = int32(img[i-width+1])
+	}
+	return int32(min(int(abs(n-nw)+abs(w-nw)+abs(ne-n)), 1<<16-1))
+}
+
+// contextLUT maps every activity up to the last threshold to its context;
+// larger activities fall in the last context.
+func contextLUT(thresholds []uint16) []uint8 {
+	if len(thresholds) == 0 {
+		return nil
+	}
+	lut := make([]uint8, int(thresholds[len(thresholds)-1])+1)
+	c := 0
+	for a := range lut {
+		if a > int(thresholds[c]) {
+			c++
+		}
+		lut[a] = uint8(c)
+	}
+	return lut
+}
+
+// contextPredict returns the MED prediction and the context of pixel (x, y)
+// at index of img, whose causal neighbours are final. k is the context
+// count; borders use context k-1.
+func contextPredict(img []uint16, index, x, y, width int, lut []uint8, k int) (int32, int) {
+	switch {
+	case x == 0 && y == 0:
+		return 0, k - 1
+	case y == 0:
+		return int32(img[index-1]), k - 1
+	case x == 0:
+		return int32(img[index-width]), k - 1
+	}
+	ctx := k - 1
+	if a := contextActivity(img, index, x, width); int(a) < len(lut) {
+		ctx = int(lut[a])
+	}
+	return medPredict(int32(img[index-1]), int32(img[index-width]), int32(img[index-width-1])), ctx
+}
+
+// decompressContextFSE decodes a PipelineContextFSE payload into dst, or
+// into a new slice if dst is nil.
+func (d *Decoder) decompressContextFSE(dst []uint16, payload []byte, width, height int, maxValue uint16) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	if maxValue == 0 {
+		return nil, errors.New("context FSE: zero max value")
+	}
+	if len(payload) < 1 {
+		return nil, errors.New("context FSE: payload truncated")
+	}
+	k := int(payload[0])
+	if k < 1 || k > MaxContexts {
+		return nil, fmt.Errorf("context FSE: %d contexts outside 1..%d", k, MaxContexts)
+	}
+	off := 1 + 2*(k-1)
+	tableEnd := off + k*contextEntrySize
+	if len(payload) < tableEnd {
+		return nil, errors.New("context FSE: context table truncated")
+	}
+	thresholds := make([]uint16, k-1)
+	for i := range thresholds {
+		thresholds[i] = binary.LittleEndian.Uint16(payload[1+2*i:])
+		if i > 0 && thresholds[i] <= thresholds[i-1] {
+			return nil, errors.New("context FSE: thresholds not increasing")
+		}
+	}
+
+	n := width * height
+	streams := make([][]uint16, k)
+	pos := tableEnd
+	for c := range streams {
+		e := payload[off+c*contextEntrySize:]
+		length := int(binary.LittleEndian.Uint32(e[1:5]))
+		if length > len(payload)-pos {
+			return nil, fmt.Errorf("context FSE: context %d stream truncated", c)
+		}
+		if length > 0 {
+			s, err := unpackSymbols(e[0], payload[pos:pos+length], symbolLimit(n))
+			if err != nil {
+				return nil, fmt.Errorf("context FSE: context %d: %w", c, err)
+			}
+			streams[c] = s
+		}
+		pos += length
+	}
+
+	out := dst
+	if out == nil {
+		out = make([]uint16, n)
+	}
+	out = out[:n]
+	lut := contextLUT(thresholds)
+	next := make([]int, k)
+	depth := bits.Len16(maxValue)
+	mask := int32(1)<<depth - 1
+	for y := 0; y < height; y++ {
+		// Borders: the first row, then the first pixel of every other row.
+		borderW := 1
+		if y == 0 {
+			borderW = width
+		}
+		for x := 0; x < borderW; x++ {
+			index := y*width + x
+			pred, ctx := contextPredict(out, index, x, y, width, lut, k)
+			if next[ctx] >= len(streams[ctx]) {
+				return nil, fmt.Errorf("context FSE: context %d stream exhausted", ctx)
+			}
+			z := int32(streams[ctx][next[ctx]])
+			next[ctx]++
+			out[index] = uint16((pred + (z>>1 ^ -(z & 1))) & mask)
+		}
+		if y == 0 {
+			continue
+		}
+
+		// Interior: contextPredict inlined over the current and previous rows.
+		row, prev := out[y*width:(y+1)*width], out[(y-1)*width:y*width]
+		for x := 1; x < width; x++ {
+			w, n, nw := int32(row[x-1]), int32(prev[x]), int32(prev[x-1])
+			ne := nw
+			if x+1 < width {
+				ne = int32(prev[x+1])
+			}
+			ctx := k - 1
+			if a := int(abs(n-nw) + abs(w-nw) + abs(ne-n)); a < len(lut) {
+				ctx = int(lut[a])
+			}
+			s := streams[ctx]
+			if next[ctx] >= len(s) {
+				return nil, fmt.Errorf("context FSE: context %d stream exhausted", ctx)
+			}
+			z := int32(s[next[ctx]])
+			next[ctx]++
+			row[x] = uint16((medPredict(w, n, nw) + (z>>1 ^ -(z & 1))) & mask)
+		}
+	}
+	for c, s := range streams {
+		if next[c] != len(s) {
+			return nil, fmt.Errorf("context FSE: context %d has %d unused symbols", c, len(s)-next[c])
+		}
+	}
+	return out, nil
+}
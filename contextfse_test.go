@@ -0,0 +1,152 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestContextFSERoundtrip verifies pixel-exact roundtrip through Decompress
+// and Decode for several context counts.
+func TestContextFSERoundtrip(t *testing.T) {
+	for _, td := range testFiles[:2] { // MR 256x256, CT 512x512
+		_, pixels, maxVal, width, height := SetupTests(td)
+
+		for _, k := range []int{0, 1, 4, MaxContexts} {
+			t.Run(fmt.Sprintf("%s_k%d", td.name, k), func(t *testing.T) {
+				blob, err := CompressContextFSE(pixels, width, height, maxVal, k)
+				if err != nil {
+					t.Fatalf("compress: %v", err)
+				}
+				got, w, h, err := Decompress(blob)
+				if err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+				if w != width || h != height {
+					t.Fatalf("dimension mismatch: got %dx%d, want %dx%d", w, h, width, height)
+				}
+				assertPixelsEqual(t, pixels, got, td.name)
+
+				img, err := Decode(blob)
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				assertPixelsEqual(t, pixels, img.Pixels, "Decode")
+			})
+		}
+	}
+}
+
+// TestContextFSEEdgeCases covers flat, tiny and full-range images.
+func TestContextFSEEdgeCases(t *testing.T) {
+	ramp := make([]uint16, 17*5)
+	for i := range ramp {
+		ramp[i] = uint16(i * 771) // spans the 16-bit range
+	}
+	for _, tc := range []struct {
+		name          string
+		pixels        []uint16
+		width, height int
+	}{
+		{"flat", make([]uint16, 64*64), 64, 64},
+		{"single", []uint16{42}, 1, 1},
+		{"row", []uint16{1, 5, 2, 9, 9, 0}, 6, 1},
+		{"column", []uint16{1, 5, 2, 9, 9, 0}, 1, 6},
+		{"ramp16", ramp, 17, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			blob, err := CompressContextFSE(tc.pixels, tc.width, tc.height, 0, 0)
+			if err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			got, _, _, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			assertPixelsEqual(t, tc.pixels, got, tc.name)
+		})
+	}
+
+	pixels, _ := fuzzPixels()
+	dst := make([]uint16, fuzzWidth*fuzzHeight)
+	blob, err := CompressContextFSE(pixels, fuzzWidth, fuzzHeight, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DecompressInto(dst, blob); err != nil {
+		t.Fatal(err)
+	}
+	assertPixelsEqual(t, pixels, dst, "DecompressInto")
+
+	if _, err := CompressContextFSE(pixels, fuzzWidth, fuzzHeight, 0, MaxContexts+1); err == nil {
+		t.Error("expected error for too many contexts")
+	}
+	if _, err := CompressContextFSE(pixels, fuzzWidth, fuzzHeight, 1, 0); err == nil {
+		t.Error("expected error for pixels above max value")
+	}
+	if _, _, _, err := Decompress(blob[:len(blob)-3]); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+}
+
+// TestContextFSEComparisonTable prints the ratio of context-bucketed FSE
+// against the single-table MIC and MED pipelines for every test image.
+func TestContextFSEComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== Compression ratio: single-table vs context-bucketed FSE ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %7s  %7s  %7s  %7s  %7s\n", "Image", "MIC", "MED", "CTX-1", "CTX-8", "CTX-16")
+	fmt.Println("------  -------  -------  -------  -------  -------")
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		if len(pixels) == 0 {
+			continue
+		}
+		orig := float64(width * height * 2)
+
+		line := fmt.Sprintf("%-6s", td.name)
+		mic, err := CompressSingleFrame(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: MIC: %v", td.name, err)
+		}
+		med, err := Compress(pixels, width, height, CompressOptions{Predictor: PredictorMED, MaxValue: maxVal})
+		if err != nil {
+			t.Fatalf("%s: MED: %v", td.name, err)
+		}
+		line += fmt.Sprintf("  %6.2fx  %6.2fx", orig/float64(len(mic)), orig/float64(len(med)))
+		for _, k := range []int{1, 8, 16} {
+			blob, err := CompressContextFSE(pixels, width, height, maxVal, k)
+			if err != nil {
+				t.Fatalf("%s: k=%d: %v", td.name, k, err)
+			}
+			line += fmt.Sprintf("  %6.2fx", orig/float64(len(blob)))
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+func BenchmarkContextFSEDecompress(b *testing.B) {
+	_, pixels, maxVal, width, height := SetupTests(testFiles[1]) // CT
+	blob, err := CompressContextFSE(pixels, width, height, maxVal, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dst := make([]uint16, width*height)
+	b.SetBytes(int64(width * height * 2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := DecompressInto(dst, blob); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
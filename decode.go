@@ -0,0 +1,322 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Universal decode entry point.
+//
+// Decode inspects the first bytes of a blob and dispatches to the matching
+// container decoder, so callers no longer need to know in advance which
+// writer produced a file:
+//
+//	"MIC1"  single-frame greyscale (CompressSingleFrame stream, Compress,
+//	        CompressLossy, CompressContextFSE, CompressArchive or
+//	        CompressSplitStream output)
+//	"MICR"  single-frame RGB (CompressRGB blob + dimensions)
+//	"MIC2"  multi-frame greyscale (CompressMultiFrame); "MC2C" when checksummed,
+//	        "MC2S" when signed
+//	"MIC3"  tiled WSI pyramid (CompressWSI) — level 0 is returned
+//	"PICS"  parallel strips (CompressParallelStrips*); "PICC" when checksummed,
+//	        "PICP" with a predictor other than avg
+//	"PICA"  adaptive parallel strips (CompressParallelStripsAdaptive); "PIAP"
+//	        when a strip uses a predictor other than avg or grad
+//	"PICT"  parallel tiles (CompressParallelTiles)
+//	"MICS"  streamed row groups (FrameWriter)
+//	"MICP"  resolution-progressive wavelet (CompressProgressive) — full resolution
+//	wavelet WaveletV2RLEFSECompressU16 / WaveletV2SIMDRLEFSECompressU16 stream
+//
+// The wavelet stream has no magic of its own; it is recognised by its
+// 11-byte header followed by the four-state FSE magic [0xFF, 0x04].
+// Bare CompressSingleFrame streams carry no dimensions and cannot be decoded
+// here; wrap them in a MIC1 container first.
+
+// Format names reported in DecodedImage.Format.
+const (
+	FormatMIC1    = "MIC1"
+	FormatMICR    = "MICR"
+	FormatMIC2    = "MIC2"
+	FormatMIC3    = "MIC3"
+	FormatPICS    = "PICS"
+	FormatPICA    = "PICA"
+	FormatPICT    = "PICT"
+	FormatMICS    = "MICS"
+	FormatMICP    = "MICP"
+	FormatWavelet = "WAVELET"
+)
+
+const waveletHeaderSize = 11 // rows + cols + maxValue + levels
+
+// ErrUnknownFormat is returned by Decode when the input does not start with
+// any recognised MIC container magic.
+var ErrUnknownFormat = errors.New("mic: unrecognised container format")
+
+// DecodedImage is the result of Decode: fully reconstructed samples plus the
+// metadata needed to interpret them.
+type DecodedImage struct {
+	Format     string // container that was decoded (one of the Format* constants)
+	Width      int
+	Height     int
+	BitDepth   int // significant bits per sample
+	Channels   int // 1 = greyscale, 3 = RGB
+	FrameCount int
+
+	// Pixels holds greyscale samples in row-major order with frames stored
+	// back to back (frame i starts at i*Width*Height). Nil for RGB images.
+	// When Signed is set each element is an int16 sample in two's
+	// complement; convert with int16(Pixels[i]).
+	Pixels []uint16
+	Signed bool
+
+	// RGB holds interleaved 8-bit RGB samples when Channels == 3.
+	RGB []byte
+}
+
+// Frame returns the greyscale samples of frame i, or nil if i is out of range.
+func (d *DecodedImage) Frame(i int) []uint16 {
+	n := d.Width * d.Height
+	if i < 0 || i >= d.FrameCount || (i+1)*n > len(d.Pixels) {
+		return nil
+	}
+	return d.Pixels[i*n : (i+1)*n]
+}
+
+// Decode decodes any MIC container, detecting the format from its magic.
+func Decode(data []byte) (*DecodedImage, error) {
+	var d Decoder
+	return d.Decode(data)
+}
+
+// Decode is Decode under d's limits.
+func (d *Decoder) Decode(data []byte) (*DecodedImage, error) {
+	if len(data) < 4 {
+		return nil, ErrUnknownFormat
+	}
+
+	switch string(data[0:4]) {
+	case mic1Magic:
+		return d.decodeMIC1(data)
+	case micrMagic:
+		return d.decodeMICR(data)
+	case mic2Magic, mic2SignedMagic, mic2CRCMagic:
+		return d.decodeMIC2(data)
+	case mic3Magic:
+		return d.decodeMIC3(data)
+	case picsMagic, picsCRCMagic, picpMagic:
+		pixels, w, h, err := d.DecompressParallelStrips(data)
+		if err != nil {
+			return nil, err
+		}
+		return greyImage(FormatPICS, pixels, w, h, 1), nil
+	case picaMagic, piapMagic:
+		pixels, w, h, err := d.DecompressParallelStripsAdaptive(data)
+		if err != nil {
+			return nil, err
+		}
+		return greyImage(FormatPICA, pixels, w, h, 1), nil
+	case pictMagic:
+		pixels, w, h, err := d.DecompressParallelTiles(data)
+		if err != nil {
+			return nil, err
+		}
+		return greyImage(FormatPICT, pixels, w, h, 1), nil
+	case micsMagic:
+		pixels, w, h, err := d.DecompressFrameStream(data)
+		if err != nil {
+			return nil, err
+		}
+		img := greyImage(FormatMICS, pixels, w, h, 1)
+		if maxValue := binary.LittleEndian.Uint16(data[16:18]); maxValue != 0 {
+			img.BitDepth = bits.Len16(maxValue)
+		}
+		return img, nil
+	case micpMagic:
+		pixels, w, h, err := d.DecodeAtLevel(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		img := greyImage(FormatMICP, pixels, w, h, 1)
+		if maxValue := binary.LittleEndian.Uint16(data[12:14]); maxValue != 0 {
+			img.BitDepth = bits.Len16(maxValue)
+		}
+		return img, nil
+	}
+
+	if isWaveletStream(data) {
+		pixels, rows, cols, err := d.WaveletV2SIMDRLEFSEDecompressU16(data)
+		if err != nil {
+			return nil, fmt.Errorf("wavelet: %w", err)
+		}
+		img := greyImage(FormatWavelet, pixels, cols, rows, 1)
+		if maxValue := binary.LittleEndian.Uint16(data[8:10]); maxValue != 0 {
+			img.BitDepth = bits.Len16(maxValue)
+		}
+		return img, nil
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// decodeMIC1 decodes a MIC1 single-frame container.
+func (d *Decoder) decodeMIC1(data []byte) (*DecodedImage, error) {
+	hdr, payload, err := ReadMIC1Header(data)
+	if err != nil {
+		return nil, err
+	}
+	pixels, err := d.decompressMIC1Payload(nil, hdr, payload)
+	if err != nil {
+		return nil, err
+	}
+	var img *DecodedImage
+	if hdr.Signed {
+		img = signedImage(FormatMIC1, pixels, hdr.MinValue, hdr.Width, hdr.Height, 1)
+	} else {
+		img = greyImage(FormatMIC1, pixels, hdr.Width, hdr.Height, 1)
+	}
+	if hdr.BitsStored != 0 {
+		img.BitDepth = hdr.BitsStored
+	}
+	return img, nil
+}
+
+// decodeMICR decodes a MICR single-frame RGB container.
+func (d *Decoder) decodeMICR(data []byte) (*DecodedImage, error) {
+	hdr, payload, err := ReadMICRHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Pipeline != PipelineDeltaRLEFSE {
+		return nil, fmt.Errorf("MICR: unsupported pipeline type %d", hdr.Pipeline)
+	}
+
+	rgb, err := d.DecompressRGB(payload, hdr.Width, hdr.Height)
+	if err != nil {
+		return nil, fmt.Errorf("MICR: %w", err)
+	}
+	return &DecodedImage{
+		Format:     FormatMICR,
+		Width:      hdr.Width,
+		Height:     hdr.Height,
+		BitDepth:   hdr.BitsStored,
+		Channels:   3,
+		FrameCount: 1,
+		RGB:        rgb,
+	}, nil
+}
+
+// decodeMIC2 decodes every frame of a MIC2 multi-frame container.
+func (d *Decoder) decodeMIC2(data []byte) (*DecodedImage, error) {
+	frames, hdr, err := d.DecompressMultiFrame(data)
+	if err != nil {
+		return nil, err
+	}
+	n := hdr.Width * hdr.Height
+	pixels := make([]uint16, 0, n*len(frames))
+	for _, f := range frames {
+		pixels = append(pixels, f...)
+	}
+	if hdr.Signed {
+		return signedImage(FormatMIC2, pixels, hdr.MinValue, hdr.Width, hdr.Height, hdr.FrameCount), nil
+	}
+	return greyImage(FormatMIC2, pixels, hdr.Width, hdr.Height, hdr.FrameCount), nil
+}
+
+// decodeMIC3 decodes the full-resolution level of a MIC3 WSI container.
+func (d *Decoder) decodeMIC3(data []byte) (*DecodedImage, error) {
+	hdr, err := ReadWSIHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := d.DecompressWSIRegion(data, 0, 0, 0, hdr.Width, hdr.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	if hdr.Channels == 3 {
+		return &DecodedImage{
+			Format:     FormatMIC3,
+			Width:      hdr.Width,
+			Height:     hdr.Height,
+			BitDepth:   hdr.BitsPerSample,
+			Channels:   3,
+			FrameCount: 1,
+			RGB:        raw,
+		}, nil
+	}
+
+	img := greyImage(FormatMIC3, bytesToUint16Slice(raw, hdr.BitsPerSample), hdr.Width, hdr.Height, 1)
+	img.BitDepth = hdr.BitsPerSample
+	return img, nil
+}
+
+// greyImage wraps decoded greyscale samples, deriving the bit depth from the
+// largest sample value.
+func greyImage(format string, pixels []uint16, width, height, frames int) *DecodedImage {
+	var maxValue uint16
+	for _, v := range pixels {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+	depth := bits.Len16(maxValue)
+	if depth == 0 {
+		depth = 1
+	}
+	return &DecodedImage{
+		Format:     format,
+		Width:      width,
+		Height:     height,
+		BitDepth:   depth,
+		Channels:   1,
+		FrameCount: frames,
+		Pixels:     pixels,
+	}
+}
+
+// signedImage wraps stored samples of a signed container, adding minValue
+// back in place. BitDepth is the two's complement width of the sample range.
+func signedImage(format string, stored []uint16, minValue int16, width, height, frames int) *DecodedImage {
+	var lo, hi int16
+	for i, v := range stored {
+		s := int16(v + uint16(minValue))
+		if i == 0 || s < lo {
+			lo = s
+		}
+		if i == 0 || s > hi {
+			hi = s
+		}
+		stored[i] = uint16(s)
+	}
+	return &DecodedImage{
+		Format:     format,
+		Width:      width,
+		Height:     height,
+		BitDepth:   signedBitDepth(lo, hi),
+		Channels:   1,
+		FrameCount: frames,
+		Pixels:     stored,
+		Signed:     true,
+	}
+}
+
+// isWaveletStream reports whether data looks like a WaveletV2 stream: a
+// plausible rows/cols/levels header followed by a four-state FSE payload.
+func isWaveletStream(data []byte) bool {
+	if len(data) < waveletHeaderSize+2 {
+		return false
+	}
+	rows := binary.LittleEndian.Uint32(data[0:4])
+	cols := binary.LittleEndian.Uint32(data[4:8])
+	levels := data[10]
+	if rows == 0 || cols == 0 || rows > 1<<20 || cols > 1<<20 || levels > 8 {
+		return false
+	}
+	return data[waveletHeaderSize] == fourStateMagic0 && data[waveletHeaderSize+1] == fourStateMagic1
+}
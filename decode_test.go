@@ -0,0 +1,161 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// legacyMIC1 builds an unversioned MIC1 container the way cmd/mic-compress
+// wrote it before the header gained a version field.
+func legacyMIC1(width, height int, compressed []byte) []byte {
+	out := make([]byte, 20+len(compressed))
+	copy(out[0:4], "MIC1")
+	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
+	binary.LittleEndian.PutUint32(out[12:16], 1)
+	binary.LittleEndian.PutUint32(out[16:20], uint32(len(compressed)))
+	copy(out[20:], compressed)
+	return out
+}
+
+func assertPixelsEqual(t *testing.T, want, got []uint16, label string) {
+	t.Helper()
+	if len(want) != len(got) {
+		t.Fatalf("%s: length mismatch: got %d, want %d", label, len(got), len(want))
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Fatalf("%s: pixel %d mismatch: got %d, want %d", label, i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeGreyscaleContainers(t *testing.T) {
+	width, height := 96, 80
+	frames, maxValue := makeSmoothFrames(width, height, 3, 7)
+	pixels := frames[0]
+
+	single, err := CompressSingleFrame(pixels, width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pics, err := CompressParallelStrips(pixels, width, height, maxValue, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pica, err := CompressParallelStripsAdaptive(pixels, width, height, maxValue, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pict, err := CompressParallelTiles(pixels, width, height, maxValue, 64, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wavelet, err := WaveletV2RLEFSECompressU16(pixels, height, width, maxValue, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		format string
+		data   []byte
+	}{
+		{FormatMIC1, legacyMIC1(width, height, single)},
+		{FormatPICS, pics},
+		{FormatPICA, pica},
+		{FormatPICT, pict},
+		{FormatWavelet, wavelet},
+	}
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			img, err := Decode(c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Format != c.format || img.Width != width || img.Height != height ||
+				img.Channels != 1 || img.FrameCount != 1 {
+				t.Fatalf("unexpected metadata: %+v", img)
+			}
+			if img.BitDepth < 9 || img.BitDepth > 16 {
+				t.Fatalf("unexpected bit depth %d", img.BitDepth)
+			}
+			assertPixelsEqual(t, pixels, img.Pixels, c.format)
+		})
+	}
+}
+
+func TestDecodeMultiFrame(t *testing.T) {
+	width, height := 64, 48
+	frames, maxValue := makeSmoothFrames(width, height, 4, 11)
+	data, err := CompressMultiFrame(frames, width, height, maxValue, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatMIC2 || img.FrameCount != len(frames) {
+		t.Fatalf("unexpected metadata: %+v", img)
+	}
+	for i, want := range frames {
+		assertPixelsEqual(t, want, img.Frame(i), "frame "+itoa(i))
+	}
+	if img.Frame(len(frames)) != nil {
+		t.Fatal("expected nil for out-of-range frame")
+	}
+}
+
+func TestDecodeRGBContainers(t *testing.T) {
+	width, height := 300, 200
+	rgb := makeWSITestImage(width, height, 3)
+
+	blob, err := CompressRGB(rgb, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	micr := make([]byte, 12+len(blob))
+	copy(micr[0:4], "MICR")
+	binary.LittleEndian.PutUint32(micr[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(micr[8:12], uint32(height))
+	copy(micr[12:], blob)
+
+	wsi, err := CompressWSI(rgb, width, height, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		format string
+		data   []byte
+	}{{FormatMICR, micr}, {FormatMIC3, wsi}} {
+		img, err := Decode(c.data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.format, err)
+		}
+		if img.Format != c.format || img.Channels != 3 || img.BitDepth != 8 ||
+			img.Width != width || img.Height != height {
+			t.Fatalf("%s: unexpected metadata: %+v", c.format, img)
+		}
+		assertBytesEqual(t, rgb, img.RGB, c.format)
+	}
+}
+
+func TestDecodeUnknownFormat(t *testing.T) {
+	for _, data := range [][]byte{nil, {1, 2}, []byte("JUNKJUNKJUNKJUNK")} {
+		if _, err := Decode(data); !errors.Is(err, ErrUnknownFormat) {
+			t.Fatalf("Decode(%q): got %v, want ErrUnknownFormat", data, err)
+		}
+	}
+
+	// A truncated MIC1 container must fail cleanly.
+	if _, err := Decode([]byte("MIC1\x01\x00")); err == nil {
+		t.Fatal("expected error for truncated MIC1")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// Decoder resource limits.
+//
+// Container headers declare dimensions, frame counts and tile counts that a
+// decoder would otherwise trust before allocating. A Decoder checks every
+// such field against its DecodeLimits first and fails with an
+// *ErrLimitExceeded instead of committing the memory:
+//
+//	d := mic.Decoder{Limits: mic.DecodeLimits{MaxPixels: 64 << 20}}
+//	img, err := d.Decode(untrusted)
+//
+// The package-level Decode and Decompress* functions use a zero Decoder, and
+// so DefaultDecodeLimits. The limits generalise ScratchU16.DecompressLimit:
+// entropy-decoder output is additionally capped at what the declared image
+// can need.
+
+// DecodeLimits bounds the resources a Decoder may commit to for one input.
+// A zero or negative field selects the matching DefaultDecodeLimits value.
+type DecodeLimits struct {
+	// MaxPixels caps width*height of any single image, frame, tile or
+	// requested region.
+	MaxPixels int
+
+	// MaxFrames caps the frame count of a MIC2 container.
+	MaxFrames int
+
+	// MaxTiles caps the tile count of a MIC3 container.
+	MaxTiles int
+
+	// MaxOutputBytes caps the total size of the decoded samples returned by
+	// one call, across all frames.
+	MaxOutputBytes int64
+}
+
+// DefaultDecodeLimits are the limits of a zero Decoder. They are far above
+// any single medical image (a 4096x3328 mammogram is ~13.6M pixels) while
+// keeping a crafted header from requesting an unbounded allocation.
+var DefaultDecodeLimits = DecodeLimits{
+	MaxPixels:      1 << 30,
+	MaxFrames:      1 << 16,
+	MaxTiles:       1 << 24,
+	MaxOutputBytes: 4 << 30,
+}
+
+// ErrLimitExceeded is returned when a header asks for more than a
+// DecodeLimits field allows. Use errors.As to recover which limit tripped.
+type ErrLimitExceeded struct {
+	Limit     string // "pixels", "frames", "tiles" or "output bytes"
+	Requested uint64
+	Max       uint64
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	return fmt.Sprintf("decode limit exceeded: %d %s requested, limit %d", e.Requested, e.Limit, e.Max)
+}
+
+// Decoder decodes MIC streams and containers under a set of resource limits.
+// The zero value uses DefaultDecodeLimits. A Decoder keeps entropy tables
+// and symbol buffers in an internal pool between calls, so reusing one
+// Decoder avoids most per-call allocation. It is safe for concurrent use
+// and must not be copied after first use.
+type Decoder struct {
+	Limits DecodeLimits
+
+	scratch sync.Pool // of *frameScratch
+}
+
+// limits returns d.Limits with unset fields filled from DefaultDecodeLimits.
+func (d *Decoder) limits() DecodeLimits {
+	l := DefaultDecodeLimits
+	if d != nil {
+		if d.Limits.MaxPixels > 0 {
+			l.MaxPixels = d.Limits.MaxPixels
+		}
+		if d.Limits.MaxFrames > 0 {
+			l.MaxFrames = d.Limits.MaxFrames
+		}
+		if d.Limits.MaxTiles > 0 {
+			l.MaxTiles = d.Limits.MaxTiles
+		}
+		if d.Limits.MaxOutputBytes > 0 {
+			l.MaxOutputBytes = d.Limits.MaxOutputBytes
+		}
+	}
+	return l
+}
+
+// checkImage validates a declared width x height of frames images with
+// bytesPerPixel bytes of output each, against MaxPixels and MaxOutputBytes.
+func (d *Decoder) checkImage(width, height, frames, bytesPerPixel int) error {
+	if width <= 0 || height <= 0 || frames < 0 {
+		return fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+	l := d.limits()
+	hi, pixels := bits.Mul64(uint64(width), uint64(height))
+	if hi != 0 || pixels > uint64(l.MaxPixels) {
+		return &ErrLimitExceeded{Limit: "pixels", Requested: pixels, Max: uint64(l.MaxPixels)}
+	}
+	hi, out := bits.Mul64(pixels, uint64(frames)*uint64(bytesPerPixel))
+	if hi != 0 || out > uint64(l.MaxOutputBytes) {
+		return &ErrLimitExceeded{Limit: "output bytes", Requested: out, Max: uint64(l.MaxOutputBytes)}
+	}
+	return nil
+}
+
+// checkFrames validates a MIC2 frame count against MaxFrames.
+func (d *Decoder) checkFrames(n int) error {
+	if l := d.limits(); n > l.MaxFrames {
+		return &ErrLimitExceeded{Limit: "frames", Requested: uint64(n), Max: uint64(l.MaxFrames)}
+	}
+	return nil
+}
+
+// checkTiles validates a MIC3 tile count against MaxTiles.
+func (d *Decoder) checkTiles(n int) error {
+	if l := d.limits(); n > l.MaxTiles {
+		return &ErrLimitExceeded{Limit: "tiles", Requested: uint64(n), Max: uint64(l.MaxTiles)}
+	}
+	return nil
+}
+
+// symbolLimit returns the ScratchU16.DecompressLimit for the Delta+RLE symbol
+// stream of an n-pixel image. Each pixel costs at most an escape plus a raw
+// value, and every run adds one count, so 4n symbols is a safe ceiling.
+func symbolLimit(n int) int {
+	return 4*n + 64
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	width, height := 96, 64
+	frames, maxValue := makeSmoothFrames(width, height, 3, 7)
+
+	mic2, err := CompressMultiFrame(frames, width, height, maxValue, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pics, err := CompressParallelStrips(frames[0], width, height, maxValue, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wavelet, err := WaveletV2SIMDRLEFSECompressU16(frames[0], height, width, maxValue, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mic3, err := CompressWSI(makeWSITestImage(300, 200, 8), 300, 200, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := width * height
+	cases := []struct {
+		name   string
+		data   []byte
+		limits DecodeLimits
+		limit  string // "" = must decode
+	}{
+		{"MIC2 defaults", mic2, DecodeLimits{}, ""},
+		{"MIC2 exact", mic2, DecodeLimits{MaxPixels: n, MaxFrames: 3, MaxOutputBytes: int64(3 * n * 2)}, ""},
+		{"MIC2 frames", mic2, DecodeLimits{MaxFrames: 2}, "frames"},
+		{"MIC2 output", mic2, DecodeLimits{MaxOutputBytes: int64(3*n*2 - 1)}, "output bytes"},
+		{"PICS pixels", pics, DecodeLimits{MaxPixels: n - 1}, "pixels"},
+		{"wavelet pixels", wavelet, DecodeLimits{MaxPixels: n - 1}, "pixels"},
+		{"MIC3 tiles", mic3, DecodeLimits{MaxTiles: 1}, "tiles"},
+		{"MIC3 output", mic3, DecodeLimits{MaxOutputBytes: 300*200*3 - 1}, "output bytes"},
+	}
+	for _, tc := range cases {
+		d := Decoder{Limits: tc.limits}
+		_, err := d.Decode(tc.data)
+		if tc.limit == "" {
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		var le *ErrLimitExceeded
+		if !errors.As(err, &le) {
+			t.Fatalf("%s: expected *ErrLimitExceeded, got %v", tc.name, err)
+		}
+		if le.Limit != tc.limit {
+			t.Fatalf("%s: tripped %q limit, want %q", tc.name, le.Limit, tc.limit)
+		}
+	}
+
+	// A single frame only needs room for itself.
+	d := Decoder{Limits: DecodeLimits{MaxOutputBytes: int64(n * 2)}}
+	if _, _, err := d.DecompressFrame(mic2, 2); err != nil {
+		t.Fatalf("DecompressFrame: %v", err)
+	}
+}
+
+func TestDecodeLimitsForgedHeader(t *testing.T) {
+	width, height := 64, 32
+	frames, maxValue := makeSmoothFrames(width, height, 1, 3)
+	pics, err := CompressParallelStrips(frames[0], width, height, maxValue, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 65535x65535 passes the structural checks but must be refused before
+	// anything is allocated.
+	binary.LittleEndian.PutUint32(pics[4:8], 0xFFFF)
+	binary.LittleEndian.PutUint32(pics[8:12], 0xFFFF)
+	_, err = Decode(pics)
+	var le *ErrLimitExceeded
+	if !errors.As(err, &le) || le.Limit != "pixels" {
+		t.Fatalf("expected pixels limit error, got %v", err)
+	}
+	if le.Requested != 0xFFFF*0xFFFF || le.Max != uint64(DefaultDecodeLimits.MaxPixels) {
+		t.Fatalf("unexpected error fields: %+v", le)
+	}
+}
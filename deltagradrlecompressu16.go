@@ -5,6 +5,7 @@
 package mic
 
 import (
+	"errors"
 	"math/bits"
 )
 
@@ -68,10 +69,24 @@ func (d *GradDeltaRleCompressU16) Compress(in []uint16, width int, height int, m
 	return d.out.Out[:], nil
 }
 
-func (d *GradDeltaRleDecompressU16) Decompress(in []uint16, width int, height int) {
+// Decompress decodes a stream produced by GradDeltaRleCompressU16.Compress
+// into d.Out. It returns an error if the stream is truncated or its
+// dimensions do not fit the input.
+func (d *GradDeltaRleDecompressU16) Decompress(in []uint16, width int, height int) error {
 	d.decomp.Init(in)
 	maxValue := d.decomp.DecodeNext2()
+	if err := d.decomp.checkImageLen(width, height); err != nil {
+		d.Out = nil
+		return err
+	}
+	if maxValue == 0 {
+		d.Out = nil
+		return errors.New("grad-delta+rle: zero max value")
+	}
 	d.Out = make([]uint16, width*height)
+	if width == 0 || height == 0 {
+		return nil
+	}
 	pixelDepth := bits.Len16(maxValue)
 	d.deltaThreshold = uint16((1 << (pixelDepth - 1)) - 1)
 	d.delimiterForOverflow = uint16((1 << pixelDepth) - 1)
@@ -121,6 +136,7 @@ func (d *GradDeltaRleDecompressU16) Decompress(in []uint16, width int, height in
 			}
 		}
 	}
+	return d.decomp.Err()
 }
 
 func (d *GradDeltaRleDecompressU16) decodePixel(idx int) {
@@ -0,0 +1,117 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"math/bits"
+)
+
+// paethPredict returns the Paeth predictor value (PNG spec).
+// a = left, b = top, c = top-left.  Always returns one of {a, b, c}.
+func paethPredict(a, b, c int32) int32 {
+	p := a + b - c
+	pa := p - a
+	if pa < 0 {
+		pa = -pa
+	}
+	pb := p - b
+	if pb < 0 {
+		pb = -pb
+	}
+	pc := p - c
+	if pc < 0 {
+		pc = -pc
+	}
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+// PaethDeltaCompressU16 applies the PNG Paeth predictor to 16-bit pixel data.
+// Paeth picks whichever of left, top and top-left is closest to
+// left+top-top-left, so it follows edges much like MED; the ablations show
+// it ahead of the avg predictor on CT and MR.
+func PaethDeltaCompressU16(in []uint16, width, height int, maxValue uint16) ([]uint16, error) {
+	pixelDepth := bits.Len16(maxValue)
+	deltaThreshold := uint16((1 << (pixelDepth - 1)) - 1)
+	delimiterForOverflow := uint16((1 << pixelDepth) - 1)
+	out := make([]uint16, 0, width*height*2)
+	out = append(out, maxValue)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := y*width + x
+
+			var predicted int32
+			if x == 0 && y == 0 {
+				predicted = 0
+			} else if y == 0 {
+				predicted = int32(in[index-1]) // left only
+			} else if x == 0 {
+				predicted = int32(in[index-width]) // top only
+			} else {
+				a := int32(in[index-1])
+				b := int32(in[index-width])
+				c := int32(in[index-width-1])
+				predicted = paethPredict(a, b, c)
+			}
+
+			inputVal := in[index]
+			diff := int32(inputVal) - predicted
+
+			if uint16(abs(diff)) >= deltaThreshold {
+				out = append(out, delimiterForOverflow)
+				out = append(out, inputVal)
+			} else {
+				out = append(out, uint16(int32(deltaThreshold)+diff))
+			}
+		}
+	}
+	return out, nil
+}
+
+// PaethDeltaDecompressU16 inverts PaethDeltaCompressU16.
+func PaethDeltaDecompressU16(in []uint16, width, height int) []uint16 {
+	maxValue := in[0]
+	pixelDepth := bits.Len16(maxValue)
+	deltaThreshold := uint16((1 << (pixelDepth - 1)) - 1)
+	delimiterForOverflow := uint16((1 << pixelDepth) - 1)
+	out := make([]uint16, width*height)
+	ic := 1
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := y*width + x
+
+			var predicted int32
+			if x == 0 && y == 0 {
+				predicted = 0
+			} else if y == 0 {
+				predicted = int32(out[index-1])
+			} else if x == 0 {
+				predicted = int32(out[index-width])
+			} else {
+				a := int32(out[index-1])
+				b := int32(out[index-width])
+				c := int32(out[index-width-1])
+				predicted = paethPredict(a, b, c)
+			}
+
+			v := in[ic]
+			ic++
+			if v == delimiterForOverflow {
+				out[index] = in[ic]
+				ic++
+			} else {
+				out[index] = uint16(int32(v) - int32(deltaThreshold) + predicted)
+			}
+		}
+	}
+	return out
+}
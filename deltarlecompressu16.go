@@ -5,6 +5,8 @@
 package mic
 
 import (
+	"errors"
+	"fmt"
 	"math/bits"
 )
 
@@ -19,13 +21,24 @@ type DeltaRleDecompressU16 struct {
 	delimiterForOverflow uint16
 	decomp               RleDecompressU16
 	Out                  []uint16
+
+	// Near is the error bound the last decoded stream was written with
+	// (see CompressNear); 0 for a lossless stream.
+	Near int
 }
 
 func (d *DeltaRleCompressU16) Compress(in []uint16, width int, height int, maxValue uint16) ([]uint16, error) {
+	d.out.Out, d.out.b = nil, nil
+	return d.compressReusing(in, width, height, maxValue), nil
+}
+
+// compressReusing is Compress writing into the RLE buffers of the previous
+// call, which the returned slice therefore aliases.
+func (d *DeltaRleCompressU16) compressReusing(in []uint16, width int, height int, maxValue uint16) []uint16 {
 	pixelDepth := bits.Len16(maxValue)
 	d.deltaThreshold = (uint16)((1 << (pixelDepth - 1)) - 1)   // For 16 bits this will be 0x7FFF. We have to ensure that 2 * deltaThreshold is less than delimiter
 	d.delimiterForOverflow = (uint16)((1 << (pixelDepth)) - 1) // For 16 bits this will be 0xFFFF
-	d.out.Init(width, height, d.delimiterForOverflow)
+	d.out.reset(width, height, d.delimiterForOverflow)
 	d.out.Encode(maxValue)
 
 	for y := 0; y < height; y++ {
@@ -63,16 +76,52 @@ func (d *DeltaRleCompressU16) Compress(in []uint16, width int, height int, maxVa
 
 	d.out.Flush()
 
-	return d.out.Out[:], nil
+	return d.out.Out[:]
 }
 
-func (d *DeltaRleDecompressU16) Decompress(in []uint16, width int, height int) {
+// Decompress decodes a stream produced by DeltaRleCompressU16.Compress into
+// d.Out. It returns an error if the stream is truncated or its dimensions do
+// not fit the input.
+func (d *DeltaRleDecompressU16) Decompress(in []uint16, width int, height int) error {
+	return d.decompressInto(nil, in, width, height)
+}
+
+// decompressInto is Decompress with d.Out set to dst[:width*height]; dst is
+// only replaced by a new slice when it is too small.
+func (d *DeltaRleDecompressU16) decompressInto(dst []uint16, in []uint16, width int, height int) error {
 	d.decomp.Init(in)
 	maxValue := d.decomp.DecodeNext2()
-	d.Out = make([]uint16, width*height)
+	d.Near = 0
+	if maxValue == nearMarker {
+		d.Near = int(d.decomp.DecodeNext2())
+		maxValue = d.decomp.DecodeNext2()
+		if d.Near == 0 || d.Near > MaxNear {
+			d.Out = nil
+			return fmt.Errorf("delta+rle: invalid near-lossless bound %d", d.Near)
+		}
+	}
+	if err := d.decomp.checkImageLen(width, height); err != nil {
+		d.Out = nil
+		return err
+	}
+	if maxValue == 0 {
+		d.Out = nil
+		return errors.New("delta+rle: zero max value")
+	}
+	if dst == nil || cap(dst) < width*height {
+		dst = make([]uint16, width*height)
+	}
+	d.Out = dst[:width*height]
+	if width == 0 || height == 0 {
+		return nil
+	}
 	pixelDepth := bits.Len16(maxValue)
 	d.deltaThreshold = (uint16)((1 << (pixelDepth - 1)) - 1)   // For 16 bits this will be 0x7FFF. We have to ensure that 2 * deltaThreshold is less than delimiter
 	d.delimiterForOverflow = (uint16)((1 << (pixelDepth)) - 1) // For 16 bits this will be 0xFFFF
+	if d.Near > 0 {
+		d.decodeNear(width, height)
+		return d.decomp.Err()
+	}
 
 	// decode for y = 0
 	for x := 0; x < width; x++ {
@@ -85,6 +134,7 @@ func (d *DeltaRleDecompressU16) Decompress(in []uint16, width int, height int) {
 			d.DecodeNextSymbolNC(x, y, width, height)
 		}
 	}
+	return d.decomp.Err()
 }
 
 func (d *DeltaRleDecompressU16) DecodeNextSymbolNC(x int, y int, width int, height int) {
@@ -5,6 +5,7 @@
 package mic
 
 import (
+	"fmt"
 	"math/bits"
 )
 
@@ -15,9 +16,19 @@ type DeltaRleHuffDecompressU16 struct {
 	Out                  []uint16
 }
 
-func (d *DeltaRleHuffDecompressU16) Decompress(in []byte, width int, height int) {
-	d.decomp.Init(in)
+// Decompress decodes a Huffman-coded Delta+RLE stream into d.Out. It returns
+// an error if the stream header is corrupt.
+func (d *DeltaRleHuffDecompressU16) Decompress(in []byte, width int, height int) error {
+	if err := d.decomp.Init(in); err != nil {
+		d.Out = nil
+		return err
+	}
 	maxValue := d.decomp.DecodeNext()
+	var limits Decoder
+	if err := limits.checkImage(width, height, 1, 2); err != nil || maxValue == 0 {
+		d.Out = nil
+		return fmt.Errorf("delta+rle+huffman: invalid stream for %dx%d image", width, height)
+	}
 	d.Out = make([]uint16, width*height)
 	pixelDepth := bits.Len16(maxValue)
 	d.deltaThreshold = (uint16)((1 << (pixelDepth - 1)) - 1)   // For 16 bits this will be 0x7FFF. We have to ensure that 2 * deltaThreshold is less than delimiter
@@ -34,6 +45,7 @@ func (d *DeltaRleHuffDecompressU16) Decompress(in []byte, width int, height int)
 			d.DecodeNextSymbolNC(x, y, width, height)
 		}
 	}
+	return nil
 }
 
 func (d *DeltaRleHuffDecompressU16) DecodeNextSymbolNC(x int, y int, width int, height int) {
@@ -41,7 +41,10 @@ func TestDeltaZZRleCompression(t *testing.T) {
 	fmt.Println(rleCompressed)
 	var rleD RleDecompressU16
 	rleD.Init(rleCompressed)
-	rleDecompressed := rleD.Decompress()
+	rleDecompressed, err := rleD.Decompress()
+	if err != nil {
+		t.Fatal(err)
+	}
 	fmt.Println(rleDecompressed)
 	var decomp DeltaZZU16
 	inAgain := decomp.Decompress(rleDecompressed, 4, 3)
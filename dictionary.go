@@ -0,0 +1,573 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Pre-trained FSE table dictionaries.
+//
+// A 256x256 tile often compresses to a few KB, of which the normalised table
+// written by writeCount can be a large share. A Dictionary holds tables
+// trained offline from a corpus of one modality (CT, MR, H&E tiles, ...) by
+// TrainDictionary. Encoder and decoder both hold the dictionary; a stream
+// only names its ID and the table it uses:
+//
+//	[0xFF][0x40][dictionary ID uint32 LE][table uint8][count uint32 LE]
+//	[escapes uint32 LE][escapes x uint16 LE][bitstream]
+//
+// The bitstream is four-state FSE, as FSECompressU16FourState. Every table
+// has a slot for each symbol of the training corpus plus an escape symbol,
+// the smallest value the corpus never produced. A symbol outside the corpus
+// is coded as the escape and its value stored raw in the escape list, in
+// stream order. The encoders that accept a dictionary
+// (CompressOptions.Dictionary, WSIOptions.Dictionary) also code every stream
+// with its own table and keep the smaller result.
+//
+// FSEDecompressU16Auto resolves the ID against ScratchU16.Dictionary and
+// then the dictionaries added with RegisterDictionary, which a decoder must
+// register before reading such a stream. MIC3 can embed the dictionary in
+// the file instead (WSIOptions.EmbedDictionary).
+//
+// MarshalBinary serialises a dictionary for distribution:
+//
+//	Bytes 0-3: Magic "MICD"
+//	Bytes 4-7: ID (uint32 LE)
+//	Bytes 8-9: Escape symbol (uint16 LE)
+//	Byte  10:  Table count
+//	After:     The FSE header (writeCount) of every table
+
+const (
+	dictMagic0 = 0xFF
+	dictMagic1 = 0x40
+
+	dictStreamHeaderSize = 15 // magic + ID + table + count + escape count
+	dictFileMagic        = "MICD"
+	dictFileHeaderSize   = 11
+	dictMaxTables        = 16
+	dictDefaultTables    = 4
+	dictTableLog         = 14 // no header to pay for, so finer than defaultTablelog
+	dictTrainIterations  = 10
+)
+
+// Dictionary is a set of normalised FSE tables that streams refer to by ID.
+// It is safe for concurrent use.
+type Dictionary struct {
+	id     uint32
+	escape uint16 // codes symbols outside the training corpus
+	tables []blockTable
+
+	decOnce sync.Once
+	dec     []decBlockTable
+	decErr  error
+}
+
+// DictionaryOptions configures TrainDictionary.
+type DictionaryOptions struct {
+	// ID names the dictionary in streams; 0 is reserved.
+	ID uint32
+
+	// Predictor must match the predictor the images will be compressed
+	// with, since the tables model its residual symbols.
+	Predictor PredictorID
+
+	// Tables is the number of tables to train, 1 to 16; 0 selects 4.
+	// Each sample is assigned to the table that codes it best.
+	Tables int
+}
+
+// DictionarySample is one training image for TrainDictionary.
+type DictionarySample struct {
+	Pixels        []uint16
+	Width, Height int
+	MaxValue      uint16 // 0 = derived from the pixels, as Compress does
+}
+
+var (
+	dictionariesMu sync.RWMutex
+	dictionaries   = map[uint32]*Dictionary{}
+)
+
+// RegisterDictionary makes d available to every decoder under its ID.
+func RegisterDictionary(d *Dictionary) error {
+	if d == nil {
+		return errors.New("register dictionary: nil dictionary")
+	}
+	dictionariesMu.Lock()
+	defer dictionariesMu.Unlock()
+	if _, ok := dictionaries[d.id]; ok {
+		return fmt.Errorf("register dictionary: id %d already registered", d.id)
+	}
+	dictionaries[d.id] = d
+	return nil
+}
+
+// LookupDictionary returns the Dictionary registered under id.
+func LookupDictionary(id uint32) (*Dictionary, bool) {
+	dictionariesMu.RLock()
+	defer dictionariesMu.RUnlock()
+	d, ok := dictionaries[id]
+	return d, ok
+}
+
+// ID returns the ID streams coded with d refer to.
+func (d *Dictionary) ID() uint32 { return d.id }
+
+// Tables returns the number of tables in d.
+func (d *Dictionary) Tables() int { return len(d.tables) }
+
+// TrainDictionary builds a Dictionary from sample images of one modality.
+// The samples are turned into Delta+RLE symbols with opts.Predictor, grouped
+// by a few rounds of k-means on their coded size, and one table is
+// normalised from each group. Every table has a slot for every symbol of the
+// corpus and for the escape, which is weighted by the number of symbols only
+// one sample produced, an estimate of how often a new image holds a symbol
+// none did.
+func TrainDictionary(samples []DictionarySample, opts DictionaryOptions) (*Dictionary, error) {
+	if opts.ID == 0 {
+		return nil, errors.New("train dictionary: id 0 is reserved")
+	}
+	k := opts.Tables
+	if k == 0 {
+		k = dictDefaultTables
+	}
+	if k < 1 || k > dictMaxTables {
+		return nil, fmt.Errorf("train dictionary: %d tables outside [1, %d]", k, dictMaxTables)
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("train dictionary: no samples")
+	}
+
+	streams := make([][]uint16, len(samples))
+	var union []uint16                    // every symbol of the corpus, once
+	seen := make([]int, maxSymbolValue+1) // samples holding each symbol
+	last := make([]int, maxSymbolValue+1) // last sample counted in seen, plus one
+	for i, sm := range samples {
+		if sm.Width <= 0 || sm.Height <= 0 || len(sm.Pixels) != sm.Width*sm.Height {
+			return nil, fmt.Errorf("train dictionary: sample %d: %d pixels for %dx%d", i, len(sm.Pixels), sm.Width, sm.Height)
+		}
+		maxValue := sm.MaxValue
+		if maxValue == 0 {
+			for _, v := range sm.Pixels {
+				if v > maxValue {
+					maxValue = v
+				}
+			}
+			if maxValue == 0 {
+				maxValue = 1
+			}
+		}
+		symbols, err := predictorSymbols(opts.Predictor, sm.Pixels, sm.Width, sm.Height, maxValue)
+		if err != nil {
+			return nil, fmt.Errorf("train dictionary: sample %d: %w", i, err)
+		}
+		streams[i] = symbols
+		for _, v := range symbols {
+			if last[v] == i+1 {
+				continue
+			}
+			if seen[v] == 0 {
+				union = append(union, v)
+			}
+			seen[v]++
+			last[v] = i + 1
+		}
+	}
+	if len(union) < 2 {
+		return nil, errors.New("train dictionary: samples hold fewer than two distinct symbols")
+	}
+	d := &Dictionary{id: opts.ID}
+	for seen[d.escape] != 0 {
+		if d.escape == maxSymbolValue {
+			return nil, errors.New("train dictionary: samples leave no symbol for the escape")
+		}
+		d.escape++
+	}
+	escapes := 1
+	for _, v := range union {
+		if seen[v] == 1 {
+			escapes++
+		}
+	}
+	for range escapes {
+		union = append(union, d.escape)
+	}
+	k = min(k, len(samples))
+
+	// Seed the groups with the samples sorted by their coded size under one
+	// table, so that each starts with a band of similar images.
+	var s ScratchU16
+	all, err := s.trainTable(streams, union)
+	if err != nil {
+		return nil, err
+	}
+	order := make([]int, len(samples))
+	size := make([]float64, len(samples))
+	for i := range order {
+		order[i] = i
+		size[i] = all.escapeCost(symbolCounts(streams[i]), d.escape)
+		size[i] /= float64(max(len(streams[i]), 1))
+	}
+	sort.SliceStable(order, func(a, b int) bool { return size[order[a]] < size[order[b]] })
+	group := make([]int, len(samples))
+	for rank, i := range order {
+		group[i] = rank * k / len(samples)
+	}
+
+	var tables []blockTable
+	for range dictTrainIterations {
+		members := make([][][]uint16, k)
+		for i, g := range group {
+			members[g] = append(members[g], streams[i])
+		}
+		tables = tables[:0]
+		index := make([]int, k) // group to table, once empty groups are gone
+		for g, m := range members {
+			if len(m) == 0 {
+				continue
+			}
+			t, err := s.trainTable(m, union)
+			if err != nil {
+				return nil, err
+			}
+			index[g] = len(tables)
+			tables = append(tables, t)
+		}
+		k = len(tables)
+		for i := range group {
+			group[i] = index[group[i]]
+		}
+
+		moved := false
+		for i, st := range streams {
+			count := symbolCounts(st)
+			best, bestBits := group[i], math.Inf(1)
+			for j := range tables {
+				if bits := tables[j].escapeCost(count, d.escape); bits < bestBits {
+					best, bestBits = j, bits
+				}
+			}
+			if best != group[i] {
+				group[i], moved = best, true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	d.tables = tables
+	return d, nil
+}
+
+// covers reports whether t codes v directly rather than as escape.
+func (t *blockTable) covers(v, escape uint16) bool {
+	return v != escape && int(v) < len(t.norm) && t.norm[v] != 0
+}
+
+// escapeCost returns the estimated size in bits of coding the histogram
+// count with t, each symbol t does not cover costing escape plus 16 raw
+// bits. escape must have a slot in t.
+func (t *blockTable) escapeCost(count []uint32, escape uint16) float64 {
+	escBits := float64(t.tableLog) - math.Log2(float64(max(int(t.norm[escape]), 1))) + 16
+	bits := 0.0
+	for sym, c := range count {
+		if c == 0 {
+			continue
+		}
+		if !t.covers(uint16(sym), escape) {
+			bits += float64(c) * escBits
+			continue
+		}
+		n := float64(t.norm[sym])
+		if n < 0 {
+			n = 1
+		}
+		bits += float64(c) * (float64(t.tableLog) - math.Log2(n))
+	}
+	return bits
+}
+
+// compressEscaped codes in with t into s.bw.out as four-state FSE, each
+// symbol t does not cover replaced by escape. It returns the replaced
+// symbols in stream order.
+func (s *ScratchU16) compressEscaped(in []uint16, t *blockTable, escape uint16) ([]uint16, error) {
+	var escaped []uint16
+	coded := in
+	for i, v := range in {
+		if t.covers(v, escape) {
+			continue
+		}
+		if escaped == nil {
+			coded = append([]uint16(nil), in...)
+		}
+		escaped = append(escaped, v)
+		coded[i] = escape
+	}
+	s.setTable(t)
+	if err := s.buildCTable(); err != nil {
+		return nil, err
+	}
+	s.Out = s.Out[:0]
+	return escaped, s.compress4State(coded)
+}
+
+// appendEscapes appends the escape count and list of a stream header.
+func appendEscapes(out []byte, escaped []uint16) []byte {
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(escaped)))
+	for _, v := range escaped {
+		out = binary.LittleEndian.AppendUint16(out, v)
+	}
+	return out
+}
+
+// readEscapes returns the escape list of b whose count is at b[pos:] and
+// the offset of the bitstream after it.
+func readEscapes(b []byte, pos, count int) ([]byte, int, error) {
+	if len(b) < pos+4 {
+		return nil, 0, errors.New("stream header truncated")
+	}
+	escapes := int(binary.LittleEndian.Uint32(b[pos:]))
+	pos += 4
+	if escapes > count || escapes > (len(b)-pos)/2 {
+		return nil, 0, fmt.Errorf("%d escapes in a %d-byte stream", escapes, len(b))
+	}
+	return b[pos : pos+2*escapes], pos + 2*escapes, nil
+}
+
+// unescape replaces each escape in out with the next value of esc, which
+// must hold exactly one value per escape.
+func unescape(out []uint16, escape uint16, esc []byte) error {
+	n := len(esc) / 2
+	for i, v := range out {
+		if v != escape {
+			continue
+		}
+		if len(esc) == 0 {
+			return fmt.Errorf("more than %d escapes", n)
+		}
+		out[i] = binary.LittleEndian.Uint16(esc)
+		esc = esc[2:]
+	}
+	if len(esc) != 0 {
+		return fmt.Errorf("%d of %d escapes unused", len(esc)/2, n)
+	}
+	return nil
+}
+
+// symbolCounts returns the histogram of symbols, up to its largest symbol.
+func symbolCounts(symbols []uint16) []uint32 {
+	top := uint16(0)
+	for _, v := range symbols {
+		if v > top {
+			top = v
+		}
+	}
+	count := make([]uint32, int(top)+1)
+	for _, v := range symbols {
+		count[v]++
+	}
+	return count
+}
+
+// trainTable normalises one table from the concatenated streams plus the
+// symbols of union.
+func (s *ScratchU16) trainTable(streams [][]uint16, union []uint16) (blockTable, error) {
+	n := len(union)
+	for _, st := range streams {
+		n += len(st)
+	}
+	in := make([]uint16, 0, n)
+	for _, st := range streams {
+		in = append(in, st...)
+	}
+	in = append(in, union...)
+
+	s.TableLog = dictTableLog
+	if _, err := s.prepare(in, nil); err != nil {
+		return blockTable{}, err
+	}
+	s.countSimple(in)
+	s.clearCount = true
+	s.maxCount = 0
+	s.optimalTableLog()
+	if err := s.normalizeCount(); err != nil {
+		return blockTable{}, fmt.Errorf("train dictionary: %w", err)
+	}
+	return blockTable{norm: append([]int32(nil), s.norm[:s.symbolLen]...), tableLog: s.actualTableLog}, nil
+}
+
+// MarshalBinary serialises d. ParseDictionary reads the result.
+func (d *Dictionary) MarshalBinary() ([]byte, error) {
+	out := make([]byte, dictFileHeaderSize)
+	copy(out, dictFileMagic)
+	binary.LittleEndian.PutUint32(out[4:8], d.id)
+	binary.LittleEndian.PutUint16(out[8:10], d.escape)
+	out[10] = byte(len(d.tables))
+	var s ScratchU16
+	for i := range d.tables {
+		s.setTable(&d.tables[i])
+		s.Out = s.Out[:0]
+		if err := s.writeCount(); err != nil {
+			return nil, fmt.Errorf("dictionary: table %d: %w", i, err)
+		}
+		out = append(out, s.Out...)
+	}
+	return out, nil
+}
+
+// ParseDictionary reads a dictionary written by MarshalBinary.
+func ParseDictionary(b []byte) (*Dictionary, error) {
+	if len(b) < dictFileHeaderSize || string(b[0:4]) != dictFileMagic {
+		return nil, errors.New("dictionary: missing magic")
+	}
+	d := &Dictionary{id: binary.LittleEndian.Uint32(b[4:8]), escape: binary.LittleEndian.Uint16(b[8:10])}
+	n := int(b[10])
+	if d.id == 0 {
+		return nil, errors.New("dictionary: id 0 is reserved")
+	}
+	if n < 1 || n > dictMaxTables {
+		return nil, fmt.Errorf("dictionary: %d tables outside [1, %d]", n, dictMaxTables)
+	}
+	var s ScratchU16
+	pos := dictFileHeaderSize
+	for i := range n {
+		if pos >= len(b) {
+			return nil, errors.New("dictionary: truncated")
+		}
+		s.brForDecomp.init(b[pos:])
+		if err := s.readNCount(); err != nil {
+			return nil, fmt.Errorf("dictionary: table %d: %w", i, err)
+		}
+		pos = len(b) - s.brForDecomp.remain()
+		if int(d.escape) >= int(s.symbolLen) || s.norm[d.escape] == 0 {
+			return nil, fmt.Errorf("dictionary: table %d has no slot for escape %d", i, d.escape)
+		}
+		d.tables = append(d.tables, blockTable{norm: append([]int32(nil), s.norm[:s.symbolLen]...), tableLog: s.actualTableLog})
+	}
+	if pos != len(b) {
+		return nil, fmt.Errorf("dictionary: %d trailing bytes", len(b)-pos)
+	}
+	return d, nil
+}
+
+// decTables returns the decoding tables of d, built on first use.
+func (d *Dictionary) decTables() ([]decBlockTable, error) {
+	d.decOnce.Do(func() {
+		var s ScratchU16
+		for i := range d.tables {
+			s.setTable(&d.tables[i])
+			s.decTable = nil // each table keeps its own
+			if err := s.buildDtable(); err != nil {
+				d.decErr = fmt.Errorf("dictionary %d: table %d: %w", d.id, i, err)
+				return
+			}
+			d.dec = append(d.dec, decBlockTable{s.decTable, s.actualTableLog, s.zeroBits})
+		}
+	})
+	return d.dec, d.decErr
+}
+
+// FSECompressU16Dict compresses in with the table of d that codes it in the
+// fewest bits, escaping the symbols outside the table.
+// FSEDecompressU16Auto and FSEDecompressU16Dict read the result.
+func FSECompressU16Dict(in []uint16, d *Dictionary, s *ScratchU16) ([]byte, error) {
+	if d == nil {
+		return nil, errors.New("fsedict: nil dictionary")
+	}
+	if len(in) <= 3 {
+		return nil, ErrIncompressible
+	}
+	if uint64(len(in)) > 1<<32-1 {
+		return nil, fmt.Errorf("fsedict: %d symbols exceed the stream limit", len(in))
+	}
+	s, err := s.prepare(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.countSimple(in)
+	s.clearCount = true
+	s.maxCount = 0
+	count := s.count[:s.symbolLen]
+	best, bestBits := 0, math.Inf(1)
+	for i := range d.tables {
+		if bits := d.tables[i].escapeCost(count, d.escape); bits < bestBits {
+			best, bestBits = i, bits
+		}
+	}
+
+	escaped, err := s.compressEscaped(in, &d.tables[best], d.escape)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, dictStreamHeaderSize-4, dictStreamHeaderSize+2*len(escaped)+len(s.bw.out))
+	out[0], out[1] = dictMagic0, dictMagic1
+	binary.LittleEndian.PutUint32(out[2:6], d.id)
+	out[6] = byte(best)
+	binary.LittleEndian.PutUint32(out[7:11], uint32(len(in)))
+	out = appendEscapes(out, escaped)
+	return append(out, s.bw.out...), nil
+}
+
+// FSEDecompressU16Dict decompresses a stream produced by FSECompressU16Dict.
+// The dictionary is s.Dictionary if its ID matches, else the registered one.
+func FSEDecompressU16Dict(b []byte, s *ScratchU16) ([]uint16, error) {
+	if len(b) < dictStreamHeaderSize || b[0] != dictMagic0 || b[1] != dictMagic1 {
+		return nil, errors.New("fsedict: missing magic bytes")
+	}
+	id := binary.LittleEndian.Uint32(b[2:6])
+	table := int(b[6])
+	count := int(binary.LittleEndian.Uint32(b[7:11]))
+	s, err := s.prepare(nil, b)
+	if err != nil {
+		return nil, err
+	}
+	if count > s.DecompressLimit {
+		return nil, fmt.Errorf("output size (%d) > DecompressLimit (%d)", count, s.DecompressLimit)
+	}
+	if count <= 3 {
+		return nil, fmt.Errorf("fsedict: %d-symbol stream", count)
+	}
+	esc, bitstream, err := readEscapes(b, dictStreamHeaderSize-4, count)
+	if err != nil {
+		return nil, fmt.Errorf("fsedict: %w", err)
+	}
+	d := s.Dictionary
+	if d == nil || d.id != id {
+		var ok bool
+		if d, ok = LookupDictionary(id); !ok {
+			return nil, fmt.Errorf("fsedict: dictionary %d not registered", id)
+		}
+	}
+	dec, err := d.decTables()
+	if err != nil {
+		return nil, err
+	}
+	if table >= len(dec) {
+		return nil, fmt.Errorf("fsedict: table %d of %d in dictionary %d", table, len(dec), id)
+	}
+
+	t := dec[table]
+	s.decTable, s.actualTableLog, s.zeroBits = t.dt, t.tableLog, t.zeroBits
+	if cap(s.ct.tableSymbol) < 65536 { // decompress4State's buffer, as allocDtable
+		s.ct.tableSymbol = make([]uint16, 65536)
+	}
+	s.ct.tableSymbol = s.ct.tableSymbol[:65536]
+	defer func() { s.decTable = nil }() // shared with d, must not be rebuilt in place
+	s.brForDecomp.init(b[bitstream:])
+	s.OutU16 = s.OutU16[:0]
+	if err := s.decompress4State(count); err != nil {
+		return nil, fmt.Errorf("fsedict: %w", err)
+	}
+	if err := unescape(s.OutU16, d.escape, esc); err != nil {
+		return nil, fmt.Errorf("fsedict: %w", err)
+	}
+	return s.OutU16, nil
+}
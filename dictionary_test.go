@@ -0,0 +1,300 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+)
+
+// smoothSamples returns n smooth 12-bit images like fuzzPixels as training
+// samples.
+func smoothSamples(n int) []DictionarySample {
+	samples := make([]DictionarySample, n)
+	for i := range samples {
+		frames, maxValue := makeSmoothFrames(fuzzWidth, fuzzHeight, 1, int64(10+i))
+		samples[i] = DictionarySample{Pixels: frames[0], Width: fuzzWidth, Height: fuzzHeight, MaxValue: maxValue}
+	}
+	return samples
+}
+
+// TestDictionaryRoundtrip verifies training, serialisation and the
+// dictionary stream through FSEDecompressU16Auto, Compress and MIC3.
+func TestDictionaryRoundtrip(t *testing.T) {
+	samples := smoothSamples(8)
+	d, err := TrainDictionary(samples, DictionaryOptions{ID: 0xD1C7, Tables: 3})
+	if err != nil {
+		t.Fatalf("train: %v", err)
+	}
+	if d.ID() != 0xD1C7 || d.Tables() < 1 || d.Tables() > 3 {
+		t.Fatalf("dictionary %d with %d tables", d.ID(), d.Tables())
+	}
+
+	var drc DeltaRleCompressU16
+	symbols, err := drc.Compress(samples[0].Pixels, fuzzWidth, fuzzHeight, samples[0].MaxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := FSECompressU16Dict(symbols, d, nil)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	own, err := FSECompressU16FourState(symbols, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d symbols: own table %d bytes, dictionary %d bytes", len(symbols), len(own), len(b))
+	if len(b) >= len(own) {
+		t.Errorf("dictionary stream %d bytes, not below the %d of its own table", len(b), len(own))
+	}
+
+	if _, err := FSEDecompressU16Auto(b, nil); err == nil {
+		t.Error("expected error for an unregistered dictionary")
+	}
+	got, err := FSEDecompressU16Auto(b, &ScratchU16{Dictionary: d})
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	assertPixelsEqual(t, symbols, got, "ScratchU16.Dictionary")
+	if _, err := FSEDecompressU16Dict(b, &ScratchU16{Dictionary: d, DecompressLimit: len(symbols) - 1}); err == nil {
+		t.Error("expected error above DecompressLimit")
+	}
+	if _, err := FSEDecompressU16Dict(b[:len(b)/2], &ScratchU16{Dictionary: d}); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+
+	// A serialised copy decodes the same streams.
+	raw, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	d2, err := ParseDictionary(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, err = FSEDecompressU16Auto(b, &ScratchU16{Dictionary: d2}); err != nil {
+		t.Fatalf("decompress with parsed dictionary: %v", err)
+	}
+	assertPixelsEqual(t, symbols, got, "ParseDictionary")
+	for _, bad := range [][]byte{raw[:dictFileHeaderSize], append(raw, 0), raw[:4]} {
+		if _, err := ParseDictionary(bad); err == nil {
+			t.Errorf("expected error parsing %d bytes", len(bad))
+		}
+	}
+
+	// Symbols the corpus never produced travel as escapes.
+	unseen := append([]uint16{60000, 60001, d.escape}, symbols[:100]...)
+	b, err = FSECompressU16Dict(unseen, d, nil)
+	if err != nil {
+		t.Fatalf("compress with escapes: %v", err)
+	}
+	if n := binary.LittleEndian.Uint32(b[11:15]); n < 3 {
+		t.Errorf("%d escapes, want at least 3", n)
+	}
+	if got, err = FSEDecompressU16Auto(b, &ScratchU16{Dictionary: d}); err != nil {
+		t.Fatalf("decompress escapes: %v", err)
+	}
+	assertPixelsEqual(t, unseen, got, "escapes")
+	n := int(binary.LittleEndian.Uint32(b[11:15]))
+	stripped := append(binary.LittleEndian.AppendUint32(slices.Clone(b[:11]), 0), b[dictStreamHeaderSize+2*n:]...)
+	if _, err := FSEDecompressU16Dict(stripped, &ScratchU16{Dictionary: d}); err == nil {
+		t.Error("expected error for a stream whose escape list was dropped")
+	}
+	binary.LittleEndian.PutUint32(b[11:15], 2)
+	if _, err := FSEDecompressU16Dict(b, &ScratchU16{Dictionary: d}); err == nil {
+		t.Error("expected error for a short escape list")
+	}
+
+	for _, opts := range []DictionaryOptions{{}, {ID: 1, Tables: dictMaxTables + 1}} {
+		if _, err := TrainDictionary(samples, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+	if _, err := TrainDictionary(nil, DictionaryOptions{ID: 1}); err == nil {
+		t.Error("expected error without samples")
+	}
+
+	// Compress needs the dictionary registered at the decoder.
+	if _, ok := LookupDictionary(d.ID()); !ok {
+		if err := RegisterDictionary(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RegisterDictionary(d); err == nil {
+		t.Error("expected error registering an ID twice")
+	}
+	blob, err := Compress(samples[1].Pixels, fuzzWidth, fuzzHeight, CompressOptions{Dictionary: d, Strips: 2})
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	img, err := Decode(blob)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	assertPixelsEqual(t, samples[1].Pixels, img.Pixels, "Compress")
+}
+
+// TestDictionaryWSI verifies a MIC3 file with an embedded dictionary decodes
+// without registering it, and that the dictionary shrinks the tiles.
+func TestDictionaryWSI(t *testing.T) {
+	const w, h = 512, 512
+	opts := WSIOptions{TileWidth: 128, TileHeight: 128}
+	var samples []DictionarySample
+	for seed := int64(1); seed <= 3; seed++ {
+		samples = append(samples, WSIDictionarySamples(makeWSITestImage(w, h, seed), w, h, 3, 8, opts)...)
+	}
+	d, err := TrainDictionary(samples, DictionaryOptions{ID: 0xD1C8})
+	if err != nil {
+		t.Fatalf("train: %v", err)
+	}
+
+	rgb := makeWSITestImage(w, h, 4)
+	plain, err := CompressWSI(rgb, w, h, 3, 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Dictionary, opts.EmbedDictionary = d, true
+	embedded, err := CompressWSI(rgb, w, h, 3, 8, opts)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	t.Logf("MIC3 %d bytes, with embedded dictionary %d bytes", len(plain), len(embedded))
+	if len(embedded) >= len(plain) {
+		t.Errorf("embedded dictionary file %d bytes, not below %d", len(embedded), len(plain))
+	}
+
+	hdr, err := ReadWSIHeader(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Dictionary == nil || hdr.Dictionary.ID() != d.ID() {
+		t.Fatal("header lost the embedded dictionary")
+	}
+	checked, err := AddChecksums(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksums(checked); err != nil {
+		t.Fatalf("VerifyChecksums: %v", err)
+	}
+	for _, file := range [][]byte{embedded, checked} {
+		got, err := DecompressWSIRegion(file, 0, 0, 0, w, h)
+		if err != nil {
+			t.Fatalf("decompress: %v", err)
+		}
+		if !bytes.Equal(got, rgb) {
+			t.Fatal("pixel mismatch")
+		}
+	}
+
+	// Without embedding, readers need the dictionary registered.
+	opts.EmbedDictionary = false
+	bare, err := CompressWSI(rgb, w, h, 3, 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecompressWSITile(bare, 0, 1, 1); err == nil {
+		t.Error("expected error for an unregistered dictionary")
+	}
+	opts.Dictionary, opts.EmbedDictionary = nil, true
+	if _, err := CompressWSI(rgb, w, h, 3, 8, opts); err == nil {
+		t.Error("expected error for EmbedDictionary without a Dictionary")
+	}
+}
+
+// TestDictionaryComparisonTable prints the size of 128x128 tiles with their
+// own FSE tables against a dictionary trained on the other half of the
+// image's tiles, and how many tiles took the dictionary.
+func TestDictionaryComparisonTable(t *testing.T) {
+	const tile = 128
+	fmt.Println()
+	fmt.Println("=== 128x128 tiles: own FSE table vs pre-trained dictionary ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %5s  %9s  %9s  %7s  %5s\n", "Image", "Tiles", "Own", "Dict", "Saving", "Used")
+	fmt.Println("------  -----  ---------  ---------  -------  -----")
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		if len(pixels) == 0 || width < 2*tile || height < tile {
+			continue
+		}
+		var train, test []DictionarySample
+		for ty := 0; ty+tile <= height; ty += tile {
+			for tx := 0; tx+tile <= width; tx += tile {
+				p := make([]uint16, 0, tile*tile)
+				for y := ty; y < ty+tile; y++ {
+					p = append(p, pixels[y*width+tx:y*width+tx+tile]...)
+				}
+				sm := DictionarySample{Pixels: p, Width: tile, Height: tile, MaxValue: maxVal}
+				if (tx/tile+ty/tile)%2 == 0 {
+					train = append(train, sm)
+				} else {
+					test = append(test, sm)
+				}
+			}
+		}
+		d, err := TrainDictionary(train, DictionaryOptions{ID: 1})
+		if err != nil {
+			t.Fatalf("%s: train: %v", td.name, err)
+		}
+		own, dict, used := 0, 0, 0
+		for _, sm := range test {
+			a, err := compressSingleFrameCoder(sm.Pixels, tile, tile, maxVal, PredictorAvg, CoderFSE2, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", td.name, err)
+			}
+			b, err := compressSingleFrameCoder(sm.Pixels, tile, tile, maxVal, PredictorAvg, CoderFSE2, d)
+			if err != nil {
+				t.Fatalf("%s: %v", td.name, err)
+			}
+			got, err := new(Decoder).decompressSingleFrame(nil, b, tile, tile, streamRefs{dict: d})
+			if err != nil {
+				t.Fatalf("%s: decompress: %v", td.name, err)
+			}
+			assertPixelsEqual(t, sm.Pixels, got, td.name)
+			own += len(a)
+			dict += len(b)
+			if b[0] == dictMagic0 && b[1] == dictMagic1 {
+				used++
+			}
+		}
+		fmt.Printf("%-6s  %5d  %9d  %9d  %6.1f%%  %5d\n", td.name, len(test), own, dict,
+			100*(1-float64(dict)/float64(own)), used)
+	}
+	fmt.Println()
+}
+
+func BenchmarkDictionaryDecompress(b *testing.B) {
+	samples := smoothSamples(8)
+	d, err := TrainDictionary(samples, DictionaryOptions{ID: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var drc DeltaRleCompressU16
+	symbols, err := drc.Compress(samples[0].Pixels, fuzzWidth, fuzzHeight, samples[0].MaxValue)
+	if err != nil {
+		b.Fatal(err)
+	}
+	comp, err := FSECompressU16Dict(symbols, d, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := ScratchU16{Dictionary: d}
+	b.SetBytes(int64(len(samples[0].Pixels) * 2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FSEDecompressU16Dict(comp, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
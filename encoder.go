@@ -0,0 +1,68 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Reusable encoders and decoders.
+//
+// Every CompressSingleFrame and DecompressSingleFrame call builds a fresh
+// ScratchU16 (histogram, normalisation and coding tables for a 16-bit
+// alphabet, ~0.5 MB), a Delta+RLE symbol buffer of width*height samples and
+// an FSE output buffer. An Encoder or Decoder keeps that state in a
+// sync.Pool instead, so one long-lived value shared by any number of
+// goroutines settles into a steady state where a call allocates only its
+// result. Decoder.DecompressInto removes that last allocation by decoding
+// into a caller-owned buffer:
+//
+//	var enc mic.Encoder
+//	var dec mic.Decoder
+//	stream, _ := enc.CompressSingleFrame(pixels, w, h, maxValue)
+//	err := dec.DecompressSingleFrameInto(texture, stream, w, h)
+
+// frameScratch is the working state of one Delta+RLE+FSE call.
+type frameScratch struct {
+	fse ScratchU16
+	drc DeltaRleCompressU16
+	drd DeltaRleDecompressU16
+}
+
+// getFrameScratch takes a frameScratch from p, allocating one if p is empty.
+func getFrameScratch(p *sync.Pool) *frameScratch {
+	if fs, ok := p.Get().(*frameScratch); ok {
+		return fs
+	}
+	return new(frameScratch)
+}
+
+// Encoder compresses frames with the CompressSingleFrame pipeline and keeps
+// its entropy tables and intermediate buffers in an internal pool between
+// calls. The zero value is ready to use. An Encoder is safe for concurrent
+// use and must not be copied after first use.
+type Encoder struct {
+	scratch sync.Pool // of *frameScratch
+}
+
+// CompressSingleFrame is CompressSingleFrame reusing e's scratch state. The
+// output is byte-identical and never aliases e's buffers.
+func (e *Encoder) CompressSingleFrame(pixels []uint16, width, height int, maxValue uint16) ([]byte, error) {
+	fs := getFrameScratch(&e.scratch)
+	defer e.scratch.Put(fs)
+
+	deltaComp := fs.drc.compressReusing(pixels, width, height, maxValue)
+	fseComp, err := FSECompressU16TwoState(deltaComp, &fs.fse)
+	if err != nil {
+		fseComp, err = FSECompressU16(deltaComp, &fs.fse)
+		if err != nil {
+			return nil, fmt.Errorf("FSE compress: %w", err)
+		}
+		// Single-state FSE returns the scratch output buffer itself.
+		fseComp = append([]byte(nil), fseComp...)
+	}
+	return fseComp, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestEncoderDecoderReuse(t *testing.T) {
+	var enc Encoder
+	var dec Decoder
+
+	// Shrinking and growing again, so every call after the first runs on
+	// recycled buffers of a different size.
+	sizes := []struct{ w, h int }{{160, 120}, {33, 17}, {64, 48}, {160, 120}}
+	for i, sz := range sizes {
+		frames, maxValue := makeSmoothFrames(sz.w, sz.h, 1, int64(i))
+		pixels := frames[0]
+
+		want, err := CompressSingleFrame(pixels, sz.w, sz.h, maxValue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := enc.CompressSingleFrame(pixels, sz.w, sz.h, maxValue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%dx%d: Encoder output differs from CompressSingleFrame", sz.w, sz.h)
+		}
+
+		dst := make([]uint16, sz.w*sz.h+5)
+		if err := dec.DecompressSingleFrameInto(dst, got, sz.w, sz.h); err != nil {
+			t.Fatal(err)
+		}
+		for j := range pixels {
+			if dst[j] != pixels[j] {
+				t.Fatalf("%dx%d: pixel %d mismatch: got %d, want %d", sz.w, sz.h, j, dst[j], pixels[j])
+			}
+		}
+		out, err := dec.DecompressSingleFrame(got, sz.w, sz.h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if &out[0] == &dst[0] {
+			t.Fatal("DecompressSingleFrame returned the previous destination buffer")
+		}
+	}
+}
+
+func TestDecompressInto(t *testing.T) {
+	width, height := 96, 64
+	frames, maxValue := makeSmoothFrames(width, height, 1, 3)
+	pixels := frames[0]
+
+	stream, err := CompressSingleFrame(pixels, width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteMIC1(&buf, MIC1Header{Width: width, Height: height, MaxValue: maxValue}, stream); err != nil {
+		t.Fatal(err)
+	}
+	options, err := Compress(pixels, width, height, CompressOptions{Predictor: PredictorMED, Strips: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d Decoder
+	for _, data := range [][]byte{buf.Bytes(), options} {
+		dst := make([]uint16, width*height)
+		w, h, err := d.DecompressInto(dst, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w != width || h != height {
+			t.Fatalf("dimensions %dx%d, want %dx%d", w, h, width, height)
+		}
+		for i := range pixels {
+			if dst[i] != pixels[i] {
+				t.Fatalf("pixel %d mismatch: got %d, want %d", i, dst[i], pixels[i])
+			}
+		}
+
+		_, _, err = DecompressInto(dst[:len(dst)-1], data)
+		if !errors.Is(err, io.ErrShortBuffer) {
+			t.Fatalf("short destination: got %v, want io.ErrShortBuffer", err)
+		}
+	}
+
+	if raceEnabled {
+		return
+	}
+	dst := make([]uint16, width*height)
+	allocs := testing.AllocsPerRun(20, func() {
+		if _, _, err := d.DecompressInto(dst, buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("DecompressInto allocated %.1f times per call, want 0", allocs)
+	}
+}
+
+func TestEncoderDecoderConcurrent(t *testing.T) {
+	width, height := 64, 48
+	frames, maxValue := makeSmoothFrames(width, height, 4, 11)
+
+	var enc Encoder
+	var dec Decoder
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(frames))
+	for g := 0; g < 4; g++ {
+		for _, pixels := range frames {
+			wg.Add(1)
+			go func(pixels []uint16) {
+				defer wg.Done()
+				stream, err := enc.CompressSingleFrame(pixels, width, height, maxValue)
+				if err != nil {
+					errs <- err
+					return
+				}
+				got, err := dec.DecompressSingleFrame(stream, width, height)
+				if err != nil {
+					errs <- err
+					return
+				}
+				for i := range pixels {
+					if got[i] != pixels[i] {
+						errs <- errors.New("round-trip mismatch")
+						return
+					}
+				}
+			}(pixels)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
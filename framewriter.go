@@ -0,0 +1,269 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MICS — streamed row groups.
+//
+// CompressSingleFrame needs the whole image plus several full-size
+// intermediate buffers. A FrameWriter instead takes rows as they arrive and
+// codes every frameGroupPixels worth of rows as an independent group, as
+// PICS does with strips, writing each group out before buffering the next.
+// Memory stays at one group of pixels and its coding buffers whatever the
+// image size.
+//
+// Binary format:
+//
+//	Bytes  0-3:  Magic "MICS"
+//	Bytes  4-7:  Width           (uint32 LE)
+//	Bytes  8-11: Height          (uint32 LE)
+//	Bytes 12-15: GroupRows       (uint32 LE) — rows per group; last group may be shorter
+//	Bytes 16-17: Max value       (uint16 LE)
+//	Byte  18:    Format version (1)
+//	Byte  19:    Reserved (0)
+//	Bytes 20..:  ceil(Height/GroupRows) group records
+//
+// Each group record is a mode byte, a uint32 LE length and that many bytes.
+// Mode 0 is a CompressSingleFrame stream of the group's rows. Mode 1 stores
+// the group's Delta+RLE symbols as uint16 LE, for bands FSE rejects — a
+// uniform detector border, for instance, leaves too few distinct symbols.
+// As in PICS, the first row of each group is predicted without the row
+// above it, so every group decodes on its own.
+
+const (
+	micsMagic      = "MICS"
+	micsHeaderSize = 20
+	micsRecordSize = 5 // group record header: mode + length
+	micsVersion    = 1
+
+	micsGroupFSE = 0x00 // CompressSingleFrame stream
+	micsGroupRaw = 0x01 // Delta+RLE symbols, uint16 LE
+
+	frameGroupPixels = 1 << 18 // target samples per row group
+)
+
+// FrameWriter compresses a single 16-bit frame supplied row by row into a
+// MICS stream. Create one with NewFrameWriter, pass the rows in order to
+// WriteRows, and call Close once all of them have been written. A
+// FrameWriter is not safe for concurrent use.
+type FrameWriter struct {
+	w         io.Writer
+	width     int
+	height    int
+	groupRows int
+	maxValue  uint16
+
+	rows    []uint16 // rows of the current group
+	written int      // rows accepted so far
+	started bool     // header written
+	fs      frameScratch
+	raw     []byte
+	err     error // sticky
+}
+
+// NewFrameWriter returns a FrameWriter that writes a width x height frame
+// with samples no larger than maxValue to w. Nothing is written until the
+// first group is complete; invalid arguments are reported by WriteRows and
+// Close.
+func NewFrameWriter(w io.Writer, width, height int, maxValue uint16) *FrameWriter {
+	fw := &FrameWriter{w: w, width: width, height: height, maxValue: maxValue}
+	switch {
+	case width <= 0 || height <= 0 || uint64(width) > 0xFFFFFFFF || uint64(height) > 0xFFFFFFFF:
+		fw.err = fmt.Errorf("frame writer: invalid dimensions %dx%d", width, height)
+		return fw
+	case maxValue == 0:
+		fw.err = errors.New("frame writer: zero max value")
+		return fw
+	}
+	fw.groupRows = min(max(frameGroupPixels/width, 1), height)
+	fw.rows = make([]uint16, 0, fw.groupRows*width)
+	return fw
+}
+
+// WriteRows appends one or more whole rows. len(pixels) must be a multiple
+// of the width, and no more rows than the height may be written in total.
+// Samples must not exceed the maxValue given to NewFrameWriter.
+func (fw *FrameWriter) WriteRows(pixels []uint16) error {
+	if fw.err != nil {
+		return fw.err
+	}
+	if len(pixels)%fw.width != 0 {
+		return fmt.Errorf("frame writer: %d samples is not a whole number of %d-sample rows", len(pixels), fw.width)
+	}
+	if n := len(pixels) / fw.width; n > fw.height-fw.written {
+		return fmt.Errorf("frame writer: %d more rows do not fit: %d of %d written", n, fw.written, fw.height)
+	}
+	for len(pixels) > 0 {
+		n := min(cap(fw.rows)-len(fw.rows), len(pixels))
+		fw.rows = append(fw.rows, pixels[:n]...)
+		pixels = pixels[n:]
+		fw.written += n / fw.width
+		if len(fw.rows) == cap(fw.rows) || fw.written == fw.height {
+			if fw.err = fw.flushGroup(); fw.err != nil {
+				return fw.err
+			}
+		}
+	}
+	return nil
+}
+
+// Close checks that every row has been written. It does not close the
+// underlying io.Writer.
+func (fw *FrameWriter) Close() error {
+	if fw.err != nil {
+		return fw.err
+	}
+	if fw.written != fw.height {
+		fw.err = fmt.Errorf("frame writer: closed after %d of %d rows", fw.written, fw.height)
+		return fw.err
+	}
+	return nil
+}
+
+// flushGroup codes the buffered rows and writes them as one group record,
+// preceded by the header for the first group.
+func (fw *FrameWriter) flushGroup() error {
+	if !fw.started {
+		hdr := make([]byte, micsHeaderSize)
+		copy(hdr[0:4], micsMagic)
+		binary.LittleEndian.PutUint32(hdr[4:8], uint32(fw.width))
+		binary.LittleEndian.PutUint32(hdr[8:12], uint32(fw.height))
+		binary.LittleEndian.PutUint32(hdr[12:16], uint32(fw.groupRows))
+		binary.LittleEndian.PutUint16(hdr[16:18], fw.maxValue)
+		hdr[18] = micsVersion
+		if _, err := fw.w.Write(hdr); err != nil {
+			return err
+		}
+		fw.started = true
+	}
+
+	rows := len(fw.rows) / fw.width
+	symbols := fw.fs.drc.compressReusing(fw.rows, fw.width, rows, fw.maxValue)
+	fw.rows = fw.rows[:0]
+
+	mode := byte(micsGroupFSE)
+	blob, err := FSECompressU16TwoState(symbols, &fw.fs.fse)
+	if err != nil {
+		blob, err = FSECompressU16(symbols, &fw.fs.fse)
+	}
+	if err != nil {
+		mode = micsGroupRaw
+		fw.raw = fw.raw[:0]
+		for _, v := range symbols {
+			fw.raw = binary.LittleEndian.AppendUint16(fw.raw, v)
+		}
+		blob = fw.raw
+	}
+
+	var rec [5]byte
+	rec[0] = mode
+	binary.LittleEndian.PutUint32(rec[1:], uint32(len(blob)))
+	if _, err := fw.w.Write(rec[:]); err != nil {
+		return err
+	}
+	_, err = fw.w.Write(blob)
+	return err
+}
+
+// DecompressFrameStream decompresses a MICS stream written by FrameWriter.
+func DecompressFrameStream(data []byte) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.DecompressFrameStream(data)
+}
+
+// DecompressFrameStream is DecompressFrameStream under d's limits.
+func (d *Decoder) DecompressFrameStream(data []byte) (pixels []uint16, width, height int, err error) {
+	if len(data) < micsHeaderSize || string(data[0:4]) != micsMagic {
+		return nil, 0, 0, errors.New("MICS: invalid magic")
+	}
+	if v := data[18]; v != micsVersion {
+		return nil, 0, 0, fmt.Errorf("MICS: unsupported version %d", v)
+	}
+	width = int(binary.LittleEndian.Uint32(data[4:8]))
+	height = int(binary.LittleEndian.Uint32(data[8:12]))
+	groupRows := int(binary.LittleEndian.Uint32(data[12:16]))
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("MICS: %w", err)
+	}
+	if groupRows <= 0 || groupRows > height {
+		return nil, 0, 0, fmt.Errorf("MICS: invalid group height %d", groupRows)
+	}
+
+	numGroups := (height + groupRows - 1) / groupRows
+	if numGroups > (len(data)-micsHeaderSize)/micsRecordSize {
+		return nil, 0, 0, fmt.Errorf("MICS: %d groups in a %d-byte stream", numGroups, len(data))
+	}
+	modes := make([]byte, numGroups)
+	blobs := make([][]byte, numGroups)
+	off := micsHeaderSize
+	for i := range blobs {
+		if len(data)-off < micsRecordSize {
+			return nil, 0, 0, fmt.Errorf("MICS: group %d: record truncated", i)
+		}
+		modes[i] = data[off]
+		n := binary.LittleEndian.Uint32(data[off+1:])
+		off += micsRecordSize
+		if uint64(n) > uint64(len(data)-off) {
+			return nil, 0, 0, fmt.Errorf("MICS: group %d: data out of bounds", i)
+		}
+		blobs[i] = data[off : off+int(n)]
+		off += int(n)
+	}
+
+	out := make([]uint16, width*height)
+	errs := make([]error, numGroups)
+
+	var wg sync.WaitGroup
+	for g := 0; g < numGroups; g++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			y0 := idx * groupRows
+			y1 := min(y0+groupRows, height)
+			if err := d.decompressGroup(out[y0*width:y1*width], modes[idx], blobs[idx], width, y1-y0); err != nil {
+				errs[idx] = fmt.Errorf("MICS: group %d: %w", idx, err)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, e := range errs {
+		if e != nil {
+			return nil, 0, 0, e
+		}
+	}
+	return out, width, height, nil
+}
+
+// decompressGroup decodes one MICS group record into dst.
+func (d *Decoder) decompressGroup(dst []uint16, mode byte, blob []byte, width, rows int) error {
+	switch mode {
+	case micsGroupFSE:
+		_, err := d.decompressSingleFrame(dst, blob, width, rows, streamRefs{})
+		return err
+	case micsGroupRaw:
+		if len(blob)%2 != 0 || len(blob)/2 > symbolLimit(width*rows) {
+			return fmt.Errorf("invalid raw group length %d", len(blob))
+		}
+		symbols := make([]uint16, len(blob)/2)
+		for i := range symbols {
+			symbols[i] = binary.LittleEndian.Uint16(blob[2*i:])
+		}
+		var drd DeltaRleDecompressU16
+		if err := drd.decompressInto(dst, symbols, width, rows); err != nil {
+			return fmt.Errorf("delta+RLE decompress: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown group mode %d", mode)
+	}
+}
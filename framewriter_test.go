@@ -0,0 +1,110 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"testing"
+)
+
+func TestFrameWriter(t *testing.T) {
+	width, height := 2048, 300 // 128-row groups: three groups, the last short
+	frames, _ := makeSmoothFrames(width, height, 1, 9)
+	pixels := frames[0]
+	maxValue := uint16(65535)
+	// A uniform border band fills the first group; at 16 bits its Delta+RLE
+	// symbols are too few for FSE.
+	for i := 0; i < 128*width; i++ {
+		pixels[i] = 0
+	}
+
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf, width, height, maxValue)
+	// Feed uneven chunks, as a parser would.
+	for y := 0; y < height; {
+		n := min(1+y%7, height-y)
+		if err := fw.WriteRows(pixels[y*width : (y+n)*width]); err != nil {
+			t.Fatal(err)
+		}
+		y += n
+		if y > 128 && y < 256 && buf.Len() == 0 {
+			t.Fatal("first group not written once complete")
+		}
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Bytes()[micsHeaderSize] != micsGroupRaw {
+		t.Fatalf("uniform group has mode %d, want %d", buf.Bytes()[micsHeaderSize], micsGroupRaw)
+	}
+
+	got, w, h, err := DecompressFrameStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != width || h != height {
+		t.Fatalf("dimensions %dx%d, want %dx%d", w, h, width, height)
+	}
+	assertPixelsEqual(t, pixels, got, "DecompressFrameStream")
+
+	img, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatMICS {
+		t.Fatalf("Decode format %s, want %s", img.Format, FormatMICS)
+	}
+	assertPixelsEqual(t, pixels, img.Pixels, "Decode")
+
+	if _, _, _, err := DecompressFrameStream(buf.Bytes()[:buf.Len()-1]); err == nil {
+		t.Fatal("truncated stream: expected an error")
+	}
+
+	// A bare header claiming 1<<26 one-row groups must fail before the
+	// per-group tables are allocated.
+	hdr := make([]byte, micsHeaderSize)
+	copy(hdr, micsMagic)
+	binary.LittleEndian.PutUint32(hdr[4:8], 1)
+	binary.LittleEndian.PutUint32(hdr[8:12], 1<<26)
+	binary.LittleEndian.PutUint32(hdr[12:16], 1)
+	binary.LittleEndian.PutUint16(hdr[16:18], 255)
+	hdr[18] = micsVersion
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, _, _, err := DecompressFrameStream(hdr); err == nil {
+		t.Fatal("header-only stream: expected an error")
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Fatalf("header-only stream: allocated %d bytes", n)
+	}
+}
+
+func TestFrameWriterErrors(t *testing.T) {
+	row := make([]uint16, 8)
+
+	fw := NewFrameWriter(new(bytes.Buffer), 8, 2, 255)
+	if err := fw.WriteRows(row[:5]); err == nil {
+		t.Fatal("partial row: expected an error")
+	}
+	if err := fw.WriteRows(make([]uint16, 3*8)); err == nil {
+		t.Fatal("rows beyond height: expected an error")
+	}
+	if err := fw.WriteRows(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Close(); err == nil {
+		t.Fatal("Close after 1 of 2 rows: expected an error")
+	}
+
+	if err := NewFrameWriter(new(bytes.Buffer), 0, 2, 255).WriteRows(row); err == nil {
+		t.Fatal("zero width: expected an error")
+	}
+	if err := NewFrameWriter(new(bytes.Buffer), 8, 2, 0).Close(); err == nil {
+		t.Fatal("zero max value: expected an error")
+	}
+}
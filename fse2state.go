@@ -94,12 +94,33 @@ func FSEDecompressU16TwoState(b []byte, s *ScratchU16) ([]uint16, error) {
 }
 
 // FSEDecompressU16Auto auto-detects the stream format based on the magic prefix:
+//   [0xFF, 0x10] → adaptive arithmetic decoder
+//   [0xFF, 0x20] → block-adaptive FSE decoder
+//   [0xFF, 0x40] → dictionary FSE decoder
+//   [0xFF, 0x41] → table reference FSE decoder
+//   [0xFF, 0x32] → two-stream Huffman decoder
+//   [0xFF, 0x34] → four-stream Huffman decoder
 //   [0xFF, 0x84] → eight-state FSE decoder
 //   [0xFF, 0x08] → eight-state rANS decoder
 //   [0xFF, 0x04] → four-state decoder
 //   [0xFF, 0x02] → two-state decoder
 //   otherwise   → single-state decoder
 func FSEDecompressU16Auto(b []byte, s *ScratchU16) ([]uint16, error) {
+	if len(b) >= 2 && b[0] == arithMagic0 && b[1] == arithMagic1 {
+		return ArithDecompressU16(b, s)
+	}
+	if len(b) >= 2 && b[0] == blocksMagic0 && b[1] == blocksMagic1 {
+		return FSEDecompressU16Blocks(b, s)
+	}
+	if len(b) >= 2 && b[0] == dictMagic0 && b[1] == dictMagic1 {
+		return FSEDecompressU16Dict(b, s)
+	}
+	if len(b) >= 2 && b[0] == refMagic0 && b[1] == refMagic1 {
+		return FSEDecompressU16Ref(b, s)
+	}
+	if len(b) >= 2 && b[0] == huffStreamsMagic0 && (b[1] == huffTwoStreamMagic1 || b[1] == huffFourStreamMagic1) {
+		return HuffDecompressU16Streams(b, s)
+	}
 	if len(b) >= 2 && b[0] == eightStateFSEMagic0 && b[1] == eightStateFSEMagic1 {
 		return FSEDecompressU16EightState(b, s)
 	}
@@ -291,6 +312,9 @@ func (s *ScratchU16) decompress2State(count int) error {
 	// Tail: drain remaining symbols alternating A then B.
 	// We use the exact count to avoid over-reading.
 	for remaining > 0 {
+		if br.exhausted(2) {
+			return errStreamExhausted
+		}
 		// stateA symbol
 		br.fill()
 		s.OutU16 = append(s.OutU16, sA.next())
@@ -254,6 +254,29 @@ func TestFSE2StateEdgeCases(t *testing.T) {
 			}
 		}
 	})
+	t.Run("wide_alphabet_small_input_roundtrip", func(t *testing.T) {
+		// 150 distinct symbols in 200 samples: the source-size cap on
+		// tableLog must not shrink the table below the alphabet.
+		data := make([]uint16, 200)
+		for i := 0; i < 150; i++ {
+			data[i] = uint16(i)
+		}
+		var sc ScratchU16
+		compressed, err := FSECompressU16TwoState(data, &sc)
+		if err != nil {
+			return // declining is fine; panicking or corrupting is not
+		}
+		var sd ScratchU16
+		got, err := FSEDecompressU16TwoState(compressed, &sd)
+		if err != nil {
+			t.Fatalf("decompress: %v", err)
+		}
+		for i, v := range data {
+			if got[i] != v {
+				t.Fatalf("mismatch at [%d]: got %d want %d", i, got[i], v)
+			}
+		}
+	})
 }
 
 // ---------------------------------------------------------------------------
@@ -326,6 +326,9 @@ func (s *ScratchU16) decompress4State(count int) error {
 
 	// Tail: drain remaining symbols in A, B, C, D order.
 	for remaining > 0 {
+		if br.exhausted(4) {
+			return errStreamExhausted
+		}
 		br.fill()
 		s.OutU16 = append(s.OutU16, sA.next())
 		remaining--
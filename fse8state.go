@@ -366,6 +366,9 @@ func (s *ScratchU16) decompress8State(count int) error {
 	// Tail: drain remaining symbols in A, B, C, D, E, F, G, H order.
 	decoders := [8]*decoderU16{&sA, &sB, &sC, &sD, &sE, &sF, &sG, &sH}
 	for remaining > 0 {
+		if br.exhausted(8) {
+			return errStreamExhausted
+		}
 		for _, d := range decoders {
 			if remaining == 0 {
 				break
@@ -0,0 +1,358 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Block-adaptive FSE.
+//
+// FSECompressU16 and its multi-state variants normalise one table for the
+// whole stream. On a mammogram the breast, the skin line and the background
+// have very different residual statistics, so one table fits none of them
+// well. FSECompressU16Blocks splits the stream into blocks of blockSize
+// symbols and, as zstd does, gives each block the cheapest of:
+//
+//   - a new table, normalised from the block's own histogram;
+//   - the previous block's table, at the cost of the mode byte;
+//   - one of the last blocksHistory distinct tables, at one more byte;
+//   - a single repeated symbol (RLE), or the raw symbols.
+//
+// The cost of a table is estimated from the block histogram as
+// sum(count[s] * (tableLog - log2(norm[s]))) bits, plus the table header for
+// a new one, and the cheapest mode is taken block by block. A table can
+// only code the symbols it has a slot for, so where the alphabet keeps
+// growing, as Delta+RLE escapes make it, every block pays for a table of its
+// own. The encoder therefore also codes the stream with the whole-stream
+// table as the first block's, which later blocks can repeat, and keeps the
+// smaller result: it is never more than a few bytes per block larger than
+// FSECompressU16FourState. Blocks with a table are coded with four-state FSE.
+//
+// Output format: [0xFF][0x20][count uint32 LE][blockSize uint32 LE][blocks]
+//
+// Every block holds blockSize symbols, the last one the remainder:
+//
+//	blockNewTable: [0][FSE header][length uint32 LE][bitstream]
+//	blockRepeat:   [1][length uint32 LE][bitstream]
+//	blockReuse:    [2][k][length uint32 LE][bitstream]  k-th most recent table
+//	blockRLE:      [3][symbol uint16 LE]
+//	blockRaw:      [4][symbols, uint16 LE each]
+//
+// The tables form a most-recently-used list: a new table goes to the front,
+// a reused one moves there. FSEDecompressU16Auto recognises the magic and
+// returns the symbols of all blocks as one slice.
+
+const (
+	blocksMagic0 = 0xFF
+	blocksMagic1 = 0x20
+
+	blocksHeaderSize  = 10 // magic + count + block size
+	blocksDefaultSize = 1 << 16
+	blocksMinSize     = 1 << 10
+	blocksMaxSize     = 1 << 24
+	blocksHistory     = 8 // tables a block can refer back to
+)
+
+const (
+	blockNewTable = iota
+	blockRepeat
+	blockReuse
+	blockRLE
+	blockRaw
+)
+
+// blockTable is a normalised table kept for reuse by later blocks.
+type blockTable struct {
+	norm     []int32 // norm[:symbolLen] of the histogram it was built from
+	tableLog uint8
+}
+
+// cost returns the estimated size in bits of coding the histogram count
+// with t, or false if count holds a symbol t cannot code.
+func (t *blockTable) cost(count []uint32) (float64, bool) {
+	bits := 0.0
+	for sym, c := range count {
+		if c == 0 {
+			continue
+		}
+		if sym >= len(t.norm) || t.norm[sym] == 0 {
+			return 0, false
+		}
+		n := float64(t.norm[sym])
+		if n < 0 {
+			n = 1
+		}
+		bits += float64(c) * (float64(t.tableLog) - math.Log2(n))
+	}
+	return bits, true
+}
+
+// FSECompressU16Blocks compresses in with a table chosen per block of
+// blockSize symbols; 0 selects 64K symbols. FSEDecompressU16Auto and
+// FSEDecompressU16Blocks read the result.
+func FSECompressU16Blocks(in []uint16, blockSize int, s *ScratchU16) ([]byte, error) {
+	if blockSize == 0 {
+		blockSize = blocksDefaultSize
+	}
+	if blockSize < blocksMinSize || blockSize > blocksMaxSize {
+		return nil, fmt.Errorf("fseblocks: block size %d outside [%d, %d]", blockSize, blocksMinSize, blocksMaxSize)
+	}
+	if uint64(len(in)) > 1<<32-1 {
+		return nil, fmt.Errorf("fseblocks: %d symbols exceed the stream limit", len(in))
+	}
+	s, err := s.prepare(in, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.appendBlocks(nil, in, blockSize, false)
+	if err != nil || len(in) <= blockSize {
+		return out, err
+	}
+	// The adaptive choice above never picks the whole-stream table, whose
+	// header pays off over many blocks rather than in the first.
+	whole, err := s.appendBlocks(nil, in, blockSize, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(whole) < len(out) {
+		out = whole
+	}
+	return out, nil
+}
+
+// appendBlocks appends the block stream of in to out. If whole is set, the
+// first block carries the table normalised from all of in.
+func (s *ScratchU16) appendBlocks(out []byte, in []uint16, blockSize int, whole bool) ([]byte, error) {
+	out = append(out, blocksMagic0, blocksMagic1)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(in)))
+	out = binary.LittleEndian.AppendUint32(out, uint32(blockSize))
+
+	var history []blockTable
+	for start := 0; start < len(in); start += blockSize {
+		block := in[start:min(start+blockSize, len(in))]
+		hist := block
+		if whole && start == 0 {
+			hist = in
+		}
+		var err error
+		if out, history, err = s.appendBlock(out, block, hist, history); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// appendBlock codes block with the cheapest mode and returns out with the
+// block appended and the updated table history. A new table is normalised
+// from hist, which is block or a superset of it.
+func (s *ScratchU16) appendBlock(out []byte, block, hist []uint16, history []blockTable) ([]byte, []blockTable, error) {
+	if _, err := s.prepare(hist, nil); err != nil {
+		return nil, nil, err
+	}
+	maxCount := s.countSimple(hist)
+	s.clearCount = true
+	s.maxCount = 0
+	if maxCount == len(hist) {
+		return append(out, blockRLE, byte(block[0]), byte(block[0]>>8)), history, nil
+	}
+
+	rawBits := 16 * float64(len(block))
+	mode, reuse, best := blockRaw, 0, rawBits
+	var header []byte
+	if len(block) > 3 { // compress4State needs four symbols
+		count := s.count[:s.symbolLen]
+		if len(hist) > len(block) {
+			// The whole-stream table is forced; only its cost matters.
+			history, best = nil, math.Inf(1)
+		}
+		for k := range history {
+			bits, ok := history[k].cost(count)
+			if k > 0 {
+				bits += 8 // the table index
+			}
+			if ok && bits < best {
+				mode, reuse, best = blockReuse, k, bits
+			}
+		}
+		if maxCount > 1 {
+			s.optimalTableLog()
+			if err := s.normalizeCount(); err != nil {
+				return nil, nil, err
+			}
+			s.Out = s.Out[:0]
+			if err := s.writeCount(); err != nil {
+				return nil, nil, err
+			}
+			t := blockTable{norm: s.norm[:s.symbolLen], tableLog: s.actualTableLog}
+			if bits, _ := t.cost(count); bits+8*float64(len(s.Out)) < best {
+				mode, best = blockNewTable, bits+8*float64(len(s.Out))
+				header = append([]byte(nil), s.Out...)
+			}
+		}
+	}
+
+	switch mode {
+	case blockRaw:
+		out = append(out, blockRaw)
+		for _, v := range block {
+			out = append(out, byte(v), byte(v>>8))
+		}
+		return out, history, nil
+	case blockNewTable:
+		t := blockTable{norm: append([]int32(nil), s.norm[:s.symbolLen]...), tableLog: s.actualTableLog}
+		history = append([]blockTable{t}, history[:min(len(history), blocksHistory-1)]...)
+		out = append(append(out, blockNewTable), header...)
+	default:
+		t := history[reuse]
+		s.setTable(&t)
+		if reuse == 0 {
+			out = append(out, blockRepeat)
+		} else {
+			copy(history[1:reuse+1], history[:reuse])
+			history[0] = t
+			out = append(out, blockReuse, byte(reuse))
+		}
+	}
+
+	if err := s.buildCTable(); err != nil {
+		return nil, nil, err
+	}
+	s.Out = s.Out[:0]
+	if err := s.compress4State(block); err != nil {
+		return nil, nil, err
+	}
+	s.Out = s.bw.out
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(s.Out)))
+	return append(out, s.Out...), history, nil
+}
+
+// setTable makes t the table of the next buildCTable.
+func (s *ScratchU16) setTable(t *blockTable) {
+	copy(s.norm[:], t.norm)
+	s.symbolLen, s.actualTableLog = uint32(len(t.norm)), t.tableLog
+}
+
+// decBlockTable is a decoding table kept for reuse by later blocks.
+type decBlockTable struct {
+	dt       []decSymbolU16
+	tableLog uint8
+	zeroBits bool
+}
+
+// FSEDecompressU16Blocks decompresses a stream produced by
+// FSECompressU16Blocks. It honours s.DecompressLimit when s is non-nil.
+func FSEDecompressU16Blocks(b []byte, s *ScratchU16) ([]uint16, error) {
+	if len(b) < blocksHeaderSize || b[0] != blocksMagic0 || b[1] != blocksMagic1 {
+		return nil, errors.New("fseblocks: missing magic bytes")
+	}
+	count := int(binary.LittleEndian.Uint32(b[2:6]))
+	blockSize := int(binary.LittleEndian.Uint32(b[6:10]))
+	if blockSize < blocksMinSize || blockSize > blocksMaxSize {
+		return nil, fmt.Errorf("fseblocks: block size %d outside [%d, %d]", blockSize, blocksMinSize, blocksMaxSize)
+	}
+	s, err := s.prepare(nil, b)
+	if err != nil {
+		return nil, err
+	}
+	if count > s.DecompressLimit {
+		return nil, fmt.Errorf("output size (%d) > DecompressLimit (%d)", count, s.DecompressLimit)
+	}
+
+	out := s.OutU16[:0]
+	var history []decBlockTable
+	pos := blocksHeaderSize
+	for len(out) < count {
+		n := min(blockSize, count-len(out))
+		if pos >= len(b) {
+			return nil, errors.New("fseblocks: stream truncated")
+		}
+		mode := b[pos]
+		pos++
+
+		var t decBlockTable
+		switch mode {
+		case blockRLE:
+			if len(b)-pos < 2 {
+				return nil, errors.New("fseblocks: stream truncated")
+			}
+			v := binary.LittleEndian.Uint16(b[pos:])
+			pos += 2
+			for range n {
+				out = append(out, v)
+			}
+			continue
+		case blockRaw:
+			if len(b)-pos < 2*n {
+				return nil, errors.New("fseblocks: stream truncated")
+			}
+			for i := range n {
+				out = append(out, binary.LittleEndian.Uint16(b[pos+2*i:]))
+			}
+			pos += 2 * n
+			continue
+		case blockNewTable:
+			s.brForDecomp.init(b[pos:])
+			if err := s.readNCount(); err != nil {
+				return nil, fmt.Errorf("fseblocks: %w", err)
+			}
+			pos = len(b) - s.brForDecomp.remain()
+			s.decTable = nil // the previous table may be in history
+			if err := s.buildDtable(); err != nil {
+				return nil, fmt.Errorf("fseblocks: %w", err)
+			}
+			t = decBlockTable{s.decTable, s.actualTableLog, s.zeroBits}
+			history = append([]decBlockTable{t}, history[:min(len(history), blocksHistory-1)]...)
+		case blockRepeat:
+			if len(history) == 0 {
+				return nil, errors.New("fseblocks: repeat without a table")
+			}
+			t = history[0]
+		case blockReuse:
+			if pos >= len(b) {
+				return nil, errors.New("fseblocks: stream truncated")
+			}
+			k := int(b[pos])
+			pos++
+			if k < 1 || k >= len(history) {
+				return nil, fmt.Errorf("fseblocks: table %d of %d", k, len(history))
+			}
+			t = history[k]
+			copy(history[1:k+1], history[:k])
+			history[0] = t
+		default:
+			return nil, fmt.Errorf("fseblocks: unknown block mode %d", mode)
+		}
+
+		if n <= 3 {
+			return nil, fmt.Errorf("fseblocks: %d-symbol block cannot carry a table", n)
+		}
+		if len(b)-pos < 4 {
+			return nil, errors.New("fseblocks: stream truncated")
+		}
+		length := int(binary.LittleEndian.Uint32(b[pos:]))
+		pos += 4
+		if length > len(b)-pos {
+			return nil, errors.New("fseblocks: stream truncated")
+		}
+		s.decTable, s.actualTableLog, s.zeroBits = t.dt, t.tableLog, t.zeroBits
+		s.brForDecomp.init(b[pos : pos+length])
+		pos += length
+		s.OutU16 = out
+		if err := s.decompress4State(n); err != nil {
+			return nil, fmt.Errorf("fseblocks: %w", err)
+		}
+		out = s.OutU16
+	}
+	if pos != len(b) {
+		return nil, fmt.Errorf("fseblocks: %d trailing bytes", len(b)-pos)
+	}
+	s.OutU16 = out
+	return out, nil
+}
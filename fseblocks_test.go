@@ -0,0 +1,209 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// fseBlockModes counts the blocks of each mode in a FSECompressU16Blocks
+// stream.
+func fseBlockModes(t *testing.T, b []byte) (modes [blockRaw + 1]int) {
+	t.Helper()
+	count := int(binary.LittleEndian.Uint32(b[2:6]))
+	blockSize := int(binary.LittleEndian.Uint32(b[6:10]))
+	pos := blocksHeaderSize
+	for done := 0; done < count; done += blockSize {
+		mode := b[pos]
+		modes[mode]++
+		pos++
+		switch mode {
+		case blockRLE:
+			pos += 2
+			continue
+		case blockRaw:
+			pos += 2 * min(blockSize, count-done)
+			continue
+		case blockNewTable:
+			var s ScratchU16
+			s.brForDecomp.init(b[pos:])
+			if err := s.readNCount(); err != nil {
+				t.Fatal(err)
+			}
+			pos = len(b) - s.brForDecomp.remain()
+		case blockReuse:
+			pos++
+		}
+		pos += 4 + int(binary.LittleEndian.Uint32(b[pos:]))
+	}
+	return modes
+}
+
+// TestFSEBlocksRoundtrip verifies FSECompressU16Blocks on streams that
+// exercise every block mode, and its errors.
+func TestFSEBlocksRoundtrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	// Three regimes that come back: narrow, wide and flat, one block each
+	// but the wide one, which spans two. The blocks run N W W F N W W N: the
+	// flat one is RLE, the second W of a pair repeats and every later N or
+	// W reuses the table of the first of its kind.
+	regimes := make([]uint16, 0, 9*4096)
+	for _, r := range []int{0, 1, 2, 0, 1, 0} {
+		for range 4096 + 4096*(r&1) {
+			switch r {
+			case 0:
+				regimes = append(regimes, uint16(rng.Intn(4)))
+			case 1:
+				regimes = append(regimes, 100+uint16(rng.Intn(40)))
+			default:
+				regimes = append(regimes, 7)
+			}
+		}
+	}
+	noise := make([]uint16, 5000)
+	for i := range noise {
+		noise[i] = uint16(rng.Uint32())
+	}
+	tail := append(append([]uint16(nil), regimes[:4096]...), 1, 2, 3) // 3-symbol last block
+
+	for _, tc := range []struct {
+		name      string
+		symbols   []uint16
+		blockSize int
+		want      [blockRaw + 1]int // 0 = not checked
+	}{
+		{"empty", nil, 0, [blockRaw + 1]int{}},
+		{"single", []uint16{65535}, 0, [blockRaw + 1]int{blockRLE: 1}},
+		{"regimes", regimes, 4096, [blockRaw + 1]int{blockNewTable: 2, blockRepeat: 2, blockReuse: 3, blockRLE: 1}},
+		{"noise", noise, 1024, [blockRaw + 1]int{blockRaw: 5}},
+		{"tail", tail, 4096, [blockRaw + 1]int{blockNewTable: 1, blockRaw: 1}},
+		{"delta", fuzzSymbols(), 0, [blockRaw + 1]int{}},
+		{"default", regimes, 0, [blockRaw + 1]int{blockNewTable: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := FSECompressU16Blocks(tc.symbols, tc.blockSize, nil)
+			if err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			got, err := FSEDecompressU16Auto(b, nil)
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			assertPixelsEqual(t, tc.symbols, got, tc.name)
+			if modes := fseBlockModes(t, b); tc.want != ([blockRaw + 1]int{}) && modes != tc.want {
+				t.Errorf("block modes %v, want %v", modes, tc.want)
+			}
+			if len(tc.symbols) > 1 {
+				if _, err := FSEDecompressU16Blocks(b, &ScratchU16{DecompressLimit: len(tc.symbols) - 1}); err == nil {
+					t.Error("expected error above DecompressLimit")
+				}
+			}
+		})
+	}
+
+	b, err := FSECompressU16Blocks(regimes, 4096, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{len(b) - 1, len(b) / 2, blocksHeaderSize} {
+		if _, err := FSEDecompressU16Blocks(b[:n], nil); err == nil {
+			t.Errorf("expected error for stream truncated to %d bytes", n)
+		}
+	}
+	if _, err := FSEDecompressU16Blocks(append(b, 0), nil); err == nil {
+		t.Error("expected error for trailing bytes")
+	}
+	if _, err := FSECompressU16Blocks(regimes, 100, nil); err == nil {
+		t.Error("expected error for block size below the minimum")
+	}
+
+	pixels, _ := fuzzPixels()
+	blob, err := Compress(pixels, fuzzWidth, fuzzHeight, CompressOptions{Coder: CoderFSEBlocks, Strips: 2})
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	img, err := Decode(blob)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	assertPixelsEqual(t, pixels, img.Pixels, "CoderFSEBlocks")
+}
+
+// TestFSEBlocksComparisonTable prints the size of the Delta+RLE stream with
+// one four-state FSE table against block-adaptive tables, with the block
+// modes chosen at 64K symbols.
+func TestFSEBlocksComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== Compression ratio: one FSE table vs a table per block ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %7s  %7s  %7s  %5s  %6s  %5s  %5s  %5s\n", "Image", "FSE4", "Blk64K", "Blk16K", "New", "Repeat", "Reuse", "RLE", "Raw")
+	fmt.Println("------  -------  -------  -------  -----  ------  -----  -----  -----")
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		if len(pixels) == 0 {
+			continue
+		}
+		orig := float64(width * height * 2)
+
+		var drc DeltaRleCompressU16
+		symbols, err := drc.Compress(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: delta+RLE: %v", td.name, err)
+		}
+		one, err := FSECompressU16FourState(symbols, nil)
+		if err != nil {
+			t.Fatalf("%s: FSE4: %v", td.name, err)
+		}
+		b64, err := FSECompressU16Blocks(symbols, 0, nil)
+		if err != nil {
+			t.Fatalf("%s: blocks: %v", td.name, err)
+		}
+		b16, err := FSECompressU16Blocks(symbols, 1<<14, nil)
+		if err != nil {
+			t.Fatalf("%s: blocks: %v", td.name, err)
+		}
+		got, err := FSEDecompressU16Auto(b16, nil)
+		if err != nil {
+			t.Fatalf("%s: decompress: %v", td.name, err)
+		}
+		assertPixelsEqual(t, symbols, got, td.name)
+
+		m := fseBlockModes(t, b64)
+		fmt.Printf("%-6s  %6.2fx  %6.2fx  %6.2fx  %5d  %6d  %5d  %5d  %5d\n", td.name,
+			orig/float64(len(one)), orig/float64(len(b64)), orig/float64(len(b16)),
+			m[blockNewTable], m[blockRepeat], m[blockReuse], m[blockRLE], m[blockRaw])
+	}
+	fmt.Println()
+}
+
+func BenchmarkFSEBlocksDecompress(b *testing.B) {
+	_, pixels, maxVal, width, height := SetupTests(testFiles[1]) // CT
+	var drc DeltaRleCompressU16
+	symbols, err := drc.Compress(pixels, width, height, maxVal)
+	if err != nil {
+		b.Fatal(err)
+	}
+	comp, err := FSECompressU16Blocks(symbols, 0, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var s ScratchU16
+	b.SetBytes(int64(width * height * 2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FSEDecompressU16Blocks(comp, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
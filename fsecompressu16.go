@@ -503,8 +503,12 @@ func (s *ScratchU16) optimalTableLog() {
 		tableLog = 12
 	}
 
-	if maxBitsSrc < tableLog {
+	// Undo the adaptive step on small inputs, but never below minBits: a
+	// table smaller than the alphabet cannot be normalised.
+	if maxBitsSrc < tableLog && minBits <= maxBitsSrc {
 		tableLog = maxBitsSrc
+	} else if minBits < tableLog && maxBitsSrc < minBits {
+		tableLog = minBits
 	}
 
 	// Need a minimum to safely represent all symbol values
@@ -34,6 +34,10 @@ var (
 
 	// ErrUseRLE is returned from the compressor when the input is a single byte value repeated.
 	ErrUseRLE = errors.New("input is single value repeated")
+
+	// errStreamExhausted is returned by the multi-state decoders when the
+	// header asks for more symbols than the bitstream holds.
+	errStreamExhausted = errors.New("corrupt stream: symbol count exceeds input")
 )
 
 // symbolTransform contains the state transform for a symbol.
@@ -69,6 +73,7 @@ type ScratchU16 struct {
 	bw          bitWriter
 	ct          cTableU16      // Compression tables.
 	decTable    []decSymbolU16 // Decompression table.
+	huffTable   []huffEntry    // Huffman multi-symbol decompression table.
 	maxCount    int            // count of the most probable symbol
 
 	// Per block parameters.
@@ -100,6 +105,14 @@ type ScratchU16 struct {
 
 	// TableLog will attempt to override the tablelog for the next block.
 	TableLog uint8
+
+	// Dictionary decodes FSECompressU16Dict streams that name its ID.
+	// Streams naming another ID use the registered dictionary.
+	Dictionary *Dictionary
+
+	// Reference returns the stream of the unit a FSECompressU16Ref stream
+	// names, unit being an index or RefPrevious.
+	Reference func(unit uint32) ([]byte, error)
 }
 
 // Histogram allows to populate the histogram and skip that step in the compression,
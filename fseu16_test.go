@@ -946,7 +946,10 @@ func DeltaZZRLEHuffTest(t *testing.T, shortData []uint16, cols int, rows int, ma
 
 	var rleD RleDecompressU16
 	rleD.Init(d.Out)
-	rleDecompressed := rleD.Decompress()
+	rleDecompressed, err := rleD.Decompress()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	elapsedFile = time.Since(start)
 	fmt.Println("Delta ZZ RLE Huff - Rle decompress took ", elapsedFile, "Huff size", len(d.Out), "Rle Size", len(rleDecompressed))
@@ -1057,7 +1060,10 @@ func DeltaZZRLEFSETest(t *testing.T, shortData []uint16, cols int, rows int, max
 
 	var rleD RleDecompressU16
 	rleD.Init(deltaDecompFSE)
-	rleDecompressed := rleD.Decompress()
+	rleDecompressed, err := rleD.Decompress()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	elapsedFile = time.Since(start)
 	fmt.Println("Delta ZZ RLE FSE - Rle decompress took ", elapsedFile, "Rle Size", len(rleDecompressed))
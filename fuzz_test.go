@@ -0,0 +1,340 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// Fuzz targets for every decoder that accepts untrusted bytes. Each target
+// is seeded with small valid streams so the fuzzer starts from inputs that
+// reach deep into the decoder; with plain `go test` only the seeds run.
+// Decoders may return any error, but must never panic.
+//
+//	go test -run=^$ -fuzz=FuzzFSEDecompressU16Auto -fuzztime=1m .
+
+const (
+	fuzzWidth  = 48
+	fuzzHeight = 32
+)
+
+// fuzzPixels returns a small smooth 12-bit test image.
+func fuzzPixels() ([]uint16, uint16) {
+	frames, maxValue := makeSmoothFrames(fuzzWidth, fuzzHeight, 2, 3)
+	return frames[0], maxValue
+}
+
+// fuzzSymbols returns a Delta+RLE symbol stream suitable for seeding the
+// entropy decoders directly.
+func fuzzSymbols() []uint16 {
+	pixels, maxValue := fuzzPixels()
+	var drc DeltaRleCompressU16
+	symbols, _ := drc.Compress(pixels, fuzzWidth, fuzzHeight, maxValue)
+	return symbols
+}
+
+func FuzzFSEDecompressU16Auto(f *testing.F) {
+	symbols := fuzzSymbols()
+	for _, compress := range []func([]uint16, *ScratchU16) ([]byte, error){
+		FSECompressU16,
+		FSECompressU16TwoState,
+		FSECompressU16FourState,
+		FSECompressU16EightState,
+		RANSCompressU16EightState,
+	} {
+		if b, err := compress(symbols, nil); err == nil {
+			f.Add(b)
+		}
+	}
+	for _, stride := range []int{0, fuzzWidth} {
+		if b, err := ArithCompressU16(symbols, stride); err == nil {
+			f.Add(b)
+		}
+	}
+	if b, err := FSECompressU16Blocks(symbols, blocksMinSize, nil); err == nil {
+		f.Add(b)
+	}
+	for _, streams := range []int{2, 4} {
+		if b, err := HuffCompressU16Streams(symbols, streams); err == nil {
+			f.Add(b)
+		}
+	}
+	d, err := TrainDictionary(smoothSamples(4), DictionaryOptions{ID: 1, Tables: 2})
+	if err != nil {
+		f.Fatal(err)
+	}
+	if b, err := FSECompressU16Dict(symbols, d, nil); err == nil {
+		f.Add(b)
+	}
+	table, err := FSECompressU16TwoState(symbols, nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+	if b, err := FSECompressU16Ref(symbols, RefPrevious, table, nil); err == nil {
+		f.Add(b)
+	}
+	reference := func(uint32) ([]byte, error) { return table, nil }
+	f.Fuzz(func(t *testing.T, data []byte) {
+		FSEDecompressU16Auto(data, &ScratchU16{Dictionary: d, Reference: reference})
+	})
+}
+
+func FuzzRANSDecompressU16EightState(f *testing.F) {
+	if b, err := RANSCompressU16EightState(fuzzSymbols(), nil); err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		RANSDecompressU16EightState(data, nil)
+	})
+}
+
+func FuzzDecompressParallelStrips(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	for _, compress := range []func([]uint16, int, int, uint16, int) ([]byte, error){
+		CompressParallelStrips,
+		CompressParallelStrips4State,
+		CompressParallelStrips8State,
+		CompressParallelStripsRANS,
+		CompressParallelStripsHuffman,
+	} {
+		if b, err := compress(pixels, fuzzWidth, fuzzHeight, maxValue, 3); err == nil {
+			f.Add(b)
+			if c, err := AddChecksums(b); err == nil {
+				f.Add(c)
+			}
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecompressParallelStrips(data)
+	})
+}
+
+func FuzzDecompressParallelStripsAdaptive(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	if b, err := CompressParallelStripsAdaptive(pixels, fuzzWidth, fuzzHeight, maxValue, 3); err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecompressParallelStripsAdaptive(data)
+	})
+}
+
+func FuzzDecompressParallelTiles(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	for _, tile := range []int{16, 20} {
+		if b, err := CompressParallelTiles(pixels, fuzzWidth, fuzzHeight, maxValue, tile, tile); err == nil {
+			f.Add(b)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecompressParallelTiles(data)
+	})
+}
+
+func FuzzDecompress(f *testing.F) {
+	pixels, _ := fuzzPixels()
+	for _, opts := range []CompressOptions{
+		{},
+		{Predictor: PredictorGrad, Coder: CoderFSE8, Strips: 3, GapRemoval: true},
+		{Coder: CoderRANS, WaveletLevels: 2},
+		{Coder: CoderHuffman, Strips: 2},
+		{Predictor: PredictorMED, GapRemoval: true},
+		{Predictor: PredictorPaeth, Coder: CoderFSE4},
+		{Predictor: PredictorMED, Coder: CoderArith},
+		{Coder: CoderFSEBlocks, Strips: 2},
+		{ValuePacking: true, Strips: 2},
+	} {
+		if b, err := Compress(pixels, fuzzWidth, fuzzHeight, opts); err == nil {
+			f.Add(b)
+		}
+	}
+	for _, sparse := range sparseVariants(pixels, slices.Max(pixels), 1) {
+		if b, err := Compress(sparse, fuzzWidth, fuzzHeight, CompressOptions{ValuePacking: true}); err == nil {
+			f.Add(b)
+		}
+	}
+	for _, step := range []float64{1, 8, 256} {
+		if b, err := CompressLossy(pixels, fuzzWidth, fuzzHeight, LossyOptions{Step: step}); err == nil {
+			f.Add(b)
+		}
+	}
+	for _, k := range []int{1, 3} {
+		if b, err := CompressContextFSE(pixels, fuzzWidth, fuzzHeight, 0, k); err == nil {
+			f.Add(b)
+		}
+	}
+	if b, err := CompressArchive(pixels, fuzzWidth, fuzzHeight, 0); err == nil {
+		f.Add(b)
+	}
+	if b, err := CompressSplitStream(pixels, fuzzWidth, fuzzHeight, 0); err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Decompress(data)
+	})
+}
+
+func FuzzDecompressFrameStream(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	for _, mv := range []uint16{maxValue, 65535} {
+		var buf bytes.Buffer
+		fw := NewFrameWriter(&buf, fuzzWidth, fuzzHeight, mv)
+		if fw.WriteRows(pixels) == nil && fw.Close() == nil {
+			f.Add(buf.Bytes())
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecompressFrameStream(data)
+	})
+}
+
+func FuzzDecodeAtLevel(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	for _, levels := range []int{1, 3} {
+		if b, err := CompressProgressive(pixels, fuzzWidth, fuzzHeight, maxValue, levels); err == nil {
+			f.Add(b, uint8(0))
+			f.Add(b, uint8(levels))
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte, k uint8) {
+		DecodeAtLevel(data, int(k))
+	})
+}
+
+func FuzzReadMIC2Header(f *testing.F) {
+	frames, maxValue := makeSmoothFrames(fuzzWidth, fuzzHeight, 3, 7)
+	for _, temporal := range []bool{false, true} {
+		if b, err := CompressMultiFrame(frames, fuzzWidth, fuzzHeight, maxValue, temporal); err == nil {
+			f.Add(b)
+			if c, err := AddChecksums(b); err == nil {
+				f.Add(c)
+			}
+		}
+	}
+	if b, err := CompressMultiFrameRANS(frames, fuzzWidth, fuzzHeight, maxValue, true); err == nil {
+		f.Add(b)
+	}
+	if b, err := CompressMultiFrameOptions(frames, fuzzWidth, fuzzHeight, maxValue, MultiFrameOptions{ReuseTables: true}); err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, entries, dataOffset, err := ReadMIC2Header(data)
+		if err != nil {
+			return
+		}
+		for i := range entries {
+			ExtractFrame(data, entries, dataOffset, i)
+		}
+		DecompressMultiFrame(data)
+		DecompressFrame(data, len(entries)-1)
+	})
+}
+
+func FuzzReadMIC3Header(f *testing.F) {
+	rgb := makeWSITestImage(300, 200, 5)
+	for _, checksums := range []bool{false, true} {
+		opts := WSIOptions{TileWidth: 128, TileHeight: 128, Checksums: checksums}
+		if b, err := CompressWSI(rgb, 300, 200, 3, 8, opts); err == nil {
+			f.Add(b)
+		}
+	}
+	if b, err := CompressWSI(rgb, 300, 200, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128, Coder: CoderRANS}); err == nil {
+		f.Add(b)
+	}
+	if b, err := CompressWSI(rgb, 300, 200, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128, ReuseTables: true}); err == nil {
+		f.Add(b)
+	}
+	samples := WSIDictionarySamples(rgb, 300, 200, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128})
+	if d, err := TrainDictionary(samples, DictionaryOptions{ID: 1}); err == nil {
+		opts := WSIOptions{TileWidth: 128, TileHeight: 128, Dictionary: d, EmbedDictionary: true}
+		if b, err := CompressWSI(rgb, 300, 200, 3, 8, opts); err == nil {
+			f.Add(b)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		hdr, entries, dataOffset, err := ReadMIC3Header(data)
+		if err != nil {
+			return
+		}
+		for i := range entries {
+			ExtractTileBlob(data, entries, dataOffset, i)
+		}
+		if len(hdr.Levels) > 0 {
+			DecompressWSITile(data, 0, 0, 0)
+		}
+	})
+}
+
+func FuzzDecompressSingleFrameGapRemoval(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	// Spread the values out so the gap-removal modes with a symbol map are
+	// exercised, not just the pass-through mode.
+	sparse := make([]uint16, len(pixels))
+	for i, v := range pixels {
+		sparse[i] = v &^ 0x0F
+	}
+	for _, p := range [][]uint16{pixels, sparse} {
+		if b, err := CompressSingleFrameGapRemoval(p, fuzzWidth, fuzzHeight, maxValue); err == nil {
+			f.Add(b, uint8(fuzzWidth), uint8(fuzzHeight))
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte, width, height uint8) {
+		DecompressSingleFrameGapRemoval(data, int(width), int(height))
+	})
+}
+
+func FuzzWaveletDecompress(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	type codec struct {
+		compress   func([]uint16, int, int, uint16, int) ([]byte, error)
+		decompress func([]byte) ([]uint16, int, int, error)
+	}
+	codecs := []codec{
+		{WaveletFSECompressU16, WaveletFSEDecompressU16},
+		{WaveletRLEFSECompressU16, WaveletRLEFSEDecompressU16},
+		{WaveletV2RLEFSECompressU16, WaveletV2RLEFSEDecompressU16},
+		{WaveletV2SIMDRLEFSECompressU16, WaveletV2SIMDRLEFSEDecompressU16},
+	}
+	for i, c := range codecs {
+		if b, err := c.compress(pixels, fuzzHeight, fuzzWidth, maxValue, 2); err == nil {
+			f.Add(uint8(i), b)
+		}
+	}
+	f.Fuzz(func(t *testing.T, which uint8, data []byte) {
+		codecs[int(which)%len(codecs)].decompress(data)
+	})
+}
+
+func FuzzCanHuffmanDecompress(f *testing.F) {
+	symbols := fuzzSymbols()
+	var enc CanHuffmanCompressU16
+	enc.Init(symbols)
+	if err := enc.Compress(); err == nil {
+		f.Add(enc.Out)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var dec CanHuffmanDecompressU16
+		dec.Init(data)
+		if err := dec.ReadTable(); err != nil {
+			return
+		}
+		dec.Decompress()
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	pixels, maxValue := fuzzPixels()
+	if b, err := CompressSingleFrame(pixels, fuzzWidth, fuzzHeight, maxValue); err == nil {
+		f.Add(legacyMIC1(fuzzWidth, fuzzHeight, b))
+	}
+	if b, err := CompressParallelStripsAdaptive(pixels, fuzzWidth, fuzzHeight, maxValue, 2); err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Decode(data)
+	})
+}
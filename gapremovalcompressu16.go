@@ -57,10 +57,39 @@ func CompressSingleFrameGapRemoval(pixels []uint16, width, height int, maxValue
 		return nil, fmt.Errorf("gap removal: delta+RLE: %w", err)
 	}
 
-	// Step 2: Build histogram and find distinct symbols.
+	// Steps 2-4: choose a map and remap the RLE stream onto a compact alphabet.
+	header, symbols := removeGaps(rleOut)
+
+	// Step 5: FSE compress the (possibly remapped) stream.
+	fseData, err := compressRLEWithFSE(symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: Assemble output behind the map header.
+	out := make([]byte, len(header)+len(fseData))
+	copy(out, header)
+	copy(out[len(header):], fseData)
+	return out, nil
+}
+
+// Gap removal map modes (byte 0 of a gap removal stream).
+const (
+	gapModeNone   = 0x00
+	gapModeRaw    = 0x01
+	gapModeBitmap = 0x02
+	gapModeDelta  = 0x03
+)
+
+// removeGaps decides whether remapping symbols onto [0, numUsed-1] pays for
+// its map, and returns the map header (mode byte included) together with the
+// stream to entropy-code. When gap removal does not pay the header is the
+// single byte gapModeNone and symbols is returned unchanged.
+func removeGaps(symbols []uint16) (header []byte, remapped []uint16) {
+	// Build histogram and find distinct symbols.
 	var hist [maxSymbolValue + 1]uint32
 	var maxSym uint16
-	for _, v := range rleOut {
+	for _, v := range symbols {
 		hist[v]++
 		if v > maxSym {
 			maxSym = v
@@ -77,29 +106,7 @@ func CompressSingleFrameGapRemoval(pixels []uint16, width, height int, maxValue
 	}
 	numUsed := uint32(len(expandMap))
 	eliminatedZeros := symLen - numUsed
-
-	// Step 3: Compute overhead for each map representation.
-	rawMapSize := 3 + numUsed*2 // 1 mode + 2 numSymbols + 2×numUsed
-	bitmapSize := 3 + (uint32(maxSym)+8)/8
-	deltaMapSize := computeDeltaMapSize(expandMap)
-
-	// Choose the representation with the smallest overhead.
-	type mapMode uint8
-	const (
-		modeRaw   mapMode = 0x01
-		modeBitmap mapMode = 0x02
-		modeDelta  mapMode = 0x03
-	)
-	minOverhead := rawMapSize
-	chosenMode := modeRaw
-	if bitmapSize < minOverhead {
-		minOverhead = bitmapSize
-		chosenMode = modeBitmap
-	}
-	if deltaMapSize < minOverhead {
-		minOverhead = deltaMapSize
-		chosenMode = modeDelta
-	}
+	header = gapMapHeader(expandMap)
 
 	// The previous0 run-length encoding in FSE's writeCount() costs roughly
 	// 2 bits per zero-count symbol.  Apply gap removal only when the map
@@ -108,138 +115,165 @@ func CompressSingleFrameGapRemoval(pixels []uint16, width, height int, maxValue
 	// Also require at least 50% of symbols to be unused.
 	applyGapRemoval := numUsed > 1 &&
 		numUsed < symLen/2 &&
-		minOverhead*8 < eliminatedZeros
+		uint32(len(header))*8 < eliminatedZeros
 
 	if !applyGapRemoval {
-		fseData, err := compressRLEWithFSE(rleOut)
-		if err != nil {
-			return nil, err
-		}
-		out := make([]byte, 1+len(fseData))
-		out[0] = 0x00
-		copy(out[1:], fseData)
-		return out, nil
+		return []byte{gapModeNone}, symbols
 	}
 
-	// Step 4: Build reverse compact mapping and remap the RLE stream.
+	// Build reverse compact mapping and remap the stream.
 	var compactIdx [maxSymbolValue + 1]uint16
 	for i, sym := range expandMap {
 		compactIdx[sym] = uint16(i)
 	}
-	remapped := make([]uint16, len(rleOut))
-	for i, v := range rleOut {
+	remapped = make([]uint16, len(symbols))
+	for i, v := range symbols {
 		remapped[i] = compactIdx[v]
 	}
+	return header, remapped
+}
 
-	// Step 5: FSE compress the remapped stream.
-	fseData, err := compressRLEWithFSE(remapped)
-	if err != nil {
-		return nil, err
+// gapMapHeader serialises expandMap, which must be sorted, as the smallest of
+// the raw, bitmap and delta-encoded map representations, mode byte included.
+func gapMapHeader(expandMap []uint16) []byte {
+	var maxSym uint16
+	if len(expandMap) > 0 {
+		maxSym = expandMap[len(expandMap)-1]
+	}
+	numUsed := uint32(len(expandMap))
+
+	// Compute overhead for each map representation.
+	rawMapSize := 3 + numUsed*2 // 1 mode + 2 numSymbols + 2×numUsed
+	bitmapSize := 3 + (uint32(maxSym)+8)/8
+	deltaMapSize := computeDeltaMapSize(expandMap)
+
+	// Choose the representation with the smallest overhead.
+	minOverhead := rawMapSize
+	chosenMode := byte(gapModeRaw)
+	if bitmapSize < minOverhead {
+		minOverhead = bitmapSize
+		chosenMode = gapModeBitmap
+	}
+	if deltaMapSize < minOverhead {
+		chosenMode = gapModeDelta
 	}
 
-	// Step 6: Assemble output with the chosen map representation.
 	switch chosenMode {
-	case modeRaw:
+	case gapModeRaw:
 		n := len(expandMap)
-		headerSize := 1 + 2 + n*2
-		out := make([]byte, headerSize+len(fseData))
-		out[0] = byte(modeRaw)
-		binary.LittleEndian.PutUint16(out[1:3], uint16(n))
+		header := make([]byte, 1+2+n*2)
+		header[0] = gapModeRaw
+		binary.LittleEndian.PutUint16(header[1:3], uint16(n))
 		for i, sym := range expandMap {
-			binary.LittleEndian.PutUint16(out[3+i*2:], sym)
+			binary.LittleEndian.PutUint16(header[3+i*2:], sym)
 		}
-		copy(out[headerSize:], fseData)
-		return out, nil
+		return header
 
-	case modeBitmap:
+	case gapModeBitmap:
 		bitmapLen := int((uint32(maxSym) + 8) / 8)
-		headerSize := 1 + 2 + bitmapLen
-		out := make([]byte, headerSize+len(fseData))
-		out[0] = byte(modeBitmap)
-		binary.LittleEndian.PutUint16(out[1:3], maxSym)
+		header := make([]byte, 1+2+bitmapLen)
+		header[0] = gapModeBitmap
+		binary.LittleEndian.PutUint16(header[1:3], maxSym)
 		for _, sym := range expandMap {
-			out[3+sym/8] |= 1 << (sym % 8)
+			header[3+sym/8] |= 1 << (sym % 8)
 		}
-		copy(out[headerSize:], fseData)
-		return out, nil
-
-	default: // modeDelta
-		header := buildDeltaMapHeader(expandMap)
-		out := make([]byte, 1+len(header)+len(fseData))
-		out[0] = byte(modeDelta)
-		copy(out[1:], header)
-		copy(out[1+len(header):], fseData)
-		return out, nil
+		return header
+
+	default: // gapModeDelta
+		return append([]byte{gapModeDelta}, buildDeltaMapHeader(expandMap)...)
 	}
 }
 
 // DecompressSingleFrameGapRemoval decompresses a stream produced by
 // CompressSingleFrameGapRemoval.
 func DecompressSingleFrameGapRemoval(compressed []byte, width, height int) ([]uint16, error) {
+	var d Decoder
+	return d.DecompressSingleFrameGapRemoval(compressed, width, height)
+}
+
+// DecompressSingleFrameGapRemoval is DecompressSingleFrameGapRemoval under
+// d's limits.
+func (d *Decoder) DecompressSingleFrameGapRemoval(compressed []byte, width, height int) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, fmt.Errorf("gap removal: %w", err)
+	}
+	expandMap, fseData, err := readGapMap(compressed)
+	if err != nil {
+		return nil, err
+	}
+	if expandMap == nil {
+		return d.DecompressSingleFrame(fseData, width, height)
+	}
+	return gapRemovalDecompressWithMap(fseData, expandMap, width, height)
+}
+
+// readGapMap parses the map header written by removeGaps. It returns the
+// expand map (nil for gapModeNone) and the data that follows the header.
+func readGapMap(compressed []byte) (expandMap []uint16, rest []byte, err error) {
 	if len(compressed) < 1 {
-		return nil, fmt.Errorf("gap removal: empty input")
+		return nil, nil, fmt.Errorf("gap removal: empty input")
 	}
 
 	mode := compressed[0]
 
 	switch mode {
-	case 0x00:
-		return DecompressSingleFrame(compressed[1:], width, height)
+	case gapModeNone:
+		return nil, compressed[1:], nil
 
-	case 0x01: // raw expand map
+	case gapModeRaw:
 		if len(compressed) < 3 {
-			return nil, fmt.Errorf("gap removal: header too short")
+			return nil, nil, fmt.Errorf("gap removal: header too short")
 		}
 		numSymbols := int(binary.LittleEndian.Uint16(compressed[1:3]))
 		headerSize := 1 + 2 + numSymbols*2
 		if len(compressed) < headerSize {
-			return nil, fmt.Errorf("gap removal: data too short for raw expandMap")
+			return nil, nil, fmt.Errorf("gap removal: data too short for raw expandMap")
 		}
-		expandMap := make([]uint16, numSymbols)
+		expandMap = make([]uint16, numSymbols)
 		for i := range expandMap {
 			expandMap[i] = binary.LittleEndian.Uint16(compressed[3+i*2:])
 		}
-		return gapRemovalDecompressWithMap(compressed[headerSize:], expandMap, width, height)
+		return expandMap, compressed[headerSize:], nil
 
-	case 0x02: // bitmap
+	case gapModeBitmap:
 		if len(compressed) < 3 {
-			return nil, fmt.Errorf("gap removal: bitmap header too short")
+			return nil, nil, fmt.Errorf("gap removal: bitmap header too short")
 		}
 		maxSym := int(binary.LittleEndian.Uint16(compressed[1:3]))
 		bitmapLen := (maxSym + 8) / 8
 		headerSize := 1 + 2 + bitmapLen
 		if len(compressed) < headerSize {
-			return nil, fmt.Errorf("gap removal: data too short for bitmap")
+			return nil, nil, fmt.Errorf("gap removal: data too short for bitmap")
 		}
 		bitmap := compressed[3:headerSize]
-		expandMap := make([]uint16, 0, 64)
+		expandMap = make([]uint16, 0, 64)
 		for sym := 0; sym <= maxSym; sym++ {
 			if bitmap[sym/8]&(1<<(sym%8)) != 0 {
 				expandMap = append(expandMap, uint16(sym))
 			}
 		}
-		return gapRemovalDecompressWithMap(compressed[headerSize:], expandMap, width, height)
+		return expandMap, compressed[headerSize:], nil
 
-	case 0x03: // delta-encoded expand map
+	case gapModeDelta:
 		if len(compressed) < 5 {
-			return nil, fmt.Errorf("gap removal: delta header too short")
+			return nil, nil, fmt.Errorf("gap removal: delta header too short")
 		}
 		numSymbols := int(binary.LittleEndian.Uint16(compressed[1:3]))
-		expandMap := make([]uint16, numSymbols)
+		expandMap = make([]uint16, numSymbols)
 		if numSymbols == 0 {
-			return gapRemovalDecompressWithMap(compressed[5:], expandMap, width, height)
+			return expandMap, compressed[5:], nil
 		}
 		expandMap[0] = binary.LittleEndian.Uint16(compressed[3:5])
 		p := 5
 		for i := 1; i < numSymbols; i++ {
 			if p >= len(compressed) {
-				return nil, fmt.Errorf("gap removal: delta map truncated at symbol %d", i)
+				return nil, nil, fmt.Errorf("gap removal: delta map truncated at symbol %d", i)
 			}
 			b := compressed[p]
 			p++
 			if b == 0xFF {
 				if p+2 > len(compressed) {
-					return nil, fmt.Errorf("gap removal: delta map escape truncated at symbol %d", i)
+					return nil, nil, fmt.Errorf("gap removal: delta map escape truncated at symbol %d", i)
 				}
 				gap := uint32(binary.LittleEndian.Uint16(compressed[p:]))
 				p += 2
@@ -248,35 +282,45 @@ func DecompressSingleFrameGapRemoval(compressed []byte, width, height int) ([]ui
 				expandMap[i] = expandMap[i-1] + uint16(b) + 1
 			}
 		}
-		return gapRemovalDecompressWithMap(compressed[p:], expandMap, width, height)
+		return expandMap, compressed[p:], nil
 
 	default:
-		return nil, fmt.Errorf("gap removal: unknown mode byte 0x%02x", mode)
+		return nil, nil, fmt.Errorf("gap removal: unknown mode byte 0x%02x", mode)
 	}
 }
 
 // gapRemovalDecompressWithMap FSE-decompresses fseData, expands the compact
 // symbols using expandMap, then Delta+RLE-decompresses to pixels.
 func gapRemovalDecompressWithMap(fseData []byte, expandMap []uint16, width, height int) ([]uint16, error) {
-	numSymbols := len(expandMap)
-
-	var s ScratchU16
+	s := ScratchU16{DecompressLimit: symbolLimit(width * height)}
 	compactSymbols, err := FSEDecompressU16Auto(fseData, &s)
 	if err != nil {
 		return nil, fmt.Errorf("gap removal: FSE decompress: %w", err)
 	}
 
-	rleSymbols := make([]uint16, len(compactSymbols))
+	rleSymbols, err := expandGaps(compactSymbols, expandMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var drd DeltaRleDecompressU16
+	if err := drd.Decompress(rleSymbols, width, height); err != nil {
+		return nil, fmt.Errorf("gap removal: delta+RLE decompress: %w", err)
+	}
+	return drd.Out, nil
+}
+
+// expandGaps maps compact symbols back to their original values.
+func expandGaps(compactSymbols, expandMap []uint16) ([]uint16, error) {
+	numSymbols := len(expandMap)
+	symbols := make([]uint16, len(compactSymbols))
 	for i, c := range compactSymbols {
 		if int(c) >= numSymbols {
 			return nil, fmt.Errorf("gap removal: compact symbol %d out of range [0, %d)", c, numSymbols)
 		}
-		rleSymbols[i] = expandMap[c]
+		symbols[i] = expandMap[c]
 	}
-
-	var drd DeltaRleDecompressU16
-	drd.Decompress(rleSymbols, width, height)
-	return drd.Out, nil
+	return symbols, nil
 }
 
 // computeDeltaMapSize returns the byte size of the delta-encoded header
@@ -0,0 +1,307 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// Interleaved canonical Huffman streams.
+//
+// CanHuffmanCompressU16 writes a single bitstream without a magic, and its
+// decoder walks it one symbol at a time. HuffCompressU16Streams splits the
+// symbols into two or four contiguous segments, each coded into its own
+// bitstream with one shared code:
+//
+//	[0xFF][0x32 or 0x34][code table][sizes of all but the last bitstream,
+//	uint32 LE each][bitstreams]
+//
+// The code table is the CanHuffmanCompressU16 header (symbol count,
+// maxValue, code lengths), padded to a byte, and the bitstreams code symbols
+// as that stream does: symbols outside the code are sent as the delimiter
+// followed by their raw value. Codes are limited to huffStreamsCodeLength
+// bits before the delimiter is added, one bit below CanHuffmanCompressU16,
+// which halves the decoding table for under 1% of output on the test images.
+//
+// The decoder looks up maxCodeLength bits at a time in a table whose entries
+// hold one symbol or, when two codes fit in those bits, two. It advances
+// every segment in the same loop, so the lookups of different streams
+// overlap as the states of multi-state FSE do.
+
+const (
+	huffStreamsMagic0    = 0xFF
+	huffTwoStreamMagic1  = 0x32
+	huffFourStreamMagic1 = 0x34
+
+	// huffStreamsCodeLength is the code length limit of
+	// HuffCompressU16Streams, before the delimiter is added.
+	huffStreamsCodeLength = 13
+)
+
+// huffEntry is an entry of the multi-symbol Huffman decoding table.
+type huffEntry struct {
+	sym0, sym1 uint16
+	bits       uint8 // code bits the entry consumes
+	n          uint8 // symbols decoded; 0 for the delimiter, whose raw value follows
+}
+
+// HuffCompressU16Streams compresses in with canonical Huffman coding into
+// streams (2 or 4) interleaved bitstreams. FSEDecompressU16Auto and
+// HuffDecompressU16Streams read the result. As with FSE, a single repeated
+// value returns ErrUseRLE.
+func HuffCompressU16Streams(in []uint16, streams int) ([]byte, error) {
+	magic := byte(huffTwoStreamMagic1)
+	switch streams {
+	case 2:
+	case 4:
+		magic = huffFourStreamMagic1
+	default:
+		return nil, fmt.Errorf("huffman: %d streams, want 2 or 4", streams)
+	}
+	if len(in) <= 1 {
+		return nil, ErrIncompressible
+	}
+	if len(in) > maxHuffmanSamples {
+		return nil, fmt.Errorf("huffman: %d symbols exceed the stream limit", len(in))
+	}
+	if !slices.ContainsFunc(in, func(v uint16) bool { return v != in[0] }) {
+		return nil, ErrUseRLE
+	}
+
+	var c CanHuffmanCompressU16
+	c.in = in
+	c.codeLengthLimit = huffStreamsCodeLength
+	c.bw.reset(append(make([]byte, 0, len(in)*2+64), huffStreamsMagic0, magic))
+	c.GenerateFrequencies()
+	c.OptimizeSymbolCount()
+	c.AddDelimiterToSymbolList()
+	c.GenerateCanHuffmanTable()
+	c.FindIndexOfDelimiter()
+	c.WriteTable()
+	c.GenerateAllSymbolTable()
+	c.bw.flushAlign()
+	if c.pixelDepth+c.maxCodeLength > 32 {
+		return nil, errors.New("huffman: pixel depth + max code length is greater than 32 bits")
+	}
+
+	out := c.bw.out
+	sizes := len(out)
+	out = append(out, make([]byte, 4*(streams-1))...)
+	seg := (len(in) + streams - 1) / streams
+	for i := range streams {
+		start := len(out)
+		c.bw.reset(out)
+		for _, v := range in[min(i*seg, len(in)):min((i+1)*seg, len(in))] {
+			e := c.allSymbols[v]
+			c.bw.addBits32(e.code, e.codeLen)
+			if e.delimiter {
+				c.bw.addBits32(uint32(v), c.pixelDepth)
+			}
+		}
+		c.bw.flushAlign()
+		out = c.bw.out
+		if i < streams-1 {
+			binary.LittleEndian.PutUint32(out[sizes+4*i:], uint32(len(out)-start))
+		}
+	}
+	return out, nil
+}
+
+// huffReader reads one bitstream of a HuffCompressU16Streams stream, most
+// significant bit first. Past the end it reads zeros, counting them in pad.
+type huffReader struct {
+	in    []byte
+	pos   int    // next byte to load
+	value uint64 // unread bits, from the top
+	avail uint   // bits of value loaded from in or padding
+	pad   uint   // zero bits loaded past the end of in
+}
+
+// refill loads whole bytes until more than 56 bits are available. The fast
+// path also ORs in the bits of the next, uncounted byte; they are the same
+// bits the next refill loads, so loading them twice is harmless.
+func (r *huffReader) refill() {
+	if r.pos+8 <= len(r.in) {
+		r.value |= binary.BigEndian.Uint64(r.in[r.pos:]) >> r.avail
+		n := (63 - r.avail) >> 3
+		r.pos += int(n)
+		r.avail += n << 3
+		return
+	}
+	for r.avail <= 56 {
+		if r.pos < len(r.in) {
+			r.value |= uint64(r.in[r.pos]) << (56 - r.avail)
+			r.pos++
+		} else {
+			r.pad += 8
+		}
+		r.avail += 8
+	}
+}
+
+// decode decodes one table entry into out[k:], which must have room for
+// two symbols, and returns the new k. shift is 64 minus the table log.
+func (r *huffReader) decode(dt []huffEntry, shift, depth uint, out []uint16, k int) int {
+	e := dt[r.value>>shift]
+	r.value <<= e.bits
+	if e.n == 0 {
+		out[k] = uint16(r.value >> (64 - depth))
+		r.value <<= depth
+		r.avail -= uint(e.bits) + depth
+		return k + 1
+	}
+	out[k], out[k+1] = e.sym0, e.sym1
+	r.avail -= uint(e.bits)
+	return k + int(e.n)
+}
+
+// HuffDecompressU16Streams decompresses a stream produced by
+// HuffCompressU16Streams.
+func HuffDecompressU16Streams(b []byte, s *ScratchU16) ([]uint16, error) {
+	if len(b) < 2 || b[0] != huffStreamsMagic0 || (b[1] != huffTwoStreamMagic1 && b[1] != huffFourStreamMagic1) {
+		return nil, errors.New("huffman: missing magic bytes")
+	}
+	streams := 2
+	if b[1] == huffFourStreamMagic1 {
+		streams = 4
+	}
+	s, err := s.prepare(nil, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var d CanHuffmanDecompressU16
+	d.Init(b[2:])
+	n, err := d.readTable()
+	if err != nil {
+		return nil, err
+	}
+	count := int(n)
+	if count > s.DecompressLimit {
+		return nil, fmt.Errorf("output size (%d) > DecompressLimit (%d)", count, s.DecompressLimit)
+	}
+	// The encoder never writes a single-symbol code, whose zero-length
+	// codes would let a short stream declare maxHuffmanSamples symbols.
+	tableLog, depth := uint(d.c.maxCodeLength), uint(d.c.pixelDepth)
+	if tableLog == 0 {
+		return nil, errors.New("huffman: single-symbol code")
+	}
+	if tableLog+depth > 32 {
+		return nil, errors.New("huffman: pixel depth + max code length is greater than 32 bits")
+	}
+	if err := s.buildHuffTable(&d); err != nil {
+		return nil, err
+	}
+
+	// The bitstreams follow the byte-aligned code table and the sizes.
+	tableBits := 8*huffMinHeaderSize + len(d.c.symbolsOfInterestList)*(int(depth)+bits.Len8(d.c.maxCodeLength))
+	sizes := 2 + (tableBits+7)/8
+	pos := sizes + 4*(streams-1)
+	if pos > len(b) {
+		return nil, errors.New("huffman: stream sizes extend beyond input")
+	}
+	var rs [4]huffReader
+	var k, end [4]int
+	seg := (count + streams - 1) / streams
+	for i := range streams {
+		size := len(b) - pos
+		if i < streams-1 {
+			size = int(binary.LittleEndian.Uint32(b[sizes+4*i:]))
+			if size > len(b)-pos {
+				return nil, fmt.Errorf("huffman: bitstream %d extends beyond input", i)
+			}
+		}
+		rs[i].in = b[pos : pos+size]
+		pos += size
+		k[i], end[i] = min(i*seg, count), min((i+1)*seg, count)
+	}
+
+	if cap(s.OutU16) < count {
+		s.OutU16 = make([]uint16, count)
+	}
+	out := s.OutU16[:count]
+	dt := s.huffTable
+	shift := 64 - tableLog
+
+	// A refill leaves more than 56 bits, enough for steps entries of up to
+	// tableLog+depth bits each; an entry writes at most two symbols.
+	steps := min(4, int(56/(tableLog+depth)))
+	for {
+		room := true
+		for i := range streams {
+			room = room && end[i]-k[i] >= 2*steps
+		}
+		if !room {
+			break
+		}
+		for i := range streams {
+			rs[i].refill()
+		}
+		for range steps {
+			for i := range streams {
+				k[i] = rs[i].decode(dt, shift, depth, out, k[i])
+			}
+		}
+	}
+
+	// The segment tails. The second symbol of the last entry may fall
+	// outside the segment, so it goes through a spare slot, and its code,
+	// at most tableLog bits, may be read from the padding.
+	var spare [2]uint16
+	for i := range streams {
+		r := &rs[i]
+		for k[i] < end[i] {
+			r.refill()
+			if end[i]-k[i] >= 2 {
+				k[i] = r.decode(dt, shift, depth, out, k[i])
+				continue
+			}
+			r.decode(dt, shift, depth, spare[:], 0)
+			out[k[i]] = spare[0]
+			k[i]++
+		}
+		if r.pad > r.avail+tableLog {
+			return nil, fmt.Errorf("huffman: bitstream %d: %w", i, errStreamExhausted)
+		}
+	}
+	s.OutU16 = out
+	return out, nil
+}
+
+// buildHuffTable fills s.huffTable from the code d has read, pairing each
+// code with the code that follows it when both fit in the table index.
+func (s *ScratchU16) buildHuffTable(d *CanHuffmanDecompressU16) error {
+	tableLog := d.c.maxCodeLength
+	filled := 0
+	for _, v := range d.c.symbolsOfInterestList {
+		filled += 1 << (tableLog - uint8(v.freq))
+	}
+	if filled != 1<<tableLog {
+		return errors.New("huffman: code lengths do not form a complete prefix code")
+	}
+
+	single := d.codeToSymbolTable
+	if cap(s.huffTable) < len(single) {
+		s.huffTable = make([]huffEntry, len(single))
+	}
+	s.huffTable = s.huffTable[:len(single)]
+	mask := len(single) - 1
+	for idx, e := range single {
+		if e.isDelimiter {
+			s.huffTable[idx] = huffEntry{bits: e.codeLen}
+			continue
+		}
+		h := huffEntry{sym0: e.symbol, sym1: e.symbol, bits: e.codeLen, n: 1}
+		if next := single[(idx<<e.codeLen)&mask]; !next.isDelimiter && next.codeLen <= tableLog-e.codeLen {
+			h.sym1, h.bits, h.n = next.symbol, e.codeLen+next.codeLen, 2
+		}
+		s.huffTable[idx] = h
+	}
+	return nil
+}
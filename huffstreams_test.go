@@ -0,0 +1,303 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestHuffStreamsRoundtrip verifies two- and four-stream Huffman on the
+// Delta+RLE symbols of the test images and on edge cases, through
+// FSEDecompressU16Auto, and the decoder's errors.
+func TestHuffStreamsRoundtrip(t *testing.T) {
+	inputs := map[string][]uint16{
+		"two":       {7, 0},
+		"delimiter": {0, 1, 2, 3, 3, 3, 1, 0},
+		"full":      {0, 65535, 65535, 1, 65534, 65535},
+		"smooth":    fuzzSymbols(),
+	}
+	for n := 2; n < 10; n++ {
+		inputs[fmt.Sprintf("short%d", n)] = fuzzSymbols()[:n]
+	}
+	// A long tail of rare symbols exceeds the code length limit and is
+	// sent through the delimiter.
+	tail := make([]uint16, 0, 1<<16)
+	for v := range 4000 {
+		for range 1 + 4000/(v+1) {
+			tail = append(tail, uint16(v))
+		}
+	}
+	inputs["tail"] = tail
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		var drc DeltaRleCompressU16
+		symbols, err := drc.Compress(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[td.name] = append([]uint16(nil), symbols...)
+	}
+
+	var s ScratchU16
+	for name, in := range inputs {
+		for _, streams := range []int{2, 4} {
+			b, err := HuffCompressU16Streams(in, streams)
+			if err != nil {
+				t.Fatalf("%s/%d: compress: %v", name, streams, err)
+			}
+			got, err := FSEDecompressU16Auto(b, &s)
+			if err != nil {
+				t.Fatalf("%s/%d: decompress: %v", name, streams, err)
+			}
+			assertPixelsEqual(t, in, got, fmt.Sprintf("%s/%d", name, streams))
+		}
+	}
+
+	if _, err := HuffCompressU16Streams(slices.Repeat([]uint16{42}, 1000), 4); err != ErrUseRLE {
+		t.Errorf("constant input: %v, want ErrUseRLE", err)
+	}
+	in := inputs["smooth"]
+	if _, err := HuffCompressU16Streams(in, 3); err == nil {
+		t.Error("expected error for three streams")
+	}
+	b, err := HuffCompressU16Streams(in, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := HuffDecompressU16Streams(b, &ScratchU16{DecompressLimit: len(in) - 1}); err == nil {
+		t.Error("expected error above DecompressLimit")
+	}
+	if _, err := HuffDecompressU16Streams(b[:len(b)/2], nil); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+	if _, err := HuffDecompressU16Streams(b[:2], nil); err == nil {
+		t.Error("expected error for a stream without a table")
+	}
+}
+
+// TestHuffmanContainers verifies that the single-frame, PICS, MIC2, MIC3
+// and MIC1 encoders write four-stream Huffman with CoderHuffman, and that
+// their usual decoders read it.
+func TestHuffmanContainers(t *testing.T) {
+	isHuff := func(b []byte) bool {
+		return len(b) >= 2 && b[0] == huffStreamsMagic0 && b[1] == huffFourStreamMagic1
+	}
+
+	t.Run("single_frame", func(t *testing.T) {
+		pixels, maxValue := fuzzPixels()
+		b, err := CompressSingleFrameHuffman(pixels, fuzzWidth, fuzzHeight, maxValue)
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		if !isHuff(b) {
+			t.Fatalf("stream starts % x, want Huffman magic", b[:2])
+		}
+		got, err := DecompressSingleFrame(b, fuzzWidth, fuzzHeight)
+		if err != nil {
+			t.Fatalf("decompress: %v", err)
+		}
+		assertPixelsEqual(t, pixels, got, "single frame")
+	})
+
+	t.Run("PICS", func(t *testing.T) {
+		frames, maxValue := makeSmoothFrames(96, 64, 1, 8)
+		b, err := CompressParallelStripsHuffman(frames[0], 96, 64, maxValue, 3)
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		p, err := readPICSHeader(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range p.strips {
+			strip, err := p.stripBlob(b, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isHuff(strip) {
+				t.Errorf("strip %d is not Huffman", i)
+			}
+		}
+		img, err := Decode(b)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		assertPixelsEqual(t, frames[0], img.Pixels, "PICS")
+	})
+
+	t.Run("MIC2", func(t *testing.T) {
+		frames, maxValue := makeSmoothFrames(64, 48, 4, 3)
+		for _, temporal := range []bool{false, true} {
+			b, err := CompressMultiFrameOptions(frames, 64, 48, maxValue, MultiFrameOptions{Temporal: temporal, Coder: CoderHuffman})
+			if err != nil {
+				t.Fatalf("temporal=%v: compress: %v", temporal, err)
+			}
+			_, entries, dataOffset, err := ReadMIC2Header(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range entries {
+				if frame, _ := ExtractFrame(b, entries, dataOffset, i); !isHuff(frame) {
+					t.Errorf("temporal=%v: frame %d is not Huffman", temporal, i)
+				}
+			}
+			got, _, err := DecompressMultiFrame(b)
+			if err != nil {
+				t.Fatalf("temporal=%v: decompress: %v", temporal, err)
+			}
+			for i := range frames {
+				assertPixelsEqual(t, frames[i], got[i], fmt.Sprintf("temporal=%v frame %d", temporal, i))
+			}
+		}
+	})
+
+	t.Run("MIC3", func(t *testing.T) {
+		const w, h = 300, 200
+		rgb := makeWSITestImage(w, h, 5)
+		b, err := CompressWSI(rgb, w, h, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128, Coder: CoderHuffman})
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		_, entries, dataOffset, err := ReadMIC3Header(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tile, err := ExtractTileBlob(b, entries, dataOffset, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tile[12] != planeCompressed || !isHuff(tile[13:]) {
+			t.Errorf("tile 0 Y plane starts % x, want a Huffman stream", tile[12:15])
+		}
+		got, err := DecompressWSIRegion(b, 0, 0, 0, w, h)
+		if err != nil {
+			t.Fatalf("region: %v", err)
+		}
+		if !bytes.Equal(got, rgb) {
+			t.Error("MIC3 Huffman roundtrip mismatch")
+		}
+	})
+
+	t.Run("MIC1", func(t *testing.T) {
+		samples := smoothSamples(4)
+		d, err := TrainDictionary(samples, DictionaryOptions{ID: 0xD1C9})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := LookupDictionary(d.ID()); !ok {
+			if err := RegisterDictionary(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, opts := range []CompressOptions{{Coder: CoderHuffman, Strips: 2}, {Coder: CoderHuffman, Dictionary: d}} {
+			b, err := Compress(samples[1].Pixels, fuzzWidth, fuzzHeight, opts)
+			if err != nil {
+				t.Fatalf("%+v: compress: %v", opts, err)
+			}
+			got, _, _, err := Decompress(b)
+			if err != nil {
+				t.Fatalf("%+v: decompress: %v", opts, err)
+			}
+			assertPixelsEqual(t, samples[1].Pixels, got, "MIC1")
+		}
+	})
+}
+
+// TestHuffStreamsComparisonTable prints the size of the Delta+RLE symbols of
+// each test image coded by the single-stream canonical Huffman coder, by
+// four-stream Huffman and by four-state FSE.
+func TestHuffStreamsComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== Delta+RLE symbols: canonical Huffman vs four-stream Huffman vs FSE4 ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %10s  %10s  %10s\n", "Image", "Huffman", "Huff4", "FSE4")
+	fmt.Println("------  ----------  ----------  ----------")
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		var drc DeltaRleCompressU16
+		symbols, err := drc.Compress(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c CanHuffmanCompressU16
+		c.Init(symbols)
+		if err := c.Compress(); err != nil {
+			t.Fatalf("%s: %v", td.name, err)
+		}
+		h4, err := HuffCompressU16Streams(symbols, 4)
+		if err != nil {
+			t.Fatalf("%s: %v", td.name, err)
+		}
+		f4, err := FSECompressU16FourState(symbols, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", td.name, err)
+		}
+		fmt.Printf("%-6s  %10d  %10d  %10d\n", td.name, len(c.Out), len(h4), len(f4))
+	}
+	fmt.Println()
+}
+
+// BenchmarkHuffDecompress compares the single-stream canonical Huffman
+// decoder with the two- and four-stream table-driven decoder.
+func BenchmarkHuffDecompress(b *testing.B) {
+	for _, tf := range testFiles {
+		if _, err := os.Stat(tf.fileName); err != nil {
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(tf)
+		var drc DeltaRleCompressU16
+		symbols, err := drc.Compress(pixels, width, height, maxVal)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var c CanHuffmanCompressU16
+		c.Init(symbols)
+		if err := c.Compress(); err != nil {
+			b.Fatal(err)
+		}
+		legacy := c.Out
+
+		b.Run(tf.name+"/1stream", func(b *testing.B) {
+			b.SetBytes(int64(len(pixels) * 2))
+			for i := 0; i < b.N; i++ {
+				var d CanHuffmanDecompressU16
+				d.Init(legacy)
+				if err := d.ReadTable(); err != nil {
+					b.Fatal(err)
+				}
+				if err := d.Decompress(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		for _, streams := range []int{2, 4} {
+			comp, err := HuffCompressU16Streams(symbols, streams)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("%s/%dstream", tf.name, streams), func(b *testing.B) {
+				var s ScratchU16
+				b.SetBytes(int64(len(pixels) * 2))
+				for i := 0; i < b.N; i++ {
+					if _, err := HuffDecompressU16Streams(comp, &s); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 )
 
@@ -17,18 +18,45 @@ import (
 //	Bytes 4-7:    Width (uint32 LE)
 //	Bytes 8-11:   Height (uint32 LE)
 //	Bytes 12-15:  Frame count (uint32 LE)
-//	Byte  16:     Pipeline flags: bit0=spatial(1), bit1=temporal
-//	Bytes 17-19:  Reserved (zero)
+//	Byte  16:     Pipeline flags: bit0=spatial(1), bit1=temporal, bit2=signed
+//	Byte  17:     PredictorID of the spatial frames (0 = avg)
+//	Bytes 18-19:  Signed offset (int16 LE) — minimum sample when bit2 is set
 //	Bytes 20..:   Frame offset table: N x {offset_u32, length_u32}
 //	After table:  Concatenated compressed frame blobs
+//
+// Readers that predate the signed flag ignore bytes 16-19 beyond the first
+// two flag bits and would decode a signed file as unsigned, so signed files
+// are written with magic "MC2S" instead, which those readers refuse. A "MIC2"
+// header with the signed flag, or any flag bit this reader does not know, is
+// rejected.
+//
+// The checksummed revision (see checksum.go) uses magic "MC2C", stores the
+// whole-file CRC32C in bytes 20-23 and appends each frame's CRC32C to its
+// table entry:
+//
+//	Bytes 0-19:   As above, with magic "MC2C"
+//	Bytes 20-23:  File CRC32C (uint32 LE, computed with this field zeroed)
+//	Bytes 24..:   Frame offset table: N x {offset_u32, length_u32, crc32c_u32}
+//	After table:  Concatenated compressed frame blobs
 
 const (
-	mic2Magic      = "MIC2"
-	mic2HeaderSize = 20
-	mic2EntrySize  = 8 // 4 bytes offset + 4 bytes length
+	mic2Magic       = "MIC2"
+	mic2SignedMagic = "MC2S" // MIC2 layout with the signed flag set
+	mic2HeaderSize  = 20
+	mic2EntrySize   = 8 // 4 bytes offset + 4 bytes length
 
-	PipelineSpatial = 0x01 // spatial delta+RLE+FSE (always set)
+	PipelineSpatial  = 0x01 // spatial delta+RLE+FSE (always set)
 	PipelineTemporal = 0x02 // inter-frame temporal delta before spatial
+	PipelineSigned   = 0x04 // int16 samples stored as sample-MinValue
+
+	mic2KnownFlags = PipelineSpatial | PipelineTemporal | PipelineSigned
+)
+
+const (
+	mic2CRCMagic      = "MC2C"
+	mic2CRCHeaderSize = 24
+	mic2CRCEntrySize  = 12 // offset + length + crc32c
+	mic2CRCFileOff    = 20 // whole-file CRC32C
 )
 
 // MIC2Header holds the parsed header of a MIC2 multiframe file.
@@ -36,24 +64,42 @@ type MIC2Header struct {
 	Width      int
 	Height     int
 	FrameCount int
-	Temporal   bool // true = inter-frame delta prediction
+	Temporal   bool  // true = inter-frame delta prediction
+	Checksums  bool  // true = checksummed revision with per-frame CRC32C
+	Signed     bool  // true = frames hold int16 samples (see CompressMultiFrameS16)
+	MinValue   int16 // offset subtracted from every sample when Signed
+
+	// Predictor is the spatial predictor of every frame that is not a
+	// temporal residual (see CompressMultiFramePredictor).
+	Predictor PredictorID
 }
 
 // MIC2FrameEntry describes one frame's compressed data location.
 type MIC2FrameEntry struct {
 	Offset uint32 // byte offset relative to data section start
 	Length uint32 // compressed byte length
+	CRC    uint32 // CRC32C of the compressed bytes (checksummed files only)
+	HasCRC bool   // true when CRC is present and must be verified
 }
 
-// WriteMIC2 writes a complete MIC2 container to w.
+// WriteMIC2 writes a complete MIC2 container to w. When hdr.Checksums is set
+// the checksummed "MC2C" revision is written, and otherwise "MC2S" when
+// hdr.Signed is set.
 func WriteMIC2(w io.Writer, hdr MIC2Header, frames [][]byte) error {
 	if len(frames) != hdr.FrameCount {
 		return fmt.Errorf("frame count mismatch: header=%d, frames=%d", hdr.FrameCount, len(frames))
 	}
 
+	headerSize, entrySize, magic := mic2HeaderSize, mic2EntrySize, mic2Magic
+	if hdr.Checksums {
+		headerSize, entrySize, magic = mic2CRCHeaderSize, mic2CRCEntrySize, mic2CRCMagic
+	} else if hdr.Signed {
+		magic = mic2SignedMagic
+	}
+
 	// Build header
-	header := make([]byte, mic2HeaderSize)
-	copy(header[0:4], mic2Magic)
+	header := make([]byte, headerSize)
+	copy(header[0:4], magic)
 	binary.LittleEndian.PutUint32(header[4:8], uint32(hdr.Width))
 	binary.LittleEndian.PutUint32(header[8:12], uint32(hdr.Height))
 	binary.LittleEndian.PutUint32(header[12:16], uint32(hdr.FrameCount))
@@ -61,21 +107,38 @@ func WriteMIC2(w io.Writer, hdr MIC2Header, frames [][]byte) error {
 	if hdr.Temporal {
 		flags |= PipelineTemporal
 	}
-	header[16] = flags
-	// bytes 17-19 are zero (reserved)
-
-	if _, err := w.Write(header); err != nil {
-		return err
+	if hdr.Signed {
+		flags |= PipelineSigned
+		binary.LittleEndian.PutUint16(header[18:20], uint16(hdr.MinValue))
 	}
+	header[16] = flags
+	header[17] = byte(hdr.Predictor)
 
-	// Build and write frame offset table
-	table := make([]byte, hdr.FrameCount*mic2EntrySize)
+	// Build frame offset table
+	table := make([]byte, hdr.FrameCount*entrySize)
 	offset := uint32(0)
 	for i, frame := range frames {
-		binary.LittleEndian.PutUint32(table[i*mic2EntrySize:], offset)
-		binary.LittleEndian.PutUint32(table[i*mic2EntrySize+4:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(table[i*entrySize:], offset)
+		binary.LittleEndian.PutUint32(table[i*entrySize+4:], uint32(len(frame)))
+		if hdr.Checksums {
+			binary.LittleEndian.PutUint32(table[i*entrySize+8:], crc32c(frame))
+		}
 		offset += uint32(len(frame))
 	}
+
+	if hdr.Checksums {
+		// Whole-file CRC32C, with its own field still zero.
+		crc := crc32.Update(0, crc32cTable, header)
+		crc = crc32.Update(crc, crc32cTable, table)
+		for _, frame := range frames {
+			crc = crc32.Update(crc, crc32cTable, frame)
+		}
+		binary.LittleEndian.PutUint32(header[mic2CRCFileOff:], crc)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
 	if _, err := w.Write(table); err != nil {
 		return err
 	}
@@ -97,8 +160,16 @@ func ReadMIC2Header(data []byte) (MIC2Header, []MIC2FrameEntry, int, error) {
 		return MIC2Header{}, nil, 0, errors.New("MIC2: file too small")
 	}
 
+	headerSize, entrySize := mic2HeaderSize, mic2EntrySize
 	magic := string(data[0:4])
-	if magic != mic2Magic {
+	switch magic {
+	case mic2Magic, mic2SignedMagic:
+	case mic2CRCMagic:
+		headerSize, entrySize = mic2CRCHeaderSize, mic2CRCEntrySize
+		if len(data) < headerSize {
+			return MIC2Header{}, nil, 0, errors.New("MIC2: file too small")
+		}
+	default:
 		return MIC2Header{}, nil, 0, fmt.Errorf("MIC2: invalid magic %q", magic)
 	}
 
@@ -107,27 +178,47 @@ func ReadMIC2Header(data []byte) (MIC2Header, []MIC2FrameEntry, int, error) {
 		Height:     int(binary.LittleEndian.Uint32(data[8:12])),
 		FrameCount: int(binary.LittleEndian.Uint32(data[12:16])),
 		Temporal:   data[16]&PipelineTemporal != 0,
+		Checksums:  magic == mic2CRCMagic,
+		Signed:     data[16]&PipelineSigned != 0,
+		Predictor:  PredictorID(data[17]),
+	}
+	if f := data[16] &^ mic2KnownFlags; f != 0 {
+		return MIC2Header{}, nil, 0, fmt.Errorf("MIC2: unknown pipeline flags 0x%02x", f)
+	}
+	if magic != mic2CRCMagic && hdr.Signed != (magic == mic2SignedMagic) {
+		return MIC2Header{}, nil, 0, fmt.Errorf("MIC2: signed flag %v with magic %q", hdr.Signed, magic)
+	}
+	if _, ok := LookupPredictor(hdr.Predictor); !ok {
+		return MIC2Header{}, nil, 0, fmt.Errorf("MIC2: unknown predictor %d", hdr.Predictor)
+	}
+	if hdr.Signed {
+		hdr.MinValue = int16(binary.LittleEndian.Uint16(data[18:20]))
 	}
 
-	tableSize := hdr.FrameCount * mic2EntrySize
-	dataOffset := mic2HeaderSize + tableSize
-	if len(data) < dataOffset {
+	if uint64(hdr.FrameCount)*uint64(entrySize) > uint64(len(data)-headerSize) {
 		return MIC2Header{}, nil, 0, errors.New("MIC2: file truncated in frame table")
 	}
+	dataOffset := headerSize + hdr.FrameCount*entrySize
 
 	entries := make([]MIC2FrameEntry, hdr.FrameCount)
 	for i := 0; i < hdr.FrameCount; i++ {
-		base := mic2HeaderSize + i*mic2EntrySize
+		base := headerSize + i*entrySize
 		entries[i] = MIC2FrameEntry{
 			Offset: binary.LittleEndian.Uint32(data[base:]),
 			Length: binary.LittleEndian.Uint32(data[base+4:]),
 		}
+		if hdr.Checksums {
+			entries[i].CRC = binary.LittleEndian.Uint32(data[base+8:])
+			entries[i].HasCRC = true
+		}
 	}
 
 	return hdr, entries, dataOffset, nil
 }
 
 // ExtractFrame returns the compressed bytes for a specific frame from a MIC2 file.
+// When the entry carries a checksum it is verified, and an *ErrChecksumMismatch
+// is returned on mismatch.
 func ExtractFrame(data []byte, entries []MIC2FrameEntry, dataOffset int, frameIdx int) ([]byte, error) {
 	if frameIdx < 0 || frameIdx >= len(entries) {
 		return nil, fmt.Errorf("MIC2: frame index %d out of range [0, %d)", frameIdx, len(entries))
@@ -138,5 +229,10 @@ func ExtractFrame(data []byte, entries []MIC2FrameEntry, dataOffset int, frameId
 	if end > len(data) {
 		return nil, fmt.Errorf("MIC2: frame %d data extends beyond file", frameIdx)
 	}
+	if e.HasCRC {
+		if err := verifyUnit(mic2Magic, "frame", frameIdx, data[start:end], e.CRC); err != nil {
+			return nil, err
+		}
+	}
 	return data[start:end], nil
 }
@@ -92,18 +92,69 @@ func CompressSingleFrame8State(pixels []uint16, width, height int, maxValue uint
 	return fseComp, nil
 }
 
+// CompressSingleFrameRANS compresses a single frame using the Delta+RLE
+// pipeline with eight-state rANS. Falls back to two-state, then single-state
+// FSE if rANS rejects the input. DecompressSingleFrame reads the result.
+func CompressSingleFrameRANS(pixels []uint16, width, height int, maxValue uint16) ([]byte, error) {
+	return compressSingleFrameCoder(pixels, width, height, maxValue, PredictorAvg, CoderRANS, nil)
+}
+
+// CompressSingleFrameHuffman compresses a single frame using the Delta+RLE
+// pipeline with four-stream canonical Huffman (see HuffCompressU16Streams).
+// DecompressSingleFrame reads the result.
+func CompressSingleFrameHuffman(pixels []uint16, width, height int, maxValue uint16) ([]byte, error) {
+	return compressSingleFrameCoder(pixels, width, height, maxValue, PredictorAvg, CoderHuffman, nil)
+}
+
 // DecompressSingleFrame decompresses FSE-compressed bytes back to 16-bit pixels.
-// Auto-detects two-state vs single-state FSE stream format.
+// Auto-detects the stream format: FSE with one to eight states, rANS or
+// Huffman.
 func DecompressSingleFrame(compressed []byte, width, height int) ([]uint16, error) {
-	var s ScratchU16
-	rleSymbols, err := FSEDecompressU16Auto(compressed, &s)
+	var d Decoder
+	return d.DecompressSingleFrame(compressed, width, height)
+}
+
+// DecompressSingleFrame is DecompressSingleFrame under d's limits.
+func (d *Decoder) DecompressSingleFrame(compressed []byte, width, height int) ([]uint16, error) {
+	return d.decompressSingleFrame(nil, compressed, width, height, streamRefs{})
+}
+
+// DecompressSingleFrameInto is DecompressSingleFrame decoding into
+// dst[:width*height] instead of a new slice. Once d has decoded an image of
+// this size it allocates nothing. dst must hold width*height samples.
+func (d *Decoder) DecompressSingleFrameInto(dst []uint16, compressed []byte, width, height int) error {
+	if err := checkDst(dst, width, height); err != nil {
+		return err
+	}
+	_, err := d.decompressSingleFrame(dst, compressed, width, height, streamRefs{})
+	return err
+}
+
+// decompressSingleFrame decodes a CompressSingleFrame stream into dst, or
+// into a new slice if dst is nil, using scratch state from d's pool. refs
+// resolves the dictionary or unit the stream may name.
+func (d *Decoder) decompressSingleFrame(dst []uint16, compressed []byte, width, height int, refs streamRefs) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	fs := getFrameScratch(&d.scratch)
+	defer d.scratch.Put(fs)
+
+	fs.fse.DecompressLimit = symbolLimit(width * height)
+	refs.apply(&fs.fse)
+	rleSymbols, err := FSEDecompressU16Auto(compressed, &fs.fse)
+	streamRefs{}.apply(&fs.fse)
 	if err != nil {
 		return nil, fmt.Errorf("FSE decompress: %w", err)
 	}
 
-	var drd DeltaRleDecompressU16
-	drd.Decompress(rleSymbols, width, height)
-	return drd.Out, nil
+	err = fs.drd.decompressInto(dst, rleSymbols, width, height)
+	pixels := fs.drd.Out
+	fs.drd.Out = nil // the pixels belong to the caller
+	if err != nil {
+		return nil, fmt.Errorf("delta+RLE decompress: %w", err)
+	}
+	return pixels, nil
 }
 
 // CompressSingleFrameGrad compresses a single frame using the gradient-adaptive
@@ -130,40 +181,48 @@ func CompressSingleFrameGrad(pixels []uint16, width, height int, maxValue uint16
 
 // DecompressSingleFrameGrad decompresses a gradient-adaptive Delta+RLE+FSE stream.
 func DecompressSingleFrameGrad(compressed []byte, width, height int) ([]uint16, error) {
-	var s ScratchU16
+	var d Decoder
+	return d.DecompressSingleFrameGrad(compressed, width, height)
+}
+
+// DecompressSingleFrameGrad is DecompressSingleFrameGrad under d's limits.
+func (d *Decoder) DecompressSingleFrameGrad(compressed []byte, width, height int) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	s := ScratchU16{DecompressLimit: symbolLimit(width * height)}
 	rleSymbols, err := FSEDecompressU16Auto(compressed, &s)
 	if err != nil {
 		return nil, fmt.Errorf("FSE decompress: %w", err)
 	}
 
 	var drd GradDeltaRleDecompressU16
-	drd.Decompress(rleSymbols, width, height)
+	if err := drd.Decompress(rleSymbols, width, height); err != nil {
+		return nil, fmt.Errorf("grad-delta+RLE decompress: %w", err)
+	}
 	return drd.Out, nil
 }
 
-// compressResidualFrame compresses temporal residual data using RLE+FSE only
-// (no spatial delta, since zigzag-encoded temporal residuals lack spatial correlation).
-func compressResidualFrame(residuals []uint16, maxValue uint16) ([]byte, error) {
+// compressResidualFrame compresses temporal residual data using RLE and coder
+// c only (no spatial delta, since zigzag-encoded temporal residuals lack
+// spatial correlation).
+func compressResidualFrame(residuals []uint16, maxValue uint16, c Coder) ([]byte, error) {
+	return c.encode(residualSymbols(residuals, maxValue))
+}
+
+// residualSymbols returns the RLE symbols compressResidualFrame codes.
+func residualSymbols(residuals []uint16, maxValue uint16) []uint16 {
 	var rle RleCompressU16
 	rle.Init(len(residuals), 1, maxValue)
-	rleOut := rle.Compress(residuals)
-
-	var s ScratchU16
-	fseComp, err := FSECompressU16TwoState(rleOut, &s)
-	if err != nil {
-		s2 := ScratchU16{}
-		fseComp, err = FSECompressU16(rleOut, &s2)
-		if err != nil {
-			return nil, fmt.Errorf("FSE compress: %w", err)
-		}
-	}
-
-	return fseComp, nil
+	return rle.Compress(residuals)
 }
 
-// decompressResidualFrame decompresses RLE+FSE compressed temporal residual data.
-func decompressResidualFrame(compressed []byte) ([]uint16, error) {
-	var s ScratchU16
+// decompressResidualFrame decompresses RLE+FSE compressed temporal residual
+// data, which must hold exactly n samples (one per pixel of the frame). refs
+// resolves the frames whose table the stream may reuse.
+func decompressResidualFrame(compressed []byte, n int, refs streamRefs) ([]uint16, error) {
+	s := ScratchU16{DecompressLimit: symbolLimit(n)}
+	refs.apply(&s)
 	rleData, err := FSEDecompressU16Auto(compressed, &s)
 	if err != nil {
 		return nil, fmt.Errorf("FSE decompress: %w", err)
@@ -171,23 +230,113 @@ func decompressResidualFrame(compressed []byte) ([]uint16, error) {
 
 	var rle RleDecompressU16
 	rle.Init(rleData)
-	return rle.Decompress(), nil
+	residuals, err := rle.Decompress()
+	if err != nil {
+		return nil, fmt.Errorf("RLE decompress: %w", err)
+	}
+	if len(residuals) != n {
+		return nil, fmt.Errorf("residual length %d != frame size %d", len(residuals), n)
+	}
+	return residuals, nil
+}
+
+// CompressMultiFrameRANS is CompressMultiFrame with every frame, residual
+// frames included, entropy coded by eight-state rANS. The decoders detect
+// the coder per frame.
+func CompressMultiFrameRANS(frames [][]uint16, width, height int, maxValue uint16, temporal bool) ([]byte, error) {
+	hdr := MIC2Header{
+		Width:      width,
+		Height:     height,
+		FrameCount: len(frames),
+		Temporal:   temporal,
+	}
+	return compressMIC2(frames, hdr, maxValue, CoderRANS, false)
 }
 
 // CompressMultiFrame compresses N frames into MIC2 format.
 // If temporal is true, inter-frame delta prediction is applied before spatial compression.
 func CompressMultiFrame(frames [][]uint16, width, height int, maxValue uint16, temporal bool) ([]byte, error) {
+	hdr := MIC2Header{
+		Width:      width,
+		Height:     height,
+		FrameCount: len(frames),
+		Temporal:   temporal,
+	}
+	return compressMIC2(frames, hdr, maxValue, CoderFSE2, false)
+}
+
+// CompressMultiFramePredictor is CompressMultiFrame with the spatial
+// predictor id for every frame that is not a temporal residual. The ID is
+// recorded in the header, so the decoders need no extra argument.
+func CompressMultiFramePredictor(frames [][]uint16, width, height int, maxValue uint16, temporal bool, id PredictorID) ([]byte, error) {
+	if _, ok := LookupPredictor(id); !ok {
+		return nil, fmt.Errorf("unknown predictor %d", id)
+	}
+	hdr := MIC2Header{
+		Width:      width,
+		Height:     height,
+		FrameCount: len(frames),
+		Temporal:   temporal,
+		Predictor:  id,
+	}
+	return compressMIC2(frames, hdr, maxValue, CoderFSE2, false)
+}
+
+// MultiFrameOptions configures CompressMultiFrameOptions. The zero value is
+// CompressMultiFrame without temporal prediction.
+type MultiFrameOptions struct {
+	// Temporal codes every frame after the first as its difference from
+	// the previous frame.
+	Temporal bool
+
+	// Predictor is the spatial predictor of the frames that are not
+	// temporal residuals; any registered ID is accepted.
+	Predictor PredictorID
+
+	// Coder is the entropy coder of every frame. The decoders detect it
+	// from each frame's stream.
+	Coder Coder
+
+	// ReuseTables lets a frame reuse the FSE table of an earlier frame that
+	// carries its own, when that codes it in fewer bytes than its own table
+	// (see FSECompressU16Ref). Random access to any frame is kept.
+	ReuseTables bool
+}
+
+// CompressMultiFrameOptions compresses N frames into MIC2 format with opts.
+func CompressMultiFrameOptions(frames [][]uint16, width, height int, maxValue uint16, opts MultiFrameOptions) ([]byte, error) {
+	if _, ok := LookupPredictor(opts.Predictor); !ok {
+		return nil, fmt.Errorf("unknown predictor %d", opts.Predictor)
+	}
+	if err := checkStreamCoder(opts.Coder); err != nil {
+		return nil, fmt.Errorf("MIC2: %w", err)
+	}
+	hdr := MIC2Header{
+		Width:      width,
+		Height:     height,
+		FrameCount: len(frames),
+		Temporal:   opts.Temporal,
+		Predictor:  opts.Predictor,
+	}
+	return compressMIC2(frames, hdr, maxValue, opts.Coder, opts.ReuseTables)
+}
+
+// compressMIC2 compresses frames with coder c and writes them as a MIC2
+// container described by hdr. With reuse, a frame may instead reference the
+// table of an earlier frame.
+func compressMIC2(frames [][]uint16, hdr MIC2Header, maxValue uint16, c Coder, reuse bool) ([]byte, error) {
 	if len(frames) == 0 {
 		return nil, fmt.Errorf("no frames to compress")
 	}
 
 	frameBlobs := make([][]byte, len(frames))
+	var tables tableReuse
 
 	for i, frame := range frames {
-		var blob []byte
+		var symbols []uint16
 		var err error
 
-		if temporal && i > 0 {
+		if hdr.Temporal && i > 0 {
 			// Apply temporal delta: zigzag-encoded residual
 			residuals := TemporalDeltaEncode(frame, frames[i-1])
 			// Find max residual value for RLE bit depth
@@ -197,24 +346,21 @@ func CompressMultiFrame(frames [][]uint16, width, height int, maxValue uint16, t
 					resMax = v
 				}
 			}
-			blob, err = compressResidualFrame(residuals, resMax)
-		} else {
-			blob, err = CompressSingleFrame(frame, width, height, maxValue)
+			symbols = residualSymbols(residuals, resMax)
+		} else if symbols, err = predictorSymbols(hdr.Predictor, frame, hdr.Width, hdr.Height, maxValue); err != nil {
+			return nil, fmt.Errorf("frame %d: delta+RLE compress: %w", i, err)
 		}
 
+		blob, err := c.encode(symbols)
 		if err != nil {
 			return nil, fmt.Errorf("frame %d: %w", i, err)
 		}
+		if reuse {
+			blob, _ = tables.code(i, symbols, blob)
+		}
 		frameBlobs[i] = blob
 	}
 
-	hdr := MIC2Header{
-		Width:      width,
-		Height:     height,
-		FrameCount: len(frames),
-		Temporal:   temporal,
-	}
-
 	var buf bytes.Buffer
 	if err := WriteMIC2(&buf, hdr, frameBlobs); err != nil {
 		return nil, fmt.Errorf("write MIC2: %w", err)
@@ -223,9 +369,36 @@ func CompressMultiFrame(frames [][]uint16, width, height int, maxValue uint16, t
 	return buf.Bytes(), nil
 }
 
-// DecompressMultiFrame decompresses all frames from a MIC2 file.
-func DecompressMultiFrame(data []byte) ([][]uint16, MIC2Header, error) {
+// readMIC2Header parses a MIC2 header and checks it against d's limits.
+// outFrames is the number of frames the caller will return; 0 means all.
+func (d *Decoder) readMIC2Header(data []byte, outFrames int) (MIC2Header, []MIC2FrameEntry, int, error) {
 	hdr, entries, dataOffset, err := ReadMIC2Header(data)
+	if err != nil {
+		return MIC2Header{}, nil, 0, err
+	}
+	if outFrames == 0 {
+		outFrames = hdr.FrameCount
+	}
+	if err := d.checkFrames(hdr.FrameCount); err != nil {
+		return MIC2Header{}, nil, 0, fmt.Errorf("MIC2: %w", err)
+	}
+	if err := d.checkImage(hdr.Width, hdr.Height, outFrames, 2); err != nil {
+		return MIC2Header{}, nil, 0, fmt.Errorf("MIC2: %w", err)
+	}
+	return hdr, entries, dataOffset, nil
+}
+
+// DecompressMultiFrame decompresses all frames from a MIC2 file. Frames of a
+// signed file are returned as stored (sample - hdr.MinValue); use
+// DecompressMultiFrameS16 to recover the signed samples.
+func DecompressMultiFrame(data []byte) ([][]uint16, MIC2Header, error) {
+	var d Decoder
+	return d.DecompressMultiFrame(data)
+}
+
+// DecompressMultiFrame is DecompressMultiFrame under d's limits.
+func (d *Decoder) DecompressMultiFrame(data []byte) ([][]uint16, MIC2Header, error) {
+	hdr, entries, dataOffset, err := d.readMIC2Header(data, 0)
 	if err != nil {
 		return nil, MIC2Header{}, err
 	}
@@ -238,16 +411,17 @@ func DecompressMultiFrame(data []byte) ([][]uint16, MIC2Header, error) {
 		if err != nil {
 			return nil, MIC2Header{}, err
 		}
+		refs := streamRefs{unit: frameUnits(data, entries, dataOffset, i)}
 
 		var pixels []uint16
 		if hdr.Temporal && i > 0 {
-			residuals, err := decompressResidualFrame(compressed)
+			residuals, err := decompressResidualFrame(compressed, len(prevFrame), refs)
 			if err != nil {
 				return nil, MIC2Header{}, fmt.Errorf("frame %d: %w", i, err)
 			}
 			pixels = TemporalDeltaDecode(residuals, prevFrame)
 		} else {
-			pixels, err = DecompressSingleFrame(compressed, hdr.Width, hdr.Height)
+			pixels, err = d.decompressSingleFramePredictor(compressed, hdr.Width, hdr.Height, hdr.Predictor, refs)
 			if err != nil {
 				return nil, MIC2Header{}, fmt.Errorf("frame %d: %w", i, err)
 			}
@@ -264,7 +438,14 @@ func DecompressMultiFrame(data []byte) ([][]uint16, MIC2Header, error) {
 // For independent mode, any frame can be decoded directly.
 // For temporal mode, frames 0..frameIdx are decoded sequentially.
 func DecompressFrame(data []byte, frameIdx int) ([]uint16, MIC2Header, error) {
-	hdr, entries, dataOffset, err := ReadMIC2Header(data)
+	var d Decoder
+	return d.DecompressFrame(data, frameIdx)
+}
+
+// DecompressFrame is DecompressFrame under d's limits. Only the requested
+// frame counts towards MaxOutputBytes.
+func (d *Decoder) DecompressFrame(data []byte, frameIdx int) ([]uint16, MIC2Header, error) {
+	hdr, entries, dataOffset, err := d.readMIC2Header(data, 1)
 	if err != nil {
 		return nil, MIC2Header{}, err
 	}
@@ -279,7 +460,8 @@ func DecompressFrame(data []byte, frameIdx int) ([]uint16, MIC2Header, error) {
 		if err != nil {
 			return nil, MIC2Header{}, err
 		}
-		pixels, err := DecompressSingleFrame(compressed, hdr.Width, hdr.Height)
+		refs := streamRefs{unit: frameUnits(data, entries, dataOffset, frameIdx)}
+		pixels, err := d.decompressSingleFramePredictor(compressed, hdr.Width, hdr.Height, hdr.Predictor, refs)
 		if err != nil {
 			return nil, MIC2Header{}, fmt.Errorf("frame %d: %w", frameIdx, err)
 		}
@@ -293,16 +475,17 @@ func DecompressFrame(data []byte, frameIdx int) ([]uint16, MIC2Header, error) {
 		if err != nil {
 			return nil, MIC2Header{}, err
 		}
+		refs := streamRefs{unit: frameUnits(data, entries, dataOffset, i)}
 
 		var pixels []uint16
 		if i > 0 {
-			residuals, err := decompressResidualFrame(compressed)
+			residuals, err := decompressResidualFrame(compressed, len(prevFrame), refs)
 			if err != nil {
 				return nil, MIC2Header{}, fmt.Errorf("frame %d: %w", i, err)
 			}
 			pixels = TemporalDeltaDecode(residuals, prevFrame)
 		} else {
-			pixels, err = DecompressSingleFrame(compressed, hdr.Width, hdr.Height)
+			pixels, err = d.decompressSingleFramePredictor(compressed, hdr.Width, hdr.Height, hdr.Predictor, refs)
 			if err != nil {
 				return nil, MIC2Header{}, fmt.Errorf("frame %d: %w", i, err)
 			}
@@ -313,3 +496,14 @@ func DecompressFrame(data []byte, frameIdx int) ([]uint16, MIC2Header, error) {
 
 	return prevFrame, hdr, nil
 }
+
+// frameUnits resolves the units named by the stream of frame idx to frame
+// streams.
+func frameUnits(data []byte, entries []MIC2FrameEntry, dataOffset, idx int) func(uint32) ([]byte, error) {
+	return func(n uint32) ([]byte, error) {
+		if n == RefPrevious {
+			n = uint32(idx - 1)
+		}
+		return ExtractFrame(data, entries, dataOffset, int(n))
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Near-lossless Delta+RLE (JPEG-LS NEAR).
+//
+// With a bound near > 0 the delta coder quantises each residual to a
+// multiple of 2*near+1 before coding it:
+//
+//	q     = sign(e) * ((|e| + near) / (2*near + 1)),  e = x - pred
+//	x'    = clamp(pred + q*(2*near + 1))
+//
+// so |x - x'| <= near for every sample. The loop is closed: the predictor
+// reads reconstructed samples x', exactly as the decoder will, so the error
+// never accumulates along a row or column. Overflow escapes still store the
+// exact sample. Quantised residuals have a far smaller alphabet and longer
+// runs, which is where the gain comes from.
+//
+// The bound travels in the stream. A lossless stream opens with its max
+// value, which is never 0; a near-lossless one opens with the symbols
+// 0, near, maxValue. Decoders that predate the mode reject it as a zero max
+// value instead of mis-decoding it.
+
+// MaxNear is the largest near-lossless error bound, as in JPEG-LS.
+const MaxNear = 255
+
+// nearMarker opens the Delta+RLE symbols of a near-lossless stream.
+const nearMarker = 0
+
+// CompressNear is Compress with a near-lossless error bound: every sample
+// that DeltaRleDecompressU16 reconstructs lies within ±near of the input.
+// near = 0 is Compress. Samples must fit in the bit depth of maxValue.
+func (d *DeltaRleCompressU16) CompressNear(in []uint16, width int, height int, maxValue uint16, near int) ([]uint16, error) {
+	if near < 0 || near > MaxNear {
+		return nil, fmt.Errorf("delta+rle: near-lossless bound %d outside 0..%d", near, MaxNear)
+	}
+	if near == 0 {
+		return d.Compress(in, width, height, maxValue)
+	}
+	pixelDepth := bits.Len16(maxValue)
+	d.deltaThreshold = (uint16)((1 << (pixelDepth - 1)) - 1)
+	d.delimiterForOverflow = (uint16)((1 << (pixelDepth)) - 1)
+	d.out.Init(width, height, d.delimiterForOverflow)
+	d.out.Encode(nearMarker)
+	d.out.Encode(uint16(near))
+	d.out.Encode(maxValue)
+
+	step := int32(2*near + 1)
+	top := int32(d.delimiterForOverflow)
+	recon := make([]uint16, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := (y * width) + x
+			prevSymbol := nearPredict(recon, index, x, y, width)
+
+			diff := int32(in[index]) - prevSymbol
+			var q int32
+			if diff >= 0 {
+				q = (diff + int32(near)) / step
+			} else {
+				q = -((int32(near) - diff) / step)
+			}
+
+			if uint16(abs(q)) >= d.deltaThreshold {
+				d.out.Encode(d.delimiterForOverflow)
+				d.out.Encode(in[index])
+				recon[index] = in[index]
+			} else {
+				d.out.Encode(uint16(int32(d.deltaThreshold) + q))
+				recon[index] = uint16(min(max(int(prevSymbol+q*step), 0), int(top)))
+			}
+		}
+	}
+
+	d.out.Flush()
+
+	return d.out.Out[:], nil
+}
+
+// decodeNear is the DecompressInto loop for near-lossless streams.
+func (d *DeltaRleDecompressU16) decodeNear(width int, height int) {
+	step := int32(2*d.Near + 1)
+	top := int(d.delimiterForOverflow)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := (y * width) + x
+			inputVal := d.decomp.DecodeNext2()
+			if inputVal == d.delimiterForOverflow {
+				d.Out[index] = d.decomp.DecodeNext2()
+				continue
+			}
+			q := int32(inputVal) - int32(d.deltaThreshold)
+			prevSymbol := nearPredict(d.Out, index, x, y, width)
+			d.Out[index] = uint16(min(max(int(prevSymbol+q*step), 0), top))
+		}
+	}
+}
+
+// nearPredict is the Delta+RLE predictor, (left+top)/2, over reconstructed
+// samples.
+func nearPredict(recon []uint16, index, x, y, width int) int32 {
+	switch {
+	case x > 0 && y > 0:
+		return (int32(recon[index-1]) + int32(recon[index-width])) >> 1
+	case x > 0:
+		return int32(recon[index-1])
+	case y > 0:
+		return int32(recon[index-width])
+	}
+	return 0
+}
+
+// CompressSingleFrameNear is CompressSingleFrame with a near-lossless error
+// bound: DecompressSingleFrame returns samples within ±near of pixels.
+// near = 0 is CompressSingleFrame.
+func CompressSingleFrameNear(pixels []uint16, width, height int, maxValue uint16, near int) ([]byte, error) {
+	var drc DeltaRleCompressU16
+	deltaComp, err := drc.CompressNear(pixels, width, height, maxValue, near)
+	if err != nil {
+		return nil, err
+	}
+
+	var s ScratchU16
+	fseComp, err := FSECompressU16TwoState(deltaComp, &s)
+	if err != nil {
+		s2 := ScratchU16{}
+		fseComp, err = FSECompressU16(deltaComp, &s2)
+		if err != nil {
+			return nil, fmt.Errorf("FSE compress: %w", err)
+		}
+	}
+
+	return fseComp, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// assertWithinNear fails unless every sample of got is within ±near of want.
+func assertWithinNear(t *testing.T, want, got []uint16, near int, label string) {
+	t.Helper()
+	if len(want) != len(got) {
+		t.Fatalf("%s: length mismatch: got %d, want %d", label, len(got), len(want))
+	}
+	for i := range want {
+		if e := int(got[i]) - int(want[i]); e < -near || e > near {
+			t.Fatalf("%s: pixel %d off by %d: got %d, want %d±%d", label, i, e, got[i], want[i], near)
+		}
+	}
+}
+
+func TestCompressSingleFrameNear(t *testing.T) {
+	width, height := 160, 120
+	frames, maxValue := makeSmoothFrames(width, height, 1, 5)
+	pixels := frames[0]
+	// Extremes exercise the clamp and the overflow escape.
+	pixels[0], pixels[1], pixels[width] = 0, maxValue, 0
+
+	lossless, err := CompressSingleFrame(pixels, width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := len(lossless)
+	for near := 0; near <= 3; near++ {
+		label := fmt.Sprintf("near=%d", near)
+		compressed, err := CompressSingleFrameNear(pixels, width, height, maxValue, near)
+		if err != nil {
+			t.Fatalf("%s: %v", label, err)
+		}
+		got, err := DecompressSingleFrame(compressed, width, height)
+		if err != nil {
+			t.Fatalf("%s: %v", label, err)
+		}
+		assertWithinNear(t, pixels, got, near, label)
+		if near > 0 && len(compressed) >= prev {
+			t.Fatalf("%s: %d bytes, no smaller than %d at near=%d", label, len(compressed), prev, near-1)
+		}
+		prev = len(compressed)
+	}
+
+	var drc DeltaRleCompressU16
+	symbols, err := drc.CompressNear(pixels, width, height, maxValue, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var drd DeltaRleDecompressU16
+	if err := drd.Decompress(symbols, width, height); err != nil {
+		t.Fatal(err)
+	}
+	if drd.Near != 2 {
+		t.Fatalf("decoder reports near=%d, want 2", drd.Near)
+	}
+
+	for _, near := range []int{-1, MaxNear + 1} {
+		if _, err := CompressSingleFrameNear(pixels, width, height, maxValue, near); err == nil {
+			t.Fatalf("near=%d: expected an error", near)
+		}
+	}
+}
+
+// TestNearLosslessBound verifies the error bound on a synthetic image and
+// on the benchmark images that are available.
+func TestNearLosslessBound(t *testing.T) {
+	checkBound := func(t *testing.T, pixels []uint16, cols, rows int, maxValue uint16) {
+		for near := 1; near <= 3; near++ {
+			compressed, err := CompressSingleFrameNear(pixels, cols, rows, maxValue, near)
+			if err != nil {
+				t.Fatalf("near=%d: %v", near, err)
+			}
+			got, err := DecompressSingleFrame(compressed, cols, rows)
+			if err != nil {
+				t.Fatalf("near=%d: %v", near, err)
+			}
+			assertWithinNear(t, pixels, got, near, fmt.Sprintf("near=%d", near))
+			t.Logf("near=%d: %.2fx", near, float64(len(pixels)*2)/float64(len(compressed)))
+		}
+	}
+
+	t.Run("synthetic", func(t *testing.T) {
+		const width, height = 256, 192
+		frames, maxValue := makeSmoothFrames(width, height, 1, 11)
+		pixels := frames[0]
+		pixels[0], pixels[1], pixels[width] = 0, maxValue, 0
+		checkBound(t, pixels, width, height, maxValue)
+	})
+	for _, tf := range testFiles {
+		t.Run(tf.name, func(t *testing.T) {
+			if _, err := os.Stat(tf.fileName); err != nil {
+				t.Skipf("test data not available: %v", err)
+			}
+			_, shortData, maxShort, cols, rows := SetupTests(tf)
+			if maxShort == 0 {
+				t.Skip("test data is empty or all zero")
+			}
+			checkBound(t, shortData, cols, rows, maxShort)
+		})
+	}
+}
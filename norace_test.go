@@ -0,0 +1,9 @@
+//go:build !race
+
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+const raceEnabled = false
@@ -7,7 +7,8 @@
 // JPEG-LS Comparison Framework (In-Process)
 //
 // Compares MIC variants (Delta+RLE+FSE 2-state and 4-state) against JPEG-LS
-// (lossless) using CharLS as an in-process library via CGO. This provides a
+// (lossless) using CharLS as an in-process library via CGO. The ratio of the
+// archive pipeline (mic.CompressArchive) is reported alongside. This provides a
 // fair apples-to-apples comparison — both codecs are invoked as library calls
 // with no subprocess or file I/O overhead.
 //
@@ -36,6 +37,7 @@ func TestJPEGLSComparison(t *testing.T) {
 		origBytes     int
 		micRatio      float64
 		mic4Ratio     float64
+		archiveRatio  float64
 		jplsRatio     float64
 		micDecompMs   float64
 		mic4DecompMs  float64
@@ -76,6 +78,13 @@ func TestJPEGLSComparison(t *testing.T) {
 			}
 			mic4Ratio := float64(origBytes) / float64(len(mic4Compressed))
 
+			// --- MIC archive compress (ratio only; verified below) ---
+			archiveCompressed, err := mic.CompressArchive(shortData, cols, rows, maxShort)
+			if err != nil {
+				t.Fatalf("MIC archive compress failed: %v", err)
+			}
+			archiveRatio := float64(origBytes) / float64(len(archiveCompressed))
+
 			// --- JPEG-LS compress ---
 			jplsCompressed, err := CharlsCompressU16(shortData, cols, rows, bitDepth)
 			if err != nil {
@@ -141,16 +150,26 @@ func TestJPEGLSComparison(t *testing.T) {
 				}
 			}
 
+			archiveDecoded, _, _, err := mic.Decompress(archiveCompressed)
+			if err != nil {
+				t.Fatalf("MIC archive decompress failed: %v", err)
+			}
+			for i := range shortData {
+				if shortData[i] != archiveDecoded[i] {
+					t.Fatalf("MIC archive roundtrip mismatch at pixel %d: want %d got %d", i, shortData[i], archiveDecoded[i])
+				}
+			}
+
 			micMBs := float64(origBytes) / micDecompMs / 1000.0
 			mic4MBs := float64(origBytes) / mic4DecompMs / 1000.0
 			jplsMBs := float64(origBytes) / jplsDecompMs / 1000.0
 
-			t.Logf("%-4s %4dx%-4d  MIC: %.2fx  MIC-4state: %.2fx  JPEG-LS: %.2fx  MIC: %.1f MB/s  MIC-4state: %.1f MB/s  JPEG-LS: %.1f MB/s",
-				ti.name, cols, rows, micRatio, mic4Ratio, jplsRatio, micMBs, mic4MBs, jplsMBs)
+			t.Logf("%-4s %4dx%-4d  MIC: %.2fx  MIC-4state: %.2fx  archive: %.2fx  JPEG-LS: %.2fx  MIC: %.1f MB/s  MIC-4state: %.1f MB/s  JPEG-LS: %.1f MB/s",
+				ti.name, cols, rows, micRatio, mic4Ratio, archiveRatio, jplsRatio, micMBs, mic4MBs, jplsMBs)
 
 			results = append(results, result{
 				name: ti.name, width: cols, height: rows, origBytes: origBytes,
-				micRatio: micRatio, mic4Ratio: mic4Ratio, jplsRatio: jplsRatio,
+				micRatio: micRatio, mic4Ratio: mic4Ratio, archiveRatio: archiveRatio, jplsRatio: jplsRatio,
 				micDecompMs: micDecompMs, mic4DecompMs: mic4DecompMs, jplsDecompMs: jplsDecompMs,
 			})
 		})
@@ -170,6 +189,14 @@ func TestJPEGLSComparison(t *testing.T) {
 		fmt.Printf("%-6s %10.2fx %12.2fx %10.2fx %13.0f %15.0f %13.0f %10s %10s\n",
 			r.name, r.micRatio, r.mic4Ratio, r.jplsRatio, micMBs, mic4MBs, jplsMBs, micSpeedup, mic4Speedup)
 	}
+
+	fmt.Println("\n=== MIC archive vs JPEG-LS (CharLS) Ratio ===")
+	fmt.Printf("%-6s %11s %11s %9s\n", "Image", "Archive", "JPLS ratio", "vs JPLS")
+	fmt.Println("------  -----------  ----------  ---------")
+	for _, r := range results {
+		fmt.Printf("%-6s %10.2fx %10.2fx %+8.1f%%\n",
+			r.name, r.archiveRatio, r.jplsRatio, 100*(r.archiveRatio/r.jplsRatio-1))
+	}
 }
 
 // BenchmarkJPEGLSDecomp benchmarks JPEG-LS decompression via CharLS (in-process)
@@ -122,10 +122,28 @@ func MICDecompressEightStateSIMD(compressed []byte, width, height int) ([]uint16
 	return pixels, nil
 }
 
+// MICDecompressRANSEightStateC decompresses a MIC eight-state rANS stream
+// (mic.CompressSingleFrameRANS) using the scalar C implementation. rANS
+// decodes with the eight-state FSE loop over its own decode table.
+func MICDecompressRANSEightStateC(compressed []byte, width, height int) ([]uint16, error) {
+	pixels := make([]uint16, width*height)
+
+	rc := C.mic_decompress_rans_eight_state(
+		(*C.uint8_t)(unsafe.Pointer(&compressed[0])),
+		C.size_t(len(compressed)),
+		(*C.uint16_t)(unsafe.Pointer(&pixels[0])),
+		C.int(width), C.int(height),
+	)
+	if rc != 0 {
+		return nil, fmt.Errorf("mic_decompress_rans_eight_state failed: rc=%d", rc)
+	}
+	return pixels, nil
+}
+
 // MICDecompressParallelC decompresses a PICS blob (produced by
 // mic.CompressParallelStrips) using C pthreads.  Each horizontal strip is
-// decompressed concurrently by mic_decompress_four_state_simd (AMD64) or
-// mic_decompress_four_state (ARM64 / other).
+// decompressed concurrently by the FSE or rANS decoder its magic selects,
+// SIMD-optimised on AMD64.
 //
 // maxThreads controls the pthread pool size; 0 = one thread per strip.
 func MICDecompressParallelC(pics []byte, width, height, maxThreads int) ([]uint16, error) {
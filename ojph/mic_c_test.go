@@ -289,6 +289,55 @@ func TestMICCorrectnessEightStateC(t *testing.T) {
 	}
 }
 
+// TestMICCorrectnessRANSC verifies the C rANS decoder against the Go rANS
+// encoder, alone and through the C PICS decoder.
+func TestMICCorrectnessRANSC(t *testing.T) {
+	for _, ti := range testImages {
+		t.Run(ti.name, func(t *testing.T) {
+			_, shortData, maxShort, cols, rows := loadTestImage(ti)
+			if len(shortData) == 0 {
+				t.Skip("could not load image")
+			}
+
+			ransComp, err := mic.CompressSingleFrameRANS(shortData, cols, rows, maxShort)
+			if err != nil {
+				t.Fatalf("Go rANS compress: %v", err)
+			}
+			if ransComp[0] != 0xFF || ransComp[1] != 0x08 {
+				t.Fatalf("stream magic % x, want ff 08", ransComp[:2])
+			}
+			cPixels, err := MICDecompressRANSEightStateC(ransComp, cols, rows)
+			if err != nil {
+				t.Fatalf("C rANS decompress: %v", err)
+			}
+			for i := range shortData {
+				if cPixels[i] != shortData[i] {
+					t.Fatalf("C rANS pixel %d: got %d, want %d", i, cPixels[i], shortData[i])
+				}
+			}
+
+			pics, err := mic.CompressParallelStripsRANS(shortData, cols, rows, maxShort, 4)
+			if err != nil {
+				t.Fatalf("Go rANS PICS compress: %v", err)
+			}
+			for name, decode := range map[string]func([]byte, int, int, int) ([]uint16, error){
+				"SIMD":   MICDecompressParallelC,
+				"scalar": MICDecompressParallelScalarC,
+			} {
+				picsPixels, err := decode(pics, cols, rows, 4)
+				if err != nil {
+					t.Fatalf("C PICS %s decompress: %v", name, err)
+				}
+				for i := range shortData {
+					if picsPixels[i] != shortData[i] {
+						t.Fatalf("C PICS %s pixel %d: got %d, want %d", name, i, picsPixels[i], shortData[i])
+					}
+				}
+			}
+		})
+	}
+}
+
 // TestFourWayComparison prints a side-by-side comparison table: MIC-Go vs MIC-C vs MIC-SIMD vs HTJ2K.
 func TestFourWayComparison(t *testing.T) {
 	const decompRuns = 10
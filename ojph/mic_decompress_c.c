@@ -365,6 +365,40 @@ static int build_dtable(const int32_t *norm, uint32_t symbol_len,
     return 0;
 }
 
+// ---------------------------------------------------------------------------
+// build_rans_dtable — rANS decode table (mirrors buildRansDecTable in Go).
+// Slots are filled linearly: every symbol owns norm[s] consecutive slots,
+// then each low-probability symbol (norm == -1) owns one slot at the end.
+// ---------------------------------------------------------------------------
+static int build_rans_dtable(const int32_t *norm, uint32_t symbol_len,
+                             uint8_t table_log, dec_symbol_t *dt) {
+    uint32_t table_size = 1u << table_log;
+    uint32_t slot = 0;
+
+    for (uint32_t s = 0; s < symbol_len; s++) {
+        if (norm[s] <= 0) continue;
+        uint32_t freq = (uint32_t)norm[s];
+        if (slot + freq > table_size) return -1;
+        for (uint32_t j = 0; j < freq; j++) {
+            uint32_t x_next = freq + j;
+            uint8_t n_bits = table_log - (uint8_t)high_bits32(x_next);
+            dt[slot].new_state = (x_next << n_bits) - table_size;
+            dt[slot].symbol = (uint16_t)s;
+            dt[slot].nb_bits = n_bits;
+            slot++;
+        }
+    }
+    for (uint32_t s = 0; s < symbol_len; s++) {
+        if (norm[s] != -1) continue;
+        if (slot >= table_size) return -1;
+        dt[slot].new_state = 0;
+        dt[slot].symbol = (uint16_t)s;
+        dt[slot].nb_bits = table_log;
+        slot++;
+    }
+    return slot == table_size ? 0 : -1;
+}
+
 // ---------------------------------------------------------------------------
 // FSE two-state decompress
 // ---------------------------------------------------------------------------
@@ -1262,15 +1296,21 @@ int mic_decompress_four_state_simd(const uint8_t *compressed, size_t compressed_
 }
 
 // ---------------------------------------------------------------------------
-// Eight-state header parser. Magic: [0xFF][0x84][count_u32_le].
+// Eight-state header parser. Magic: [0xFF][0x84][count_u32_le] for FSE,
+// [0xFF][0x08][count_u32_le] for rANS. The two share the FSE header and the
+// bitstream layout; only the decode table differs.
 // ---------------------------------------------------------------------------
+#define EIGHT_STATE_FSE_MAGIC  0x84
+#define EIGHT_STATE_RANS_MAGIC 0x08
+
 static int parse_eight_state_header(const uint8_t *compressed, size_t compressed_len,
+                                    uint8_t magic,
                                     uint32_t *symbol_count_out,
                                     dec_symbol_t **dt_out, uint8_t *table_log_out,
                                     int *zero_bits_out,
                                     const uint8_t **bitstream_out, size_t *bitstream_len_out) {
     if (compressed_len < 6) return -1;
-    if (compressed[0] != 0xFF || compressed[1] != 0x84) return -1;
+    if (compressed[0] != 0xFF || compressed[1] != magic) return -1;
 
     *symbol_count_out = (uint32_t)compressed[2] |
                         ((uint32_t)compressed[3] << 8) |
@@ -1294,7 +1334,10 @@ static int parse_eight_state_header(const uint8_t *compressed, size_t compressed
     dec_symbol_t *dt = (dec_symbol_t *)dec_table_alloc(table_size, sizeof(dec_symbol_t));
     if (!dt) return -3;
 
-    if (build_dtable(norm, symbol_len, *table_log_out, dt) != 0) {
+    int built = magic == EIGHT_STATE_RANS_MAGIC
+                    ? build_rans_dtable(norm, symbol_len, *table_log_out, dt)
+                    : build_dtable(norm, symbol_len, *table_log_out, dt);
+    if (built != 0) {
         free(dt);
         return -4;
     }
@@ -1317,7 +1360,7 @@ int mic_decompress_eight_state(const uint8_t *compressed, size_t compressed_len,
     const uint8_t *bitstream = NULL;
     size_t bitstream_len = 0;
 
-    int rc = parse_eight_state_header(compressed, compressed_len,
+    int rc = parse_eight_state_header(compressed, compressed_len, EIGHT_STATE_FSE_MAGIC,
                                       &symbol_count, &dt, &table_log, &zero_bits,
                                       &bitstream, &bitstream_len);
     if (rc != 0) return rc;
@@ -1353,7 +1396,7 @@ int mic_decompress_eight_state_simd(const uint8_t *compressed, size_t compressed
     const uint8_t *bitstream = NULL;
     size_t bitstream_len = 0;
 
-    int rc = parse_eight_state_header(compressed, compressed_len,
+    int rc = parse_eight_state_header(compressed, compressed_len, EIGHT_STATE_FSE_MAGIC,
                                       &symbol_count, &dt, &table_log, &zero_bits,
                                       &bitstream, &bitstream_len);
     if (rc != 0) return rc;
@@ -1380,3 +1423,37 @@ int mic_decompress_eight_state_simd(const uint8_t *compressed, size_t compressed
     return 0;
 #endif
 }
+
+// ---------------------------------------------------------------------------
+// Public API: MIC eight-state rANS decompress (scalar). The rANS decode step
+// is the tANS step over a differently built table, so the eight-lane FSE
+// loop decodes it unchanged.
+// ---------------------------------------------------------------------------
+int mic_decompress_rans_eight_state(const uint8_t *compressed, size_t compressed_len,
+                                    uint16_t *pixels_out, int width, int height) {
+    uint32_t symbol_count = 0;
+    dec_symbol_t *dt = NULL;
+    uint8_t table_log = 0;
+    int zero_bits = 0;
+    const uint8_t *bitstream = NULL;
+    size_t bitstream_len = 0;
+
+    int rc = parse_eight_state_header(compressed, compressed_len, EIGHT_STATE_RANS_MAGIC,
+                                      &symbol_count, &dt, &table_log, &zero_bits,
+                                      &bitstream, &bitstream_len);
+    if (rc != 0) return rc;
+
+    uint16_t *rle_out = (uint16_t *)malloc(symbol_count * sizeof(uint16_t));
+    if (!rle_out) { free(dt); return -5; }
+
+    rc = fse_decompress_eight_state(bitstream, bitstream_len,
+                                    rle_out, (int)symbol_count,
+                                    dt, table_log, zero_bits);
+    free(dt);
+    if (rc != 0) { free(rle_out); return -6; }
+
+    rle_delta_decompress(rle_out, (int)symbol_count, pixels_out, width, height);
+
+    free(rle_out);
+    return 0;
+}
@@ -49,6 +49,12 @@ int mic_decompress_eight_state(const uint8_t *compressed, size_t compressed_len,
 int mic_decompress_eight_state_simd(const uint8_t *compressed, size_t compressed_len,
                                     uint16_t *pixels_out, int width, int height);
 
+// mic_decompress_rans_eight_state decompresses a MIC eight-state rANS
+// stream (RANSCompressU16EightState over Delta+RLE symbols).
+// Input format: [0xFF][0x08][count_u32_le][FSE header][bitstream]
+int mic_decompress_rans_eight_state(const uint8_t *compressed, size_t compressed_len,
+                                    uint16_t *pixels_out, int width, int height);
+
 #ifdef __cplusplus
 }
 #endif
@@ -195,15 +195,22 @@ static int decompress_parallel_impl(const uint8_t *compressed, size_t compressed
 // MIC strip blobs carry a format byte at offset 1:
 //   0x02 → two-state FSE (produced by CompressSingleFrame / Go PICS)
 //   0x04 → four-state FSE (produced by MICCompressFourStateC / C pipeline)
+//   0x84 → eight-state FSE (produced by CompressSingleFrame8State)
+//   0x08 → eight-state rANS (produced by CompressParallelStripsRANS)
 //
 // mic_decompress_auto_simd inspects that byte and dispatches to the
 // appropriate SIMD-optimised decoder, so mic_decompress_parallel works
 // transparently with PICS blobs produced by either the Go or C encoder.
+// rANS has no SIMD variant; its scalar decoder serves both paths.
 
 static int mic_decompress_auto_simd(const uint8_t *data, size_t len,
                                     uint16_t *out, int w, int h) {
     if (len >= 2 && data[1] == 0x04)
         return mic_decompress_four_state_simd(data, len, out, w, h);
+    if (len >= 2 && data[1] == 0x84)
+        return mic_decompress_eight_state_simd(data, len, out, w, h);
+    if (len >= 2 && data[1] == 0x08)
+        return mic_decompress_rans_eight_state(data, len, out, w, h);
     return mic_decompress_two_state_simd(data, len, out, w, h);
 }
 
@@ -211,6 +218,10 @@ static int mic_decompress_auto_scalar(const uint8_t *data, size_t len,
                                       uint16_t *out, int w, int h) {
     if (len >= 2 && data[1] == 0x04)
         return mic_decompress_four_state(data, len, out, w, h);
+    if (len >= 2 && data[1] == 0x84)
+        return mic_decompress_eight_state(data, len, out, w, h);
+    if (len >= 2 && data[1] == 0x08)
+        return mic_decompress_rans_eight_state(data, len, out, w, h);
     return mic_decompress_two_state(data, len, out, w, h);
 }
 
@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"runtime"
+	"sort"
 	"sync"
 )
 
@@ -27,6 +28,20 @@ import (
 //	Bytes 20+:   Offset table    (NumStrips × [offset_u32, length_u32])
 //	After table: Concatenated compressed strip blobs (each a CompressSingleFrame output)
 //
+// The checksummed revision (magic "PICC", see checksum.go) stores a
+// whole-file CRC32C in bytes 20-23, moves the offset table to byte 24 and
+// appends each strip's CRC32C to its entry: [offset_u32, length_u32, crc32c_u32].
+//
+// Strips coded with a predictor other than avg (CompressParallelStripsPredictor)
+// use the "PICP" revision, whose header records the predictor:
+//
+//	Bytes  0-19: As PICS
+//	Byte  20:    PredictorID
+//	Byte  21:    Flags: bit0=checksums
+//	Bytes 22-23: Reserved (0)
+//	Bytes 24-27: Whole-file CRC32C (0 without checksums)
+//	Bytes 28+:   Strip table, entries as PICS, or as PICC with checksums
+//
 // Compression ratio impact
 //
 // The only accuracy loss is at strip boundaries: the first row of each non-zero
@@ -44,6 +59,17 @@ import (
 const (
 	picsMagic      = "PICS"
 	picsHeaderBase = 20 // 4+4+4+4+4 bytes before offset table
+	picsEntrySize  = 8  // offset + length
+
+	picsCRCMagic      = "PICC"
+	picsCRCHeaderBase = 24
+	picsCRCEntrySize  = 12 // offset + length + crc32c
+	picsCRCFileOff    = 20
+
+	picpMagic         = "PICP"
+	picpHeaderBase    = 28
+	picpFileOff       = 24
+	picpFlagChecksums = 0x01
 )
 
 // CompressParallelStrips compresses pixels using numStrips goroutines, one per
@@ -98,29 +124,7 @@ func CompressParallelStrips(pixels []uint16, width, height int, maxValue uint16,
 		}
 	}
 
-	// Build output: fixed header + offset table + strip blobs.
-	headerSize := picsHeaderBase + actual*8
-	totalData := 0
-	for _, r := range results {
-		totalData += len(r)
-	}
-
-	out := make([]byte, headerSize+totalData)
-	copy(out[0:4], picsMagic)
-	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
-	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
-	binary.LittleEndian.PutUint32(out[12:16], uint32(actual))
-	binary.LittleEndian.PutUint32(out[16:20], uint32(stripH))
-
-	offset := 0
-	for s, r := range results {
-		tblOff := picsHeaderBase + s*8
-		binary.LittleEndian.PutUint32(out[tblOff:tblOff+4], uint32(offset))
-		binary.LittleEndian.PutUint32(out[tblOff+4:tblOff+8], uint32(len(r)))
-		copy(out[headerSize+offset:], r)
-		offset += len(r)
-	}
-	return out, nil
+	return buildPICS(results, width, height, stripH, PredictorAvg, false), nil
 }
 
 // CompressParallelStrips4State is like CompressParallelStrips but compresses each
@@ -169,28 +173,7 @@ func CompressParallelStrips4State(pixels []uint16, width, height int, maxValue u
 		}
 	}
 
-	headerSize := picsHeaderBase + actual*8
-	totalData := 0
-	for _, r := range results {
-		totalData += len(r)
-	}
-
-	out := make([]byte, headerSize+totalData)
-	copy(out[0:4], picsMagic)
-	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
-	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
-	binary.LittleEndian.PutUint32(out[12:16], uint32(actual))
-	binary.LittleEndian.PutUint32(out[16:20], uint32(stripH))
-
-	offset := 0
-	for s, r := range results {
-		tblOff := picsHeaderBase + s*8
-		binary.LittleEndian.PutUint32(out[tblOff:tblOff+4], uint32(offset))
-		binary.LittleEndian.PutUint32(out[tblOff+4:tblOff+8], uint32(len(r)))
-		copy(out[headerSize+offset:], r)
-		offset += len(r)
-	}
-	return out, nil
+	return buildPICS(results, width, height, stripH, PredictorAvg, false), nil
 }
 
 // CompressParallelStrips8State is like CompressParallelStrips but compresses each
@@ -240,91 +223,358 @@ func CompressParallelStrips8State(pixels []uint16, width, height int, maxValue u
 		}
 	}
 
-	headerSize := picsHeaderBase + actual*8
-	totalData := 0
-	for _, r := range results {
-		totalData += len(r)
+	return buildPICS(results, width, height, stripH, PredictorAvg, false), nil
+}
+
+// CompressParallelStripsPredictor is like CompressParallelStrips but codes
+// each strip with the registered predictor id (see CompressSingleFramePredictor).
+// PredictorAvg produces the same PICS blob as CompressParallelStrips; any
+// other predictor is recorded in a PICP header.
+func CompressParallelStripsPredictor(pixels []uint16, width, height int, maxValue uint16, numStrips int, id PredictorID) ([]byte, error) {
+	return compressParallelStrips(pixels, width, height, maxValue, numStrips, id, CoderFSE2)
+}
+
+// CompressParallelStripsRANS is like CompressParallelStrips but codes each
+// strip with CompressSingleFrameRANS. The PICS layout is unchanged; the
+// decoders detect the rANS strips by their magic.
+func CompressParallelStripsRANS(pixels []uint16, width, height int, maxValue uint16, numStrips int) ([]byte, error) {
+	return compressParallelStrips(pixels, width, height, maxValue, numStrips, PredictorAvg, CoderRANS)
+}
+
+// CompressParallelStripsHuffman is like CompressParallelStrips but codes
+// each strip with CompressSingleFrameHuffman. The PICS layout is unchanged;
+// the decoders detect the Huffman strips by their magic.
+func CompressParallelStripsHuffman(pixels []uint16, width, height int, maxValue uint16, numStrips int) ([]byte, error) {
+	return compressParallelStrips(pixels, width, height, maxValue, numStrips, PredictorAvg, CoderHuffman)
+}
+
+// compressParallelStrips codes each strip with predictor id and coder c.
+func compressParallelStrips(pixels []uint16, width, height int, maxValue uint16, numStrips int, id PredictorID, c Coder) ([]byte, error) {
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("parallelstrips: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if _, ok := LookupPredictor(id); !ok {
+		return nil, fmt.Errorf("parallelstrips: unknown predictor %d", id)
+	}
+	if numStrips <= 0 {
+		numStrips = runtime.GOMAXPROCS(0)
+	}
+	if numStrips > height {
+		numStrips = height
+	}
+	if numStrips < 1 {
+		numStrips = 1
 	}
 
-	out := make([]byte, headerSize+totalData)
-	copy(out[0:4], picsMagic)
-	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
-	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
-	binary.LittleEndian.PutUint32(out[12:16], uint32(actual))
-	binary.LittleEndian.PutUint32(out[16:20], uint32(stripH))
+	stripH := (height + numStrips - 1) / numStrips
+	actual := (height + stripH - 1) / stripH
 
-	offset := 0
-	for s, r := range results {
-		tblOff := picsHeaderBase + s*8
-		binary.LittleEndian.PutUint32(out[tblOff:tblOff+4], uint32(offset))
-		binary.LittleEndian.PutUint32(out[tblOff+4:tblOff+8], uint32(len(r)))
-		copy(out[headerSize+offset:], r)
-		offset += len(r)
+	results := make([][]byte, actual)
+	errs := make([]error, actual)
+
+	var wg sync.WaitGroup
+	for s := 0; s < actual; s++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			y0 := idx * stripH
+			y1 := min(y0+stripH, height)
+			results[idx], errs[idx] = compressSingleFrameCoder(pixels[y0*width:y1*width], width, y1-y0, maxValue, id, c, nil)
+		}(s)
 	}
-	return out, nil
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("parallelstrips: strip %d: %w", i, err)
+		}
+	}
+
+	return buildPICS(results, width, height, stripH, id, false), nil
 }
 
 // DecompressParallelStrips recovers an image from a PICS blob produced by
 // CompressParallelStrips.  All strips are decompressed concurrently.
 // Returns pixels (row-major, uint16), width, height.
+//
+// PICP blobs (CompressParallelStripsPredictor) and checksummed "PICC" blobs
+// are accepted too: every strip's CRC32C is
+// verified before decoding, and an *ErrChecksumMismatch names the failing strip.
 func DecompressParallelStrips(compressed []byte) (pixels []uint16, width, height int, err error) {
-	if len(compressed) < picsHeaderBase || string(compressed[0:4]) != picsMagic {
-		return nil, 0, 0, fmt.Errorf("parallelstrips: invalid magic")
+	var d Decoder
+	return d.DecompressParallelStrips(compressed)
+}
+
+// DecompressParallelStrips is DecompressParallelStrips under d's limits.
+func (d *Decoder) DecompressParallelStrips(compressed []byte) (pixels []uint16, width, height int, err error) {
+	p, err := readPICSHeader(compressed)
+	if err != nil {
+		return nil, 0, 0, err
 	}
+	if err := d.checkImage(p.width, p.height, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("parallelstrips: %w", err)
+	}
+	pixels, err = d.picsRegion(compressed, p, 0, 0, p.width, p.height)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, p.width, p.height, nil
+}
 
-	width = int(binary.LittleEndian.Uint32(compressed[4:8]))
-	height = int(binary.LittleEndian.Uint32(compressed[8:12]))
-	numStrips := int(binary.LittleEndian.Uint32(compressed[12:16]))
-	stripH := int(binary.LittleEndian.Uint32(compressed[16:20]))
+// DecompressParallelStripsRegion decodes the w x h rectangle at (x, y) of a
+// PICS or PICC blob, clipped to the image, and returns its pixels
+// (row-major) and clipped size. Only the strips that overlap the rectangle
+// are decoded, concurrently, and only their checksums are verified.
+func DecompressParallelStripsRegion(compressed []byte, x, y, w, h int) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.DecompressParallelStripsRegion(compressed, x, y, w, h)
+}
+
+// DecompressParallelStripsRegion is DecompressParallelStripsRegion under d's
+// limits; the clipped region counts towards MaxPixels and MaxOutputBytes.
+func (d *Decoder) DecompressParallelStripsRegion(compressed []byte, x, y, w, h int) (pixels []uint16, width, height int, err error) {
+	p, err := readPICSHeader(compressed)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	w, h, err = clipRegion(x, y, w, h, p.width, p.height)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("parallelstrips: %w", err)
+	}
+	if err := d.checkImage(w, h, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("parallelstrips: %w", err)
+	}
+	pixels, err = d.picsRegion(compressed, p, x, y, w, h)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, w, h, nil
+}
+
+// picsRegion decodes a clipped region of the PICS blob laid out as p.
+func (d *Decoder) picsRegion(compressed []byte, p *picsLayout, x, y, w, h int) ([]uint16, error) {
+	starts := make([]int, len(p.strips))
+	for i := range starts {
+		starts[i] = i * p.stripH
+		if starts[i] >= p.height {
+			return nil, fmt.Errorf("strip %d: starts beyond image height", i)
+		}
+	}
+	return decodeStripRegion(starts, p.width, p.height, x, y, w, h, func(idx, rows int) ([]uint16, error) {
+		blob, err := p.stripBlob(compressed, idx)
+		if err != nil {
+			return nil, err
+		}
+		stripPixels, err := d.DecompressSingleFramePredictor(blob, p.width, rows, p.predictor)
+		if err != nil {
+			return nil, fmt.Errorf("strip %d: %w", idx, err)
+		}
+		return stripPixels, nil
+	})
+}
 
-	headerSize := picsHeaderBase + numStrips*8
-	if len(compressed) < headerSize {
-		return nil, 0, 0, fmt.Errorf("parallelstrips: truncated header")
+// clipRegion clips a w x h region at (x, y) to a width x height image and
+// returns the clipped size. The origin must lie inside the image.
+func clipRegion(x, y, w, h, width, height int) (int, int, error) {
+	if x < 0 || y < 0 || x >= width || y >= height {
+		return 0, 0, fmt.Errorf("region origin (%d, %d) outside %dx%d image", x, y, width, height)
+	}
+	w, h = min(w, width-x), min(h, height-y)
+	if w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("empty region")
 	}
-	if width <= 0 || height <= 0 || numStrips <= 0 || stripH <= 0 {
-		return nil, 0, 0, fmt.Errorf("parallelstrips: invalid dimensions")
+	return w, h, nil
+}
+
+// decodeStripRegion decodes, concurrently, the horizontal strips that
+// overlap rows [y, y+h) and copies columns [x, x+w) of those rows into a
+// w x h result. starts holds the increasing first row of every strip, the
+// last strip ending at height; decode returns strip idx's rows x width
+// pixels, or an error naming the strip. The lowest failing strip's error is
+// returned.
+func decodeStripRegion(starts []int, width, height, x, y, w, h int, decode func(idx, rows int) ([]uint16, error)) ([]uint16, error) {
+	first := sort.Search(len(starts), func(i int) bool { return starts[i] > y }) - 1
+	if first < 0 {
+		return nil, fmt.Errorf("row %d is not covered by any strip", y)
 	}
+	last := sort.Search(len(starts), func(i int) bool { return starts[i] >= y+h }) - 1
 
-	out := make([]uint16, width*height)
-	errs := make([]error, numStrips)
+	out := make([]uint16, w*h)
+	errs := make([]error, last-first+1)
 
 	var wg sync.WaitGroup
-	for s := 0; s < numStrips; s++ {
+	for s := first; s <= last; s++ {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			tblOff := picsHeaderBase + idx*8
-			stripOffset := int(binary.LittleEndian.Uint32(compressed[tblOff : tblOff+4]))
-			stripLen := int(binary.LittleEndian.Uint32(compressed[tblOff+4 : tblOff+8]))
-
-			start := headerSize + stripOffset
-			end := start + stripLen
-			if start < 0 || end > len(compressed) || start > end {
-				errs[idx] = fmt.Errorf("strip %d: offset out of bounds", idx)
-				return
-			}
-
-			y0 := idx * stripH
-			y1 := y0 + stripH
-			if y1 > height {
-				y1 = height
+			y0, y1 := starts[idx], height
+			if idx+1 < len(starts) {
+				y1 = starts[idx+1]
 			}
-			sh := y1 - y0
-
-			stripPixels, decErr := DecompressSingleFrame(compressed[start:end], width, sh)
-			if decErr != nil {
-				errs[idx] = fmt.Errorf("strip %d: %w", idx, decErr)
+			stripPixels, err := decode(idx, y1-y0)
+			if err != nil {
+				errs[idx-first] = err
 				return
 			}
-			copy(out[y0*width:], stripPixels)
+			for r := max(y0, y); r < min(y1, y+h); r++ {
+				src := (r-y0)*width + x
+				copy(out[(r-y)*w:(r-y+1)*w], stripPixels[src:src+w])
+			}
 		}(s)
 	}
 	wg.Wait()
 
 	for _, e := range errs {
 		if e != nil {
-			return nil, 0, 0, e
+			return nil, e
 		}
 	}
-	return out, width, height, nil
+	return out, nil
+}
+
+// picsLayout is the parsed header and strip table of a PICS/PICC/PICP blob.
+type picsLayout struct {
+	width, height, stripH int
+	strips                []picsStrip
+	dataOffset            int
+	checksums             bool
+	crcOff                int // whole-file CRC32C offset when checksums is set
+	predictor             PredictorID
+}
+
+type picsStrip struct {
+	offset, length int
+	crc            uint32
+}
+
+// readPICSHeader parses the header and strip table of a PICS, checksummed
+// PICC or predictor-tagged PICP blob.
+func readPICSHeader(compressed []byte) (*picsLayout, error) {
+	if len(compressed) < picsHeaderBase {
+		return nil, fmt.Errorf("parallelstrips: invalid magic")
+	}
+	p := &picsLayout{
+		width:  int(binary.LittleEndian.Uint32(compressed[4:8])),
+		height: int(binary.LittleEndian.Uint32(compressed[8:12])),
+		stripH: int(binary.LittleEndian.Uint32(compressed[16:20])),
+	}
+	headerBase, entrySize := picsHeaderBase, picsEntrySize
+	switch string(compressed[0:4]) {
+	case picsMagic:
+	case picsCRCMagic:
+		headerBase, entrySize = picsCRCHeaderBase, picsCRCEntrySize
+		if len(compressed) < headerBase {
+			return nil, fmt.Errorf("parallelstrips: truncated header")
+		}
+		p.checksums, p.crcOff = true, picsCRCFileOff
+	case picpMagic:
+		headerBase = picpHeaderBase
+		if len(compressed) < headerBase {
+			return nil, fmt.Errorf("parallelstrips: truncated header")
+		}
+		p.predictor = PredictorID(compressed[20])
+		flags := compressed[21]
+		if flags&^picpFlagChecksums != 0 {
+			return nil, fmt.Errorf("parallelstrips: unsupported flags 0x%02x", flags)
+		}
+		if flags&picpFlagChecksums != 0 {
+			entrySize = picsCRCEntrySize
+			p.checksums, p.crcOff = true, picpFileOff
+		}
+		if _, ok := LookupPredictor(p.predictor); !ok {
+			return nil, fmt.Errorf("parallelstrips: unknown predictor %d", p.predictor)
+		}
+	default:
+		return nil, fmt.Errorf("parallelstrips: invalid magic")
+	}
+	numStrips := int(binary.LittleEndian.Uint32(compressed[12:16]))
+
+	if uint64(numStrips)*uint64(entrySize) > uint64(len(compressed)-headerBase) {
+		return nil, fmt.Errorf("parallelstrips: truncated header")
+	}
+	if p.width <= 0 || p.height <= 0 || numStrips <= 0 || p.stripH <= 0 {
+		return nil, fmt.Errorf("parallelstrips: invalid dimensions")
+	}
+	p.dataOffset = headerBase + numStrips*entrySize
+
+	p.strips = make([]picsStrip, numStrips)
+	for i := range p.strips {
+		tblOff := headerBase + i*entrySize
+		p.strips[i] = picsStrip{
+			offset: int(binary.LittleEndian.Uint32(compressed[tblOff : tblOff+4])),
+			length: int(binary.LittleEndian.Uint32(compressed[tblOff+4 : tblOff+8])),
+		}
+		if p.checksums {
+			p.strips[i].crc = binary.LittleEndian.Uint32(compressed[tblOff+8 : tblOff+12])
+		}
+	}
+	return p, nil
+}
+
+// stripBlob returns the compressed bytes of strip idx, verifying its
+// checksum when the blob carries one.
+func (p *picsLayout) stripBlob(compressed []byte, idx int) ([]byte, error) {
+	e := p.strips[idx]
+	start := p.dataOffset + e.offset
+	end := start + e.length
+	if start < 0 || end > len(compressed) || start > end {
+		return nil, fmt.Errorf("strip %d: offset out of bounds", idx)
+	}
+	if p.checksums {
+		if err := verifyUnit(picsMagic, "strip", idx, compressed[start:end], e.crc); err != nil {
+			return nil, err
+		}
+	}
+	return compressed[start:end], nil
+}
+
+// buildPICS assembles strip blobs into a PICS container, or into the
+// checksummed PICC revision when checksums is set. Strips coded with a
+// predictor other than avg produce a PICP container.
+func buildPICS(results [][]byte, width, height, stripH int, predictor PredictorID, checksums bool) []byte {
+	headerBase, entrySize, magic, crcOff := picsHeaderBase, picsEntrySize, picsMagic, 0
+	switch {
+	case predictor != PredictorAvg:
+		headerBase, magic, crcOff = picpHeaderBase, picpMagic, picpFileOff
+		if checksums {
+			entrySize = picsCRCEntrySize
+		}
+	case checksums:
+		headerBase, entrySize, magic, crcOff = picsCRCHeaderBase, picsCRCEntrySize, picsCRCMagic, picsCRCFileOff
+	}
+
+	headerSize := headerBase + len(results)*entrySize
+	totalData := 0
+	for _, r := range results {
+		totalData += len(r)
+	}
+
+	out := make([]byte, headerSize+totalData)
+	copy(out[0:4], magic)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
+	binary.LittleEndian.PutUint32(out[12:16], uint32(len(results)))
+	binary.LittleEndian.PutUint32(out[16:20], uint32(stripH))
+	if magic == picpMagic {
+		out[20] = byte(predictor)
+		if checksums {
+			out[21] = picpFlagChecksums
+		}
+	}
+
+	offset := 0
+	for s, r := range results {
+		tblOff := headerBase + s*entrySize
+		binary.LittleEndian.PutUint32(out[tblOff:tblOff+4], uint32(offset))
+		binary.LittleEndian.PutUint32(out[tblOff+4:tblOff+8], uint32(len(r)))
+		if checksums {
+			binary.LittleEndian.PutUint32(out[tblOff+8:tblOff+12], crc32c(r))
+		}
+		copy(out[headerSize+offset:], r)
+		offset += len(r)
+	}
+	if checksums {
+		binary.LittleEndian.PutUint32(out[crcOff:], fileCRC32C(out, crcOff))
+	}
+	return out
 }
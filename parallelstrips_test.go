@@ -260,12 +260,12 @@ func TestPICSComparisonTable(t *testing.T) {
 	const iters = 10
 
 	type row struct {
-		image                string
-		origMB               float64
-		micRatio, mic4Ratio  float64
-		picsRatio            [5]float64 // index = strips: 1,2,4,8 at [0..3]
-		micGBs, mic4GBs      float64
-		picsGBs              [4]float64
+		image               string
+		origMB              float64
+		micRatio, mic4Ratio float64
+		picsRatio           [5]float64 // index = strips: 1,2,4,8 at [0..3]
+		micGBs, mic4GBs     float64
+		picsGBs             [4]float64
 	}
 
 	stripCounts := []int{1, 2, 4, 8}
@@ -332,12 +332,12 @@ func TestPICSComparisonTable(t *testing.T) {
 
 		gbDiv := float64(origBytes) / (1 << 30)
 		r := row{
-			image:    td.name,
-			origMB:   float64(origBytes) / (1 << 20),
-			micRatio: float64(origBytes) / float64(len(micComp)),
+			image:     td.name,
+			origMB:    float64(origBytes) / (1 << 20),
+			micRatio:  float64(origBytes) / float64(len(micComp)),
 			mic4Ratio: float64(origBytes) / float64(len(mic4Comp)),
-			micGBs:   gbDiv / (micMs / 1000.0),
-			mic4GBs:  gbDiv / (mic4Ms / 1000.0),
+			micGBs:    gbDiv / (micMs / 1000.0),
+			mic4GBs:   gbDiv / (mic4Ms / 1000.0),
 		}
 		for i, n := range stripCounts {
 			_ = n
@@ -427,3 +427,63 @@ func itoa(n int) string {
 	}
 	return string(buf[pos:])
 }
+
+// TestParallelStripsRegion checks that region decodes of PICS and PICA blobs
+// match the same crop of the full image, including rectangles that straddle
+// strip boundaries and ones clipped at the right and bottom edges.
+func TestParallelStripsRegion(t *testing.T) {
+	td := testFiles[0] // MR 256x256
+	_, pixels, maxVal, width, height := SetupTests(td)
+
+	pics, err := CompressParallelStrips(pixels, width, height, maxVal, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pica, err := CompressParallelStripsAdaptive(pixels, width, height, maxVal, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regions := []struct{ x, y, w, h int }{
+		{0, 0, width, height},
+		{10, 60, 50, 10},   // straddles the first strip boundary
+		{0, 0, 1, 1},       // single pixel
+		{100, 30, 40, 150}, // spans three strips
+		{200, 200, 100, 100},
+	}
+	decoders := map[string]func(int, int, int, int) ([]uint16, int, int, error){
+		"PICS": func(x, y, w, h int) ([]uint16, int, int, error) {
+			return DecompressParallelStripsRegion(pics, x, y, w, h)
+		},
+		"PICA": func(x, y, w, h int) ([]uint16, int, int, error) {
+			return DecompressParallelStripsAdaptiveRegion(pica, x, y, w, h)
+		},
+	}
+	for name, decode := range decoders {
+		for _, r := range regions {
+			got, w, h, err := decode(r.x, r.y, r.w, r.h)
+			if err != nil {
+				t.Fatalf("%s %+v: %v", name, r, err)
+			}
+			wantW, wantH := min(r.w, width-r.x), min(r.h, height-r.y)
+			if w != wantW || h != wantH {
+				t.Fatalf("%s %+v: dims %dx%d, want %dx%d", name, r, w, h, wantW, wantH)
+			}
+			for row := 0; row < h; row++ {
+				for col := 0; col < w; col++ {
+					want := pixels[(r.y+row)*width+r.x+col]
+					if g := got[row*w+col]; g != want {
+						t.Fatalf("%s %+v: pixel (%d, %d): got %d want %d", name, r, col, row, g, want)
+					}
+				}
+			}
+		}
+
+		if _, _, _, err := decode(width, 0, 1, 1); err == nil {
+			t.Fatalf("%s: expected error for origin outside image", name)
+		}
+		if _, _, _, err := decode(0, 0, 0, 5); err == nil {
+			t.Fatalf("%s: expected error for empty region", name)
+		}
+	}
+}
@@ -15,8 +15,8 @@ import (
 //
 // Extends PICS with two improvements:
 //
-//  1. Per-strip predictor selection: each strip independently chooses avg or
-//     gradient-adaptive predictor by trying both and keeping the smaller result.
+//  1. Per-strip predictor selection: each strip independently tries every
+//     registered predictor (see RegisterPredictor) and keeps the smallest result.
 //
 //  2. Content-adaptive strip partitioning: strip boundaries are placed at
 //     entropy transitions (equal-cost partitioning on inter-row variance)
@@ -35,9 +35,15 @@ import (
 //
 // flags bits:
 //
-//	bit 0: picaFlagGradPredictor — strip was encoded with gradient-adaptive predictor
+//	bit 0:     picaFlagGradPredictor — strip was encoded with gradient-adaptive predictor
+//	bits 8-15: PredictorID of the strip (PIAP only)
+//
+// When every strip chose avg or grad the blob is a plain PICA. Otherwise the
+// magic is "PIAP", so readers that only know bit 0 reject the file instead of
+// decoding its strips with the wrong predictor.
 const (
 	picaMagic     = "PICA"
+	piapMagic     = "PIAP"
 	picaEntrySize = 16 // y0(4) + offset(4) + length(4) + flags(4)
 	picaHdrSize   = 16 // magic(4) + width(4) + height(4) + numStrips(4)
 )
@@ -46,9 +52,14 @@ const (
 // CompressSingleFrameGrad (CALIC-style predictor) rather than CompressSingleFrame.
 const picaFlagGradPredictor = uint32(1 << 0)
 
+const (
+	picaPredictorShift = 8
+	picaFlagsKnown     = picaFlagGradPredictor | 0xff<<picaPredictorShift
+)
+
 // CompressParallelStripsAdaptive compresses pixels using content-adaptive strip
-// boundaries and per-strip predictor selection (tries both avg and grad, keeps
-// the smaller). numStrips <= 0 selects GOMAXPROCS automatically.
+// boundaries and per-strip predictor selection (tries every registered
+// predictor, keeps the smallest). numStrips <= 0 selects GOMAXPROCS automatically.
 //
 // The resulting PICA blob can be decoded with DecompressParallelStripsAdaptive.
 func CompressParallelStripsAdaptive(pixels []uint16, width, height int, maxValue uint16, numStrips int) ([]byte, error) {
@@ -68,6 +79,7 @@ func CompressParallelStripsAdaptive(pixels []uint16, width, height int, maxValue
 	// Compute content-adaptive strip start rows.
 	starts := adaptiveStripBoundaries(pixels, width, height, numStrips)
 	actual := len(starts)
+	ids := Predictors()
 
 	results := make([][]byte, actual)
 	stripFlags := make([]uint32, actual)
@@ -86,29 +98,34 @@ func CompressParallelStripsAdaptive(pixels []uint16, width, height int, maxValue
 			sh := y1 - y0
 			strip := pixels[y0*width : y1*width]
 
-			// Try avg predictor.
-			blobAvg, err1 := CompressSingleFrame(strip, width, sh, maxValue)
-			// Try gradient-adaptive predictor.
-			blobGrad, err2 := CompressSingleFrameGrad(strip, width, sh, maxValue)
-
-			// Keep whichever is smaller (or the one that succeeded).
-			if err2 == nil && (err1 != nil || len(blobGrad) <= len(blobAvg)) {
-				results[idx] = blobGrad
-				stripFlags[idx] = picaFlagGradPredictor
-				errs[idx] = nil
-			} else {
-				results[idx] = blobAvg
-				stripFlags[idx] = 0
-				errs[idx] = err1
+			// Keep the smallest result; the first error is reported only
+			// when every predictor fails.
+			for _, id := range ids {
+				blob, err := CompressSingleFramePredictor(strip, width, sh, maxValue, id)
+				if err != nil {
+					if results[idx] == nil && errs[idx] == nil {
+						errs[idx] = err
+					}
+					continue
+				}
+				if results[idx] == nil || len(blob) < len(results[idx]) {
+					results[idx] = blob
+					stripFlags[idx] = picaStripFlags(id)
+					errs[idx] = nil
+				}
 			}
 		}(s)
 	}
 	wg.Wait()
 
+	magic := picaMagic
 	for i, err := range errs {
 		if err != nil {
 			return nil, fmt.Errorf("pica: strip %d: %w", i, err)
 		}
+		if stripFlags[i]>>picaPredictorShift != 0 {
+			magic = piapMagic
+		}
 	}
 
 	// Build output: header + offset table + blobs.
@@ -119,7 +136,7 @@ func CompressParallelStripsAdaptive(pixels []uint16, width, height int, maxValue
 	}
 
 	out := make([]byte, headerSize+totalData)
-	copy(out[0:4], picaMagic)
+	copy(out[0:4], magic)
 	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
 	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
 	binary.LittleEndian.PutUint32(out[12:16], uint32(actual))
@@ -140,81 +157,163 @@ func CompressParallelStripsAdaptive(pixels []uint16, width, height int, maxValue
 // DecompressParallelStripsAdaptive recovers an image from a PICA blob.
 // All strips are decompressed concurrently using the pipeline recorded per strip.
 func DecompressParallelStripsAdaptive(compressed []byte) (pixels []uint16, width, height int, err error) {
-	if len(compressed) < picaHdrSize || string(compressed[0:4]) != picaMagic {
-		return nil, 0, 0, fmt.Errorf("pica: invalid magic")
+	var d Decoder
+	return d.DecompressParallelStripsAdaptive(compressed)
+}
+
+// DecompressParallelStripsAdaptive is DecompressParallelStripsAdaptive under
+// d's limits.
+func (d *Decoder) DecompressParallelStripsAdaptive(compressed []byte) (pixels []uint16, width, height int, err error) {
+	p, err := readPICAHeader(compressed)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := d.checkImage(p.width, p.height, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("pica: %w", err)
 	}
+	pixels, err = d.picaRegion(compressed, p, 0, 0, p.width, p.height)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, p.width, p.height, nil
+}
 
-	width = int(binary.LittleEndian.Uint32(compressed[4:8]))
-	height = int(binary.LittleEndian.Uint32(compressed[8:12]))
-	numStrips := int(binary.LittleEndian.Uint32(compressed[12:16]))
+// DecompressParallelStripsAdaptiveRegion is DecompressParallelStripsRegion
+// for PICA blobs.
+func DecompressParallelStripsAdaptiveRegion(compressed []byte, x, y, w, h int) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.DecompressParallelStripsAdaptiveRegion(compressed, x, y, w, h)
+}
+
+// DecompressParallelStripsAdaptiveRegion is
+// DecompressParallelStripsAdaptiveRegion under d's limits; the clipped
+// region counts towards MaxPixels and MaxOutputBytes.
+func (d *Decoder) DecompressParallelStripsAdaptiveRegion(compressed []byte, x, y, w, h int) (pixels []uint16, width, height int, err error) {
+	p, err := readPICAHeader(compressed)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	w, h, err = clipRegion(x, y, w, h, p.width, p.height)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("pica: %w", err)
+	}
+	if err := d.checkImage(w, h, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("pica: %w", err)
+	}
+	pixels, err = d.picaRegion(compressed, p, x, y, w, h)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, w, h, nil
+}
+
+// picaLayout is the parsed header and strip table of a PICA blob.
+type picaLayout struct {
+	width, height int
+	strips        []picaStrip
+	dataOffset    int
+}
+
+type picaStrip struct {
+	y0, offset, length int
+	flags              uint32
+}
+
+// picaStripFlags returns the strip flags recording predictor id. Avg and
+// grad keep their PICA encoding.
+func picaStripFlags(id PredictorID) uint32 {
+	switch id {
+	case PredictorAvg:
+		return 0
+	case PredictorGrad:
+		return picaFlagGradPredictor
+	}
+	return uint32(id) << picaPredictorShift
+}
+
+// predictor returns the predictor recorded in the strip's flags.
+func (e picaStrip) predictor() (PredictorID, error) {
+	if e.flags&^picaFlagsKnown != 0 {
+		return 0, fmt.Errorf("unsupported flags 0x%x", e.flags)
+	}
+	id := PredictorID(e.flags >> picaPredictorShift)
+	if e.flags&picaFlagGradPredictor != 0 {
+		if id != PredictorAvg && id != PredictorGrad {
+			return 0, fmt.Errorf("conflicting predictor flags 0x%x", e.flags)
+		}
+		id = PredictorGrad
+	}
+	return id, nil
+}
 
-	headerSize := picaHdrSize + numStrips*picaEntrySize
-	if len(compressed) < headerSize {
-		return nil, 0, 0, fmt.Errorf("pica: truncated header")
+// readPICAHeader parses the header and strip table of a PICA or PIAP blob.
+func readPICAHeader(compressed []byte) (*picaLayout, error) {
+	if len(compressed) < picaHdrSize {
+		return nil, fmt.Errorf("pica: invalid magic")
 	}
-	if width <= 0 || height <= 0 || numStrips <= 0 {
-		return nil, 0, 0, fmt.Errorf("pica: invalid dimensions")
+	magic := string(compressed[0:4])
+	if magic != picaMagic && magic != piapMagic {
+		return nil, fmt.Errorf("pica: invalid magic")
+	}
+
+	p := &picaLayout{
+		width:  int(binary.LittleEndian.Uint32(compressed[4:8])),
+		height: int(binary.LittleEndian.Uint32(compressed[8:12])),
 	}
+	numStrips := int(binary.LittleEndian.Uint32(compressed[12:16]))
 
-	type entry struct {
-		y0, offset, length int
-		flags              uint32
+	if uint64(numStrips)*picaEntrySize > uint64(len(compressed)-picaHdrSize) {
+		return nil, fmt.Errorf("pica: truncated header")
+	}
+	if p.width <= 0 || p.height <= 0 || numStrips <= 0 {
+		return nil, fmt.Errorf("pica: invalid dimensions")
 	}
-	entries := make([]entry, numStrips)
-	for i := 0; i < numStrips; i++ {
+	p.dataOffset = picaHdrSize + numStrips*picaEntrySize
+
+	p.strips = make([]picaStrip, numStrips)
+	for i := range p.strips {
 		off := picaHdrSize + i*picaEntrySize
-		entries[i] = entry{
+		p.strips[i] = picaStrip{
 			y0:     int(binary.LittleEndian.Uint32(compressed[off : off+4])),
 			offset: int(binary.LittleEndian.Uint32(compressed[off+4 : off+8])),
 			length: int(binary.LittleEndian.Uint32(compressed[off+8 : off+12])),
 			flags:  binary.LittleEndian.Uint32(compressed[off+12 : off+16]),
 		}
+		if magic == picaMagic && p.strips[i].flags>>picaPredictorShift != 0 {
+			return nil, fmt.Errorf("pica: strip %d: predictor id in a PICA blob", i)
+		}
+		// Strips must start at row 0 and tile the image top to bottom.
+		if (i == 0 && p.strips[i].y0 != 0) || (i > 0 && p.strips[i].y0 <= p.strips[i-1].y0) || p.strips[i].y0 >= p.height {
+			return nil, fmt.Errorf("pica: strip %d has invalid start row %d", i, p.strips[i].y0)
+		}
 	}
+	return p, nil
+}
 
-	out := make([]uint16, width*height)
-	decErrs := make([]error, numStrips)
-
-	var wg sync.WaitGroup
-	for s := 0; s < numStrips; s++ {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-			e := entries[idx]
-			y1 := height
-			if idx+1 < numStrips {
-				y1 = entries[idx+1].y0
-			}
-			sh := y1 - e.y0
-
-			start := headerSize + e.offset
-			end := start + e.length
-			if start < 0 || end > len(compressed) || start > end {
-				decErrs[idx] = fmt.Errorf("strip %d: offset out of bounds", idx)
-				return
-			}
-
-			var stripPixels []uint16
-			var decErr error
-			if e.flags&picaFlagGradPredictor != 0 {
-				stripPixels, decErr = DecompressSingleFrameGrad(compressed[start:end], width, sh)
-			} else {
-				stripPixels, decErr = DecompressSingleFrame(compressed[start:end], width, sh)
-			}
-			if decErr != nil {
-				decErrs[idx] = fmt.Errorf("strip %d: %w", idx, decErr)
-				return
-			}
-			copy(out[e.y0*width:], stripPixels)
-		}(s)
+// picaRegion decodes a clipped region of the PICA blob laid out as p.
+func (d *Decoder) picaRegion(compressed []byte, p *picaLayout, x, y, w, h int) ([]uint16, error) {
+	starts := make([]int, len(p.strips))
+	for i, e := range p.strips {
+		starts[i] = e.y0
 	}
-	wg.Wait()
+	return decodeStripRegion(starts, p.width, p.height, x, y, w, h, func(idx, rows int) ([]uint16, error) {
+		e := p.strips[idx]
+		start := p.dataOffset + e.offset
+		end := start + e.length
+		if start < 0 || end > len(compressed) || start > end {
+			return nil, fmt.Errorf("strip %d: offset out of bounds", idx)
+		}
 
-	for _, e := range decErrs {
-		if e != nil {
-			return nil, 0, 0, e
+		id, err := e.predictor()
+		if err != nil {
+			return nil, fmt.Errorf("strip %d: %w", idx, err)
 		}
-	}
-	return out, width, height, nil
+		stripPixels, err := d.DecompressSingleFramePredictor(compressed[start:end], p.width, rows, id)
+		if err != nil {
+			return nil, fmt.Errorf("strip %d: %w", idx, err)
+		}
+		return stripPixels, nil
+	})
 }
 
 // adaptiveStripBoundaries computes content-adaptive strip start rows using
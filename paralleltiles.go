@@ -0,0 +1,335 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// PICT — Parallel Image Compressed Tiles
+//
+// PICT is the 2D counterpart of PICS: the image is divided into a grid of
+// tileW x tileH tiles, each compressed independently with CompressSingleFrame.
+// Tiles in the last column and row are clipped to the image, not padded.
+// A grid balances load on images that are tall and narrow (NM1, 256x1024) or
+// very wide, where a handful of strips leaves cores idle, and lets a region
+// decode skip columns as well as rows.
+//
+// Binary format:
+//
+//	Bytes  0-3:  Magic "PICT"
+//	Bytes  4-7:  Width        (uint32 LE)
+//	Bytes  8-11: Height       (uint32 LE)
+//	Bytes 12-15: TileWidth    (uint32 LE)
+//	Bytes 16-19: TileHeight   (uint32 LE)
+//	Bytes 20+:   Tile table (TilesX*TilesY × [offset_u32, length_u32, flags_u32]), raster order
+//	After table: Concatenated compressed tile blobs
+//
+// TilesX and TilesY are not stored; they follow from the image and tile
+// sizes. As with MIC3, an entry's offset is relative to the end of the table.
+// A tile blob is a CompressSingleFrame stream, or, when flags bit 0 is set,
+// the tile's Delta+RLE symbols as uint16 LE. Small tiles of uniform
+// background often leave FSE too few distinct symbols to code, so the raw
+// form is as common here as it is rare for MICS groups.
+//
+// Compression ratio impact
+//
+// Every tile restarts prediction on its top row and left column and carries
+// its own FSE table. Against PICS with the same number of units, a grid
+// trades boundary rows for boundary columns; TestPICTComparisonTable puts
+// the difference between about -4% and +3% of output size on the test
+// images, with 2x2 grids typically 0.5–2% larger than 4 strips.
+
+const (
+	pictMagic      = "PICT"
+	pictHeaderBase = 20 // 4+4+4+4+4 bytes before offset table
+	pictEntrySize  = 12 // offset + length + flags
+
+	pictFlagRawSymbols = 0x01 // blob holds Delta+RLE symbols, uint16 LE
+
+	// pictMaxSamplesPerByte bounds the samples a tile blob can hold, so a
+	// header cannot make the decoder allocate an image its data could never
+	// fill. An RLE run covers at most 32766 samples in a count and a value
+	// symbol, which FSE codes in no less than two bits together; the bound
+	// allows twice that density.
+	pictMaxSamplesPerByte = 1 << 18
+
+	// DefaultPICTTileSize is the tile edge used when CompressParallelTiles
+	// is given a non-positive tile size.
+	DefaultPICTTileSize = 512
+)
+
+// pictLayout is the parsed header and tile table of a PICT blob.
+type pictLayout struct {
+	width, height  int
+	tileW, tileH   int
+	tilesX, tilesY int
+	tiles          []pictTile
+	dataOffset     int
+}
+
+type pictTile struct {
+	offset, length int
+	flags          uint32
+}
+
+// CompressParallelTiles compresses pixels as a grid of tileW x tileH tiles,
+// compressing up to GOMAXPROCS tiles concurrently. A non-positive tile size
+// selects DefaultPICTTileSize; sizes larger than the image are clamped to it.
+// The resulting PICT blob can be decoded with DecompressParallelTiles.
+func CompressParallelTiles(pixels []uint16, width, height int, maxValue uint16, tileW, tileH int) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("paralleltiles: invalid dimensions %dx%d", width, height)
+	}
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("paralleltiles: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if tileW <= 0 {
+		tileW = DefaultPICTTileSize
+	}
+	if tileH <= 0 {
+		tileH = DefaultPICTTileSize
+	}
+	tileW, tileH = min(tileW, width), min(tileH, height)
+	tilesX := (width + tileW - 1) / tileW
+	tilesY := (height + tileH - 1) / tileH
+
+	results := make([][]byte, tilesX*tilesY)
+	flags := make([]uint32, len(results))
+	errs := make([]error, len(results))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for t := range results {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer func() { <-sem; wg.Done() }()
+			x0, y0 := (idx%tilesX)*tileW, (idx/tilesX)*tileH
+			tw, th := min(tileW, width-x0), min(tileH, height-y0)
+			tile := make([]uint16, tw*th)
+			for r := 0; r < th; r++ {
+				src := (y0+r)*width + x0
+				copy(tile[r*tw:(r+1)*tw], pixels[src:src+tw])
+			}
+			blob, err := CompressSingleFrame(tile, tw, th, maxValue)
+			if errors.Is(err, ErrUseRLE) || errors.Is(err, ErrIncompressible) {
+				var drc DeltaRleCompressU16
+				symbols, _ := drc.Compress(tile, tw, th, maxValue)
+				blob, err = make([]byte, 0, 2*len(symbols)), nil
+				for _, v := range symbols {
+					blob = binary.LittleEndian.AppendUint16(blob, v)
+				}
+				flags[idx] = pictFlagRawSymbols
+			}
+			results[idx], errs[idx] = blob, err
+		}(t)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("paralleltiles: tile %d: %w", i, err)
+		}
+	}
+
+	return buildPICT(results, flags, width, height, tileW, tileH), nil
+}
+
+// DecompressParallelTiles recovers an image from a PICT blob produced by
+// CompressParallelTiles. Tiles are decompressed concurrently. Returns pixels
+// (row-major, uint16), width, height.
+func DecompressParallelTiles(compressed []byte) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.DecompressParallelTiles(compressed)
+}
+
+// DecompressParallelTiles is DecompressParallelTiles under d's limits.
+func (d *Decoder) DecompressParallelTiles(compressed []byte) (pixels []uint16, width, height int, err error) {
+	p, err := d.readPICTHeader(compressed)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := d.checkImage(p.width, p.height, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("paralleltiles: %w", err)
+	}
+	pixels, err = d.pictRegion(compressed, p, 0, 0, p.width, p.height)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, p.width, p.height, nil
+}
+
+// DecompressParallelTilesRegion decodes the w x h rectangle at (x, y) of a
+// PICT blob, clipped to the image, and returns its pixels (row-major) and
+// clipped size. Only the tiles that overlap the rectangle are decoded.
+func DecompressParallelTilesRegion(compressed []byte, x, y, w, h int) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.DecompressParallelTilesRegion(compressed, x, y, w, h)
+}
+
+// DecompressParallelTilesRegion is DecompressParallelTilesRegion under d's
+// limits; the clipped region counts towards MaxPixels and MaxOutputBytes.
+func (d *Decoder) DecompressParallelTilesRegion(compressed []byte, x, y, w, h int) (pixels []uint16, width, height int, err error) {
+	p, err := d.readPICTHeader(compressed)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	w, h, err = clipRegion(x, y, w, h, p.width, p.height)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("paralleltiles: %w", err)
+	}
+	if err := d.checkImage(w, h, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("paralleltiles: %w", err)
+	}
+	pixels, err = d.pictRegion(compressed, p, x, y, w, h)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, w, h, nil
+}
+
+// pictRegion decodes, concurrently, the tiles that overlap the clipped
+// region at (x, y) and copies their overlap into a w x h result. The lowest
+// failing tile's error is returned.
+func (d *Decoder) pictRegion(compressed []byte, p *pictLayout, x, y, w, h int) ([]uint16, error) {
+	tx0, tx1 := x/p.tileW, (x+w-1)/p.tileW
+	ty0, ty1 := y/p.tileH, (y+h-1)/p.tileH
+	cols := tx1 - tx0 + 1
+
+	out := make([]uint16, w*h)
+	errs := make([]error, cols*(ty1-ty0+1))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i := range errs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			tx, ty := tx0+i%cols, ty0+i/cols
+			idx := ty*p.tilesX + tx
+			x0, y0 := tx*p.tileW, ty*p.tileH
+			tw, th := min(p.tileW, p.width-x0), min(p.tileH, p.height-y0)
+
+			blob := p.tileBlob(compressed, idx)
+			mode := byte(micsGroupFSE)
+			if p.tiles[idx].flags&pictFlagRawSymbols != 0 {
+				mode = micsGroupRaw
+			}
+			tile := make([]uint16, tw*th)
+			if err := d.decompressGroup(tile, mode, blob, tw, th); err != nil {
+				errs[i] = fmt.Errorf("tile %d: %w", idx, err)
+				return
+			}
+
+			cx0, cx1 := max(x0, x), min(x0+tw, x+w)
+			for r := max(y0, y); r < min(y0+th, y+h); r++ {
+				src := (r-y0)*tw + cx0 - x0
+				dst := (r-y)*w + cx0 - x
+				copy(out[dst:dst+cx1-cx0], tile[src:src+cx1-cx0])
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, e := range errs {
+		if e != nil {
+			return nil, e
+		}
+	}
+	return out, nil
+}
+
+// readPICTHeader parses the header and tile table of a PICT blob, checking
+// the tile count against d's MaxTiles.
+func (d *Decoder) readPICTHeader(compressed []byte) (*pictLayout, error) {
+	if len(compressed) < pictHeaderBase || string(compressed[0:4]) != pictMagic {
+		return nil, fmt.Errorf("paralleltiles: invalid magic")
+	}
+
+	p := &pictLayout{
+		width:  int(binary.LittleEndian.Uint32(compressed[4:8])),
+		height: int(binary.LittleEndian.Uint32(compressed[8:12])),
+		tileW:  int(binary.LittleEndian.Uint32(compressed[12:16])),
+		tileH:  int(binary.LittleEndian.Uint32(compressed[16:20])),
+	}
+	if p.width <= 0 || p.height <= 0 || p.tileW <= 0 || p.tileH <= 0 {
+		return nil, fmt.Errorf("paralleltiles: invalid dimensions")
+	}
+	p.tilesX = (p.width + p.tileW - 1) / p.tileW
+	p.tilesY = (p.height + p.tileH - 1) / p.tileH
+
+	numTiles := uint64(p.tilesX) * uint64(p.tilesY)
+	if numTiles > uint64(len(compressed)-pictHeaderBase)/pictEntrySize {
+		return nil, fmt.Errorf("paralleltiles: truncated header")
+	}
+	if err := d.checkTiles(int(numTiles)); err != nil {
+		return nil, fmt.Errorf("paralleltiles: %w", err)
+	}
+	p.dataOffset = pictHeaderBase + int(numTiles)*pictEntrySize
+
+	// Every tile must lie within the data and hold enough bytes for its
+	// samples before the decoder allocates anything for them.
+	p.tiles = make([]pictTile, numTiles)
+	for i := range p.tiles {
+		tblOff := pictHeaderBase + i*pictEntrySize
+		e := pictTile{
+			offset: int(binary.LittleEndian.Uint32(compressed[tblOff : tblOff+4])),
+			length: int(binary.LittleEndian.Uint32(compressed[tblOff+4 : tblOff+8])),
+			flags:  binary.LittleEndian.Uint32(compressed[tblOff+8 : tblOff+12]),
+		}
+		if e.offset > len(compressed)-p.dataOffset || e.length > len(compressed)-p.dataOffset-e.offset {
+			return nil, fmt.Errorf("paralleltiles: tile %d: offset out of bounds", i)
+		}
+		x0, y0 := (i%p.tilesX)*p.tileW, (i/p.tilesX)*p.tileH
+		tw, th := min(p.tileW, p.width-x0), min(p.tileH, p.height-y0)
+		if uint64(tw)*uint64(th) > uint64(e.length)*pictMaxSamplesPerByte {
+			return nil, fmt.Errorf("paralleltiles: tile %d: %d bytes cannot hold %dx%d samples", i, e.length, tw, th)
+		}
+		p.tiles[i] = e
+	}
+	return p, nil
+}
+
+// tileBlob returns the compressed bytes of tile idx, whose bounds
+// readPICTHeader has checked.
+func (p *pictLayout) tileBlob(compressed []byte, idx int) []byte {
+	e := p.tiles[idx]
+	start := p.dataOffset + e.offset
+	return compressed[start : start+e.length]
+}
+
+// buildPICT assembles tile blobs and their flags, in raster order, into a
+// PICT container.
+func buildPICT(results [][]byte, flags []uint32, width, height, tileW, tileH int) []byte {
+	headerSize := pictHeaderBase + len(results)*pictEntrySize
+	totalData := 0
+	for _, r := range results {
+		totalData += len(r)
+	}
+
+	out := make([]byte, headerSize+totalData)
+	copy(out[0:4], pictMagic)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
+	binary.LittleEndian.PutUint32(out[12:16], uint32(tileW))
+	binary.LittleEndian.PutUint32(out[16:20], uint32(tileH))
+
+	offset := 0
+	for t, r := range results {
+		tblOff := pictHeaderBase + t*pictEntrySize
+		binary.LittleEndian.PutUint32(out[tblOff:tblOff+4], uint32(offset))
+		binary.LittleEndian.PutUint32(out[tblOff+4:tblOff+8], uint32(len(r)))
+		binary.LittleEndian.PutUint32(out[tblOff+8:tblOff+12], flags[t])
+		copy(out[headerSize+offset:], r)
+		offset += len(r)
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestParallelTilesRoundtrip verifies pixel-exact PICT roundtrip for square,
+// strip-shaped and column-shaped grids, including clipped edge tiles.
+func TestParallelTilesRoundtrip(t *testing.T) {
+	grids := []struct{ tileW, tileH int }{
+		{0, 0},     // default size, clamped to the image
+		{128, 128}, // 2x2 on MR
+		{100, 70},  // clipped last column and row
+		{64, 512},  // column tiles
+		{512, 64},  // strip-shaped tiles
+	}
+
+	for _, td := range testFiles[:2] { // MR 256x256, CT 512x512
+		_, pixels, maxVal, width, height := SetupTests(td)
+
+		for _, g := range grids {
+			t.Run(fmt.Sprintf("%s_%dx%d", td.name, g.tileW, g.tileH), func(t *testing.T) {
+				blob, err := CompressParallelTiles(pixels, width, height, maxVal, g.tileW, g.tileH)
+				if err != nil {
+					t.Fatalf("compress: %v", err)
+				}
+				got, w, h, err := DecompressParallelTiles(blob)
+				if err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+				if w != width || h != height {
+					t.Fatalf("dimension mismatch: got %dx%d, want %dx%d", w, h, width, height)
+				}
+				assertPixelsEqual(t, pixels, got, td.name)
+			})
+		}
+	}
+}
+
+// TestParallelTilesRegion checks that region decodes match the same crop of
+// the full image, for rectangles inside one tile, across tile corners and
+// clipped at the image edge.
+func TestParallelTilesRegion(t *testing.T) {
+	td := testFiles[0] // MR 256x256
+	_, pixels, maxVal, width, height := SetupTests(td)
+
+	blob, err := CompressParallelTiles(pixels, width, height, maxVal, 100, 70)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []struct{ x, y, w, h int }{
+		{0, 0, width, height},
+		{10, 10, 20, 20},    // inside tile 0
+		{90, 60, 30, 30},    // across a tile corner
+		{150, 200, 40, 300}, // clipped at the bottom
+		{255, 255, 10, 10},  // last pixel
+	} {
+		got, w, h, err := DecompressParallelTilesRegion(blob, r.x, r.y, r.w, r.h)
+		if err != nil {
+			t.Fatalf("%+v: %v", r, err)
+		}
+		wantW, wantH := min(r.w, width-r.x), min(r.h, height-r.y)
+		if w != wantW || h != wantH {
+			t.Fatalf("%+v: dims %dx%d, want %dx%d", r, w, h, wantW, wantH)
+		}
+		for row := 0; row < h; row++ {
+			for col := 0; col < w; col++ {
+				want := pixels[(r.y+row)*width+r.x+col]
+				if g := got[row*w+col]; g != want {
+					t.Fatalf("%+v: pixel (%d, %d): got %d want %d", r, col, row, g, want)
+				}
+			}
+		}
+	}
+
+	if _, _, _, err := DecompressParallelTilesRegion(blob, -1, 0, 5, 5); err == nil {
+		t.Fatal("expected error for origin outside image")
+	}
+}
+
+// TestParallelTilesFormatValidation checks header parsing and error paths.
+func TestParallelTilesFormatValidation(t *testing.T) {
+	td := testFiles[0] // MR 256x256
+	_, pixels, maxVal, width, height := SetupTests(td)
+
+	blob, err := CompressParallelTiles(pixels, width, height, maxVal, 128, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := append([]byte(nil), blob...)
+	bad[0] = 'X'
+	if _, _, _, err := DecompressParallelTiles(bad); err == nil {
+		t.Fatal("expected error on bad magic, got nil")
+	}
+	if _, _, _, err := DecompressParallelTiles(blob[:30]); err == nil {
+		t.Fatal("expected error on truncated table, got nil")
+	}
+	if _, _, _, err := DecompressParallelTiles(blob[:len(blob)-10]); err == nil {
+		t.Fatal("expected error on truncated data, got nil")
+	}
+
+	img, err := Decode(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatPICT || img.Width != width || img.Height != height {
+		t.Fatalf("unexpected metadata: %+v", img)
+	}
+	assertPixelsEqual(t, pixels, img.Pixels, "Decode")
+
+	// A header whose one tile claims far more samples than its bytes can
+	// hold fails before anything is allocated.
+	huge := append([]byte(nil), blob[:pictHeaderBase]...)
+	binary.LittleEndian.PutUint32(huge[4:], 30000)
+	binary.LittleEndian.PutUint32(huge[8:], 25000)
+	binary.LittleEndian.PutUint32(huge[12:], 30000)
+	binary.LittleEndian.PutUint32(huge[16:], 25000)
+	huge = binary.LittleEndian.AppendUint32(huge, 0)
+	huge = binary.LittleEndian.AppendUint32(huge, 64)
+	huge = binary.LittleEndian.AppendUint32(huge, 0)
+	huge = append(huge, make([]byte, 64)...)
+	if _, _, _, err := DecompressParallelTiles(huge); err == nil {
+		t.Fatal("expected error for a tile too short for its samples, got nil")
+	}
+	constant := make([]uint16, 1024*1024)
+	packed, err := CompressParallelTiles(constant, 1024, 1024, 1, 1024, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _, err := DecompressParallelTiles(packed); err != nil {
+		t.Fatalf("constant image in %d bytes: %v", len(packed), err)
+	} else {
+		assertPixelsEqual(t, constant, got, "constant")
+	}
+
+	d := Decoder{Limits: DecodeLimits{MaxTiles: 3}}
+	if _, _, _, err := d.DecompressParallelTiles(blob); err == nil {
+		t.Fatal("expected tile limit error, got nil")
+	}
+}
+
+// TestPICTComparisonTable prints the compression ratio of PICT tile grids
+// against PICS with the same number of units, to show what skipping columns
+// costs over strips alone.
+func TestPICTComparisonTable(t *testing.T) {
+	grids := []struct{ nx, ny int }{{1, 1}, {2, 1}, {2, 2}, {4, 2}, {4, 4}}
+
+	fmt.Println()
+	fmt.Println("=== Compression ratio: PICS-N vs PICT grid with N tiles ===")
+	fmt.Println()
+	hdr := "Image "
+	sep := "------"
+	for _, g := range grids {
+		hdr += fmt.Sprintf("  %7s %7s %7s", fmt.Sprintf("PICS-%d", g.nx*g.ny), fmt.Sprintf("PICT%dx%d", g.nx, g.ny), "cost")
+		sep += "  ------- ------- -------"
+	}
+	fmt.Println(hdr)
+	fmt.Println(sep)
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, shortData, maxShort, cols, rows := SetupTests(td)
+		if len(shortData) == 0 {
+			t.Logf("skip %s: load failed", td.name)
+			continue
+		}
+		origBytes := float64(cols * rows * 2)
+
+		line := fmt.Sprintf("%-6s", td.name)
+		for _, g := range grids {
+			pict, err := CompressParallelTiles(shortData, cols, rows, maxShort,
+				(cols+g.nx-1)/g.nx, (rows+g.ny-1)/g.ny)
+			if err != nil {
+				t.Fatalf("%s: PICT %dx%d: %v", td.name, g.nx, g.ny, err)
+			}
+			pics, err := CompressParallelStrips(shortData, cols, rows, maxShort, g.nx*g.ny)
+			if err != nil {
+				// PICS has no fallback for strips FSE rejects.
+				t.Logf("%s: PICS-%d: %v", td.name, g.nx*g.ny, err)
+				line += fmt.Sprintf("  %7s %6.2fx %7s", "n/a", origBytes/float64(len(pict)), "")
+				continue
+			}
+			cost := float64(len(pict)-len(pics)) / float64(len(pics)) * 100
+			line += fmt.Sprintf("  %6.2fx %6.2fx %+6.2f%%",
+				origBytes/float64(len(pics)), origBytes/float64(len(pict)), cost)
+		}
+		fmt.Println(line)
+	}
+	fmt.Println(sep)
+	fmt.Println()
+}
@@ -0,0 +1,324 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// Pluggable spatial predictors.
+//
+// Every Delta+RLE pipeline predicts each pixel from its causal neighbours and
+// codes the residual. A Predictor supplies the prediction for interior
+// pixels; the shared coder handles the borders the same way for every
+// predictor: the first pixel is predicted as 0, the rest of the first row
+// from the left neighbour and the rest of the first column from the top.
+//
+// Predictors are identified in streams by a PredictorID. The built-in IDs
+// below are always registered; RegisterPredictor adds custom ones from
+// PredictorCustom upwards, which a decoder must register under the same ID
+// before reading such a stream. Compress, CompressParallelStripsPredictor,
+// CompressParallelStripsAdaptive, CompressMultiFramePredictor and CompressWSI
+// (WSIOptions.Predictor) record the ID they used.
+
+// PredictorID identifies a registered Predictor in a stream.
+type PredictorID uint8
+
+const (
+	PredictorAvg   PredictorID = iota // (left+top)/2, as CompressSingleFrame
+	PredictorGrad                     // gradient-adaptive, as CompressSingleFrameGrad
+	PredictorMED                      // JPEG-LS median edge detector (MEDDeltaCompressU16)
+	PredictorPaeth                    // PNG Paeth (PaethDeltaCompressU16)
+	PredictorLeft                     // left neighbour
+	PredictorUp                       // top neighbour
+
+	// PredictorCustom is the first ID available to RegisterPredictor.
+	// Lower IDs are reserved for predictors built into this package.
+	PredictorCustom PredictorID = 128
+)
+
+// Predictor predicts an interior pixel (x > 0, y > 0) from its reconstructed
+// neighbours: w (left), n (top), nw (top-left) and ne (top-right, equal to
+// nw in the last column). Predictions outside the sample range are
+// allowed; the residual coder escapes large errors.
+//
+// Predict must be deterministic and safe for concurrent use, since strips
+// and tiles are coded in parallel.
+type Predictor interface {
+	Predict(w, n, nw, ne int32) int32
+}
+
+// PredictorFunc adapts an ordinary function to the Predictor interface.
+type PredictorFunc func(w, n, nw, ne int32) int32
+
+// Predict returns f(w, n, nw, ne).
+func (f PredictorFunc) Predict(w, n, nw, ne int32) int32 {
+	return f(w, n, nw, ne)
+}
+
+type registeredPredictor struct {
+	name string
+	p    Predictor
+}
+
+var (
+	predictorsMu sync.RWMutex
+	predictors   = map[PredictorID]registeredPredictor{
+		PredictorAvg:   {"avg", PredictorFunc(func(w, n, _, _ int32) int32 { return (w + n) >> 1 })},
+		PredictorGrad:  {"grad", PredictorFunc(func(w, n, nw, ne int32) int32 { return gradPredict(w, n, nw, ne) })},
+		PredictorMED:   {"med", PredictorFunc(func(w, n, nw, _ int32) int32 { return medPredict(w, n, nw) })},
+		PredictorPaeth: {"paeth", PredictorFunc(func(w, n, nw, _ int32) int32 { return paethPredict(w, n, nw) })},
+		PredictorLeft:  {"left", PredictorFunc(func(w, _, _, _ int32) int32 { return w })},
+		PredictorUp:    {"up", PredictorFunc(func(_, n, _, _ int32) int32 { return n })},
+	}
+)
+
+// RegisterPredictor makes p available under id to every encoder and decoder
+// in the process. id must be at least PredictorCustom and not yet taken.
+func RegisterPredictor(id PredictorID, name string, p Predictor) error {
+	if p == nil {
+		return errors.New("register predictor: nil predictor")
+	}
+	if id < PredictorCustom {
+		return fmt.Errorf("register predictor: id %d is reserved (custom ids start at %d)", id, PredictorCustom)
+	}
+	predictorsMu.Lock()
+	defer predictorsMu.Unlock()
+	if r, ok := predictors[id]; ok {
+		return fmt.Errorf("register predictor: id %d already registered as %q", id, r.name)
+	}
+	predictors[id] = registeredPredictor{name, p}
+	return nil
+}
+
+// LookupPredictor returns the Predictor registered under id.
+func LookupPredictor(id PredictorID) (Predictor, bool) {
+	predictorsMu.RLock()
+	defer predictorsMu.RUnlock()
+	r, ok := predictors[id]
+	return r.p, ok
+}
+
+// Predictors returns the registered IDs in increasing order.
+func Predictors() []PredictorID {
+	predictorsMu.RLock()
+	ids := make([]PredictorID, 0, len(predictors))
+	for id := range predictors {
+		ids = append(ids, id)
+	}
+	predictorsMu.RUnlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// String returns the name id was registered with.
+func (id PredictorID) String() string {
+	predictorsMu.RLock()
+	defer predictorsMu.RUnlock()
+	if r, ok := predictors[id]; ok {
+		return r.name
+	}
+	return fmt.Sprintf("predictor(%d)", id)
+}
+
+// PredictorDeltaCompressU16 applies p to 16-bit pixel data and returns the
+// residual stream in the layout of MEDDeltaCompressU16: the max value, then
+// one biased residual per pixel, or the overflow delimiter followed by the
+// raw pixel when the residual does not fit.
+func PredictorDeltaCompressU16(p Predictor, in []uint16, width, height int, maxValue uint16) ([]uint16, error) {
+	pixelDepth := bits.Len16(maxValue)
+	if pixelDepth == 0 {
+		return nil, errors.New("predictor delta: max value must be positive")
+	}
+	deltaThreshold := uint16((1 << (pixelDepth - 1)) - 1)
+	delimiterForOverflow := uint16((1 << pixelDepth) - 1)
+	out := make([]uint16, 0, width*height*2)
+	out = append(out, maxValue)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := y*width + x
+			predicted := predictAt(p, in, index, x, y, width)
+
+			inputVal := in[index]
+			diff := int32(inputVal) - predicted
+
+			if uint16(abs(diff)) >= deltaThreshold {
+				out = append(out, delimiterForOverflow)
+				out = append(out, inputVal)
+			} else {
+				out = append(out, uint16(int32(deltaThreshold)+diff))
+			}
+		}
+	}
+	return out, nil
+}
+
+// PredictorDeltaDecompressU16 inverts PredictorDeltaCompressU16. The stream
+// must hold exactly width*height residuals (see checkDeltaStream).
+func PredictorDeltaDecompressU16(p Predictor, in []uint16, width, height int) []uint16 {
+	maxValue := in[0]
+	out := make([]uint16, width*height)
+	pixelDepth := bits.Len16(maxValue)
+	deltaThreshold := uint16((1 << (pixelDepth - 1)) - 1)
+	delimiterForOverflow := uint16((1 << pixelDepth) - 1)
+	ic := 1
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := y*width + x
+			inputVal := in[ic]
+			ic++
+			if inputVal == delimiterForOverflow {
+				out[index] = in[ic]
+				ic++
+				continue
+			}
+			diff := int32(inputVal) - int32(deltaThreshold)
+			out[index] = uint16(predictAt(p, out, index, x, y, width) + diff)
+		}
+	}
+	return out
+}
+
+// predictAt returns p's prediction for pixel (x, y) at index of img,
+// applying the shared border rules.
+func predictAt(p Predictor, img []uint16, index, x, y, width int) int32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0
+	case y == 0:
+		return int32(img[index-1])
+	case x == 0:
+		return int32(img[index-width])
+	}
+	nw := int32(img[index-width-1])
+	ne := nw
+	if x+1 < width {
+		ne = int32(img[index-width+1])
+	}
+	return p.Predict(int32(img[index-1]), int32(img[index-width]), nw, ne)
+}
+
+// predictorSymbols runs the Delta+RLE stage of predictor id over pixels.
+// The built-in predictors use their dedicated coders, which produce the
+// streams CompressSingleFrame and CompressSingleFrameGrad always have.
+func predictorSymbols(id PredictorID, pixels []uint16, width, height int, maxValue uint16) ([]uint16, error) {
+	switch id {
+	case PredictorAvg:
+		var drc DeltaRleCompressU16
+		return drc.Compress(pixels, width, height, maxValue)
+	case PredictorGrad:
+		var drc GradDeltaRleCompressU16
+		return drc.Compress(pixels, width, height, maxValue)
+	case PredictorMED:
+		return deltaRLE(MEDDeltaCompressU16(pixels, width, height, maxValue))
+	case PredictorPaeth:
+		return deltaRLE(PaethDeltaCompressU16(pixels, width, height, maxValue))
+	}
+	p, ok := LookupPredictor(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown predictor %d", id)
+	}
+	return deltaRLE(PredictorDeltaCompressU16(p, pixels, width, height, maxValue))
+}
+
+// predictorPixels reverses predictorSymbols.
+func predictorPixels(id PredictorID, symbols []uint16, width, height int) ([]uint16, error) {
+	switch id {
+	case PredictorAvg:
+		var drd DeltaRleDecompressU16
+		if err := drd.Decompress(symbols, width, height); err != nil {
+			return nil, fmt.Errorf("delta+RLE decompress: %w", err)
+		}
+		return drd.Out, nil
+	case PredictorGrad:
+		var drd GradDeltaRleDecompressU16
+		if err := drd.Decompress(symbols, width, height); err != nil {
+			return nil, fmt.Errorf("grad-delta+RLE decompress: %w", err)
+		}
+		return drd.Out, nil
+	}
+	p, ok := LookupPredictor(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown predictor %d", id)
+	}
+
+	var rle RleDecompressU16
+	rle.Init(symbols)
+	delta, err := rle.Decompress()
+	if err != nil {
+		return nil, fmt.Errorf("RLE decompress: %w", err)
+	}
+	if err := checkDeltaStream(delta, width*height); err != nil {
+		return nil, err
+	}
+	switch id {
+	case PredictorMED:
+		return MEDDeltaDecompressU16(delta, width, height), nil
+	case PredictorPaeth:
+		return PaethDeltaDecompressU16(delta, width, height), nil
+	}
+	return PredictorDeltaDecompressU16(p, delta, width, height), nil
+}
+
+// CompressSingleFramePredictor is CompressSingleFrame with the spatial
+// predictor id. The stream does not record id; containers store it and pass
+// it to DecompressSingleFramePredictor. PredictorAvg yields exactly the
+// CompressSingleFrame stream and PredictorGrad the CompressSingleFrameGrad
+// stream.
+func CompressSingleFramePredictor(pixels []uint16, width, height int, maxValue uint16, id PredictorID) ([]byte, error) {
+	if id == PredictorAvg {
+		return CompressSingleFrame(pixels, width, height, maxValue)
+	}
+	return compressSingleFrameCoder(pixels, width, height, maxValue, id, CoderFSE2, nil)
+}
+
+// compressSingleFrameCoder is CompressSingleFramePredictor with the entropy
+// coder c, which must pass checkStreamCoder, and the optional dictionary d.
+// The decoders need not be told c: FSEDecompressU16Auto recognises the
+// stream.
+func compressSingleFrameCoder(pixels []uint16, width, height int, maxValue uint16, id PredictorID, c Coder, d *Dictionary) ([]byte, error) {
+	symbols, err := predictorSymbols(id, pixels, width, height, maxValue)
+	if err != nil {
+		return nil, fmt.Errorf("delta+RLE compress: %w", err)
+	}
+	return c.encodeDict(symbols, d)
+}
+
+// DecompressSingleFramePredictor decompresses a CompressSingleFramePredictor
+// stream written with predictor id.
+func DecompressSingleFramePredictor(compressed []byte, width, height int, id PredictorID) ([]uint16, error) {
+	var d Decoder
+	return d.DecompressSingleFramePredictor(compressed, width, height, id)
+}
+
+// DecompressSingleFramePredictor is DecompressSingleFramePredictor under d's
+// limits.
+func (d *Decoder) DecompressSingleFramePredictor(compressed []byte, width, height int, id PredictorID) ([]uint16, error) {
+	return d.decompressSingleFramePredictor(compressed, width, height, id, streamRefs{})
+}
+
+// decompressSingleFramePredictor is DecompressSingleFramePredictor with refs
+// resolving the dictionary or unit the stream may name.
+func (d *Decoder) decompressSingleFramePredictor(compressed []byte, width, height int, id PredictorID, refs streamRefs) ([]uint16, error) {
+	if id == PredictorAvg {
+		return d.decompressSingleFrame(nil, compressed, width, height, refs)
+	}
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	s := ScratchU16{DecompressLimit: symbolLimit(width * height)}
+	refs.apply(&s)
+	symbols, err := FSEDecompressU16Auto(compressed, &s)
+	if err != nil {
+		return nil, fmt.Errorf("FSE decompress: %w", err)
+	}
+	return predictorPixels(id, symbols, width, height)
+}
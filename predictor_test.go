@@ -0,0 +1,212 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// testPlanarPredictor is registered by registerTestPredictor: the unclamped
+// planar prediction w+n-nw, which regularly leaves the sample range.
+const testPlanarPredictor = PredictorCustom + 1
+
+var registerOnce sync.Once
+
+func registerTestPredictor(t *testing.T) {
+	t.Helper()
+	registerOnce.Do(func() {
+		err := RegisterPredictor(testPlanarPredictor, "planar", PredictorFunc(func(w, n, nw, _ int32) int32 {
+			return w + n - nw
+		}))
+		if err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	})
+}
+
+// TestPredictorRegistry checks the built-in registrations and the
+// RegisterPredictor error paths.
+func TestPredictorRegistry(t *testing.T) {
+	registerTestPredictor(t)
+
+	for id, name := range map[PredictorID]string{
+		PredictorAvg: "avg", PredictorGrad: "grad", PredictorMED: "med",
+		PredictorPaeth: "paeth", PredictorLeft: "left", PredictorUp: "up",
+		testPlanarPredictor: "planar",
+	} {
+		if _, ok := LookupPredictor(id); !ok {
+			t.Errorf("predictor %d not registered", id)
+		}
+		if id.String() != name {
+			t.Errorf("predictor %d: name %q, want %q", id, id.String(), name)
+		}
+	}
+	if _, ok := LookupPredictor(PredictorCustom + 100); ok {
+		t.Error("unregistered id found")
+	}
+
+	left := PredictorFunc(func(w, _, _, _ int32) int32 { return w })
+	if err := RegisterPredictor(PredictorUp+1, "mine", left); err == nil {
+		t.Error("expected error for reserved id")
+	}
+	if err := RegisterPredictor(testPlanarPredictor, "again", left); err == nil {
+		t.Error("expected error for duplicate id")
+	}
+	if err := RegisterPredictor(PredictorCustom+2, "nil", nil); err == nil {
+		t.Error("expected error for nil predictor")
+	}
+
+	ids := Predictors()
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("Predictors not sorted: %v", ids)
+		}
+	}
+}
+
+// TestPredictorRoundtrip verifies every registered predictor through the
+// single-frame stream and Compress on MR and CT.
+func TestPredictorRoundtrip(t *testing.T) {
+	registerTestPredictor(t)
+
+	for _, td := range testFiles[:2] {
+		_, pixels, maxVal, width, height := SetupTests(td)
+
+		for _, id := range Predictors() {
+			t.Run(td.name+"_"+id.String(), func(t *testing.T) {
+				blob, err := CompressSingleFramePredictor(pixels, width, height, maxVal, id)
+				if err != nil {
+					t.Fatalf("compress: %v", err)
+				}
+				got, err := DecompressSingleFramePredictor(blob, width, height, id)
+				if err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+				assertPixelsEqual(t, pixels, got, "single frame")
+
+				mic1, err := Compress(pixels, width, height, CompressOptions{Predictor: id, Strips: 3})
+				if err != nil {
+					t.Fatalf("Compress: %v", err)
+				}
+				img, err := Decode(mic1)
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				assertPixelsEqual(t, pixels, img.Pixels, "Compress")
+				t.Logf("%s %-6s %6.2fx", td.name, id, float64(len(pixels)*2)/float64(len(blob)))
+			})
+		}
+	}
+
+	// Avg and grad keep their existing streams.
+	_, pixels, maxVal, width, height := SetupTests(testFiles[0])
+	for id, compress := range map[PredictorID]func([]uint16, int, int, uint16) ([]byte, error){
+		PredictorAvg:  CompressSingleFrame,
+		PredictorGrad: CompressSingleFrameGrad,
+	} {
+		want, err := compress(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := CompressSingleFramePredictor(pixels, width, height, maxVal, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: stream differs from the dedicated compressor", id)
+		}
+	}
+}
+
+// TestPredictorContainers verifies that PICS, PICA, MIC2 and MIC3 record a
+// non-default predictor and decode it without being told.
+func TestPredictorContainers(t *testing.T) {
+	registerTestPredictor(t)
+	_, pixels, maxVal, width, height := SetupTests(testFiles[0]) // MR 256x256
+
+	for _, id := range []PredictorID{PredictorLeft, PredictorUp, testPlanarPredictor} {
+		t.Run(id.String(), func(t *testing.T) {
+			pics, err := CompressParallelStripsPredictor(pixels, width, height, maxVal, 4, id)
+			if err != nil {
+				t.Fatalf("PICS: %v", err)
+			}
+			if string(pics[0:4]) != picpMagic || PredictorID(pics[20]) != id {
+				t.Fatalf("PICS: header %q predictor %d", pics[0:4], pics[20])
+			}
+			crc, err := AddChecksums(pics)
+			if err != nil {
+				t.Fatalf("AddChecksums: %v", err)
+			}
+			if err := VerifyChecksums(crc); err != nil {
+				t.Fatalf("VerifyChecksums: %v", err)
+			}
+			for _, blob := range [][]byte{pics, crc} {
+				img, err := Decode(blob)
+				if err != nil {
+					t.Fatalf("PICS decode: %v", err)
+				}
+				assertPixelsEqual(t, pixels, img.Pixels, "PICS")
+			}
+
+			mic2, err := CompressMultiFramePredictor([][]uint16{pixels, pixels}, width, height, maxVal, false, id)
+			if err != nil {
+				t.Fatalf("MIC2: %v", err)
+			}
+			frame, hdr, err := DecompressFrame(mic2, 1)
+			if err != nil {
+				t.Fatalf("MIC2 frame: %v", err)
+			}
+			if hdr.Predictor != id {
+				t.Fatalf("MIC2: predictor %d, want %d", hdr.Predictor, id)
+			}
+			assertPixelsEqual(t, pixels, frame, "MIC2")
+
+			mic3, err := CompressWSI(uint16ToBytes(pixels, 16), width, height, 1, 16,
+				WSIOptions{TileWidth: 128, TileHeight: 128, PyramidLevels: 1, Predictor: id})
+			if err != nil {
+				t.Fatalf("MIC3: %v", err)
+			}
+			img, err := Decode(mic3)
+			if err != nil {
+				t.Fatalf("MIC3 decode: %v", err)
+			}
+			assertPixelsEqual(t, pixels, img.Pixels, "MIC3")
+		})
+	}
+
+	// PICA picks per strip among all registered predictors.
+	pica, err := CompressParallelStripsAdaptive(pixels, width, height, maxVal, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, _, err := DecompressParallelStripsAdaptive(pica)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPixelsEqual(t, pixels, got, "PICA")
+
+	// Unregistered IDs are rejected, not decoded with the wrong predictor.
+	pics, err := CompressParallelStripsPredictor(pixels, width, height, maxVal, 2, PredictorLeft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pics[20] = byte(PredictorCustom + 100)
+	if _, _, _, err := DecompressParallelStrips(pics); err == nil {
+		t.Error("PICS: expected error for unregistered predictor")
+	}
+	mic2, err := CompressMultiFramePredictor([][]uint16{pixels}, width, height, maxVal, false, PredictorLeft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mic2[17] = byte(PredictorCustom + 100)
+	if _, _, err := DecompressMultiFrame(mic2); err == nil {
+		t.Error("MIC2: expected error for unregistered predictor")
+	}
+	if _, err := Compress(pixels, width, height, CompressOptions{Predictor: PredictorCustom + 100}); err == nil {
+		t.Error("Compress: expected error for unregistered predictor")
+	}
+}
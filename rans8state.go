@@ -399,6 +399,9 @@ func (s *ScratchU16) ransDecompress8State(count int) error {
 	states := [8]*uint32{&sA, &sB, &sC, &sD, &sE, &sF, &sG, &sH}
 	lane := 0
 	for remaining > 0 {
+		if br.exhausted(8) {
+			return errStreamExhausted
+		}
 		br.fill()
 		n := dt[*states[lane]]
 		bits := br.getBits32(n.nbBits)
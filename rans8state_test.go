@@ -5,6 +5,8 @@
 package mic
 
 import (
+	"bytes"
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -146,6 +148,129 @@ func TestRANS8StateEdgeCases(t *testing.T) {
 	})
 }
 
+// TestRANSContainers verifies that rANS streams written by the single-frame,
+// PICS, MIC2 and MIC3 encoders carry the rANS magic and decode through the
+// containers' usual decoders, which detect the coder from the stream.
+func TestRANSContainers(t *testing.T) {
+	isRANS := func(b []byte) bool {
+		return len(b) >= 2 && b[0] == eightStateMagic0 && b[1] == eightStateMagic1
+	}
+
+	t.Run("single_frame", func(t *testing.T) {
+		for _, td := range testFiles[:2] {
+			_, pixels, maxVal, width, height := SetupTests(td)
+			b, err := CompressSingleFrameRANS(pixels, width, height, maxVal)
+			if err != nil {
+				t.Fatalf("%s: compress: %v", td.name, err)
+			}
+			if !isRANS(b) {
+				t.Fatalf("%s: stream starts % x, want rANS magic", td.name, b[:2])
+			}
+			got, err := DecompressSingleFrame(b, width, height)
+			if err != nil {
+				t.Fatalf("%s: decompress: %v", td.name, err)
+			}
+			assertPixelsEqual(t, pixels, got, td.name)
+		}
+	})
+
+	t.Run("PICS", func(t *testing.T) {
+		_, pixels, maxVal, width, height := SetupTests(testFiles[1])
+		b, err := CompressParallelStripsRANS(pixels, width, height, maxVal, 4)
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		p, err := readPICSHeader(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range p.strips {
+			strip, err := p.stripBlob(b, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isRANS(strip) {
+				t.Errorf("strip %d is not rANS", i)
+			}
+		}
+		img, err := Decode(b)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		assertPixelsEqual(t, pixels, img.Pixels, "PICS")
+		got, _, _, err := DecompressParallelStripsRegion(b, 10, 100, 200, 150)
+		if err != nil {
+			t.Fatalf("region: %v", err)
+		}
+		for y := 0; y < 150; y++ {
+			assertPixelsEqual(t, pixels[(100+y)*width+10:(100+y)*width+210], got[y*200:(y+1)*200], "PICS region")
+		}
+	})
+
+	t.Run("MIC2", func(t *testing.T) {
+		frames, maxValue := makeSmoothFrames(64, 48, 4, 3)
+		for _, temporal := range []bool{false, true} {
+			b, err := CompressMultiFrameRANS(frames, 64, 48, maxValue, temporal)
+			if err != nil {
+				t.Fatalf("temporal=%v: compress: %v", temporal, err)
+			}
+			_, entries, dataOffset, err := ReadMIC2Header(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range entries {
+				frame, err := ExtractFrame(b, entries, dataOffset, i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !isRANS(frame) {
+					t.Errorf("temporal=%v: frame %d is not rANS", temporal, i)
+				}
+			}
+			got, _, err := DecompressMultiFrame(b)
+			if err != nil {
+				t.Fatalf("temporal=%v: decompress: %v", temporal, err)
+			}
+			for i := range frames {
+				assertPixelsEqual(t, frames[i], got[i], fmt.Sprintf("temporal=%v frame %d", temporal, i))
+			}
+			last, _, err := DecompressFrame(b, len(frames)-1)
+			if err != nil {
+				t.Fatalf("temporal=%v: DecompressFrame: %v", temporal, err)
+			}
+			assertPixelsEqual(t, frames[len(frames)-1], last, "DecompressFrame")
+		}
+	})
+
+	t.Run("MIC3", func(t *testing.T) {
+		const w, h = 300, 200
+		rgb := makeWSITestImage(w, h, 5)
+		b, err := CompressWSI(rgb, w, h, 3, 8, WSIOptions{TileWidth: 128, TileHeight: 128, Coder: CoderRANS})
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		_, entries, dataOffset, err := ReadMIC3Header(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tile, err := ExtractTileBlob(b, entries, dataOffset, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// [Y_len][Co_len][Cg_len][Y plane...]; the Y plane is coded, not constant.
+		if tile[12] != planeCompressed || !isRANS(tile[13:]) {
+			t.Errorf("tile 0 Y plane starts % x, want a rANS stream", tile[12:15])
+		}
+		got, err := DecompressWSIRegion(b, 0, 0, 0, w, h)
+		if err != nil {
+			t.Fatalf("region: %v", err)
+		}
+		if !bytes.Equal(got, rgb) {
+			t.Error("MIC3 rANS roundtrip mismatch")
+		}
+	})
+}
+
 // BenchmarkRANSDecompress8State compares 1-state, 2-state, 4-state FSE and
 // 8-state rANS decompression speeds across standard test images.
 func BenchmarkRANSDecompress8State(b *testing.B) {
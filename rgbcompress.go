@@ -23,11 +23,20 @@ package mic
 //	[Co plane blob  ]
 //	[Cg plane blob  ]
 func CompressRGB(rgb []byte, width, height int) ([]byte, error) {
-	return compressRGBTileBlob(rgb, width, height, true)
+	return compressRGBTileBlob(rgb, width, height, true, PredictorAvg, CoderFSE2, nil)
 }
 
 // DecompressRGB decompresses a blob produced by CompressRGB.
 // Returns interleaved RGB bytes of width*height*3 length.
 func DecompressRGB(data []byte, width, height int) ([]byte, error) {
-	return decompressRGBTileBlob(data, width, height, true)
+	var d Decoder
+	return d.DecompressRGB(data, width, height)
+}
+
+// DecompressRGB is DecompressRGB under d's limits.
+func (d *Decoder) DecompressRGB(data []byte, width, height int) ([]byte, error) {
+	if err := d.checkImage(width, height, 1, 3); err != nil {
+		return nil, err
+	}
+	return d.decompressRGBTileBlob(data, width, height, true, PredictorAvg, streamRefs{})
 }
@@ -13,10 +13,22 @@ type RleCompressU16 struct {
 }
 
 func (r *RleCompressU16) Init(width int, height int, maxValue uint16) {
+	r.Out, r.b = nil, nil
+	r.reset(width, height, maxValue)
+}
+
+// reset is Init reusing the buffers of a previous call when they are large
+// enough. The previous Out is overwritten.
+func (r *RleCompressU16) reset(width int, height int, maxValue uint16) {
 	pixelDepth := bits.Len16(maxValue)
 	r.midCount = uint16((1 << (pixelDepth - 1)) - 1)
-	r.Out = make([]uint16, 0, width*height)
-	r.b = make([]uint16, 0, r.midCount+1)
+	if cap(r.Out) < width*height {
+		r.Out = make([]uint16, 0, width*height)
+	}
+	if cap(r.b) < int(r.midCount)+1 {
+		r.b = make([]uint16, 0, r.midCount+1)
+	}
+	r.Out, r.b = r.Out[:0], r.b[:0]
 	r.same = false
 	r.Out = append(r.Out, maxValue)
 }
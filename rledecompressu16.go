@@ -4,9 +4,14 @@
 package mic
 
 import (
+	"errors"
+	"fmt"
 	"math/bits"
 )
 
+// errRLEOverrun is recorded when a run extends past the end of the input.
+var errRLEOverrun = errors.New("rle: run extends beyond input")
+
 type RleDecompressU16 struct {
 	in             []uint16
 	maxValue       uint16
@@ -16,17 +21,73 @@ type RleDecompressU16 struct {
 	out            []uint16
 	c              uint16
 	recurringValue uint16
+	err            error // sticky error, reported by Err
 }
 
 func (r *RleDecompressU16) Init(input []uint16) {
 	r.in = input
-	r.maxValue = r.in[0]
-	pixelDepth := bits.Len16(r.maxValue)
-	r.midCount = uint16((1 << (pixelDepth - 1)) - 1)
 	r.i = 1
 	r.o = 0
-	r.out = make([]uint16, 0, r.midCount)
 	r.c = 0
+	r.err = nil
+	r.maxValue = 0
+	if len(r.in) > 0 {
+		r.maxValue = r.in[0]
+	}
+	if r.maxValue == 0 {
+		// Not a valid stream; leave midCount at 0 so every read overruns.
+		r.err = errors.New("rle: missing or zero max value")
+		r.in = nil
+		r.midCount = 0
+		r.out = r.out[:0]
+		return
+	}
+	pixelDepth := bits.Len16(r.maxValue)
+	r.midCount = uint16((1 << (pixelDepth - 1)) - 1)
+	if cap(r.out) < int(r.midCount) {
+		r.out = make([]uint16, 0, r.midCount)
+	}
+	r.out = r.out[:0]
+}
+
+// Err returns the first error encountered while decoding, if any. Reads past
+// the end of the input return 0 and record an error instead of panicking.
+func (r *RleDecompressU16) Err() error {
+	return r.err
+}
+
+// checkLen returns an error if the input cannot possibly expand to n
+// symbols. A same-value run turns two input symbols into at most midCount
+// outputs, which bounds the allocation a corrupt header can request.
+func (r *RleDecompressU16) checkLen(n uint64) error {
+	if n > uint64(len(r.in))*(uint64(r.midCount)+1) {
+		return fmt.Errorf("rle: output length (%d) too large for input", n)
+	}
+	return nil
+}
+
+// checkImageLen is checkLen for a width x height image.
+func (r *RleDecompressU16) checkImageLen(width, height int) error {
+	if r.err != nil {
+		return r.err
+	}
+	if width < 0 || height < 0 {
+		return fmt.Errorf("rle: invalid dimensions %dx%d", width, height)
+	}
+	hi, n := bits.Mul64(uint64(width), uint64(height))
+	if hi != 0 {
+		return fmt.Errorf("rle: invalid dimensions %dx%d", width, height)
+	}
+	return r.checkLen(n)
+}
+
+// overrun records errRLEOverrun and returns a zero symbol.
+func (r *RleDecompressU16) overrun() uint16 {
+	if r.err == nil {
+		r.err = errRLEOverrun
+	}
+	r.c = 0
+	return 0
 }
 
 func (r *RleDecompressU16) DecodeNextBlock() {
@@ -36,9 +97,18 @@ func (r *RleDecompressU16) DecodeNextBlock() {
 		count := r.in[r.i]
 		r.i += 1
 		if count > r.midCount {
-			r.out = append(r.out, r.in[r.i:(r.i+int(count-r.midCount))]...)
-			r.i += int(count - r.midCount)
+			end := r.i + int(count-r.midCount)
+			if end > len(r.in) {
+				r.overrun()
+				return
+			}
+			r.out = append(r.out, r.in[r.i:end]...)
+			r.i = end
 		} else {
+			if r.i >= len(r.in) {
+				r.overrun()
+				return
+			}
 			for k := 0; k < int(count); k++ {
 				r.out = append(r.out, r.in[r.i])
 			}
@@ -50,6 +120,9 @@ func (r *RleDecompressU16) DecodeNextBlock() {
 func (r *RleDecompressU16) DecodeNext() uint16 {
 	if len(r.out) == 0 || r.o > len(r.out)-1 {
 		r.DecodeNextBlock()
+		if len(r.out) == 0 {
+			return r.overrun()
+		}
 	}
 	retVal := r.out[r.o]
 	r.o += 1
@@ -67,6 +140,9 @@ func (r *RleDecompressU16) DecodeNext2() uint16 {
 
 	// Need to read a new block header (c==0 means same-run done, c==midCount means diff-run done).
 	if r.c == 0 || r.c == r.midCount {
+		if r.i+1 >= len(r.in) {
+			return r.overrun()
+		}
 		r.c = r.in[r.i]
 		r.i++
 		if r.c <= r.midCount {
@@ -78,20 +154,36 @@ func (r *RleDecompressU16) DecodeNext2() uint16 {
 	}
 
 	// "diff" run: each symbol is distinct
+	if r.i >= len(r.in) {
+		return r.overrun()
+	}
 	output := r.in[r.i]
 	r.i++
 	r.c--
 	return output
 }
 
-func (r *RleDecompressU16) Decompress() []uint16 {
+// Decompress decodes a length-prefixed stream produced by RleCompressU16.Compress.
+func (r *RleDecompressU16) Decompress() ([]uint16, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.i+1 >= len(r.in) {
+		return nil, errRLEOverrun
+	}
 	outlen := uint32(r.in[r.i]) << 16
 	outlen += uint32(r.in[r.i+1])
 	r.i += 2
+	if err := r.checkLen(uint64(outlen)); err != nil {
+		return nil, err
+	}
 	out := make([]uint16, outlen)
 
 	for i := uint32(0); i < outlen; i++ {
 		out[i] = r.DecodeNext2()
 	}
-	return out
+	if r.err != nil {
+		return nil, r.err
+	}
+	return out, nil
 }
@@ -4,6 +4,7 @@
 package mic
 
 import (
+	"errors"
 	"math/bits"
 )
 
@@ -17,16 +18,24 @@ type RleHuffDecompressU16 struct {
 	recurringValue uint16
 }
 
-func (r *RleHuffDecompressU16) Init(input []byte) {
+func (r *RleHuffDecompressU16) Init(input []byte) error {
 	r.d.Init(input)
-	r.d.ReadTable()
-	r.d.DecompressInit()
+	if err := r.d.ReadTable(); err != nil {
+		return err
+	}
+	if err := r.d.DecompressInit(); err != nil {
+		return err
+	}
 	r.maxValue = r.d.DecodeNext()
+	if r.maxValue == 0 {
+		return errors.New("rle: zero max value")
+	}
 	pixelDepth := bits.Len16(r.maxValue)
 	r.midCount = uint16((1 << (pixelDepth - 1)) - 1)
 	r.o = 0
 	r.out = make([]uint16, 0, r.midCount)
 	r.c = 0
+	return nil
 }
 
 func (r *RleHuffDecompressU16) DecodeNext() uint16 {
@@ -0,0 +1,159 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Signed 16-bit samples (DICOM PixelRepresentation = 1).
+//
+// CT and some MR series store int16 samples, e.g. Hounsfield units down to
+// -1024 and below. Every pipeline works on unsigned values, so the S16 entry
+// points shift each sample by the image minimum before compressing:
+//
+//	stored = sample - minValue
+//
+// This maps [min, max] onto [0, max-min] losslessly, so the delta coder sees
+// the same value range as an unsigned image of equal contrast. Like the
+// dimensions, the offset travels outside the bare stream: MIC1 records it in
+// header bytes 22-23 and MIC2 in bytes 18-19, each next to a signed flag,
+// and Decode returns the original signed values.
+
+// S16ToU16 maps signed samples to stored unsigned values by subtracting
+// minValue. Samples below minValue wrap, so pass the true minimum.
+func S16ToU16(pixels []int16, minValue int16) []uint16 {
+	out := make([]uint16, len(pixels))
+	for i, v := range pixels {
+		out[i] = uint16(v) - uint16(minValue)
+	}
+	return out
+}
+
+// U16ToS16 reverses S16ToU16.
+func U16ToS16(stored []uint16, minValue int16) []int16 {
+	out := make([]int16, len(stored))
+	for i, v := range stored {
+		out[i] = int16(v + uint16(minValue))
+	}
+	return out
+}
+
+// s16Range returns the smallest and largest sample across frames.
+func s16Range(frames ...[]int16) (minValue, maxValue int16) {
+	first := true
+	for _, f := range frames {
+		for _, v := range f {
+			if first {
+				minValue, maxValue = v, v
+				first = false
+			}
+			if v < minValue {
+				minValue = v
+			}
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+	return minValue, maxValue
+}
+
+// signedBitDepth returns the two's complement width that holds every sample
+// in [lo, hi]: n bits hold [-2^(n-1), 2^(n-1)-1].
+func signedBitDepth(lo, hi int16) int {
+	m := int(hi)
+	if int(^lo) > m { // ^lo is -lo-1
+		m = int(^lo)
+	}
+	return bits.Len16(uint16(m)) + 1
+}
+
+// storedMax returns the maxValue to compress samples in [minValue, maxValue]
+// with. The Delta+RLE coder needs at least one bit, so a constant image
+// still reports 1.
+func storedMax(minValue, maxValue int16) uint16 {
+	if maxValue == minValue {
+		return 1
+	}
+	return uint16(maxValue) - uint16(minValue)
+}
+
+// CompressSingleFrameS16 compresses signed 16-bit pixels with the
+// CompressSingleFrame pipeline. It returns the stream and the offset
+// (the smallest sample) that DecompressSingleFrameS16 needs back; MIC1
+// containers record it in MIC1Header.MinValue.
+func CompressSingleFrameS16(pixels []int16, width, height int) ([]byte, int16, error) {
+	if len(pixels) != width*height {
+		return nil, 0, errors.New("pixel count does not match width*height")
+	}
+	minValue, maxValue := s16Range(pixels)
+	compressed, err := CompressSingleFrame(S16ToU16(pixels, minValue), width, height, storedMax(minValue, maxValue))
+	if err != nil {
+		return nil, 0, err
+	}
+	return compressed, minValue, nil
+}
+
+// DecompressSingleFrameS16 decompresses a CompressSingleFrameS16 stream.
+// minValue is the offset CompressSingleFrameS16 returned.
+func DecompressSingleFrameS16(compressed []byte, width, height int, minValue int16) ([]int16, error) {
+	var d Decoder
+	return d.DecompressSingleFrameS16(compressed, width, height, minValue)
+}
+
+// DecompressSingleFrameS16 is DecompressSingleFrameS16 under d's limits.
+func (d *Decoder) DecompressSingleFrameS16(compressed []byte, width, height int, minValue int16) ([]int16, error) {
+	stored, err := d.DecompressSingleFrame(compressed, width, height)
+	if err != nil {
+		return nil, err
+	}
+	return U16ToS16(stored, minValue), nil
+}
+
+// CompressMultiFrameS16 compresses signed 16-bit frames into a MIC2 container
+// with the signed flag set. All frames share one offset, so temporal
+// residuals are the same as for the original signed values.
+func CompressMultiFrameS16(frames [][]int16, width, height int, temporal bool) ([]byte, error) {
+	minValue, maxValue := s16Range(frames...)
+	stored := make([][]uint16, len(frames))
+	for i, f := range frames {
+		if len(f) != width*height {
+			return nil, fmt.Errorf("frame %d: pixel count does not match width*height", i)
+		}
+		stored[i] = S16ToU16(f, minValue)
+	}
+	hdr := MIC2Header{
+		Width:      width,
+		Height:     height,
+		FrameCount: len(frames),
+		Temporal:   temporal,
+		Signed:     true,
+		MinValue:   minValue,
+	}
+	return compressMIC2(stored, hdr, storedMax(minValue, maxValue), CoderFSE2, false)
+}
+
+// DecompressMultiFrameS16 decompresses all frames of a MIC2 file as signed
+// samples. Files without the signed flag are returned reinterpreted as int16.
+func DecompressMultiFrameS16(data []byte) ([][]int16, MIC2Header, error) {
+	var d Decoder
+	return d.DecompressMultiFrameS16(data)
+}
+
+// DecompressMultiFrameS16 is DecompressMultiFrameS16 under d's limits.
+func (d *Decoder) DecompressMultiFrameS16(data []byte) ([][]int16, MIC2Header, error) {
+	frames, hdr, err := d.DecompressMultiFrame(data)
+	if err != nil {
+		return nil, MIC2Header{}, err
+	}
+	out := make([][]int16, len(frames))
+	for i, f := range frames {
+		out[i] = U16ToS16(f, hdr.MinValue)
+	}
+	return out, hdr, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"testing"
+)
+
+// makeSignedFrames returns CT-like frames in Hounsfield units: air at -1024
+// around a body whose values reach well above zero.
+func makeSignedFrames(width, height, nFrames int) [][]int16 {
+	frames, _ := makeSmoothFrames(width, height, nFrames, 11)
+	out := make([][]int16, nFrames)
+	for f, frame := range frames {
+		out[f] = make([]int16, len(frame))
+		for i, v := range frame {
+			x, y := i%width, i/width
+			if x < width/8 || y < height/8 {
+				out[f][i] = -1024
+			} else {
+				out[f][i] = int16(v)*8 - 1200
+			}
+		}
+	}
+	return out
+}
+
+func assertS16Equal(t *testing.T, want, got []int16, label string) {
+	t.Helper()
+	if len(want) != len(got) {
+		t.Fatalf("%s: length mismatch: got %d, want %d", label, len(got), len(want))
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Fatalf("%s: pixel %d mismatch: got %d, want %d", label, i, got[i], want[i])
+		}
+	}
+}
+
+func TestSingleFrameS16(t *testing.T) {
+	width, height := 128, 96
+	pixels := makeSignedFrames(width, height, 1)[0]
+
+	compressed, minValue, err := CompressSingleFrameS16(pixels, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := s16Range(pixels); minValue != want {
+		t.Fatalf("offset %d, want the minimum sample %d", minValue, want)
+	}
+	got, err := DecompressSingleFrameS16(compressed, width, height, minValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertS16Equal(t, pixels, got, "S16")
+
+	// MIC1 records the offset, and Decode restores the signed samples.
+	var buf bytes.Buffer
+	hdr := MIC1Header{Width: width, Height: height, Signed: true, MinValue: minValue}
+	if err := WriteMIC1(&buf, hdr, compressed); err != nil {
+		t.Fatal(err)
+	}
+	back, _, err := ReadMIC1Header(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !back.Signed || back.MinValue != minValue {
+		t.Fatalf("MIC1 header: signed=%v offset=%d, want offset %d", back.Signed, back.MinValue, minValue)
+	}
+	img, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !img.Signed {
+		t.Fatal("Decode: expected a signed image")
+	}
+	for i, v := range img.Pixels {
+		if int16(v) != pixels[i] {
+			t.Fatalf("Decode: pixel %d = %d, want %d", i, int16(v), pixels[i])
+		}
+	}
+}
+
+func TestSingleFrameS16Extremes(t *testing.T) {
+	width, height := 256, 128
+	cases := map[string][]int16{
+		"constant": make([]int16, width*height),
+		"full range": func() []int16 {
+			p := make([]int16, width*height)
+			for i := range p {
+				noise := int16(i * 7 % 31)
+				p[i] = -32768 + noise
+				if i%width >= width/2 {
+					p[i] = 32767 - noise
+				}
+			}
+			return p
+		}(),
+	}
+	for i := range cases["constant"] {
+		cases["constant"][i] = -2000
+	}
+	for name, pixels := range cases {
+		compressed, minValue, err := CompressSingleFrameS16(pixels, width, height)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := DecompressSingleFrameS16(compressed, width, height, minValue)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		assertS16Equal(t, pixels, got, name)
+	}
+}
+
+func TestMultiFrameS16(t *testing.T) {
+	width, height := 64, 48
+	frames := makeSignedFrames(width, height, 4)
+
+	for _, temporal := range []bool{false, true} {
+		data, err := CompressMultiFrameS16(frames, width, height, temporal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, hdr, err := DecompressMultiFrameS16(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hdr.Signed || hdr.Temporal != temporal {
+			t.Fatalf("header: %+v", hdr)
+		}
+		for f := range frames {
+			assertS16Equal(t, frames[f], got[f], "MIC2 S16")
+		}
+
+		// Readers that predate the flag accept only the "MIC2" magic, and
+		// a "MIC2" header may not carry flags this reader does not know.
+		if magic := string(data[0:4]); magic != mic2SignedMagic {
+			t.Fatalf("signed file has magic %q, want %q", magic, mic2SignedMagic)
+		}
+		relabelled := bytes.Clone(data)
+		copy(relabelled, mic2Magic)
+		if _, _, err := DecompressMultiFrameS16(relabelled); err == nil {
+			t.Fatal("expected error for a MIC2 header with the signed flag")
+		}
+		copy(relabelled, mic2SignedMagic)
+		relabelled[16] &^= PipelineSigned
+		if _, _, err := DecompressMultiFrameS16(relabelled); err == nil {
+			t.Fatal("expected error for an MC2S header without the signed flag")
+		}
+		relabelled = bytes.Clone(data)
+		relabelled[16] |= 0x80
+		if _, _, err := DecompressMultiFrameS16(relabelled); err == nil {
+			t.Fatal("expected error for an unknown pipeline flag")
+		}
+
+		// Checksumming rewrites the header and must keep the offset.
+		checked, err := AddChecksums(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := Decode(checked)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !img.Signed || img.FrameCount != len(frames) {
+			t.Fatalf("Decode: signed=%v frames=%d", img.Signed, img.FrameCount)
+		}
+		for f := range frames {
+			for i, v := range img.Frame(f) {
+				if int16(v) != frames[f][i] {
+					t.Fatalf("Decode: frame %d pixel %d = %d, want %d", f, i, int16(v), frames[f][i])
+				}
+			}
+		}
+	}
+}
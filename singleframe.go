@@ -0,0 +1,263 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// MIC1 / MICR container formats for single-frame images.
+//
+// Both containers share one 24-byte versioned header; MIC1 carries a
+// greyscale CompressSingleFrame stream and MICR a CompressRGB blob.
+//
+//	Bytes 0-3:    Magic "MIC1" or "MICR"
+//	Bytes 4-7:    Width (uint32 LE)
+//	Bytes 8-11:   Height (uint32 LE)
+//	Byte  12:     Pipeline ID (see Pipeline* constants below)
+//	Byte  13:     Bits stored (1-16)
+//	Byte  14:     Flags: bit0=signed samples
+//	Byte  15:     0x80 | format version
+//	Bytes 16-19:  Payload length (uint32 LE)
+//	Bytes 20-21:  Max stored value (uint16 LE)
+//	Bytes 22-23:  Signed offset (int16 LE) — minimum sample when bit0 of the
+//	              flags is set, zero otherwise
+//	Bytes 24..:   Payload
+//
+// Files written before the header was versioned (version 0) are still read:
+// legacy MIC1 used a 20-byte header with a uint32 pipeline (always 1) in
+// bytes 12-15, and legacy MICR a 12-byte header followed directly by the
+// CompressRGB blob. In both cases byte 15 has its top bit clear — the
+// pipeline value is 1 and the first RGB plane length is far below 2^31 — so
+// the 0x80 marker distinguishes the two layouts unambiguously.
+
+const (
+	mic1Magic = "MIC1"
+	micrMagic = "MICR"
+
+	singleFrameHeaderSize = 24
+	singleFrameVersion    = 1    // current header version
+	singleFrameVersioned  = 0x80 // set in byte 15 of versioned headers
+
+	mic1LegacyHeaderSize = 20 // magic + width + height + pipeline + length
+	micrLegacyHeaderSize = 12 // magic + width + height
+
+	singleFrameFlagSigned = 0x01
+)
+
+// Pipeline IDs recorded in MIC1/MICR headers.
+const (
+	PipelineDeltaRLEFSE  = 1 // Delta+RLE+FSE (MIC1) or YCoCg-R + per-plane Delta+RLE+FSE (MICR)
+	PipelineOptions      = 2 // MIC1 only: Compress output, configuration recorded in the payload
+	PipelineLossyWavelet = 3 // MIC1 only: CompressLossy output, quantisation steps recorded in the payload
+	PipelineContextFSE   = 4 // MIC1 only: CompressContextFSE output, one FSE table per activity context
+	PipelineArchive      = 5 // MIC1 only: CompressArchive output, bias-corrected MED + adaptive arithmetic coding
+	PipelineSplitStream  = 6 // MIC1 only: CompressSplitStream output, separate run, literal and escape streams
+)
+
+// MIC1Header holds the parsed header of a MIC1 single-frame file.
+type MIC1Header struct {
+	Width      int
+	Height     int
+	Pipeline   uint8  // one of the Pipeline* IDs
+	BitsStored int    // significant bits per sample; 0 = derive from MaxValue
+	Signed     bool   // samples were signed before being mapped to uint16
+	MinValue   int16  // offset subtracted from every sample when Signed
+	MaxValue   uint16 // largest stored sample (0 in legacy files)
+	Version    int    // header version; 0 = legacy unversioned file
+}
+
+// MICRHeader holds the parsed header of a MICR single-frame RGB file.
+type MICRHeader struct {
+	Width      int
+	Height     int
+	Pipeline   uint8 // one of the Pipeline* IDs
+	BitsStored int   // bits per channel sample (always 8 today)
+	Version    int   // header version; 0 = legacy unversioned file
+}
+
+// WriteMIC1 writes a complete MIC1 container to w. The header is always
+// written at the current version; hdr.Version is ignored.
+func WriteMIC1(w io.Writer, hdr MIC1Header, payload []byte) error {
+	if hdr.Pipeline == 0 {
+		hdr.Pipeline = PipelineDeltaRLEFSE
+	}
+	if hdr.BitsStored == 0 && hdr.Signed {
+		hdr.BitsStored = signedBitDepth(hdr.MinValue, hdr.MinValue+int16(hdr.MaxValue))
+	} else if hdr.BitsStored == 0 {
+		hdr.BitsStored = max(bits.Len16(hdr.MaxValue), 1)
+	}
+	var flags byte
+	var offset int16
+	if hdr.Signed {
+		flags |= singleFrameFlagSigned
+		offset = hdr.MinValue
+	}
+	return writeSingleFrame(w, mic1Magic, hdr.Width, hdr.Height, hdr.Pipeline, hdr.BitsStored, flags, hdr.MaxValue, offset, payload)
+}
+
+// WriteMICR writes a complete MICR container to w. The header is always
+// written at the current version; hdr.Version is ignored.
+func WriteMICR(w io.Writer, hdr MICRHeader, payload []byte) error {
+	if hdr.Pipeline == 0 {
+		hdr.Pipeline = PipelineDeltaRLEFSE
+	}
+	if hdr.BitsStored == 0 {
+		hdr.BitsStored = 8
+	}
+	return writeSingleFrame(w, micrMagic, hdr.Width, hdr.Height, hdr.Pipeline, hdr.BitsStored, 0, uint16(1)<<hdr.BitsStored-1, 0, payload)
+}
+
+func writeSingleFrame(w io.Writer, magic string, width, height int, pipeline uint8, bitsStored int, flags byte, maxValue uint16, offset int16, payload []byte) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%s: invalid dimensions %dx%d", magic, width, height)
+	}
+	if bitsStored < 1 || bitsStored > 16 {
+		return fmt.Errorf("%s: invalid bits stored %d", magic, bitsStored)
+	}
+
+	header := make([]byte, singleFrameHeaderSize)
+	copy(header[0:4], magic)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(height))
+	header[12] = pipeline
+	header[13] = byte(bitsStored)
+	header[14] = flags
+	header[15] = singleFrameVersioned | singleFrameVersion
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(payload)))
+	binary.LittleEndian.PutUint16(header[20:22], maxValue)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(offset))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// ReadMIC1Header parses the header of a MIC1 file, accepting both the
+// current versioned layout and legacy unversioned files. It returns the
+// header and the payload slice (a sub-slice of data).
+func ReadMIC1Header(data []byte) (MIC1Header, []byte, error) {
+	if len(data) < mic1LegacyHeaderSize {
+		return MIC1Header{}, nil, errors.New("MIC1: file too small")
+	}
+	if string(data[0:4]) != mic1Magic {
+		return MIC1Header{}, nil, fmt.Errorf("MIC1: invalid magic %q", data[0:4])
+	}
+
+	hdr := MIC1Header{
+		Width:  int(binary.LittleEndian.Uint32(data[4:8])),
+		Height: int(binary.LittleEndian.Uint32(data[8:12])),
+	}
+
+	var payload []byte
+	if data[15]&singleFrameVersioned == 0 {
+		pipeline := binary.LittleEndian.Uint32(data[12:16])
+		if pipeline != PipelineDeltaRLEFSE {
+			return MIC1Header{}, nil, fmt.Errorf("MIC1: unsupported legacy pipeline type %d", pipeline)
+		}
+		hdr.Pipeline = PipelineDeltaRLEFSE
+		n := binary.LittleEndian.Uint32(data[16:20])
+		if uint64(n) > uint64(len(data)-mic1LegacyHeaderSize) {
+			return MIC1Header{}, nil, errors.New("MIC1: compressed data extends beyond file")
+		}
+		payload = data[mic1LegacyHeaderSize : mic1LegacyHeaderSize+int(n)]
+	} else {
+		f, p, err := readSingleFrameHeader(mic1Magic, data)
+		if err != nil {
+			return MIC1Header{}, nil, err
+		}
+		hdr.Version, hdr.Pipeline, hdr.BitsStored = f.version, f.pipeline, f.bitsStored
+		hdr.Signed = f.flags&singleFrameFlagSigned != 0
+		if hdr.Signed {
+			hdr.MinValue = f.offset
+		}
+		hdr.MaxValue = f.maxValue
+		payload = p
+	}
+
+	if hdr.Width <= 0 || hdr.Height <= 0 {
+		return MIC1Header{}, nil, fmt.Errorf("MIC1: invalid dimensions %dx%d", hdr.Width, hdr.Height)
+	}
+	return hdr, payload, nil
+}
+
+// ReadMICRHeader parses the header of a MICR file, accepting both the
+// current versioned layout and legacy unversioned files. It returns the
+// header and the CompressRGB payload (a sub-slice of data).
+func ReadMICRHeader(data []byte) (MICRHeader, []byte, error) {
+	if len(data) < micrLegacyHeaderSize {
+		return MICRHeader{}, nil, errors.New("MICR: file too small")
+	}
+	if magic := string(data[0:4]); magic != micrMagic {
+		return MICRHeader{}, nil, fmt.Errorf("MICR: invalid magic %q", magic)
+	}
+
+	hdr := MICRHeader{
+		Width:      int(binary.LittleEndian.Uint32(data[4:8])),
+		Height:     int(binary.LittleEndian.Uint32(data[8:12])),
+		Pipeline:   PipelineDeltaRLEFSE,
+		BitsStored: 8,
+	}
+
+	var payload []byte
+	if len(data) < singleFrameHeaderSize || data[15]&singleFrameVersioned == 0 {
+		payload = data[micrLegacyHeaderSize:]
+	} else {
+		f, p, err := readSingleFrameHeader(micrMagic, data)
+		if err != nil {
+			return MICRHeader{}, nil, err
+		}
+		hdr.Version, hdr.Pipeline, hdr.BitsStored = f.version, f.pipeline, f.bitsStored
+		payload = p
+	}
+
+	if hdr.Width <= 0 || hdr.Height <= 0 {
+		return MICRHeader{}, nil, fmt.Errorf("MICR: invalid dimensions %dx%d", hdr.Width, hdr.Height)
+	}
+	return hdr, payload, nil
+}
+
+// singleFrameFields holds the header fields shared by MIC1 and MICR.
+type singleFrameFields struct {
+	version    int
+	pipeline   uint8
+	bitsStored int
+	flags      byte
+	maxValue   uint16
+	offset     int16
+}
+
+// readSingleFrameHeader parses the versioned part of a MIC1/MICR header.
+func readSingleFrameHeader(magic string, data []byte) (singleFrameFields, []byte, error) {
+	if len(data) < singleFrameHeaderSize {
+		return singleFrameFields{}, nil, fmt.Errorf("%s: file too small", magic)
+	}
+	f := singleFrameFields{
+		version:    int(data[15] &^ singleFrameVersioned),
+		pipeline:   data[12],
+		bitsStored: int(data[13]),
+		flags:      data[14],
+		maxValue:   binary.LittleEndian.Uint16(data[20:22]),
+		offset:     int16(binary.LittleEndian.Uint16(data[22:24])),
+	}
+	if f.version > singleFrameVersion {
+		return singleFrameFields{}, nil, fmt.Errorf("%s: unsupported header version %d", magic, f.version)
+	}
+	if f.bitsStored < 1 || f.bitsStored > 16 {
+		return singleFrameFields{}, nil, fmt.Errorf("%s: invalid bits stored %d", magic, f.bitsStored)
+	}
+
+	n := binary.LittleEndian.Uint32(data[16:20])
+	if uint64(n) > uint64(len(data)-singleFrameHeaderSize) {
+		return singleFrameFields{}, nil, fmt.Errorf("%s: payload extends beyond file", magic)
+	}
+	return f, data[singleFrameHeaderSize : singleFrameHeaderSize+int(n)], nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMIC1RoundTrip(t *testing.T) {
+	width, height := 80, 60
+	frames, maxValue := makeSmoothFrames(width, height, 1, 5)
+	compressed, err := CompressSingleFrame(frames[0], width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	in := MIC1Header{Width: width, Height: height, Pipeline: PipelineDeltaRLEFSE, BitsStored: 12, Signed: true, MaxValue: maxValue}
+	if err := WriteMIC1(&buf, in, compressed); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if len(data) != singleFrameHeaderSize+len(compressed) {
+		t.Fatalf("unexpected container size %d", len(data))
+	}
+
+	hdr, payload, err := ReadMIC1Header(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Version = singleFrameVersion
+	if hdr != in {
+		t.Fatalf("header mismatch: got %+v, want %+v", hdr, in)
+	}
+	assertBytesEqual(t, compressed, payload, "payload")
+
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.BitDepth != 12 {
+		t.Fatalf("BitDepth = %d, want 12 from header", img.BitDepth)
+	}
+	assertPixelsEqual(t, frames[0], img.Pixels, "MIC1 v1")
+}
+
+func TestMIC1Legacy(t *testing.T) {
+	width, height := 64, 48
+	frames, maxValue := makeSmoothFrames(width, height, 1, 9)
+	compressed, err := CompressSingleFrame(frames[0], width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hdr, payload, err := ReadMIC1Header(legacyMIC1(width, height, compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Version != 0 || hdr.Width != width || hdr.Height != height || hdr.Pipeline != PipelineDeltaRLEFSE {
+		t.Fatalf("unexpected legacy header: %+v", hdr)
+	}
+	assertBytesEqual(t, compressed, payload, "legacy payload")
+}
+
+func TestMICRRoundTrip(t *testing.T) {
+	width, height := 300, 200
+	rgb := makeWSITestImage(width, height, 4)
+	blob, err := CompressRGB(rgb, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMICR(&buf, MICRHeader{Width: width, Height: height}, blob); err != nil {
+		t.Fatal(err)
+	}
+	hdr, payload, err := ReadMICRHeader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MICRHeader{Width: width, Height: height, Pipeline: PipelineDeltaRLEFSE, BitsStored: 8, Version: singleFrameVersion}
+	if hdr != want {
+		t.Fatalf("header mismatch: got %+v, want %+v", hdr, want)
+	}
+	assertBytesEqual(t, blob, payload, "payload")
+
+	// Legacy 12-byte header followed directly by the blob.
+	legacy := make([]byte, micrLegacyHeaderSize+len(blob))
+	copy(legacy[0:4], micrMagic)
+	binary.LittleEndian.PutUint32(legacy[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(legacy[8:12], uint32(height))
+	copy(legacy[micrLegacyHeaderSize:], blob)
+	hdr, payload, err = ReadMICRHeader(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Version != 0 || hdr.BitsStored != 8 {
+		t.Fatalf("unexpected legacy header: %+v", hdr)
+	}
+	assertBytesEqual(t, blob, payload, "legacy payload")
+}
+
+func TestSingleFrameHeaderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMIC1(&buf, MIC1Header{Width: 4, Height: 4, MaxValue: 255}, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+
+	future := append([]byte(nil), good...)
+	future[15] = singleFrameVersioned | (singleFrameVersion + 1)
+	if _, _, err := ReadMIC1Header(future); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+
+	if _, _, err := ReadMIC1Header(good[:len(good)-1]); err == nil {
+		t.Fatal("expected error for truncated payload")
+	}
+
+	if err := WriteMIC1(&buf, MIC1Header{Width: 0, Height: 4}, nil); err == nil {
+		t.Fatal("expected error for zero width")
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Split-stream Delta+RLE.
+//
+// The Delta+RLE stream of CompressSingleFrame mixes RLE counts, repeated
+// values, residuals and the raw 16-bit values behind delimiterForOverflow
+// into one alphabet that shares one FSE table. CompressSplitStream keeps the
+// avg predictor but separates the three kinds of data:
+//
+//   - Runs: (literal count, run length) pairs in their own FSE stream. A pair
+//     reads that many literals, then repeats one more literal run-length
+//     times; pairs with a run length of 0 carry literals only.
+//   - Literals: ZigZag residuals, reduced modulo 2^depth, in a second FSE
+//     stream. Residuals of 2^k or more are replaced by the escape symbol 2^k.
+//   - Escapes: the pixel value of every escape symbol, bit-packed at depth
+//     bits each, least significant bit first.
+//
+// The encoder picks k from the residual histogram; k = depth disables
+// escapes. The decoder walks the three streams in lockstep.
+//
+// The result is a MIC1 container with pipeline PipelineSplitStream:
+//
+//	Byte  0:     Escape shift k (1..depth)
+//	Byte  1:     Run stream coding (as packSymbols)
+//	Byte  2:     Literal stream coding
+//	Bytes 3-6:   Run stream length (uint32 LE)
+//	Bytes 7-10:  Literal stream length (uint32 LE)
+//	Bytes 11-14: Escape stream length (uint32 LE)
+//	After:       Run stream, literal stream, escape stream
+
+const (
+	splitHeaderSize = 15
+	splitMinRun     = 4      // shorter repeats stay literals
+	splitMaxCount   = 0xFFFF // longer counts continue in the next pair
+	splitMinShift   = 4      // smallest escape shift the encoder considers
+	splitTableBits  = 12     // estimated table cost per distinct literal
+)
+
+// CompressSplitStream compresses a single 16-bit frame with the avg
+// predictor and separate run, literal and escape streams. maxValue 0 derives
+// it from the pixels. Decompress and Decode read the result.
+func CompressSplitStream(pixels []uint16, width, height int, maxValue uint16) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("split stream: invalid dimensions %dx%d", width, height)
+	}
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("split stream: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if maxValue == 0 {
+		for _, v := range pixels {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+		if maxValue == 0 {
+			maxValue = 1
+		}
+	}
+	for i, v := range pixels {
+		if v > maxValue {
+			return nil, fmt.Errorf("split stream: pixel %d value %d exceeds max value %d", i, v, maxValue)
+		}
+	}
+
+	depth := bits.Len16(maxValue)
+	mask, half := int32(1)<<depth-1, int32(1)<<(depth-1)
+	residuals := make([]uint16, len(pixels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			e := (int32(pixels[i])-splitPredict(pixels, i, x, y, width)+half)&mask - half
+			residuals[i] = uint16((e << 1) ^ (e >> 31))
+		}
+	}
+	shift := splitEscapeShift(residuals, depth)
+	escape := uint16(1) << shift
+	if shift == depth {
+		escape = 0 // never matches: residuals stay below 2^depth
+	}
+
+	var runs, lits []uint16
+	var esc splitBitWriter
+	pending := 0
+	emit := func(run int, value uint16) {
+		for pending > splitMaxCount {
+			runs = append(runs, splitMaxCount, 0)
+			pending -= splitMaxCount
+		}
+		for run > splitMaxCount {
+			runs = append(runs, uint16(pending), splitMaxCount)
+			lits = append(lits, value)
+			pending, run = 0, run-splitMaxCount
+		}
+		if pending > 0 || run > 0 {
+			runs = append(runs, uint16(pending), uint16(run))
+			if run > 0 {
+				lits = append(lits, value)
+			}
+		}
+		pending = 0
+	}
+	for i := 0; i < len(residuals); {
+		z := residuals[i]
+		if escape != 0 && z >= escape {
+			lits = append(lits, escape)
+			esc.write(uint64(pixels[i]), depth)
+			pending++
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(residuals) && residuals[j] == z {
+			j++
+		}
+		if j-i >= splitMinRun {
+			emit(j-i, z)
+		} else {
+			for ; i < j; i++ {
+				lits = append(lits, z)
+				pending++
+			}
+		}
+		i = j
+	}
+	emit(0, 0)
+
+	payload := make([]byte, splitHeaderSize)
+	payload[0] = byte(shift)
+	var data []byte
+	for s, symbols := range [][]uint16{runs, lits} {
+		coding, coded := byte(symbolsRaw), []byte(nil)
+		if len(symbols) > 0 {
+			coding, coded = packSymbols(symbols)
+		}
+		payload[1+s] = coding
+		binary.LittleEndian.PutUint32(payload[3+4*s:], uint32(len(coded)))
+		data = append(data, coded...)
+	}
+	escBytes := esc.flush()
+	binary.LittleEndian.PutUint32(payload[11:], uint32(len(escBytes)))
+	payload = append(append(payload, data...), escBytes...)
+
+	var buf bytes.Buffer
+	hdr := MIC1Header{Width: width, Height: height, MaxValue: maxValue, Pipeline: PipelineSplitStream}
+	if err := WriteMIC1(&buf, hdr, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// splitPredict returns the avg prediction of pixel (x, y) at index i of img,
+// with the border rules of DeltaCompressU16.
+func splitPredict(img []uint16, i, x, y, width int) int32 {
+	switch {
+	case y == 0 && x == 0:
+		return 0
+	case y == 0:
+		return int32(img[i-1])
+	case x == 0:
+		return int32(img[i-width])
+	}
+	return (int32(img[i-1]) + int32(img[i-width])) >> 1
+}
+
+// splitEscapeShift returns the escape shift k that minimises the estimated
+// size of the literal and escape streams: the entropy of the literals with
+// everything from 2^k up collapsed into one symbol, a table cost per
+// distinct literal, and depth bits per escape.
+func splitEscapeShift(residuals []uint16, depth int) int {
+	hist := make([]int, 1<<depth)
+	for _, z := range residuals {
+		hist[z]++
+	}
+	n := float64(len(residuals))
+	best, bestCost := depth, math.Inf(1)
+	for k := min(splitMinShift, depth); k <= depth; k++ {
+		cost, escapes, distinct := 0.0, 0, 0
+		for z, c := range hist {
+			if c == 0 {
+				continue
+			}
+			if k < depth && z >= 1<<k {
+				escapes += c
+				continue
+			}
+			distinct++
+			cost -= float64(c) * math.Log2(float64(c)/n)
+		}
+		if escapes > 0 {
+			distinct++
+			cost -= float64(escapes) * math.Log2(float64(escapes)/n)
+		}
+		cost += float64(escapes*depth + distinct*splitTableBits)
+		if cost < bestCost {
+			best, bestCost = k, cost
+		}
+	}
+	return best
+}
+
+// splitBitWriter packs values LSB first.
+type splitBitWriter struct {
+	out   []byte
+	acc   uint64
+	nbits int
+}
+
+func (w *splitBitWriter) write(v uint64, n int) {
+	w.acc |= v << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.out = append(w.out, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *splitBitWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.out = append(w.out, byte(w.acc))
+	}
+	return w.out
+}
+
+// splitBitReader reverses splitBitWriter.
+type splitBitReader struct {
+	in    []byte
+	acc   uint64
+	nbits int
+}
+
+func (r *splitBitReader) read(n int) (uint64, error) {
+	for r.nbits < n {
+		if len(r.in) == 0 {
+			return 0, errors.New("split stream: escape stream truncated")
+		}
+		r.acc |= uint64(r.in[0]) << r.nbits
+		r.in = r.in[1:]
+		r.nbits += 8
+	}
+	v := r.acc & (1<<n - 1)
+	r.acc >>= n
+	r.nbits -= n
+	return v, nil
+}
+
+// decompressSplitStream decodes a PipelineSplitStream payload into dst, or
+// into a new slice if dst is nil.
+func (d *Decoder) decompressSplitStream(dst []uint16, payload []byte, width, height int, maxValue uint16) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	if maxValue == 0 {
+		return nil, errors.New("split stream: zero max value")
+	}
+	if len(payload) < splitHeaderSize {
+		return nil, errors.New("split stream: payload truncated")
+	}
+	depth := bits.Len16(maxValue)
+	shift := int(payload[0])
+	if shift < 1 || shift > depth {
+		return nil, fmt.Errorf("split stream: escape shift %d outside 1..%d", shift, depth)
+	}
+	escape := uint32(1) << shift
+	if shift == depth {
+		escape = 1 << 16 // no literal matches
+	}
+
+	n := width * height
+	var streams [2][]uint16
+	pos := splitHeaderSize
+	for s := range streams {
+		length := int(binary.LittleEndian.Uint32(payload[3+4*s:]))
+		if length > len(payload)-pos {
+			return nil, errors.New("split stream: stream truncated")
+		}
+		if length > 0 {
+			symbols, err := unpackSymbols(payload[1+s], payload[pos:pos+length], symbolLimit(n))
+			if err != nil {
+				return nil, fmt.Errorf("split stream: %w", err)
+			}
+			streams[s] = symbols
+		}
+		pos += length
+	}
+	escLen := int(binary.LittleEndian.Uint32(payload[11:]))
+	if escLen != len(payload)-pos {
+		return nil, fmt.Errorf("split stream: escape stream length %d, %d bytes left", escLen, len(payload)-pos)
+	}
+	runs, lits := streams[0], streams[1]
+	if len(runs)%2 != 0 {
+		return nil, errors.New("split stream: odd run stream length")
+	}
+	esc := splitBitReader{in: payload[pos:]}
+
+	out := dst
+	if out == nil {
+		out = make([]uint16, n)
+	}
+	out = out[:n]
+	mask := int32(1)<<depth - 1
+	var pendingLits, pendingRun, ri, li int
+	var runValue int32
+	x, y := 0, 0
+	for i := range out {
+		for pendingLits == 0 && pendingRun == 0 {
+			if ri == len(runs) {
+				return nil, errors.New("split stream: run stream exhausted")
+			}
+			pendingLits, pendingRun = int(runs[ri]), int(runs[ri+1])
+			ri += 2
+			if pendingLits == 0 && pendingRun == 0 {
+				return nil, errors.New("split stream: empty run pair")
+			}
+			if pendingLits == 0 {
+				if li == len(lits) || uint32(lits[li]) == escape {
+					return nil, errors.New("split stream: invalid run value")
+				}
+				runValue = int32(lits[li])
+				li++
+			}
+		}
+
+		var z int32
+		if pendingLits > 0 {
+			if li == len(lits) {
+				return nil, errors.New("split stream: literal stream exhausted")
+			}
+			lit := lits[li]
+			li++
+			pendingLits--
+			if pendingLits == 0 && pendingRun > 0 {
+				if li == len(lits) || uint32(lits[li]) == escape {
+					return nil, errors.New("split stream: invalid run value")
+				}
+				runValue = int32(lits[li])
+				li++
+			}
+			if uint32(lit) == escape {
+				v, err := esc.read(depth)
+				if err != nil {
+					return nil, err
+				}
+				out[i] = uint16(v)
+				if x++; x == width {
+					x, y = 0, y+1
+				}
+				continue
+			}
+			z = int32(lit)
+		} else {
+			z = runValue
+			pendingRun--
+		}
+		out[i] = uint16((splitPredict(out, i, x, y, width) + (z>>1 ^ -(z & 1))) & mask)
+		if x++; x == width {
+			x, y = 0, y+1
+		}
+	}
+	if ri != len(runs) || li != len(lits) || pendingLits != 0 || pendingRun != 0 {
+		return nil, errors.New("split stream: unused symbols")
+	}
+	if len(esc.in) > 0 {
+		return nil, errors.New("split stream: unused escape bytes")
+	}
+	return out, nil
+}
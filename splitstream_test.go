@@ -0,0 +1,144 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestSplitStreamRoundtrip verifies pixel-exact roundtrip on MR, CT and
+// images that exercise escapes, long runs and the count limits.
+func TestSplitStreamRoundtrip(t *testing.T) {
+	for _, td := range testFiles[:2] { // MR 256x256, CT 512x512
+		_, pixels, maxVal, width, height := SetupTests(td)
+		blob, err := CompressSplitStream(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: compress: %v", td.name, err)
+		}
+		img, err := Decode(blob)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", td.name, err)
+		}
+		assertPixelsEqual(t, pixels, img.Pixels, td.name)
+	}
+
+	// Noise over a flat background: many escapes between long runs.
+	spiky := make([]uint16, 300*300)
+	for i := range spiky {
+		if i%97 == 0 {
+			spiky[i] = uint16(i * 7919)
+		}
+	}
+	// Runs and literal stretches longer than splitMaxCount.
+	long := make([]uint16, 400*400)
+	for i := 100000; i < len(long); i++ {
+		long[i] = uint16(i)
+	}
+	ramp := make([]uint16, 17*5)
+	for i := range ramp {
+		ramp[i] = uint16(i * 771)
+	}
+	for _, tc := range []struct {
+		name          string
+		pixels        []uint16
+		width, height int
+	}{
+		{"flat", make([]uint16, 64*64), 64, 64},
+		{"single", []uint16{42}, 1, 1},
+		{"row", []uint16{1, 5, 2, 9, 9, 9, 9, 0}, 8, 1},
+		{"column", []uint16{1, 5, 2, 9, 9, 0}, 1, 6},
+		{"spiky", spiky, 300, 300},
+		{"long", long, 400, 400},
+		{"ramp16", ramp, 17, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			blob, err := CompressSplitStream(tc.pixels, tc.width, tc.height, 0)
+			if err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			got, _, _, err := Decompress(blob)
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			assertPixelsEqual(t, tc.pixels, got, tc.name)
+		})
+	}
+
+	pixels, _ := fuzzPixels()
+	blob, err := CompressSplitStream(pixels, fuzzWidth, fuzzHeight, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]uint16, fuzzWidth*fuzzHeight)
+	if _, _, err := DecompressInto(dst, blob); err != nil {
+		t.Fatal(err)
+	}
+	assertPixelsEqual(t, pixels, dst, "DecompressInto")
+	if _, _, _, err := Decompress(blob[:len(blob)-1]); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+	if _, err := CompressSplitStream(pixels, fuzzWidth, fuzzHeight, 1); err == nil {
+		t.Error("expected error for pixels above max value")
+	}
+}
+
+// TestSplitStreamComparisonTable prints the split-stream ratio against the
+// single-alphabet Delta+RLE+FSE stream, with the escape shift chosen.
+func TestSplitStreamComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== Compression ratio: single alphabet vs split streams ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %7s  %7s  %6s  %8s  %8s  %8s\n", "Image", "MIC", "Split", "Shift", "Runs", "Literals", "Escapes")
+	fmt.Println("------  -------  -------  ------  --------  --------  --------")
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		if len(pixels) == 0 {
+			continue
+		}
+		orig := float64(width * height * 2)
+
+		mic, err := CompressSingleFrame(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: MIC: %v", td.name, err)
+		}
+		split, err := CompressSplitStream(pixels, width, height, maxVal)
+		if err != nil {
+			t.Fatalf("%s: split: %v", td.name, err)
+		}
+		_, payload, err := ReadMIC1Header(split)
+		if err != nil {
+			t.Fatalf("%s: split header: %v", td.name, err)
+		}
+		le := binary.LittleEndian
+		fmt.Printf("%-6s  %6.2fx  %6.2fx  %6d  %8d  %8d  %8d\n", td.name,
+			orig/float64(len(mic)), orig/float64(len(split)), payload[0],
+			le.Uint32(payload[3:]), le.Uint32(payload[7:]), le.Uint32(payload[11:]))
+	}
+	fmt.Println()
+}
+
+func BenchmarkSplitStreamDecompress(b *testing.B) {
+	_, pixels, maxVal, width, height := SetupTests(testFiles[1]) // CT
+	blob, err := CompressSplitStream(pixels, width, height, maxVal)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dst := make([]uint16, width*height)
+	b.SetBytes(int64(width * height * 2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := DecompressInto(dst, blob); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// FSE table reuse across the units of a container.
+//
+// Consecutive MIC2 frames and neighbouring MIC3 tiles have nearly the same
+// residual histogram, yet each unit normally carries its own FSE table. A
+// reference stream codes a unit with the table of another unit instead:
+//
+//	[0xFF][0x41][unit uint32 LE][escape uint16 LE][count uint32 LE]
+//	[escapes uint32 LE][escapes x uint16 LE][bitstream]
+//
+// unit is the index of the unit whose table is reused, or RefPrevious for
+// the unit before this one. That unit's stream must be two-, four- or
+// eight-state FSE, which carry their table in the header, so decoding a
+// unit reads at most the header of one other unit and random access is
+// kept. The bitstream is four-state FSE. escape is a symbol of the reused
+// table; symbols the table has no slot for, and escape itself, are coded as
+// escape with their values stored raw in the escape list, in stream order,
+// as in dictionary streams.
+//
+// The decoder asks ScratchU16.Reference for the stream of the named unit.
+// MIC2 and MIC3 readers resolve units to frames and to the same plane of
+// other tiles; MultiFrameOptions.ReuseTables and WSIOptions.ReuseTables
+// enable the encoders.
+
+const (
+	refMagic0 = 0xFF
+	refMagic1 = 0x41
+
+	refStreamHeaderSize = 16 // magic + unit + escape + count + escape count
+
+	// RefPrevious names the unit before the one being decoded in a
+	// FSECompressU16Ref stream.
+	RefPrevious = math.MaxUint32
+
+	// refRecentUnits is how many of the latest units with their own table
+	// the encoders try, besides the previous and neighbouring units.
+	refRecentUnits = 4
+)
+
+// readStreamTable loads the table of a two-, four- or eight-state FSE
+// stream into s.
+func (s *ScratchU16) readStreamTable(b []byte) error {
+	if len(b) < 6 || b[0] != twoStateMagic0 || (b[1] != twoStateMagic1 && b[1] != fourStateMagic1 && b[1] != eightStateFSEMagic1) {
+		return errors.New("unit carries no FSE table")
+	}
+	s.brForDecomp.init(b[6:])
+	return s.readNCount()
+}
+
+// streamTable returns the table of a two-, four- or eight-state FSE stream.
+func (s *ScratchU16) streamTable(b []byte) (blockTable, error) {
+	if err := s.readStreamTable(b); err != nil {
+		return blockTable{}, err
+	}
+	return blockTable{norm: append([]int32(nil), s.norm[:s.symbolLen]...), tableLog: s.actualTableLog}, nil
+}
+
+// refEscape returns the symbol of t that occurs least in the histogram
+// count, the most probable in t among ties.
+func refEscape(t *blockTable, count []uint32) uint16 {
+	best, bestCount, bestNorm := 0, uint32(math.MaxUint32), int32(0)
+	for v, n := range t.norm {
+		if n == 0 {
+			continue
+		}
+		c := uint32(0)
+		if v < len(count) {
+			c = count[v]
+		}
+		if c < bestCount || (c == bestCount && n > bestNorm) {
+			best, bestCount, bestNorm = v, c, n
+		}
+	}
+	return uint16(best)
+}
+
+// FSECompressU16Ref compresses in with the table of table, the two-, four-
+// or eight-state FSE stream of the unit named by unit, which is either that
+// unit's index or RefPrevious. FSEDecompressU16Auto and FSEDecompressU16Ref
+// read the result given a ScratchU16.Reference that returns table for unit.
+func FSECompressU16Ref(in []uint16, unit uint32, table []byte, s *ScratchU16) ([]byte, error) {
+	var ts ScratchU16
+	t, err := ts.streamTable(table)
+	if err != nil {
+		return nil, fmt.Errorf("fseref: %w", err)
+	}
+	return compressRef(in, unit, &t, symbolCounts(in), s)
+}
+
+// compressRef is FSECompressU16Ref with the table already read and the
+// histogram of in.
+func compressRef(in []uint16, unit uint32, t *blockTable, count []uint32, s *ScratchU16) ([]byte, error) {
+	if len(in) <= 3 {
+		return nil, ErrIncompressible
+	}
+	if uint64(len(in)) > 1<<32-1 {
+		return nil, fmt.Errorf("fseref: %d symbols exceed the stream limit", len(in))
+	}
+	s, err := s.prepare(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	escape := refEscape(t, count)
+	escaped, err := s.compressEscaped(in, t, escape)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, refStreamHeaderSize-4, refStreamHeaderSize+2*len(escaped)+len(s.bw.out))
+	out[0], out[1] = refMagic0, refMagic1
+	binary.LittleEndian.PutUint32(out[2:6], unit)
+	binary.LittleEndian.PutUint16(out[6:8], escape)
+	binary.LittleEndian.PutUint32(out[8:12], uint32(len(in)))
+	out = appendEscapes(out, escaped)
+	return append(out, s.bw.out...), nil
+}
+
+// FSEDecompressU16Ref decompresses a stream produced by FSECompressU16Ref,
+// reading the table of the unit it names from s.Reference.
+func FSEDecompressU16Ref(b []byte, s *ScratchU16) ([]uint16, error) {
+	if len(b) < refStreamHeaderSize || b[0] != refMagic0 || b[1] != refMagic1 {
+		return nil, errors.New("fseref: missing magic bytes")
+	}
+	unit := binary.LittleEndian.Uint32(b[2:6])
+	escape := binary.LittleEndian.Uint16(b[6:8])
+	count := int(binary.LittleEndian.Uint32(b[8:12]))
+	s, err := s.prepare(nil, b)
+	if err != nil {
+		return nil, err
+	}
+	if count > s.DecompressLimit {
+		return nil, fmt.Errorf("output size (%d) > DecompressLimit (%d)", count, s.DecompressLimit)
+	}
+	if count <= 3 {
+		return nil, fmt.Errorf("fseref: %d-symbol stream", count)
+	}
+	esc, bitstream, err := readEscapes(b, refStreamHeaderSize-4, count)
+	if err != nil {
+		return nil, fmt.Errorf("fseref: %w", err)
+	}
+	if s.Reference == nil {
+		return nil, errors.New("fseref: no Reference to resolve units")
+	}
+	table, err := s.Reference(unit)
+	if err != nil {
+		return nil, fmt.Errorf("fseref: unit %d: %w", unit, err)
+	}
+	if err := s.readStreamTable(table); err != nil {
+		return nil, fmt.Errorf("fseref: unit %d: %w", unit, err)
+	}
+	if int(escape) >= int(s.symbolLen) || s.norm[escape] == 0 {
+		return nil, fmt.Errorf("fseref: escape %d outside the table of unit %d", escape, unit)
+	}
+	if err := s.buildDtable(); err != nil {
+		return nil, err
+	}
+	s.brForDecomp.init(b[bitstream:])
+	s.OutU16 = s.OutU16[:0]
+	if err := s.decompress4State(count); err != nil {
+		return nil, fmt.Errorf("fseref: %w", err)
+	}
+	if err := unescape(s.OutU16, escape, esc); err != nil {
+		return nil, fmt.Errorf("fseref: %w", err)
+	}
+	return s.OutU16, nil
+}
+
+// tableReuse picks, unit by unit in order, between a unit's own stream and a
+// reference to the table of an earlier unit that carries its own.
+type tableReuse struct {
+	window int // units back that code may name in near
+
+	s, enc ScratchU16
+	tables map[int]*blockTable // units whose chosen stream carries a table
+	recent []int               // the latest of them, oldest first
+}
+
+// code returns the stream to store for unit: own, its symbols coded with
+// their own table, or a reference stream when one is smaller, reporting
+// which. The previous unit, the units in near and the most recent units
+// with a table are tried, the estimated cost deciding which ones get coded.
+func (r *tableReuse) code(unit int, symbols []uint16, own []byte, near ...int) ([]byte, bool) {
+	if r.tables == nil {
+		r.tables = map[int]*blockTable{}
+	}
+	count := symbolCounts(symbols)
+	best, reused := own, false
+	tried := map[int]bool{}
+	for _, c := range append(append([]int{unit - 1}, near...), r.recent...) {
+		t, ok := r.tables[c]
+		if !ok || tried[c] {
+			continue
+		}
+		tried[c] = true
+		escape := refEscape(t, count)
+		if t.escapeCost(count, escape)/8+refStreamHeaderSize >= float64(len(best)) {
+			continue
+		}
+		ref := uint32(c)
+		if c == unit-1 {
+			ref = RefPrevious
+		}
+		if b, err := compressRef(symbols, ref, t, count, &r.enc); err == nil && len(b) < len(best) {
+			best, reused = b, true
+		}
+	}
+	if !reused {
+		if t, err := r.s.streamTable(own); err == nil {
+			r.tables[unit] = &t
+			r.recent = append(r.recent, unit)
+			if len(r.recent) > refRecentUnits {
+				r.recent = r.recent[1:]
+			}
+		}
+	}
+	keep := unit - r.window
+	if len(r.recent) > 0 {
+		keep = min(keep, r.recent[0])
+	}
+	for u := range r.tables {
+		if u < keep {
+			delete(r.tables, u)
+		}
+	}
+	return best, reused
+}
+
+// streamRefs is what the entropy stream of a unit may name outside itself:
+// a dictionary, and the streams of other units whose table it reuses.
+type streamRefs struct {
+	dict *Dictionary
+	unit func(n uint32) ([]byte, error) // the stream of unit n, RefPrevious resolved
+}
+
+// apply points s at r.
+func (r streamRefs) apply(s *ScratchU16) {
+	s.Dictionary, s.Reference = r.dict, r.unit
+}
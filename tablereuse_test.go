@@ -0,0 +1,257 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestFSERefRoundtrip verifies reference streams against the tables of two-,
+// four- and eight-state streams, escapes included, and their errors.
+func TestFSERefRoundtrip(t *testing.T) {
+	frames, maxValue := makeSmoothFrames(128, 128, 2, 21)
+	var drc DeltaRleCompressU16
+	a, err := drc.Compress(frames[0], 128, 128, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = append([]uint16(nil), a...)
+	b, err := drc.Compress(frames[1], 128, 128, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, 65000, 65001) // symbols no table has a slot for
+
+	for _, compress := range []func([]uint16, *ScratchU16) ([]byte, error){
+		FSECompressU16TwoState,
+		FSECompressU16FourState,
+		FSECompressU16EightState,
+	} {
+		table, err := compress(a, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, unit := range []uint32{RefPrevious, 7} {
+			ref, err := FSECompressU16Ref(b, unit, table, nil)
+			if err != nil {
+				t.Fatalf("compress: %v", err)
+			}
+			if n := binary.LittleEndian.Uint32(ref[12:16]); n < 2 {
+				t.Errorf("%d escapes, want at least 2", n)
+			}
+			s := ScratchU16{Reference: func(u uint32) ([]byte, error) {
+				if u != unit {
+					return nil, fmt.Errorf("unit %d, want %d", u, unit)
+				}
+				return table, nil
+			}}
+			got, err := FSEDecompressU16Auto(ref, &s)
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			assertPixelsEqual(t, b, got, "reference stream")
+		}
+	}
+
+	table, err := FSECompressU16FourState(a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := FSECompressU16Ref(b, RefPrevious, table, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(u uint32) ([]byte, error) { return table, nil }
+	if _, err := FSEDecompressU16Auto(ref, nil); err == nil {
+		t.Error("expected error without a Reference")
+	}
+	if _, err := FSEDecompressU16Ref(ref, &ScratchU16{Reference: func(uint32) ([]byte, error) { return nil, errors.New("gone") }}); err == nil {
+		t.Error("expected error for an unresolved unit")
+	}
+	if _, err := FSEDecompressU16Ref(ref, &ScratchU16{Reference: func(uint32) ([]byte, error) { return ref, nil }}); err == nil {
+		t.Error("expected error for a unit without its own table")
+	}
+	if _, err := FSEDecompressU16Ref(ref, &ScratchU16{Reference: resolve, DecompressLimit: len(b) - 1}); err == nil {
+		t.Error("expected error above DecompressLimit")
+	}
+	if _, err := FSEDecompressU16Ref(ref[:len(ref)/2], &ScratchU16{Reference: resolve}); err == nil {
+		t.Error("expected error for truncated stream")
+	}
+	if _, err := FSECompressU16Ref(b, 0, ref, nil); err == nil {
+		t.Error("expected error for a table stream without a table")
+	}
+}
+
+// TestTableReuseMIC2 verifies that MIC2 frames reuse earlier tables, and
+// that every frame still decodes on its own.
+func TestTableReuseMIC2(t *testing.T) {
+	frames, maxValue := makeSmoothFrames(128, 128, 6, 4)
+	for _, temporal := range []bool{false, true} {
+		opts := MultiFrameOptions{Temporal: temporal, Coder: CoderFSE4}
+		plain, err := CompressMultiFrameOptions(frames, 128, 128, maxValue, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.ReuseTables = true
+		reused, err := CompressMultiFrameOptions(frames, 128, 128, maxValue, opts)
+		if err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		hdr, entries, dataOffset, err := ReadMIC2Header(reused)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := 0
+		for i := range entries {
+			if b, _ := ExtractFrame(reused, entries, dataOffset, i); b[0] == refMagic0 && b[1] == refMagic1 {
+				refs++
+			}
+		}
+		t.Logf("temporal=%v: MIC2 %d bytes, with table reuse %d bytes, %d of %d frames reuse a table",
+			temporal, len(plain), len(reused), refs, hdr.FrameCount)
+		if refs == 0 || len(reused) >= len(plain) {
+			t.Errorf("temporal=%v: %d frames reuse a table, %d bytes against %d", temporal, refs, len(reused), len(plain))
+		}
+
+		got, _, err := DecompressMultiFrame(reused)
+		if err != nil {
+			t.Fatalf("decompress: %v", err)
+		}
+		for i := range frames {
+			assertPixelsEqual(t, frames[i], got[i], fmt.Sprintf("frame %d", i))
+			frame, _, err := DecompressFrame(reused, i)
+			if err != nil {
+				t.Fatalf("DecompressFrame %d: %v", i, err)
+			}
+			assertPixelsEqual(t, frames[i], frame, fmt.Sprintf("DecompressFrame %d", i))
+		}
+	}
+}
+
+// TestTableReuseWSI verifies that MIC3 tile planes reuse the tables of
+// other tiles and that every tile still decodes on its own.
+func TestTableReuseWSI(t *testing.T) {
+	const w, h = 512, 384
+	rgb := makeWSITestImage(w, h, 9)
+	opts := WSIOptions{TileWidth: 128, TileHeight: 128, Checksums: true}
+	plain, err := CompressWSI(rgb, w, h, 3, 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.ReuseTables = true
+	reused, err := CompressWSI(rgb, w, h, 3, 8, opts)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	t.Logf("MIC3 %d bytes, with table reuse %d bytes", len(plain), len(reused))
+	if len(reused) >= len(plain) {
+		t.Errorf("table reuse file %d bytes, not below %d", len(reused), len(plain))
+	}
+	if err := VerifyChecksums(reused); err != nil {
+		t.Fatalf("VerifyChecksums: %v", err)
+	}
+
+	hdr, err := ReadWSIHeader(reused)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, lv := range hdr.Levels {
+		for ty := range lv.TilesY {
+			for tx := range lv.TilesX {
+				want, err := DecompressWSITile(plain, level, tx, ty)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := DecompressWSITile(reused, level, tx, ty)
+				if err != nil {
+					t.Fatalf("tile (%d,%d) level %d: %v", tx, ty, level, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("tile (%d,%d) level %d: pixel mismatch", tx, ty, level)
+				}
+			}
+		}
+	}
+	got, err := DecompressWSIRegion(reused, 0, 0, 0, w, h)
+	if err != nil {
+		t.Fatalf("region: %v", err)
+	}
+	if !bytes.Equal(got, rgb) {
+		t.Fatal("region pixel mismatch")
+	}
+}
+
+// TestTableReuseComparisonTable prints the size of 16-bit greyscale MIC3
+// files of 128x128 tiles with and without table reuse.
+func TestTableReuseComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== MIC3 128x128 tiles: own FSE tables vs table reuse ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %5s  %9s  %9s  %7s\n", "Image", "Tiles", "Own", "Reuse", "Saving")
+	fmt.Println("------  -----  ---------  ---------  -------")
+
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, _, width, height := SetupTests(td)
+		if len(pixels) == 0 {
+			continue
+		}
+		raw := uint16ToBytes(pixels, 16)
+		opts := WSIOptions{TileWidth: 128, TileHeight: 128, PyramidLevels: 1}
+		own, err := CompressWSI(raw, width, height, 1, 16, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", td.name, err)
+		}
+		opts.ReuseTables = true
+		reused, err := CompressWSI(raw, width, height, 1, 16, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", td.name, err)
+		}
+		got, err := DecompressWSIRegion(reused, 0, 0, 0, width, height)
+		if err != nil {
+			t.Fatalf("%s: decompress: %v", td.name, err)
+		}
+		if !bytes.Equal(got, raw) {
+			t.Fatalf("%s: pixel mismatch", td.name)
+		}
+		tiles := ((width + 127) / 128) * ((height + 127) / 128)
+		fmt.Printf("%-6s  %5d  %9d  %9d  %6.1f%%\n", td.name, tiles, len(own), len(reused),
+			100*(1-float64(len(reused))/float64(len(own))))
+	}
+	fmt.Println()
+}
+
+func BenchmarkFSERefDecompress(b *testing.B) {
+	_, pixels, maxVal, width, height := SetupTests(testFiles[1]) // CT
+	var drc DeltaRleCompressU16
+	symbols, err := drc.Compress(pixels, width, height, maxVal)
+	if err != nil {
+		b.Fatal(err)
+	}
+	table, err := FSECompressU16FourState(symbols, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	comp, err := FSECompressU16Ref(symbols, RefPrevious, table, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := ScratchU16{Reference: func(uint32) ([]byte, error) { return table, nil }}
+	b.SetBytes(int64(width * height * 2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FSEDecompressU16Ref(comp, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Pixel-value packing.
+//
+// Many images use a sparse subset of their value range: 12-bit data stored
+// shifted left with the low bits zero, rescaled CT whose values step by a
+// common divisor, MR with a few hundred distinct intensities. Gap removal
+// compacts the residual alphabet after prediction, but by then the
+// predictor has seen the gaps: every residual is a multiple of the step and
+// the Delta+RLE bit depth follows the raw maxValue. packValues instead
+// remaps the pixels onto a dense range before prediction, and Compress
+// stores the map once, after the options header:
+//
+//	Byte 0: kind
+//	  0x00 = none; the pixels are coded as they are
+//	  0x01 = affine: base (uint16 LE), step (uint16 LE); value = base + step*p
+//	  0x02 = table: the sorted distinct values as a gap removal map (raw,
+//	         bitmap or delta list, whichever is smallest); value = table[p]
+//
+// The affine map covers a common shift, a common divisor and an offset for
+// five bytes. The table is tried when the values leave gaps in the affine
+// range. It is kept when the bit lengths of the left-neighbour differences
+// it yields, plus its size, come out at least 1/32 below the affine map's:
+// the estimate overstates the gain, and on CT, whose few large jumps the
+// Delta+RLE escape already codes cheaply, a smaller margin loses.
+
+// Value map kinds (byte 0 of a value map).
+const (
+	valueMapNone   = 0x00
+	valueMapAffine = 0x01
+	valueMapTable  = 0x02
+)
+
+// valueMap maps packed samples back to pixel values.
+type valueMap struct {
+	kind       byte
+	base, step uint16   // valueMapAffine
+	table      []uint16 // valueMapTable
+}
+
+// packValues chooses the value map for pixels, rows of width samples, and
+// returns it with the packed pixels. When no map applies it returns the
+// valueMapNone map and pixels itself.
+func packValues(pixels []uint16, width int) (valueMap, []uint16) {
+	if len(pixels) == 0 {
+		return valueMap{}, pixels
+	}
+	used := make([]bool, 1<<16)
+	for _, v := range pixels {
+		used[v] = true
+	}
+	var values []uint16
+	step := 0
+	for v, ok := range used {
+		if !ok {
+			continue
+		}
+		if len(values) > 0 {
+			step = gcd(step, v-int(values[0]))
+		}
+		values = append(values, uint16(v))
+	}
+	lo, hi := values[0], values[len(values)-1]
+	step = max(step, 1)
+
+	m := valueMap{kind: valueMapAffine, base: lo, step: uint16(step)}
+	if lo == 0 && step == 1 {
+		m = valueMap{}
+	}
+	if len(values)-1 < int(hi-lo)/step {
+		// Rank every value in both domains and compare the residual estimates.
+		affine, rank := make([]uint16, 1<<16), make([]uint16, 1<<16)
+		for i, v := range values {
+			affine[v], rank[v] = (v-lo)/uint16(step), uint16(i)
+		}
+		t := valueMap{kind: valueMapTable, table: values}
+		cost := residualBits(pixels, width, affine) + 8*len(m.marshal())
+		if residualBits(pixels, width, rank)+8*len(t.marshal()) < cost-cost/32 {
+			m = t
+		}
+	}
+	if m.kind == valueMapNone {
+		return m, pixels
+	}
+	return m, m.pack(pixels)
+}
+
+// gcd returns the greatest common divisor of a and b, which are not negative.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// residualBits estimates the cost of coding pixels mapped through index: the
+// summed bit lengths of each sample's zigzagged difference from its left
+// neighbour, or from the sample above at the start of a row.
+func residualBits(pixels []uint16, width int, index []uint16) int {
+	n := 0
+	for i := 1; i < len(pixels); i++ {
+		ref := i - 1
+		if i%width == 0 {
+			ref = i - width
+		}
+		d := int32(index[pixels[i]]) - int32(index[pixels[ref]])
+		n += bits.Len32(uint32(d<<1 ^ d>>31))
+	}
+	return n
+}
+
+// pack returns pixels mapped to their packed samples under an affine or
+// table map.
+func (m *valueMap) pack(pixels []uint16) []uint16 {
+	packed := make([]uint16, len(pixels))
+	switch m.kind {
+	case valueMapAffine:
+		for i, v := range pixels {
+			packed[i] = (v - m.base) / m.step
+		}
+	case valueMapTable:
+		rank := make([]uint16, 1<<16)
+		for i, v := range m.table {
+			rank[v] = uint16(i)
+		}
+		for i, v := range pixels {
+			packed[i] = rank[v]
+		}
+	}
+	return packed
+}
+
+// limit returns the largest packed sample m maps back to a pixel value.
+func (m *valueMap) limit() int {
+	if m.kind == valueMapTable {
+		return len(m.table) - 1
+	}
+	return int(0xFFFF-m.base) / int(m.step)
+}
+
+// marshal serialises m, kind byte included.
+func (m *valueMap) marshal() []byte {
+	switch m.kind {
+	case valueMapAffine:
+		b := []byte{valueMapAffine, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint16(b[1:3], m.base)
+		binary.LittleEndian.PutUint16(b[3:5], m.step)
+		return b
+	case valueMapTable:
+		return append([]byte{valueMapTable}, gapMapHeader(m.table)...)
+	}
+	return []byte{valueMapNone}
+}
+
+// readValueMap parses the value map at the start of b and returns it with
+// the number of bytes it occupies.
+func readValueMap(b []byte) (valueMap, int, error) {
+	if len(b) < 1 {
+		return valueMap{}, 0, errors.New("value map: missing")
+	}
+	switch b[0] {
+	case valueMapNone:
+		return valueMap{}, 1, nil
+	case valueMapAffine:
+		if len(b) < 5 {
+			return valueMap{}, 0, errors.New("value map: affine map truncated")
+		}
+		m := valueMap{kind: valueMapAffine, base: binary.LittleEndian.Uint16(b[1:3]), step: binary.LittleEndian.Uint16(b[3:5])}
+		if m.step == 0 {
+			return valueMap{}, 0, errors.New("value map: zero step")
+		}
+		return m, 5, nil
+	case valueMapTable:
+		table, rest, err := readGapMap(b[1:])
+		if err != nil {
+			return valueMap{}, 0, fmt.Errorf("value map: %w", err)
+		}
+		if len(table) == 0 {
+			return valueMap{}, 0, errors.New("value map: empty table")
+		}
+		return valueMap{kind: valueMapTable, table: table}, len(b) - len(rest), nil
+	}
+	return valueMap{}, 0, fmt.Errorf("value map: unknown kind 0x%02x", b[0])
+}
+
+// unpack maps packed samples back to pixel values in place.
+func (m *valueMap) unpack(samples []uint16) error {
+	switch m.kind {
+	case valueMapAffine:
+		limit := m.limit()
+		for i, p := range samples {
+			if int(p) > limit {
+				return fmt.Errorf("value map: packed sample %d above %d", p, limit)
+			}
+			samples[i] = m.base + m.step*p
+		}
+	case valueMapTable:
+		for i, p := range samples {
+			if int(p) >= len(m.table) {
+				return fmt.Errorf("value map: packed sample %d outside a %d-value table", p, len(m.table))
+			}
+			samples[i] = m.table[p]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+)
+
+// sparseVariants returns pixels with their values spread out as sparse
+// images store them: shifted left by up to four bits, rescaled by a divisor
+// with an offset, and mapped onto an irregular set of values. Variants that
+// would not fit in 16 bits are left out.
+func sparseVariants(pixels []uint16, maxValue uint16, seed int64) map[string][]uint16 {
+	rng := rand.New(rand.NewSource(seed))
+	table := make([]int, int(maxValue)+1)
+	for i := 1; i < len(table); i++ {
+		table[i] = table[i-1] + 1 + rng.Intn(3)
+	}
+	shift := min(4, 16-bits.Len16(maxValue))
+	div := min(7, (0xFFFF-100)/max(int(maxValue), 1))
+
+	out := map[string][]uint16{}
+	if shift > 0 {
+		out["shifted"] = make([]uint16, len(pixels))
+	}
+	if div > 1 {
+		out["divisor"] = make([]uint16, len(pixels))
+	}
+	if table[maxValue] <= 0xFFFF {
+		out["table"] = make([]uint16, len(pixels))
+	}
+	for i, v := range pixels {
+		if s := out["shifted"]; s != nil {
+			s[i] = v << shift
+		}
+		if d := out["divisor"]; d != nil {
+			d[i] = uint16(100 + int(v)*div)
+		}
+		if t := out["table"]; t != nil {
+			t[i] = uint16(table[v])
+		}
+	}
+	return out
+}
+
+// TestValuePackingRoundtrip verifies Compress with value packing on shifted,
+// rescaled, sparse and constant images, checks which map each one stores,
+// and the decoder's errors.
+func TestValuePackingRoundtrip(t *testing.T) {
+	const width, height = 160, 120
+	frames, maxValue := makeSmoothFrames(width, height, 1, 25)
+	inputs := sparseVariants(frames[0], maxValue, 25)
+	inputs["offset"] = frames[0] // its smallest value is above zero
+	inputs["constant"] = slices.Repeat([]uint16{1234}, width*height)
+	inputs["zero"] = make([]uint16, width*height)
+	wantKind := map[string]byte{
+		"shifted":  valueMapAffine,
+		"divisor":  valueMapAffine,
+		"table":    valueMapTable,
+		"offset":   valueMapAffine,
+		"constant": valueMapAffine,
+		"zero":     valueMapNone,
+	}
+
+	for name, pixels := range inputs {
+		for _, opts := range []CompressOptions{
+			{ValuePacking: true},
+			{ValuePacking: true, Strips: 3, GapRemoval: true, Coder: CoderFSE4},
+			{ValuePacking: true, Predictor: PredictorMED, Coder: CoderHuffman},
+			{ValuePacking: true, WaveletLevels: 3},
+		} {
+			if opts.WaveletLevels > 0 && slices.Min(pixels) == slices.Max(pixels) {
+				continue // the wavelet cannot code a constant image at all
+			}
+			label := fmt.Sprintf("%s %+v", name, opts)
+			data, err := Compress(pixels, width, height, opts)
+			if err != nil {
+				t.Fatalf("%s: compress: %v", label, err)
+			}
+			_, payload, err := ReadMIC1Header(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payload[optionsHeaderSize] != wantKind[name] {
+				t.Errorf("%s: value map kind %d, want %d", label, payload[optionsHeaderSize], wantKind[name])
+			}
+			got, _, _, err := Decompress(data)
+			if err != nil {
+				t.Fatalf("%s: decompress: %v", label, err)
+			}
+			assertPixelsEqual(t, pixels, got, label)
+			if read, err := ReadCompressOptions(data); err != nil || !read.ValuePacking {
+				t.Errorf("%s: ReadCompressOptions %+v, %v", label, read, err)
+			}
+		}
+	}
+
+	data, err := Compress(inputs["shifted"], width, height, CompressOptions{ValuePacking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, payload, err := ReadMIC1Header(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := slices.Max(inputs["shifted"]); hdr.MaxValue != want {
+		t.Errorf("header maxValue %d, want the unpacked %d", hdr.MaxValue, want)
+	}
+	var d Decoder
+	bad := slices.Clone(payload)
+	bad[optionsHeaderSize+3], bad[optionsHeaderSize+4] = 0, 0 // zero step
+	if _, err := d.decompressOptions(nil, bad, width, height); err == nil {
+		t.Error("expected error for a zero step")
+	}
+	if _, err := d.decompressOptions(nil, payload[:optionsHeaderSize+2], width, height); err == nil {
+		t.Error("expected error for a truncated value map")
+	}
+
+	m := valueMap{kind: valueMapTable, table: []uint16{3, 9}}
+	if err := m.unpack([]uint16{0, 1, 2}); err == nil {
+		t.Error("expected error for a packed sample outside the table")
+	}
+	m = valueMap{kind: valueMapAffine, base: 0xFF00, step: 16}
+	if err := m.unpack([]uint16{15, 16}); err == nil {
+		t.Error("expected error for a packed sample that overflows")
+	}
+}
+
+// TestValuePackingComparisonTable prints the size of Compress output with
+// and without value packing for each test image, as stored and with its
+// values shifted, rescaled or spread over a sparse set.
+func TestValuePackingComparisonTable(t *testing.T) {
+	fmt.Println()
+	fmt.Println("=== Compress: without vs with value packing ===")
+	fmt.Println()
+	fmt.Printf("%-6s  %-8s  %-6s  %10s  %10s  %7s\n", "Image", "Values", "Map", "Plain", "Packed", "Saving")
+	fmt.Println("------  --------  ------  ----------  ----------  -------")
+
+	kinds := map[byte]string{valueMapNone: "none", valueMapAffine: "affine", valueMapTable: "table"}
+	for _, td := range testFiles {
+		if _, err := os.Stat(td.fileName); err != nil {
+			t.Logf("skip %s: %v", td.name, err)
+			continue
+		}
+		_, pixels, maxVal, width, height := SetupTests(td)
+		if len(pixels) == 0 {
+			continue
+		}
+		variants := sparseVariants(pixels, maxVal, 1)
+		variants["stored"] = pixels
+		for _, name := range []string{"stored", "shifted", "divisor", "table"} {
+			in, ok := variants[name]
+			if !ok {
+				continue
+			}
+			plain, err := Compress(in, width, height, CompressOptions{})
+			if err != nil {
+				t.Fatalf("%s/%s: %v", td.name, name, err)
+			}
+			packed, err := Compress(in, width, height, CompressOptions{ValuePacking: true})
+			if err != nil {
+				t.Fatalf("%s/%s: %v", td.name, name, err)
+			}
+			got, _, _, err := Decompress(packed)
+			if err != nil {
+				t.Fatalf("%s/%s: decompress: %v", td.name, name, err)
+			}
+			assertPixelsEqual(t, in, got, td.name+"/"+name)
+			_, payload, _ := ReadMIC1Header(packed)
+			fmt.Printf("%-6s  %-8s  %-6s  %10d  %10d  %6.1f%%\n", td.name, name, kinds[payload[optionsHeaderSize]],
+				len(plain), len(packed), 100*(1-float64(len(packed))/float64(len(plain))))
+		}
+	}
+	fmt.Println()
+}
+
+func BenchmarkPackValues(b *testing.B) {
+	_, pixels, maxVal, width, _ := SetupTests(testFiles[0]) // MR
+	variants := sparseVariants(pixels, maxVal, 1)
+	variants["stored"] = pixels
+	for name, in := range variants {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(in) * 2))
+			for i := 0; i < b.N; i++ {
+				packValues(in, width)
+			}
+		})
+	}
+}
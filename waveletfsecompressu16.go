@@ -7,6 +7,7 @@ package mic
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math/bits"
 )
 
@@ -39,8 +40,17 @@ func waveletCoeffsToU16(coeffs []int32) []uint16 {
 	return out
 }
 
-// u16ToWaveletCoeffs converts a uint16 stream back to int32 wavelet coefficients.
-func u16ToWaveletCoeffs(in []uint16, n int) []int32 {
+// u16ToWaveletCoeffs converts a uint16 stream back to the rows*cols int32
+// wavelet coefficients of an image. It returns an error if the stream holds
+// too few coefficients or ends inside an escape sequence.
+func u16ToWaveletCoeffs(in []uint16, rows, cols int) ([]int32, error) {
+	// Every coefficient takes at least one uint16, which also bounds the
+	// allocation a corrupt header can request.
+	hi, lo := bits.Mul64(uint64(rows), uint64(cols))
+	if rows < 0 || cols < 0 || hi != 0 || lo > uint64(len(in)) {
+		return nil, fmt.Errorf("wavelet: %dx%d image does not fit in %d symbols", rows, cols, len(in))
+	}
+	n := int(lo)
 	out := make([]int32, 0, n)
 	i := 0
 	for i < len(in) && len(out) < n {
@@ -48,13 +58,19 @@ func u16ToWaveletCoeffs(in []uint16, n int) []int32 {
 			out = append(out, zigzagDecode16(in[i]))
 			i++
 		} else {
+			if i+2 >= len(in) {
+				return nil, errors.New("wavelet: truncated escape sequence")
+			}
 			i++ // skip escape
 			v := int32(uint32(in[i])<<16 | uint32(in[i+1]))
 			out = append(out, v)
 			i += 2
 		}
 	}
-	return out
+	if len(out) != n {
+		return nil, fmt.Errorf("wavelet: got %d coefficients, want %d", len(out), n)
+	}
+	return out, nil
 }
 
 // WaveletFSECompressU16 compresses 16-bit image data using:
@@ -122,6 +138,12 @@ func WaveletFSECompressU16(pixels []uint16, rows, cols int, maxValue uint16, lev
 
 // WaveletFSEDecompressU16 decompresses data produced by WaveletFSECompressU16.
 func WaveletFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
+	var d Decoder
+	return d.WaveletFSEDecompressU16(compressed)
+}
+
+// WaveletFSEDecompressU16 is WaveletFSEDecompressU16 under d's limits.
+func (d *Decoder) WaveletFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	if len(compressed) < 11 {
 		return nil, 0, 0, errors.New("compressed data too short")
 	}
@@ -130,16 +152,22 @@ func WaveletFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	cols := int(binary.LittleEndian.Uint32(compressed[4:8]))
 	_ = binary.LittleEndian.Uint16(compressed[8:10]) // maxValue (for future use)
 	levels := int(compressed[10])
+	if err := d.checkImage(cols, rows, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("wavelet: %w", err)
+	}
 
 	// FSE decompress (4-state)
-	var s ScratchU16
+	s := ScratchU16{DecompressLimit: symbolLimit(2 * rows * cols)}
 	encoded, err := FSEDecompressU16FourState(compressed[11:], &s)
 	if err != nil {
 		return nil, 0, 0, err
 	}
 
 	// Decode uint16 stream back to int32 wavelet coefficients
-	data := u16ToWaveletCoeffs(encoded, rows*cols)
+	data, err := u16ToWaveletCoeffs(encoded, rows, cols)
+	if err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Inverse multi-level 2D wavelet transform (from coarsest to finest)
 	dims := make([][2]int, levels)
@@ -372,6 +400,12 @@ func WaveletV2RLEFSECompressU16(pixels []uint16, rows, cols int, maxValue uint16
 
 // WaveletV2RLEFSEDecompressU16 decompresses data produced by WaveletV2RLEFSECompressU16.
 func WaveletV2RLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
+	var d Decoder
+	return d.WaveletV2RLEFSEDecompressU16(compressed)
+}
+
+// WaveletV2RLEFSEDecompressU16 is WaveletV2RLEFSEDecompressU16 under d's limits.
+func (d *Decoder) WaveletV2RLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	if len(compressed) < 11 {
 		return nil, 0, 0, errors.New("compressed data too short")
 	}
@@ -380,9 +414,12 @@ func WaveletV2RLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error)
 	cols := int(binary.LittleEndian.Uint32(compressed[4:8]))
 	_ = binary.LittleEndian.Uint16(compressed[8:10]) // maxValue
 	levels := int(compressed[10])
+	if err := d.checkImage(cols, rows, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("wavelet: %w", err)
+	}
 
 	// FSE decompress (4-state)
-	var s ScratchU16
+	s := ScratchU16{DecompressLimit: symbolLimit(2 * rows * cols)}
 	fseOut, err := FSEDecompressU16FourState(compressed[11:], &s)
 	if err != nil {
 		return nil, 0, 0, err
@@ -391,10 +428,16 @@ func WaveletV2RLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error)
 	// RLE decompress
 	var rleD RleDecompressU16
 	rleD.Init(fseOut)
-	encoded := rleD.Decompress()
+	encoded, err := rleD.Decompress()
+	if err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Decode ZigZag+escape uint16 stream back to int32 coefficients
-	ordered := u16ToWaveletCoeffs(encoded, rows*cols)
+	ordered, err := u16ToWaveletCoeffs(encoded, rows, cols)
+	if err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Scatter back into Mallat layout
 	data := make([]int32, rows*cols)
@@ -428,12 +471,58 @@ func WaveletV2SIMDRLEFSECompressU16(pixels []uint16, rows, cols int, maxValue ui
 	if len(pixels) != rows*cols {
 		return nil, errors.New("pixel count does not match rows*cols")
 	}
+	rleOut, levels := waveletSymbols(pixels, rows, cols, levels)
+
+	var s ScratchU16
+	fseOut, err := FSECompressU16FourState(rleOut, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 11)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(rows))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(cols))
+	binary.LittleEndian.PutUint16(header[8:10], maxValue)
+	header[10] = byte(levels)
+
+	out := make([]byte, len(header)+len(fseOut))
+	copy(out, header)
+	copy(out[len(header):], fseOut)
+	return out, nil
+}
+
+// waveletLevels clamps a requested level count to [1, 8] and to the number
+// of halvings a rows x cols image allows.
+func waveletLevels(rows, cols, levels int) int {
 	if levels < 1 {
 		levels = 1
 	}
 	if levels > 8 {
 		levels = 8
 	}
+	r, c := rows, cols
+	for l := 0; l < levels; l++ {
+		if r < 2 || c < 2 {
+			return l
+		}
+		r = (r + 1) / 2
+		c = (c + 1) / 2
+	}
+	return levels
+}
+
+// waveletSymbols runs the SIMD wavelet front end of the V2 pipeline — 5/3
+// transform, subband scan, ZigZag with escape, RLE — and returns the symbol
+// stream ready for entropy coding plus the level count actually applied.
+func waveletSymbols(pixels []uint16, rows, cols, levels int) ([]uint16, int) {
+	ordered, levels := waveletCoefficients(pixels, rows, cols, levels)
+	return coefficientSymbols(ordered, 1), levels
+}
+
+// waveletCoefficients applies the SIMD 5/3 transform and returns the
+// coefficients in subband-scan order plus the level count actually applied.
+func waveletCoefficients(pixels []uint16, rows, cols, levels int) ([]int32, int) {
+	levels = waveletLevels(rows, cols, levels)
 
 	data := make([]int32, len(pixels))
 	for i, v := range pixels {
@@ -442,16 +531,19 @@ func WaveletV2SIMDRLEFSECompressU16(pixels []uint16, rows, cols int, maxValue ui
 
 	r, c := rows, cols
 	for l := 0; l < levels; l++ {
-		if r < 2 || c < 2 {
-			levels = l
-			break
-		}
 		wt53Forward2DSeparatedSIMD(data, r, c, cols)
 		r = (r + 1) / 2
 		c = (c + 1) / 2
 	}
 
-	ordered := collectSubbandOrder(data, rows, cols, cols, levels)
+	return collectSubbandOrder(data, rows, cols, cols, levels), levels
+}
+
+// coefficientSymbols ZigZag-encodes subband-ordered coefficients, with the
+// escape for large values, and RLE-codes the result. The RLE run limit
+// follows the bit depth of the largest symbol, but no less than minDepth
+// bits.
+func coefficientSymbols(ordered []int32, minDepth int) []uint16 {
 	encoded := waveletCoeffsToU16(ordered)
 
 	zzMax := uint16(0)
@@ -461,36 +553,25 @@ func WaveletV2SIMDRLEFSECompressU16(pixels []uint16, rows, cols int, maxValue ui
 		}
 	}
 	pixelDepth := bits.Len16(zzMax)
-	if pixelDepth < 1 {
-		pixelDepth = 1
+	if pixelDepth < minDepth {
+		pixelDepth = minDepth
 	}
 	rleMaxVal := uint16((1 << pixelDepth) - 1)
 	var rleC RleCompressU16
 	rleC.Init(len(encoded), 1, rleMaxVal)
-	rleOut := rleC.Compress(encoded)
-
-	var s ScratchU16
-	fseOut, err := FSECompressU16FourState(rleOut, &s)
-	if err != nil {
-		return nil, err
-	}
-
-	header := make([]byte, 11)
-	binary.LittleEndian.PutUint32(header[0:4], uint32(rows))
-	binary.LittleEndian.PutUint32(header[4:8], uint32(cols))
-	binary.LittleEndian.PutUint16(header[8:10], maxValue)
-	header[10] = byte(levels)
-
-	out := make([]byte, len(header)+len(fseOut))
-	copy(out, header)
-	copy(out[len(header):], fseOut)
-	return out, nil
+	return rleC.Compress(encoded)
 }
 
 // WaveletV2SIMDRLEFSEDecompressU16 decompresses data produced by either
 // WaveletV2RLEFSECompressU16 or WaveletV2SIMDRLEFSECompressU16.
 // Uses the SIMD-accelerated inverse wavelet transform.
 func WaveletV2SIMDRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
+	var d Decoder
+	return d.WaveletV2SIMDRLEFSEDecompressU16(compressed)
+}
+
+// WaveletV2SIMDRLEFSEDecompressU16 is WaveletV2SIMDRLEFSEDecompressU16 under d's limits.
+func (d *Decoder) WaveletV2SIMDRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	if len(compressed) < 11 {
 		return nil, 0, 0, errors.New("compressed data too short")
 	}
@@ -499,19 +580,53 @@ func WaveletV2SIMDRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, er
 	cols := int(binary.LittleEndian.Uint32(compressed[4:8]))
 	_ = binary.LittleEndian.Uint16(compressed[8:10])
 	levels := int(compressed[10])
+	if err := d.checkImage(cols, rows, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("wavelet: %w", err)
+	}
 
-	var s ScratchU16
+	s := ScratchU16{DecompressLimit: symbolLimit(2 * rows * cols)}
 	fseOut, err := FSEDecompressU16FourState(compressed[11:], &s)
 	if err != nil {
 		return nil, 0, 0, err
 	}
 
-	var rleD RleDecompressU16
-	rleD.Init(fseOut)
-	encoded := rleD.Decompress()
+	pixels, err := waveletPixels(fseOut, rows, cols, levels)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pixels, rows, cols, nil
+}
+
+// waveletPixels reverses waveletSymbols: it expands the RLE symbol stream and
+// runs the SIMD inverse transform over levels levels.
+func waveletPixels(rleSymbols []uint16, rows, cols, levels int) ([]uint16, error) {
+	ordered, err := symbolCoefficients(rleSymbols, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	data := inverseWavelet(ordered, rows, cols, levels)
 
-	ordered := u16ToWaveletCoeffs(encoded, rows*cols)
+	pixels := make([]uint16, len(data))
+	for i, v := range data {
+		pixels[i] = uint16(v)
+	}
+	return pixels, nil
+}
 
+// symbolCoefficients reverses coefficientSymbols for a rows x cols image.
+func symbolCoefficients(rleSymbols []uint16, rows, cols int) ([]int32, error) {
+	var rleD RleDecompressU16
+	rleD.Init(rleSymbols)
+	encoded, err := rleD.Decompress()
+	if err != nil {
+		return nil, err
+	}
+	return u16ToWaveletCoeffs(encoded, rows, cols)
+}
+
+// inverseWavelet reverses waveletCoefficients, returning the samples before
+// they are narrowed to uint16.
+func inverseWavelet(ordered []int32, rows, cols, levels int) []int32 {
 	data := make([]int32, rows*cols)
 	scatterSubbandOrder(ordered, data, rows, cols, cols, levels)
 
@@ -525,12 +640,7 @@ func WaveletV2SIMDRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, er
 	for l := levels - 1; l >= 0; l-- {
 		wt53Inverse2DSeparatedSIMD(data, dims[l][0], dims[l][1], cols)
 	}
-
-	pixels := make([]uint16, len(data))
-	for i, v := range data {
-		pixels[i] = uint16(v)
-	}
-	return pixels, rows, cols, nil
+	return data
 }
 
 // zigzagEncode16 maps a signed int32 to an unsigned uint16 using ZigZag encoding.
@@ -622,6 +732,12 @@ func WaveletRLEFSECompressU16(pixels []uint16, rows, cols int, maxValue uint16,
 
 // WaveletRLEFSEDecompressU16 decompresses data produced by WaveletRLEFSECompressU16.
 func WaveletRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
+	var d Decoder
+	return d.WaveletRLEFSEDecompressU16(compressed)
+}
+
+// WaveletRLEFSEDecompressU16 is WaveletRLEFSEDecompressU16 under d's limits.
+func (d *Decoder) WaveletRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	if len(compressed) < 15 {
 		return nil, 0, 0, errors.New("compressed data too short")
 	}
@@ -630,10 +746,13 @@ func WaveletRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	cols := int(binary.LittleEndian.Uint32(compressed[4:8]))
 	_ = binary.LittleEndian.Uint16(compressed[8:10]) // maxValue
 	levels := int(compressed[10])
+	if err := d.checkImage(cols, rows, 1, 2); err != nil {
+		return nil, 0, 0, fmt.Errorf("wavelet: %w", err)
+	}
 	_ = int(binary.LittleEndian.Uint32(compressed[11:15])) // encodedLen (RLE handles its own length)
 
 	// FSE decompress (4-state)
-	var s ScratchU16
+	s := ScratchU16{DecompressLimit: symbolLimit(2 * rows * cols)}
 	fseOut, err := FSEDecompressU16FourState(compressed[15:], &s)
 	if err != nil {
 		return nil, 0, 0, err
@@ -642,10 +761,16 @@ func WaveletRLEFSEDecompressU16(compressed []byte) ([]uint16, int, int, error) {
 	// RLE decompress
 	var rleD RleDecompressU16
 	rleD.Init(fseOut)
-	encoded := rleD.Decompress()
+	encoded, err := rleD.Decompress()
+	if err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Decode uint16 stream back to int32 wavelet coefficients
-	data := u16ToWaveletCoeffs(encoded, rows*cols)
+	data, err := u16ToWaveletCoeffs(encoded, rows, cols)
+	if err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Inverse multi-level 2D wavelet transform
 	dims := make([][2]int, levels)
@@ -0,0 +1,367 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Lossy wavelet compression.
+//
+// CompressLossy runs the 5/3 transform of the V2 wavelet pipeline, then
+// dead-zone quantises every subband with its own step before the usual
+// subband scan, ZigZag, RLE and FSE:
+//
+//	q  = sign(c) * floor(|c| / step)
+//	c' = sign(q) * (|q| + 1/2) * step      (0 when q == 0)
+//
+// The steps derive from one base step, divided by the square root of each
+// subband's synthesis gain (1.5 per low-pass and 0.71875 per high-pass
+// filtering of the 5/3 synthesis filters) so that every subband contributes
+// comparable error to the image. A subband step of 1 or less leaves that
+// subband lossless, and a base step of 1 or less the whole image. The base
+// step is either given or found by bisection for a target PSNR or output
+// size.
+//
+// The result is a MIC1 container with pipeline PipelineLossyWavelet:
+//
+//	Byte  0:    Transform (0 = 5/3 integer)
+//	Byte  1:    Levels
+//	Byte  2:    Symbol coding: 0 = FSE, 1 = raw uint16 LE
+//	Bytes 3..:  3*Levels+1 quantisation steps (float32 LE) in subband-scan
+//	            order: LL, then HL, LH, HH per level, coarsest first
+//	After:      RLE stream of the quantised coefficients
+//
+// Coarse steps leave so few RLE symbols that the FSE table would outweigh
+// them; those are stored raw, as MICS does for groups FSE rejects.
+
+const (
+	lossyTransform53 = 0
+
+	symbolsFSE = 0x00 // four-state FSE, falling back to fewer states
+	symbolsRaw = 0x01 // uint16 LE
+
+	lossyDefaultLevels = 5
+	lossySearchSteps   = 24 // bisection iterations of the rate search
+	lossyRLEDepth      = 12 // coarse quantisation leaves few symbols but long zero runs
+
+	synthesisGainLow  = 1.5     // squared norm of the 5/3 low-pass synthesis filter
+	synthesisGainHigh = 0.71875 // squared norm of the 5/3 high-pass synthesis filter
+)
+
+// LossyOptions configures CompressLossy. At most one of TargetPSNR,
+// TargetBytes and Step may be set; with none, Step defaults to 8.
+type LossyOptions struct {
+	// Levels is the number of 5/3 decomposition levels, 1-8; 0 selects 5.
+	Levels int
+
+	// TargetPSNR, in dB, selects the coarsest quantisation whose
+	// reconstruction reaches it.
+	TargetPSNR float64
+
+	// TargetBytes selects the finest quantisation whose container fits.
+	TargetBytes int
+
+	// Step is a fixed base quantisation step.
+	Step float64
+
+	// MaxValue is the largest sample and the PSNR peak. 0 derives it from
+	// the pixels.
+	MaxValue uint16
+}
+
+// CompressLossy compresses a single 16-bit frame with per-subband dead-zone
+// quantisation of the 5/3 wavelet. Decompress and Decode read the result.
+func CompressLossy(pixels []uint16, width, height int, opts LossyOptions) ([]byte, error) {
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("compress lossy: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	set := 0
+	for _, v := range []bool{opts.TargetPSNR != 0, opts.TargetBytes != 0, opts.Step != 0} {
+		if v {
+			set++
+		}
+	}
+	switch {
+	case set > 1:
+		return nil, errors.New("compress lossy: set at most one of TargetPSNR, TargetBytes and Step")
+	case opts.TargetPSNR < 0 || opts.TargetBytes < 0 || opts.Step < 0 || math.IsNaN(opts.Step) || math.IsNaN(opts.TargetPSNR):
+		return nil, errors.New("compress lossy: negative target or step")
+	case opts.Levels < 0 || opts.Levels > 8:
+		return nil, fmt.Errorf("compress lossy: %d wavelet levels, want 0-8", opts.Levels)
+	case set == 0:
+		opts.Step = 8
+	}
+	if opts.Levels == 0 {
+		opts.Levels = lossyDefaultLevels
+	}
+	if opts.MaxValue == 0 {
+		for _, v := range pixels {
+			if v > opts.MaxValue {
+				opts.MaxValue = v
+			}
+		}
+	}
+
+	coeffs, levels := waveletCoefficients(pixels, height, width, opts.Levels)
+	if levels == 0 {
+		return nil, fmt.Errorf("compress lossy: %dx%d is too small to transform", width, height)
+	}
+	e := lossyEncoder{coeffs: coeffs, sizes: subbandSizes(height, width, levels), levels: levels,
+		width: width, height: height, maxValue: opts.MaxValue}
+
+	if opts.Step != 0 {
+		return e.encode(opts.Step)
+	}
+
+	// Bisect log2(step) over [0, 18]: step 1 is lossless, 2^18 zeroes every
+	// detail coefficient of a 16-bit image. PSNR and size both fall as the
+	// step grows, but not strictly, so every feasible try is kept: the
+	// smallest one meeting a PSNR target, the largest one within a size.
+	var best []byte
+	var d Decoder
+	lo, hi := 0.0, 18.0
+	for i := 0; i < lossySearchSteps; i++ {
+		mid := (lo + hi) / 2
+		out, err := e.encode(math.Exp2(mid))
+		if err != nil {
+			return nil, err
+		}
+		if opts.TargetBytes > 0 {
+			if len(out) > opts.TargetBytes {
+				lo = mid
+				continue
+			}
+			hi = mid
+			if len(out) > len(best) {
+				best = out
+			}
+			continue
+		}
+		got, err := d.decompressLossy(out[singleFrameHeaderSize:], width, height, opts.MaxValue)
+		if err != nil {
+			return nil, err
+		}
+		if psnr(pixels, got, opts.MaxValue) < opts.TargetPSNR {
+			hi = mid
+			continue
+		}
+		lo = mid
+		if best == nil || len(out) < len(best) {
+			best = out
+		}
+	}
+	switch {
+	case best != nil:
+		return best, nil
+	case opts.TargetBytes > 0:
+		return nil, fmt.Errorf("compress lossy: no quantisation fits %d bytes", opts.TargetBytes)
+	}
+	// Step 1 keeps every subband lossless: no finer quantisation exists.
+	return e.encode(1)
+}
+
+// lossyEncoder holds the transform of one image while the rate search tries
+// base steps on it.
+type lossyEncoder struct {
+	coeffs        []int32 // subband-scan order
+	sizes         []int   // coefficients per subband, same order
+	levels        int
+	width, height int
+	maxValue      uint16
+}
+
+// encode quantises e's coefficients with base step and returns the MIC1
+// container.
+func (e *lossyEncoder) encode(base float64) ([]byte, error) {
+	steps := subbandSteps(base, e.levels)
+	q := make([]int32, len(e.coeffs))
+	pos := 0
+	for b, n := range e.sizes {
+		step := float64(steps[b])
+		for i := pos; i < pos+n; i++ {
+			c := e.coeffs[i]
+			switch {
+			case step <= 1:
+				q[i] = c
+			case c < 0:
+				q[i] = -int32(float64(-c) / step)
+			default:
+				q[i] = int32(float64(c) / step)
+			}
+		}
+		pos += n
+	}
+
+	coding, coded := packSymbols(coefficientSymbols(q, lossyRLEDepth))
+	payload := make([]byte, 3, 3+4*len(steps)+len(coded))
+	payload[0] = lossyTransform53
+	payload[1] = byte(e.levels)
+	payload[2] = coding
+	for _, st := range steps {
+		payload = binary.LittleEndian.AppendUint32(payload, math.Float32bits(st))
+	}
+	payload = append(payload, coded...)
+
+	var buf bytes.Buffer
+	hdr := MIC1Header{Width: e.width, Height: e.height, MaxValue: e.maxValue, Pipeline: PipelineLossyWavelet}
+	if err := WriteMIC1(&buf, hdr, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// subbandSteps returns the quantisation step of every subband, in
+// subband-scan order, for base step base.
+func subbandSteps(base float64, levels int) []float32 {
+	steps := make([]float32, 0, 3*levels+1)
+	if base <= 1 {
+		// The high-pass steps would exceed the base step.
+		for range cap(steps) {
+			steps = append(steps, 1)
+		}
+		return steps
+	}
+	gain := func(low, high int) float64 {
+		return math.Pow(synthesisGainLow, float64(low)) * math.Pow(synthesisGainHigh, float64(high))
+	}
+	steps = append(steps, float32(base/math.Sqrt(gain(2*levels, 0))))
+	for l := levels; l >= 1; l-- {
+		lh := float32(base / math.Sqrt(gain(2*(l-1)+1, 1))) // HL and LH
+		hh := float32(base / math.Sqrt(gain(2*(l-1), 2)))
+		steps = append(steps, lh, lh, hh)
+	}
+	return steps
+}
+
+// subbandSizes returns the coefficient count of every subband of a
+// rows x cols transform, in the order collectSubbandOrder scans them.
+func subbandSizes(rows, cols, levels int) []int {
+	nR := make([]int, levels+1)
+	nC := make([]int, levels+1)
+	nR[0], nC[0] = rows, cols
+	for l := 1; l <= levels; l++ {
+		nR[l] = (nR[l-1] + 1) / 2
+		nC[l] = (nC[l-1] + 1) / 2
+	}
+	sizes := []int{nR[levels] * nC[levels]}
+	for l := levels; l >= 1; l-- {
+		sizes = append(sizes,
+			nR[l]*(nC[l-1]-nC[l]),
+			(nR[l-1]-nR[l])*nC[l],
+			(nR[l-1]-nR[l])*(nC[l-1]-nC[l]))
+	}
+	return sizes
+}
+
+// packSymbols entropy-codes an RLE symbol stream, storing it raw when FSE
+// rejects it or would not make it smaller.
+func packSymbols(symbols []uint16) (coding byte, data []byte) {
+	if data, err := CoderFSE4.encode(symbols); err == nil && len(data) < 2*len(symbols) {
+		return symbolsFSE, data
+	}
+	data = make([]byte, 0, 2*len(symbols))
+	for _, v := range symbols {
+		data = binary.LittleEndian.AppendUint16(data, v)
+	}
+	return symbolsRaw, data
+}
+
+// unpackSymbols reverses packSymbols, producing at most limit symbols.
+func unpackSymbols(coding byte, data []byte, limit int) ([]uint16, error) {
+	switch coding {
+	case symbolsFSE:
+		return CoderFSE4.decode(data, limit)
+	case symbolsRaw:
+		if len(data)%2 != 0 || len(data)/2 > limit {
+			return nil, fmt.Errorf("invalid raw symbol length %d", len(data))
+		}
+		symbols := make([]uint16, len(data)/2)
+		for i := range symbols {
+			symbols[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+		return symbols, nil
+	}
+	return nil, fmt.Errorf("unknown symbol coding %d", coding)
+}
+
+// decompressLossy decodes a PipelineLossyWavelet payload.
+func (d *Decoder) decompressLossy(payload []byte, width, height int, maxValue uint16) ([]uint16, error) {
+	if err := d.checkImage(width, height, 1, 2); err != nil {
+		return nil, err
+	}
+	if len(payload) < 3 {
+		return nil, errors.New("lossy wavelet: payload truncated")
+	}
+	if payload[0] != lossyTransform53 {
+		return nil, fmt.Errorf("lossy wavelet: unknown transform %d", payload[0])
+	}
+	levels := int(payload[1])
+	if levels < 1 || levels != waveletLevels(height, width, levels) {
+		return nil, fmt.Errorf("lossy wavelet: %d levels invalid for %dx%d", levels, width, height)
+	}
+	nSteps := 3*levels + 1
+	if len(payload) < 3+4*nSteps {
+		return nil, errors.New("lossy wavelet: step table truncated")
+	}
+	steps := make([]float64, nSteps)
+	for i := range steps {
+		steps[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(payload[3+4*i:])))
+		if !(steps[i] > 0) || math.IsInf(steps[i], 0) {
+			return nil, fmt.Errorf("lossy wavelet: invalid step %v", steps[i])
+		}
+	}
+
+	symbols, err := unpackSymbols(payload[2], payload[3+4*nSteps:], symbolLimit(2*width*height))
+	if err != nil {
+		return nil, fmt.Errorf("lossy wavelet: %w", err)
+	}
+	q, err := symbolCoefficients(symbols, height, width)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := 0
+	for b, n := range subbandSizes(height, width, levels) {
+		step := steps[b]
+		if step > 1 {
+			for i := pos; i < pos+n; i++ {
+				switch v := q[i]; {
+				case v > 0:
+					q[i] = int32((float64(v) + 0.5) * step)
+				case v < 0:
+					q[i] = -int32((float64(-v) + 0.5) * step)
+				}
+			}
+		}
+		pos += n
+	}
+
+	data := inverseWavelet(q, height, width, levels)
+	pixels := make([]uint16, len(data))
+	for i, v := range data {
+		pixels[i] = uint16(min(max(int(v), 0), int(maxValue)))
+	}
+	return pixels, nil
+}
+
+// psnr returns the peak signal-to-noise ratio of got against want in dB,
+// with peak maxValue; +Inf when they are equal.
+func psnr(want, got []uint16, maxValue uint16) float64 {
+	var sse float64
+	for i := range want {
+		d := float64(want[i]) - float64(got[i])
+		sse += d * d
+	}
+	if sse == 0 {
+		return math.Inf(1)
+	}
+	peak := float64(max(int(maxValue), 1))
+	return 10 * math.Log10(peak*peak*float64(len(want))/sse)
+}
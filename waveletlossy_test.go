@@ -0,0 +1,141 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCompressLossy(t *testing.T) {
+	width, height := 203, 157 // odd sizes exercise the uneven subbands
+	frames, maxValue := makeSmoothFrames(width, height, 1, 11)
+	pixels := frames[0]
+
+	lossless, err := CompressSingleFrame(pixels, width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := math.Inf(1)
+	for _, step := range []float64{1, 4, 16, 64} {
+		label := fmt.Sprintf("step=%v", step)
+		compressed, err := CompressLossy(pixels, width, height, LossyOptions{Step: step})
+		if err != nil {
+			t.Fatalf("%s: %v", label, err)
+		}
+		got, w, h, err := Decompress(compressed)
+		if err != nil {
+			t.Fatalf("%s: %v", label, err)
+		}
+		if w != width || h != height {
+			t.Fatalf("%s: dimensions %dx%d, want %dx%d", label, w, h, width, height)
+		}
+		p := psnr(pixels, got, maxValue)
+		if step == 1 {
+			assertPixelsEqual(t, pixels, got, label)
+		} else if p >= prev {
+			t.Fatalf("%s: PSNR %.2f dB, no lower than %.2f at a finer step", label, p, prev)
+		}
+		prev = p
+		t.Logf("%s: %d bytes (lossless %d), %.2f dB", label, len(compressed), len(lossless), p)
+	}
+
+	compressed, err := CompressLossy(pixels, width, height, LossyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatMIC1 {
+		t.Fatalf("Decode format %s, want %s", img.Format, FormatMIC1)
+	}
+	dst := make([]uint16, width*height)
+	if _, _, err := DecompressInto(dst, compressed); err != nil {
+		t.Fatal(err)
+	}
+	assertPixelsEqual(t, img.Pixels, dst, "DecompressInto")
+}
+
+func TestCompressLossyTargets(t *testing.T) {
+	width, height := 256, 192
+	frames, maxValue := makeSmoothFrames(width, height, 1, 12)
+	pixels := frames[0]
+
+	for _, target := range []float64{40, 50, 60} {
+		compressed, err := CompressLossy(pixels, width, height, LossyOptions{TargetPSNR: target})
+		if err != nil {
+			t.Fatalf("PSNR %v: %v", target, err)
+		}
+		got, _, _, err := Decompress(compressed)
+		if err != nil {
+			t.Fatalf("PSNR %v: %v", target, err)
+		}
+		if p := psnr(pixels, got, maxValue); p < target {
+			t.Fatalf("PSNR %v: reached %.2f dB", target, p)
+		}
+	}
+
+	lossless, err := CompressSingleFrame(pixels, width, height, maxValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, div := range []int{2, 4, 8} {
+		target := len(lossless) / div
+		compressed, err := CompressLossy(pixels, width, height, LossyOptions{TargetBytes: target})
+		if err != nil {
+			t.Fatalf("%d bytes: %v", target, err)
+		}
+		if len(compressed) > target {
+			t.Fatalf("%d bytes: got %d", target, len(compressed))
+		}
+		if _, _, _, err := Decompress(compressed); err != nil {
+			t.Fatalf("%d bytes: %v", target, err)
+		}
+	}
+	if _, err := CompressLossy(pixels, width, height, LossyOptions{TargetBytes: 30}); err == nil {
+		t.Fatal("30-byte target: expected an error")
+	}
+}
+
+func TestCompressLossyErrors(t *testing.T) {
+	pixels := make([]uint16, 16*16)
+	for i := range pixels {
+		pixels[i] = uint16(i)
+	}
+	for _, opts := range []LossyOptions{
+		{Step: 4, TargetPSNR: 40},
+		{Step: -1},
+		{TargetBytes: -1},
+		{Step: math.NaN()},
+		{Levels: 9},
+	} {
+		if _, err := CompressLossy(pixels, 16, 16, opts); err == nil {
+			t.Fatalf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := CompressLossy(pixels, 15, 16, LossyOptions{}); err == nil {
+		t.Fatal("pixel count mismatch: expected an error")
+	}
+	if _, err := CompressLossy(pixels, 256, 1, LossyOptions{}); err == nil {
+		t.Fatal("single row: expected an error")
+	}
+
+	compressed, err := CompressLossy(pixels, 16, 16, LossyOptions{Step: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := append([]byte(nil), compressed...)
+	copy(bad[singleFrameHeaderSize+3:], []byte{0xFF, 0xFF, 0xFF, 0xFF}) // first step becomes NaN
+	if _, _, _, err := Decompress(bad); err == nil {
+		t.Fatal("NaN step: expected an error")
+	}
+	if _, _, _, err := Decompress(compressed[:singleFrameHeaderSize+6]); err == nil {
+		t.Fatal("truncated step table: expected an error")
+	}
+}
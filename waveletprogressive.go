@@ -0,0 +1,216 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// MICP — resolution-progressive wavelet stream.
+//
+// After k inverse levels the top-left corner of a 5/3 transform holds the
+// LL subband of level k: the image at 1/2^k resolution. The V2 wavelet
+// stream codes all subbands as one FSE block, so even a thumbnail needs the
+// whole file. MICP codes them as one segment per resolution, coarsest first,
+// behind an index of segment lengths, so the prefix up to segment L-k is
+// enough to decode level k.
+//
+// Binary format:
+//
+//	Bytes  0-3:  Magic "MICP"
+//	Bytes  4-7:  Width           (uint32 LE)
+//	Bytes  8-11: Height          (uint32 LE)
+//	Bytes 12-13: Max value       (uint16 LE)
+//	Byte  14:    Format version (1)
+//	Byte  15:    Levels L (1-8)
+//	Bytes 16..:  L+1 segment lengths (uint32 LE)
+//	After:       L+1 segments
+//
+// Segment 0 holds LL_L, and segment j the HL, LH and HH subbands of level
+// L+1-j. Each segment is a symbol-coding byte (0 = FSE, 1 = raw uint16 LE)
+// followed by the RLE stream of its ZigZag-coded coefficients.
+
+const (
+	micpMagic      = "MICP"
+	micpHeaderSize = 16
+	micpVersion    = 1
+)
+
+// CompressProgressive compresses a single 16-bit frame with the lossless
+// 5/3 wavelet into a MICP stream. levels is clamped as for
+// WaveletV2SIMDRLEFSECompressU16; every level adds a resolution that
+// DecodeAtLevel can stop at.
+func CompressProgressive(pixels []uint16, width, height int, maxValue uint16, levels int) ([]byte, error) {
+	if len(pixels) != width*height {
+		return nil, fmt.Errorf("compress progressive: pixel count %d != width*height %d", len(pixels), width*height)
+	}
+	if uint64(width) > 0xFFFFFFFF || uint64(height) > 0xFFFFFFFF {
+		return nil, fmt.Errorf("compress progressive: invalid dimensions %dx%d", width, height)
+	}
+	coeffs, levels := waveletCoefficients(pixels, height, width, levels)
+	if levels == 0 {
+		return nil, fmt.Errorf("compress progressive: %dx%d is too small to transform", width, height)
+	}
+
+	indexSize := 4 * (levels + 1)
+	out := make([]byte, micpHeaderSize+indexSize, micpHeaderSize+indexSize+len(pixels))
+	copy(out[0:4], micpMagic)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(out[8:12], uint32(height))
+	binary.LittleEndian.PutUint16(out[12:14], maxValue)
+	out[14] = micpVersion
+	out[15] = byte(levels)
+
+	for j, n := range segmentSizes(height, width, levels) {
+		coding, data := packSymbols(coefficientSymbols(coeffs[:n], 1))
+		coeffs = coeffs[n:]
+		binary.LittleEndian.PutUint32(out[micpHeaderSize+4*j:], uint32(1+len(data)))
+		out = append(out, coding)
+		out = append(out, data...)
+	}
+	return out, nil
+}
+
+// segmentSizes returns the coefficient count of every MICP segment.
+func segmentSizes(rows, cols, levels int) []int {
+	sizes := subbandSizes(rows, cols, levels)
+	segs := []int{sizes[0]}
+	for i := 1; i < len(sizes); i += 3 {
+		segs = append(segs, sizes[i]+sizes[i+1]+sizes[i+2])
+	}
+	return segs
+}
+
+// micpHeader is the parsed fixed header and index of a MICP stream.
+type micpHeader struct {
+	width, height int
+	maxValue      uint16
+	levels        int
+	segments      []int // segment lengths
+	dataOffset    int   // start of segment 0
+}
+
+// readMICPHeader parses the header and index, which need not be followed by
+// any segment data.
+func (d *Decoder) readMICPHeader(data []byte) (*micpHeader, error) {
+	if len(data) < micpHeaderSize || string(data[0:4]) != micpMagic {
+		return nil, errors.New("MICP: invalid magic")
+	}
+	if v := data[14]; v != micpVersion {
+		return nil, fmt.Errorf("MICP: unsupported version %d", v)
+	}
+	h := &micpHeader{
+		width:    int(binary.LittleEndian.Uint32(data[4:8])),
+		height:   int(binary.LittleEndian.Uint32(data[8:12])),
+		maxValue: binary.LittleEndian.Uint16(data[12:14]),
+		levels:   int(data[15]),
+	}
+	if err := d.checkImage(h.width, h.height, 1, 2); err != nil {
+		return nil, fmt.Errorf("MICP: %w", err)
+	}
+	if h.levels < 1 || h.levels != waveletLevels(h.height, h.width, h.levels) {
+		return nil, fmt.Errorf("MICP: %d levels invalid for %dx%d", h.levels, h.width, h.height)
+	}
+	h.dataOffset = micpHeaderSize + 4*(h.levels+1)
+	if len(data) < h.dataOffset {
+		return nil, errors.New("MICP: index truncated")
+	}
+	h.segments = make([]int, h.levels+1)
+	for j := range h.segments {
+		n := binary.LittleEndian.Uint32(data[micpHeaderSize+4*j:])
+		if n == 0 {
+			return nil, fmt.Errorf("MICP: segment %d: empty", j)
+		}
+		h.segments[j] = int(n)
+	}
+	return h, nil
+}
+
+// ProgressivePrefixSize returns how many leading bytes of a MICP stream
+// DecodeAtLevel needs for level k. data need only hold the header and
+// index: 16 + 4*(levels+1) bytes, at most 52.
+func ProgressivePrefixSize(data []byte, k int) (int, error) {
+	var d Decoder
+	h, err := d.readMICPHeader(data)
+	if err != nil {
+		return 0, err
+	}
+	if k < 0 || k > h.levels {
+		return 0, fmt.Errorf("MICP: level %d outside 0..%d", k, h.levels)
+	}
+	n := h.dataOffset
+	for _, s := range h.segments[:h.levels+1-k] {
+		n += s
+	}
+	return n, nil
+}
+
+// DecodeAtLevel decodes a MICP stream at 1/2^k resolution: k = 0 is the
+// full image, k = levels the coarsest LL subband. Each level halves the
+// width and height, rounding up. data may be cut short after the first
+// ProgressivePrefixSize(data, k) bytes. Samples at k > 0 are the wavelet's
+// low-pass approximation, clamped to the max value.
+func DecodeAtLevel(data []byte, k int) (pixels []uint16, width, height int, err error) {
+	var d Decoder
+	return d.DecodeAtLevel(data, k)
+}
+
+// DecodeAtLevel is DecodeAtLevel under d's limits.
+func (d *Decoder) DecodeAtLevel(data []byte, k int) (pixels []uint16, width, height int, err error) {
+	h, err := d.readMICPHeader(data)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if k < 0 || k > h.levels {
+		return nil, 0, 0, fmt.Errorf("MICP: level %d outside 0..%d", k, h.levels)
+	}
+
+	rows, cols := h.height, h.width
+	for range k {
+		rows, cols = (rows+1)/2, (cols+1)/2
+	}
+	levels := h.levels - k
+
+	// Check the whole prefix before committing memory to any segment.
+	end := h.dataOffset
+	for j, seg := range h.segments[:levels+1] {
+		if seg > len(data)-end {
+			return nil, 0, 0, fmt.Errorf("MICP: segment %d: data out of bounds", j)
+		}
+		end += seg
+	}
+
+	sizes := segmentSizes(h.height, h.width, h.levels)
+	var ordered []int32
+	off := h.dataOffset
+	for j, n := range sizes[:levels+1] {
+		seg := h.segments[j]
+		symbols, err := unpackSymbols(data[off], data[off+1:off+seg], symbolLimit(2*n))
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("MICP: segment %d: %w", j, err)
+		}
+		coeffs, err := symbolCoefficients(symbols, n, 1)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("MICP: segment %d: %w", j, err)
+		}
+		ordered = append(ordered, coeffs...)
+		off += seg
+	}
+
+	// The full image is exact; only the low-pass approximations can stray
+	// outside the sample range.
+	top := 0xFFFF
+	if k > 0 {
+		top = int(h.maxValue)
+	}
+	samples := inverseWavelet(ordered, rows, cols, levels)
+	pixels = make([]uint16, len(samples))
+	for i, v := range samples {
+		pixels[i] = uint16(min(max(int(v), 0), top))
+	}
+	return pixels, cols, rows, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 Kuldeep Singh
+// This source code is licensed under a MIT-style
+// license that can be found in the LICENSE file.
+
+package mic
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompressProgressive(t *testing.T) {
+	width, height := 301, 199 // odd sizes round every level up
+	frames, maxValue := makeSmoothFrames(width, height, 1, 13)
+	pixels := frames[0]
+
+	data, err := CompressProgressive(pixels, width, height, maxValue, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, w, h, err := DecodeAtLevel(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != width || h != height {
+		t.Fatalf("level 0: %dx%d, want %dx%d", w, h, width, height)
+	}
+	assertPixelsEqual(t, pixels, got, "level 0")
+
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatMICP {
+		t.Fatalf("Decode format %s, want %s", img.Format, FormatMICP)
+	}
+	assertPixelsEqual(t, pixels, img.Pixels, "Decode")
+
+	prev := len(data) + 1
+	for k := 0; k <= 4; k++ {
+		label := fmt.Sprintf("level %d", k)
+		n, err := ProgressivePrefixSize(data[:micpHeaderSize+4*5], k)
+		if err != nil {
+			t.Fatalf("%s: %v", label, err)
+		}
+		if n >= prev {
+			t.Fatalf("%s: prefix %d bytes, no shorter than %d", label, n, prev)
+		}
+		prev = n
+
+		thumb, w, h, err := DecodeAtLevel(data[:n], k)
+		if err != nil {
+			t.Fatalf("%s: %v", label, err)
+		}
+		scale := 1 << k
+		if w != (width+scale-1)/scale || h != (height+scale-1)/scale {
+			t.Fatalf("%s: %dx%d, want 1/%d of %dx%d", label, w, h, scale, width, height)
+		}
+		// The low-pass band tracks the image sampled on the 2^k grid.
+		sampled := make([]uint16, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				sampled[y*w+x] = pixels[y*scale*width+x*scale]
+			}
+		}
+		if p := psnr(sampled, thumb, maxValue); p < 25 {
+			t.Fatalf("%s: %.2f dB against the subsampled image", label, p)
+		}
+		if k > 0 {
+			if _, _, _, err := DecodeAtLevel(data[:n-1], k); err == nil {
+				t.Fatalf("%s: prefix one byte short: expected an error", label)
+			}
+		}
+	}
+}
+
+func TestCompressProgressiveErrors(t *testing.T) {
+	pixels := make([]uint16, 16*16)
+	if _, err := CompressProgressive(pixels, 15, 16, 255, 3); err == nil {
+		t.Fatal("pixel count mismatch: expected an error")
+	}
+	if _, err := CompressProgressive(pixels, 256, 1, 255, 3); err == nil {
+		t.Fatal("single row: expected an error")
+	}
+
+	data, err := CompressProgressive(pixels, 16, 16, 255, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []int{-1, 4} {
+		if _, _, _, err := DecodeAtLevel(data, k); err == nil {
+			t.Fatalf("level %d: expected an error", k)
+		}
+		if _, err := ProgressivePrefixSize(data, k); err == nil {
+			t.Fatalf("prefix for level %d: expected an error", k)
+		}
+	}
+	if _, err := ProgressivePrefixSize(data[:micpHeaderSize+3], 0); err == nil {
+		t.Fatal("truncated index: expected an error")
+	}
+	bad := append([]byte(nil), data...)
+	bad[15] = 7 // more levels than a 16x16 image has
+	if _, _, _, err := DecodeAtLevel(bad, 0); err == nil {
+		t.Fatal("invalid level count: expected an error")
+	}
+}
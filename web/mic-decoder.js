@@ -1101,13 +1101,22 @@ function rleDecompress(input) {
 }
 
 // ─── Container Format ────────────────────────────────────────────────────────
-// .mic file format:
-//   Bytes 0-3:   Magic "MIC1"
+// .mic single-frame format (MIC1 greyscale / MICR RGB), versioned header:
+//   Bytes 0-3:   Magic "MIC1" or "MICR"
 //   Bytes 4-7:   Width  (uint32 LE)
 //   Bytes 8-11:  Height (uint32 LE)
-//   Bytes 12-15: Pipeline type (uint32 LE): 1=Delta+RLE+FSE
-//   Bytes 16-19: Compressed data length (uint32 LE)
-//   Bytes 20+:   FSE compressed data
+//   Byte  12:    Pipeline ID: 1=Delta+RLE+FSE
+//   Byte  13:    Bits stored
+//   Byte  14:    Flags: bit0=signed samples
+//   Byte  15:    0x80 | format version
+//   Bytes 16-19: Payload length (uint32 LE)
+//   Bytes 20-21: Max stored value (uint16 LE)
+//   Bytes 22-23: Reserved
+//   Bytes 24+:   Payload
+//
+// Legacy (version 0) files have byte 15 bit 7 clear: MIC1 used a 20-byte
+// header with a uint32 pipeline at 12-15 and the data length at 16-19; MICR
+// used a 12-byte header followed directly by the CompressRGB blob.
 
 const MIC_MAGIC  = 0x3143494D; // "MIC1" in LE
 const MIC2_MAGIC = 0x3243494D; // "MIC2" in LE
@@ -1118,6 +1127,9 @@ const MIC2_HEADER_SIZE = 20;
 const MIC2_ENTRY_SIZE = 8;
 const PICS_HEADER_BASE = 20;   // 4+4+4+4+4 bytes before offset table
 const PIPELINE_TEMPORAL = 0x02;
+const SINGLE_FRAME_HEADER_SIZE = 24;
+const SINGLE_FRAME_VERSIONED = 0x80;
+const SINGLE_FRAME_VERSION = 1;
 
 // MIC3 WSI constants
 const MIC3_HEADER_SIZE = 48;
@@ -1128,6 +1140,49 @@ const PLANE_CONSTANT = 1;
 const PLANE_COMPRESSED = 2;
 const PLANE_RAW = 3;
 
+// ─── MIC1 / MICR Single-Frame Header ─────────────────────────────────────────
+
+/**
+ * Parse a MIC1 / MICR header (versioned or legacy).
+ * @param {Uint8Array} fileBytes
+ * @param {string} name - "MIC1" or "MICR" (for error messages)
+ * @returns {{ width: number, height: number, pipeline: number, bitsStored: number, signed: boolean, maxValue: number, version: number, payload: Uint8Array }}
+ */
+function parseSingleFrameHeader(fileBytes, name) {
+  const legacySize = name === 'MICR' ? 12 : 20;
+  if (fileBytes.length < legacySize) throw new Error(`${name}: file too small`);
+  const dv = new DataView(fileBytes.buffer, fileBytes.byteOffset, fileBytes.byteLength);
+  const width  = dv.getUint32(4, true);
+  const height = dv.getUint32(8, true);
+
+  if (fileBytes.length < SINGLE_FRAME_HEADER_SIZE || !(fileBytes[15] & SINGLE_FRAME_VERSIONED)) {
+    if (name === 'MICR') {
+      return { width, height, pipeline: 1, bitsStored: 8, signed: false, maxValue: 255, version: 0,
+               payload: fileBytes.subarray(12) };
+    }
+    const pipeline = dv.getUint32(12, true);
+    const compLen = dv.getUint32(16, true);
+    return { width, height, pipeline, bitsStored: 0, signed: false, maxValue: 0, version: 0,
+             payload: fileBytes.subarray(20, 20 + compLen) };
+  }
+
+  const version = fileBytes[15] & ~SINGLE_FRAME_VERSIONED;
+  if (version > SINGLE_FRAME_VERSION) throw new Error(`${name}: unsupported header version ${version}`);
+  const payloadLen = dv.getUint32(16, true);
+  if (payloadLen > fileBytes.length - SINGLE_FRAME_HEADER_SIZE) {
+    throw new Error(`${name}: payload extends beyond file`);
+  }
+  return {
+    width, height,
+    pipeline: fileBytes[12],
+    bitsStored: fileBytes[13],
+    signed: !!(fileBytes[14] & 0x01),
+    maxValue: dv.getUint16(20, true),
+    version,
+    payload: fileBytes.subarray(SINGLE_FRAME_HEADER_SIZE, SINGLE_FRAME_HEADER_SIZE + payloadLen),
+  };
+}
+
 // ─── PICS Parallel Strip Support ─────────────────────────────────────────────
 
 /**
@@ -1502,13 +1557,12 @@ export const MICDecoder = {
 
     if (magic === MICR_MAGIC) {
       // MICR: single-frame RGB (Ultrasound, Visible Light)
-      // Header: [MICR magic 4B][width 4B][height 4B][CompressRGB blob...]
-      if (fileBytes.length < 12) throw new Error('MICR: file too small');
-      const width  = dv.getUint32(4, true);
-      const height = dv.getUint32(8, true);
-      const blob   = fileBytes.subarray(12);
-      const rgb    = decompressRGBTileBlob(blob, width, height, true);
-      return { rgb, width, height, channels: 3, isMICR: true };
+      const hdr = parseSingleFrameHeader(fileBytes, 'MICR');
+      if (hdr.pipeline !== 1) {
+        throw new Error(`Unsupported pipeline type: ${hdr.pipeline} (expected 1 = Delta+RLE+FSE)`);
+      }
+      const rgb = decompressRGBTileBlob(hdr.payload, hdr.width, hdr.height, true);
+      return { rgb, width: hdr.width, height: hdr.height, channels: 3, isMICR: true };
     }
 
     if (magic === MIC3_MAGIC) {
@@ -1533,19 +1587,14 @@ export const MICDecoder = {
       throw new Error(`Invalid .mic file (bad magic: 0x${magic.toString(16)})`);
     }
 
-    const width = dv.getUint32(4, true);
-    const height = dv.getUint32(8, true);
-    const pipeline = dv.getUint32(12, true);
-    const compLen = dv.getUint32(16, true);
-
-    if (pipeline !== 1) {
-      throw new Error(`Unsupported pipeline type: ${pipeline} (expected 1 = Delta+RLE+FSE)`);
+    const hdr = parseSingleFrameHeader(fileBytes, 'MIC1');
+    if (hdr.pipeline !== 1) {
+      throw new Error(`Unsupported pipeline type: ${hdr.pipeline} (expected 1 = Delta+RLE+FSE)`);
     }
 
-    const compressedBytes = fileBytes.subarray(20, 20 + compLen);
-    const pixels = this.decode(compressedBytes, width, height);
+    const pixels = this.decode(hdr.payload, hdr.width, hdr.height);
 
-    return { pixels, width, height, isMIC2: false };
+    return { pixels, width: hdr.width, height: hdr.height, isMIC2: false };
   },
 
   /**
@@ -1691,11 +1740,7 @@ export const MICDecoder = {
    * @returns {{ width: number, height: number, yBlob: Uint8Array, coBlob: Uint8Array, cgBlob: Uint8Array }}
    */
   parseMICRPlanes(fileBytes) {
-    if (fileBytes.length < 12) throw new Error('MICR: file too small');
-    const dv = new DataView(fileBytes.buffer, fileBytes.byteOffset, fileBytes.byteLength);
-    const width  = dv.getUint32(4, true);
-    const height = dv.getUint32(8, true);
-    const blob   = fileBytes.subarray(12);
+    const { width, height, payload: blob } = parseSingleFrameHeader(fileBytes, 'MICR');
     if (blob.length < 12) throw new Error('MICR: blob too small');
     const bdv   = new DataView(blob.buffer, blob.byteOffset, blob.byteLength);
     const yLen  = bdv.getUint32(0, true);
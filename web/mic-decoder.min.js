@@ -1 +1 @@
-const MIN_TABLELOG=5,MAX_TABLELOG=17,MAX_SYMBOL_VALUE=65535;class BitReader{constructor(){this.in=null,this.off=0,this.value=0n,this.bitsRead=64}init(t){if(t.length<1)throw new Error("corrupt stream: too short");this.in=t,this.off=t.length;const e=t[t.length-1];if(0===e)throw new Error("corrupt stream: did not find end of stream");this.bitsRead=64,this.value=0n,t.length>=8?this._fillFastStart():(this.fill(),this.fill()),this.bitsRead+=8-highBits(e)}_fillFastStart(){const t=this.off-8,e=this.in;this.value=BigInt(e[t])|BigInt(e[t+1])<<8n|BigInt(e[t+2])<<16n|BigInt(e[t+3])<<24n|BigInt(e[t+4])<<32n|BigInt(e[t+5])<<40n|BigInt(e[t+6])<<48n|BigInt(e[t+7])<<56n,this.bitsRead=0,this.off-=8}fillFast(){if(this.bitsRead<32)return;const t=this.off-4,e=this.in,s=BigInt((e[t]|e[t+1]<<8|e[t+2]<<16|e[t+3]<<24)>>>0);this.value=0xFFFFFFFFFFFFFFFFn&(this.value<<32n|s),this.bitsRead-=32,this.off-=4}fill(){if(!(this.bitsRead<32)){if(this.off>4){const t=this.off-4,e=this.in,s=BigInt((e[t]|e[t+1]<<8|e[t+2]<<16|e[t+3]<<24)>>>0);return this.value=0xFFFFFFFFFFFFFFFFn&(this.value<<32n|s),this.bitsRead-=32,void(this.off-=4)}for(;this.off>0;)this.value=0xFFFFFFFFFFFFFFFFn&(this.value<<8n|BigInt(this.in[this.off-1])),this.bitsRead-=8,this.off--}}getBitsFast(t){const e=BigInt(63&this.bitsRead),s=Number((this.value<<e&0xFFFFFFFFFFFFFFFFn)>>BigInt(64-t&63));return this.bitsRead+=t,s>>>0}getBits(t){return 0===t||this.bitsRead>=64?0:this.getBitsFast(t)}finished(){return this.bitsRead>=64&&0===this.off}close(){if(this.bitsRead>64)throw new Error("unexpected EOF in bitstream")}}class ByteReader{constructor(){this.b=null,this.off=0}init(t){this.b=t,this.off=0}advance(t){this.off+=t}uint32(){const t=this.off,e=this.b;return(e[t]|e[t+1]<<8|e[t+2]<<16|e[t+3]<<24>>>0)>>>0}unread(){return this.b.subarray(this.off)}remain(){return this.b.length-this.off}}function highBits(t){return 0===t?0:31-Math.clz32(t)}function bitsLen16(t){return 0===t?0:32-Math.clz32(t)}function tableStep(t){return(t>>>1)+(t>>>3)+3>>>0}class FSEDecompressor{constructor(){this.norm=new Int32Array(65536),this.decTable=null,this.symbolLen=0,this.actualTableLog=0,this.zeroBits=!1,this.byteReader=new ByteReader,this.bitReader=new BitReader}decompress(t){return t.length>=2&&255===t[0]&&132===t[1]?this._decompress8State(t):t.length>=2&&255===t[0]&&4===t[1]?this._decompress4State(t):t.length>=2&&255===t[0]&&2===t[1]?this._decompress2State(t):(this.byteReader.init(t),this._readNCount(),this._buildDtable(),this._decompressStream())}_decompress8State(t){if(t.length<6)throw new Error("fse8state: input too small");const e=(t[2]|t[3]<<8|t[4]<<16|t[5]<<24>>>0)>>>0;return this.byteReader.init(t.subarray(6)),this._readNCount(),this._buildDtable(),this._decompressStream8State(e)}_decompress4State(t){if(t.length<6)throw new Error("fse4state: input too small");const e=(t[2]|t[3]<<8|t[4]<<16|t[5]<<24>>>0)>>>0;return this.byteReader.init(t.subarray(6)),this._readNCount(),this._buildDtable(),this._decompressStream4State(e)}_decompress2State(t){if(t.length<6)throw new Error("fse2state: input too small");const e=(t[2]|t[3]<<8|t[4]<<16|t[5]<<24>>>0)>>>0;return this.byteReader.init(t.subarray(6)),this._readNCount(),this._buildDtable(),this._decompressStream2State(e)}_decompressStream2State(t){const e=this.bitReader;e.init(this.byteReader.unread());const s=this.decTable,i=this.actualTableLog;let n=e.getBits(i);e.fill();let r=e.getBits(i);const o=new Uint16Array(t);let a=0,l=t;if(this.zeroBits)for(;e.off>=8&&l>=4;){e.fillFast();const t=s[n],i=s[r],h=e.getBits(t.nbBits),c=e.getBits(i.nbBits);n=t.newState+h,r=i.newState+c,e.fillFast();const f=s[n],b=s[r],d=e.getBits(f.nbBits),g=e.getBits(b.nbBits);n=f.newState+d,r=b.newState+g,o[a++]=t.symbol,o[a++]=i.symbol,o[a++]=f.symbol,o[a++]=b.symbol,l-=4}else for(;e.off>=8&&l>=4;){e.fillFast();const t=s[n],i=s[r],h=e.getBitsFast(t.nbBits),c=e.getBitsFast(i.nbBits);n=t.newState+h,r=i.newState+c,e.fillFast();const f=s[n],b=s[r],d=e.getBitsFast(f.nbBits),g=e.getBitsFast(b.nbBits);n=f.newState+d,r=b.newState+g,o[a++]=t.symbol,o[a++]=i.symbol,o[a++]=f.symbol,o[a++]=b.symbol,l-=4}for(;l>0;){e.fill();const t=s[n];if(n=t.newState+e.getBits(t.nbBits),o[a++]=t.symbol,0===--l)break;e.fill();const i=s[r];r=i.newState+e.getBits(i.nbBits),o[a++]=i.symbol,l--}return e.close(),o}_decompressStream4State(t){const e=this.bitReader;e.init(this.byteReader.unread());const s=this.decTable,i=this.actualTableLog;let n=e.getBits(i),r=e.getBits(i);e.fill();let o=e.getBits(i);e.fill();let a=e.getBits(i);const l=new Uint16Array(t);let h=0,c=t;if(this.zeroBits)for(;e.off>=8&&c>=4;){e.fillFast();const t=s[n],i=s[r],f=e.getBits(t.nbBits),b=e.getBits(i.nbBits);n=t.newState+f,r=i.newState+b,e.fillFast();const d=s[o],g=s[a],m=e.getBits(d.nbBits),u=e.getBits(g.nbBits);o=d.newState+m,a=g.newState+u,l[h++]=t.symbol,l[h++]=i.symbol,l[h++]=d.symbol,l[h++]=g.symbol,c-=4}else for(;e.off>=8&&c>=4;){e.fillFast();const t=s[n],i=s[r],f=e.getBitsFast(t.nbBits),b=e.getBitsFast(i.nbBits);n=t.newState+f,r=i.newState+b,e.fillFast();const d=s[o],g=s[a],m=e.getBitsFast(d.nbBits),u=e.getBitsFast(g.nbBits);o=d.newState+m,a=g.newState+u,l[h++]=t.symbol,l[h++]=i.symbol,l[h++]=d.symbol,l[h++]=g.symbol,c-=4}for(;c>0;){e.fill();const t=s[n];if(n=t.newState+e.getBits(t.nbBits),l[h++]=t.symbol,0===--c)break;e.fill();const i=s[r];if(r=i.newState+e.getBits(i.nbBits),l[h++]=i.symbol,0===--c)break;e.fill();const f=s[o];if(o=f.newState+e.getBits(f.nbBits),l[h++]=f.symbol,0===--c)break;e.fill();const b=s[a];a=b.newState+e.getBits(b.nbBits),l[h++]=b.symbol,c--}return e.close(),l}_decompressStream8State(t){const e=this.bitReader;e.init(this.byteReader.unread());const s=this.decTable,i=this.actualTableLog;let n=e.getBits(i),r=e.getBits(i);e.fill();let o=e.getBits(i),a=e.getBits(i);e.fill();let l=e.getBits(i),h=e.getBits(i);e.fill();let c=e.getBits(i),f=e.getBits(i);const b=new Uint16Array(t);let d=0,g=t;if(this.zeroBits)for(;e.off>=16&&g>=8;){e.fillFast();const t=s[n],i=s[r],m=e.getBits(t.nbBits),u=e.getBits(i.nbBits);n=t.newState+m,r=i.newState+u,e.fillFast();const w=s[o],B=s[a],y=e.getBits(w.nbBits),p=e.getBits(B.nbBits);o=w.newState+y,a=B.newState+p,e.fillFast();const F=s[l],S=s[h],I=e.getBits(F.nbBits),C=e.getBits(S.nbBits);l=F.newState+I,h=S.newState+C,e.fillFast();const E=s[c],R=s[f],M=e.getBits(E.nbBits),U=e.getBits(R.nbBits);c=E.newState+M,f=R.newState+U,b[d++]=t.symbol,b[d++]=i.symbol,b[d++]=w.symbol,b[d++]=B.symbol,b[d++]=F.symbol,b[d++]=S.symbol,b[d++]=E.symbol,b[d++]=R.symbol,g-=8}else for(;e.off>=16&&g>=8;){e.fillFast();const t=s[n],i=s[r],m=e.getBitsFast(t.nbBits),u=e.getBitsFast(i.nbBits);n=t.newState+m,r=i.newState+u,e.fillFast();const w=s[o],B=s[a],y=e.getBitsFast(w.nbBits),p=e.getBitsFast(B.nbBits);o=w.newState+y,a=B.newState+p,e.fillFast();const F=s[l],S=s[h],I=e.getBitsFast(F.nbBits),C=e.getBitsFast(S.nbBits);l=F.newState+I,h=S.newState+C,e.fillFast();const E=s[c],R=s[f],M=e.getBitsFast(E.nbBits),U=e.getBitsFast(R.nbBits);c=E.newState+M,f=R.newState+U,b[d++]=t.symbol,b[d++]=i.symbol,b[d++]=w.symbol,b[d++]=B.symbol,b[d++]=F.symbol,b[d++]=S.symbol,b[d++]=E.symbol,b[d++]=R.symbol,g-=8}const m=[n,r,o,a,l,h,c,f];for(;g>0;)for(let t=0;t<8&&0!==g;t++){e.fill();const i=s[m[t]];m[t]=i.newState+e.getBits(i.nbBits),b[d++]=i.symbol,g--}return e.close(),b}_readNCount(){const t=this.byteReader;if(t.remain()<4)throw new Error("input too small");let e=t.uint32(),s=5+(15&e);if(s>17)throw new Error("tableLog too large");e>>>=4;let i=4;this.actualTableLog=s;let n=1+(1<<s),r=1<<s,o=0;s++;let a=0,l=!1;const h=t.remain();for(;n>1;){if(l){let s=a;for(;!(65535&~e);)s+=24,t.off<h-5?(t.advance(2),e=t.uint32()>>>i):(e>>>=16,i+=16);for(;!(3&~e);)s+=3,e>>>=2,i+=2;if(s+=3&e,i+=2,s>65535)throw new Error("maxSymbolValue too small");for(;a<s;)this.norm[a]=0,a++;t.off<=h-7||t.off+(i>>>3)<=h-4?(t.advance(i>>>3),i&=7,e=t.uint32()>>>i):e>>>=2}const c=2*r-1-n;let f;for((e&r-1)<c?(f=e&r-1,i+=s-1):(f=e&2*r-1,f>=r&&(f-=c),i+=s),f--,f<0?(n+=f,o-=f):(n-=f,o+=f),this.norm[a]=f,a++,l=0===f;n<r;)s--,r>>=1;t.off<=h-7||t.off+(i>>>3)<=h-4?(t.advance(i>>>3),i&=7):(i-=8*(t.b.length-4-t.off),t.off=t.b.length-4),e=t.uint32()>>>(31&i)}if(this.symbolLen=a,this.symbolLen<=1)throw new Error(`symbolLen (${this.symbolLen}) too small`);if(this.symbolLen>65536)throw new Error("symbolLen too big");if(1!==n)throw new Error(`corruption detected (remaining ${n} != 1)`);if(i>32)throw new Error(`corruption detected (bitCount ${i} > 32)`);if(o!==1<<this.actualTableLog)throw new Error(`corruption detected (total ${o} != ${1<<this.actualTableLog})`);t.advance(i+7>>>3)}_buildDtable(){const t=1<<this.actualTableLog;let e=t-1;this.decTable=new Array(t);for(let e=0;e<t;e++)this.decTable[e]={newState:0,symbol:0,nbBits:0};const s=new Uint32Array(this.symbolLen);this.zeroBits=!1;const i=1<<this.actualTableLog-1;for(let t=0;t<this.symbolLen;t++){const n=this.norm[t];-1===n?(this.decTable[e].symbol=t,e--,s[t]=1):(n>=i&&(this.zeroBits=!0),s[t]=n)}const n=t-1,r=tableStep(t);let o=0;for(let t=0;t<this.symbolLen;t++){const s=this.norm[t];for(let i=0;i<s;i++)for(this.decTable[o].symbol=t,o=o+r&n;o>e;)o=o+r&n}if(0!==o)throw new Error("corrupted input (position != 0)");for(let e=0;e<t;e++){const i=this.decTable[e].symbol,n=s[i];s[i]=n+1;const r=this.actualTableLog-highBits(n);this.decTable[e].nbBits=r;const o=(n<<r)-t;if(o>=t)throw new Error(`newState (${o}) outside table size (${t})`);this.decTable[e].newState=o}}_decompressStream(){const t=this.bitReader;t.init(this.byteReader.unread());const e=this.decTable;let s=t.getBits(this.actualTableLog),i=new Uint16Array(65536),n=0;function r(t){if(n+t>i.length){const e=new Uint16Array(Math.max(2*i.length,n+t));e.set(i),i=e}}if(this.zeroBits)for(;t.off>=8;){t.fillFast();const o=e[s],a=t.getBits(o.nbBits);s=o.newState+a;const l=e[s],h=t.getBits(l.nbBits);s=l.newState+h,t.fillFast();const c=e[s],f=t.getBits(c.nbBits);s=c.newState+f;const b=e[s],d=t.getBits(b.nbBits);s=b.newState+d,r(4),i[n++]=o.symbol,i[n++]=l.symbol,i[n++]=c.symbol,i[n++]=b.symbol}else for(;t.off>=8;){t.fillFast();const o=e[s],a=t.getBitsFast(o.nbBits);s=o.newState+a;const l=e[s],h=t.getBitsFast(l.nbBits);s=l.newState+h,t.fillFast();const c=e[s],f=t.getBitsFast(c.nbBits);s=c.newState+f;const b=e[s],d=t.getBitsFast(b.nbBits);s=b.newState+d,r(4),i[n++]=o.symbol,i[n++]=l.symbol,i[n++]=c.symbol,i[n++]=b.symbol}for(;;){if(t.finished()&&e[s].nbBits>0){0!==s&&(r(1),i[n++]=e[s].symbol);break}t.fill();const o=e[s],a=t.getBits(o.nbBits);s=o.newState+a,r(1),i[n++]=o.symbol}return t.close(),i.subarray(0,n)}}class RLEDecompressor{constructor(t,e){this.in=t,this.i=e,this.c=0,this.midCount=0,this.recurringValue=0}initFromMaxValue(t){const e=bitsLen16(t);this.midCount=(1<<e-1)-1}decodeNext(){if(this.c>0&&this.c<this.midCount)return this.c--,this.recurringValue;if((0===this.c||this.c===this.midCount)&&(this.c=this.in[this.i++],this.c<=this.midCount))return this.recurringValue=this.in[this.i++],this.c--,this.recurringValue;const t=this.in[this.i++];return this.c--,t}}function deltaRleDecompress(t,e,s){const i=t[0],n=new RLEDecompressor(t,1);n.initFromMaxValue(i);const r=bitsLen16(n.decodeNext()),o=(1<<r-1)-1,a=(1<<r)-1,l=new Uint16Array(e*s);function h(t,e){const s=n.decodeNext();if(s===a)l[t]=n.decodeNext();else{const i=s-o;l[t]=e+i&65535}}{const t=n.decodeNext();l[0]=t===a?n.decodeNext():t-o&65535}for(let t=1;t<e;t++)h(t,l[t-1]);for(let t=1;t<s;t++){const s=t*e;h(s,l[s-e]);for(let t=1;t<e;t++){const i=s+t;h(i,l[i-1]+l[i-e]>>1)}}return l}function deltaDecompress(t,e,s){const i=bitsLen16(t[0]),n=(1<<i-1)-1,r=(1<<i)-1,o=new Uint16Array(e*s);let a=1;{const e=t[a++];o[0]=e===r?t[a++]:e-n&65535}for(let s=1;s<e;s++){const e=t[a++];o[s]=e===r?t[a++]:o[s-1]+e-n&65535}for(let i=1;i<s;i++){const s=i*e,l=t[a++];o[s]=l===r?t[a++]:o[s-e]+l-n&65535;for(let i=1;i<e;i++){const l=s+i,h=t[a++];if(h===r)o[l]=t[a++];else{const t=o[l-1]+o[l-e]>>1;o[l]=t+h-n&65535}}}return o}function rleDecompress(t){const e=(1<<bitsLen16(t[0])-1)-1;let s=1;const i=(t[s]<<16)+t[s+1];s+=2;const n=new RLEDecompressor(t,s);n.midCount=e;const r=new Uint16Array(i);for(let t=0;t<i;t++)r[t]=n.decodeNext();return r}const MIC_MAGIC=826493261,MIC2_MAGIC=843270477,MIC3_MAGIC=860047693,MICR_MAGIC=1380141389,PICS_MAGIC=1396918608,MIC2_HEADER_SIZE=20,MIC2_ENTRY_SIZE=8,PICS_HEADER_BASE=20,PIPELINE_TEMPORAL=2,MIC3_HEADER_SIZE=48,MIC3_LEVEL_SIZE=20,MIC3_TILE_ENTRY_SIZE=16,PLANE_CONSTANT_ZERO=0,PLANE_CONSTANT=1,PLANE_COMPRESSED=2,PLANE_RAW=3;function parsePICSHeader(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength);if(t.length<20)throw new Error("PICS: file too small");if(1396918608!==e.getUint32(0,!0))throw new Error("PICS: bad magic");const s=e.getUint32(4,!0),i=e.getUint32(8,!0),n=e.getUint32(12,!0),r=e.getUint32(16,!0),o=20+8*n;if(t.length<o)throw new Error("PICS: truncated offset table");const a=[];for(let t=0;t<n;t++){const s=20+8*t;a.push({offset:e.getUint32(s,!0),length:e.getUint32(s+4,!0)})}return{width:s,height:i,numStrips:n,stripH:r,strips:a,dataOffset:o}}function decodePICS(t){const e=parsePICSHeader(t),{width:s,height:i,numStrips:n,stripH:r,strips:o,dataOffset:a}=e,l=new Uint16Array(s*i);for(let e=0;e<n;e++){const n=e*r,h=Math.min(n+r,i)-n,c=a+o[e].offset,f=t.subarray(c,c+o[e].length),b=deltaRleDecompress((new FSEDecompressor).decompress(f),s,h);l.set(b,n*s)}return{pixels:l,width:s,height:i,isPICS:!0,numStrips:n}}function parseMIC2Header(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength);if(t.length<20)throw new Error("MIC2: file too small");if(843270477!==e.getUint32(0,!0))throw new Error("MIC2: invalid magic");const s=e.getUint32(4,!0),i=e.getUint32(8,!0),n=e.getUint32(12,!0),r=!!(2&t[16]),o=20+8*n;if(t.length<o)throw new Error("MIC2: file truncated in frame table");const a=[];for(let t=0;t<n;t++){const s=20+8*t;a.push({offset:e.getUint32(s,!0),length:e.getUint32(s+4,!0)})}return{width:s,height:i,frameCount:n,temporal:r,frameTable:a,dataOffset:o}}function temporalDeltaDecode(t,e){const s=new Uint16Array(t.length);for(let i=0;i<t.length;i++){const n=t[i],r=n>>>1^-(1&n);s[i]=e[i]+r&65535}return s}function decompressResidualFrame(t){return rleDecompress((new FSEDecompressor).decompress(t))}function parseMIC3Header(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength);if(t.length<48)throw new Error("MIC3: file too small");if(860047693!==e.getUint32(0,!0))throw new Error("MIC3: invalid magic");const s=e.getUint32(4,!0);if(1!==s)throw new Error(`MIC3: unsupported version ${s}`);const i=e.getUint32(8,!0),n=e.getUint32(12,!0),r=e.getUint32(16,!0),o=e.getUint32(20,!0),a=e.getUint16(24,!0),l=t[26],h=!!(2&t[27]),c=e.getUint16(28,!0),f=e.getUint32(32,!0);let b=48;const d=[];for(let t=0;t<c;t++)d.push({width:e.getUint32(b,!0),height:e.getUint32(b+4,!0),tilesX:e.getUint32(b+8,!0),tilesY:e.getUint32(b+12,!0),firstTileIdx:e.getUint32(b+16,!0)}),b+=20;const g=[];for(let t=0;t<f;t++)g.push({offset:e.getUint32(b,!0),length:e.getUint32(b+8,!0)}),b+=16;return{width:i,height:n,tileWidth:r,tileHeight:o,channels:a,bitsPerSample:l,colorTransform:h,levels:d,tileTable:g,dataOffset:b,totalTiles:f,isMIC3:!0}}function yCoCgRInverse(t,e,s,i,n){const r=i*n,o=new Uint8Array(3*r);for(let i=0;i<r;i++){const n=t[i],r=e[i]>>>1^-(1&e[i]),a=s[i]>>>1^-(1&s[i]),l=n-(a>>1),h=a+l,c=l-(r>>1),f=r+c;o[3*i]=255&f,o[3*i+1]=255&h,o[3*i+2]=255&c}return o}function decompressWSIPlane(t,e,s){if(0===t.length)throw new Error("empty plane data");const i=t[0],n=e*s;switch(i){case 0:return new Uint16Array(n);case 1:{if(t.length<3)throw new Error("constant plane truncated");const e=t[1]|t[2]<<8,s=new Uint16Array(n);return s.fill(e),s}case 2:{const i=t.subarray(1);return deltaRleDecompress((new FSEDecompressor).decompress(i),e,s)}case 3:{if(t.length<1+2*n)throw new Error("raw plane truncated");const e=new Uint16Array(n);for(let s=0;s<n;s++)e[s]=t[1+2*s]|t[2+2*s]<<8;return e}default:throw new Error(`unknown plane mode ${i}`)}}function decompressRGBTileBlob(t,e,s,i){if(t.length<12)throw new Error("MIC3: RGB tile blob too small");const n=new DataView(t.buffer,t.byteOffset,t.byteLength),r=n.getUint32(0,!0),o=n.getUint32(4,!0),a=n.getUint32(8,!0);let l=12;const h=decompressWSIPlane(t.subarray(l,l+r),e,s);l+=r;const c=decompressWSIPlane(t.subarray(l,l+o),e,s);l+=o;const f=decompressWSIPlane(t.subarray(l,l+a),e,s);if(i)return yCoCgRInverse(h,c,f,e,s);const b=e*s,d=new Uint8Array(3*b);for(let t=0;t<b;t++)d[3*t]=255&h[t],d[3*t+1]=255&c[t],d[3*t+2]=255&f[t];return d}function decompressMIC3Level(t,e,s){const i=e.levels[s],{tileWidth:n,tileHeight:r,channels:o,bitsPerSample:a,colorTransform:l}=e,h=o,c=new Uint8Array(i.width*i.height*h);for(let s=0;s<i.tilesY;s++)for(let f=0;f<i.tilesX;f++){const b=i.firstTileIdx+s*i.tilesX+f,d=e.tileTable[b],g=e.dataOffset+d.offset,m=t.subarray(g,g+d.length);let u;if(3!==o||8!==a)throw new Error("MIC3: only 8-bit RGB supported in browser decoder");u=decompressRGBTileBlob(m,n,r,l);const w=f*n,B=s*r,y=Math.min(n,i.width-w),p=Math.min(r,i.height-B);for(let t=0;t<p;t++){const e=t*n*h,s=((B+t)*i.width+w)*h,r=y*h;c.set(u.subarray(e,e+r),s)}}return c}export const MICDecoder={decode:(t,e,s)=>deltaRleDecompress((new FSEDecompressor).decompress(t),e,s),decodeFile(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength),s=e.getUint32(0,!0);if(1396918608===s)return decodePICS(t);if(1380141389===s){if(t.length<12)throw new Error("MICR: file too small");const s=e.getUint32(4,!0),i=e.getUint32(8,!0);return{rgb:decompressRGBTileBlob(t.subarray(12),s,i,!0),width:s,height:i,channels:3,isMICR:!0}}if(860047693===s){const e=parseMIC3Header(t);return{rgb:decompressMIC3Level(t,e,0),width:e.levels[0].width,height:e.levels[0].height,channels:e.channels,isMIC3:!0,mic3Header:e}}if(843270477===s){const e=parseMIC2Header(t);return{pixels:this.decodeMIC2Frame(t,0,null,e),width:e.width,height:e.height,isMIC2:!0,frameCount:e.frameCount,temporal:e.temporal}}if(826493261!==s)throw new Error(`Invalid .mic file (bad magic: 0x${s.toString(16)})`);const i=e.getUint32(4,!0),n=e.getUint32(8,!0),r=e.getUint32(12,!0),o=e.getUint32(16,!0);if(1!==r)throw new Error(`Unsupported pipeline type: ${r} (expected 1 = Delta+RLE+FSE)`);const a=t.subarray(20,20+o);return{pixels:this.decode(a,i,n),width:i,height:n,isMIC2:!1}},parseMIC2Header:t=>parseMIC2Header(t),parsePICSHeader:t=>parsePICSHeader(t),decodeMIC2Frame(t,e,s,i){i||(i=parseMIC2Header(t));const n=i.frameTable[e],r=i.dataOffset+n.offset,o=t.subarray(r,r+n.length);if(i.temporal&&e>0){const t=decompressResidualFrame(o);if(!s)throw new Error(`MIC2 temporal: prevPixels required for frame ${e}`);return temporalDeltaDecode(t,s)}return this.decode(o,i.width,i.height)},fseDecompress:t=>(new FSEDecompressor).decompress(t),rleDecompress:t=>rleDecompress(t),deltaDecompress:(t,e,s)=>deltaDecompress(t,e,s),deltaRleDecompress:(t,e,s)=>deltaRleDecompress(t,e,s),parseMIC3Header:t=>parseMIC3Header(t),decodeMIC3Level(t,e){const s=parseMIC3Header(t);if(e<0||e>=s.levels.length)throw new Error(`MIC3: level ${e} out of range [0, ${s.levels.length})`);return{rgb:decompressMIC3Level(t,s,e),width:s.levels[e].width,height:s.levels[e].height}},decodeRGBPlane:(t,e,s)=>decompressWSIPlane(t,e,s),applyYCoCgRInverse:(t,e,s,i,n)=>yCoCgRInverse(t,e,s,i,n),parseMICRPlanes(t){if(t.length<12)throw new Error("MICR: file too small");const e=new DataView(t.buffer,t.byteOffset,t.byteLength),s=e.getUint32(4,!0),i=e.getUint32(8,!0),n=t.subarray(12);if(n.length<12)throw new Error("MICR: blob too small");const r=new DataView(n.buffer,n.byteOffset,n.byteLength),o=r.getUint32(0,!0),a=r.getUint32(4,!0),l=r.getUint32(8,!0);let h=12;const c=n.subarray(h,h+o);h+=o;const f=n.subarray(h,h+a);h+=a;return{width:s,height:i,yBlob:c,coBlob:f,cgBlob:n.subarray(h,h+l)}}};export default MICDecoder;
\ No newline at end of file
+const MIN_TABLELOG=5,MAX_TABLELOG=17,MAX_SYMBOL_VALUE=65535;class BitReader{constructor(){this.in=null,this.off=0,this.value=0n,this.bitsRead=64}init(t){if(t.length<1)throw new Error("corrupt stream: too short");this.in=t,this.off=t.length;const e=t[t.length-1];if(0===e)throw new Error("corrupt stream: did not find end of stream");this.bitsRead=64,this.value=0n,t.length>=8?this._fillFastStart():(this.fill(),this.fill()),this.bitsRead+=8-highBits(e)}_fillFastStart(){const t=this.off-8,e=this.in;this.value=BigInt(e[t])|BigInt(e[t+1])<<8n|BigInt(e[t+2])<<16n|BigInt(e[t+3])<<24n|BigInt(e[t+4])<<32n|BigInt(e[t+5])<<40n|BigInt(e[t+6])<<48n|BigInt(e[t+7])<<56n,this.bitsRead=0,this.off-=8}fillFast(){if(this.bitsRead<32)return;const t=this.off-4,e=this.in,s=BigInt((e[t]|e[t+1]<<8|e[t+2]<<16|e[t+3]<<24)>>>0);this.value=0xFFFFFFFFFFFFFFFFn&(this.value<<32n|s),this.bitsRead-=32,this.off-=4}fill(){if(!(this.bitsRead<32)){if(this.off>4){const t=this.off-4,e=this.in,s=BigInt((e[t]|e[t+1]<<8|e[t+2]<<16|e[t+3]<<24)>>>0);return this.value=0xFFFFFFFFFFFFFFFFn&(this.value<<32n|s),this.bitsRead-=32,void(this.off-=4)}for(;this.off>0;)this.value=0xFFFFFFFFFFFFFFFFn&(this.value<<8n|BigInt(this.in[this.off-1])),this.bitsRead-=8,this.off--}}getBitsFast(t){const e=BigInt(63&this.bitsRead),s=Number((this.value<<e&0xFFFFFFFFFFFFFFFFn)>>BigInt(64-t&63));return this.bitsRead+=t,s>>>0}getBits(t){return 0===t||this.bitsRead>=64?0:this.getBitsFast(t)}finished(){return this.bitsRead>=64&&0===this.off}close(){if(this.bitsRead>64)throw new Error("unexpected EOF in bitstream")}}class ByteReader{constructor(){this.b=null,this.off=0}init(t){this.b=t,this.off=0}advance(t){this.off+=t}uint32(){const t=this.off,e=this.b;return(e[t]|e[t+1]<<8|e[t+2]<<16|e[t+3]<<24>>>0)>>>0}unread(){return this.b.subarray(this.off)}remain(){return this.b.length-this.off}}function highBits(t){return 0===t?0:31-Math.clz32(t)}function bitsLen16(t){return 0===t?0:32-Math.clz32(t)}function tableStep(t){return(t>>>1)+(t>>>3)+3>>>0}class FSEDecompressor{constructor(){this.norm=new Int32Array(65536),this.decTable=null,this.symbolLen=0,this.actualTableLog=0,this.zeroBits=!1,this.byteReader=new ByteReader,this.bitReader=new BitReader}decompress(t){return t.length>=2&&255===t[0]&&132===t[1]?this._decompress8State(t):t.length>=2&&255===t[0]&&4===t[1]?this._decompress4State(t):t.length>=2&&255===t[0]&&2===t[1]?this._decompress2State(t):(this.byteReader.init(t),this._readNCount(),this._buildDtable(),this._decompressStream())}_decompress8State(t){if(t.length<6)throw new Error("fse8state: input too small");const e=(t[2]|t[3]<<8|t[4]<<16|t[5]<<24>>>0)>>>0;return this.byteReader.init(t.subarray(6)),this._readNCount(),this._buildDtable(),this._decompressStream8State(e)}_decompress4State(t){if(t.length<6)throw new Error("fse4state: input too small");const e=(t[2]|t[3]<<8|t[4]<<16|t[5]<<24>>>0)>>>0;return this.byteReader.init(t.subarray(6)),this._readNCount(),this._buildDtable(),this._decompressStream4State(e)}_decompress2State(t){if(t.length<6)throw new Error("fse2state: input too small");const e=(t[2]|t[3]<<8|t[4]<<16|t[5]<<24>>>0)>>>0;return this.byteReader.init(t.subarray(6)),this._readNCount(),this._buildDtable(),this._decompressStream2State(e)}_decompressStream2State(t){const e=this.bitReader;e.init(this.byteReader.unread());const s=this.decTable,i=this.actualTableLog;let n=e.getBits(i);e.fill();let r=e.getBits(i);const o=new Uint16Array(t);let a=0,l=t;if(this.zeroBits)for(;e.off>=8&&l>=4;){e.fillFast();const t=s[n],i=s[r],h=e.getBits(t.nbBits),c=e.getBits(i.nbBits);n=t.newState+h,r=i.newState+c,e.fillFast();const f=s[n],b=s[r],d=e.getBits(f.nbBits),g=e.getBits(b.nbBits);n=f.newState+d,r=b.newState+g,o[a++]=t.symbol,o[a++]=i.symbol,o[a++]=f.symbol,o[a++]=b.symbol,l-=4}else for(;e.off>=8&&l>=4;){e.fillFast();const t=s[n],i=s[r],h=e.getBitsFast(t.nbBits),c=e.getBitsFast(i.nbBits);n=t.newState+h,r=i.newState+c,e.fillFast();const f=s[n],b=s[r],d=e.getBitsFast(f.nbBits),g=e.getBitsFast(b.nbBits);n=f.newState+d,r=b.newState+g,o[a++]=t.symbol,o[a++]=i.symbol,o[a++]=f.symbol,o[a++]=b.symbol,l-=4}for(;l>0;){e.fill();const t=s[n];if(n=t.newState+e.getBits(t.nbBits),o[a++]=t.symbol,0===--l)break;e.fill();const i=s[r];r=i.newState+e.getBits(i.nbBits),o[a++]=i.symbol,l--}return e.close(),o}_decompressStream4State(t){const e=this.bitReader;e.init(this.byteReader.unread());const s=this.decTable,i=this.actualTableLog;let n=e.getBits(i),r=e.getBits(i);e.fill();let o=e.getBits(i);e.fill();let a=e.getBits(i);const l=new Uint16Array(t);let h=0,c=t;if(this.zeroBits)for(;e.off>=8&&c>=4;){e.fillFast();const t=s[n],i=s[r],f=e.getBits(t.nbBits),b=e.getBits(i.nbBits);n=t.newState+f,r=i.newState+b,e.fillFast();const d=s[o],g=s[a],m=e.getBits(d.nbBits),u=e.getBits(g.nbBits);o=d.newState+m,a=g.newState+u,l[h++]=t.symbol,l[h++]=i.symbol,l[h++]=d.symbol,l[h++]=g.symbol,c-=4}else for(;e.off>=8&&c>=4;){e.fillFast();const t=s[n],i=s[r],f=e.getBitsFast(t.nbBits),b=e.getBitsFast(i.nbBits);n=t.newState+f,r=i.newState+b,e.fillFast();const d=s[o],g=s[a],m=e.getBitsFast(d.nbBits),u=e.getBitsFast(g.nbBits);o=d.newState+m,a=g.newState+u,l[h++]=t.symbol,l[h++]=i.symbol,l[h++]=d.symbol,l[h++]=g.symbol,c-=4}for(;c>0;){e.fill();const t=s[n];if(n=t.newState+e.getBits(t.nbBits),l[h++]=t.symbol,0===--c)break;e.fill();const i=s[r];if(r=i.newState+e.getBits(i.nbBits),l[h++]=i.symbol,0===--c)break;e.fill();const f=s[o];if(o=f.newState+e.getBits(f.nbBits),l[h++]=f.symbol,0===--c)break;e.fill();const b=s[a];a=b.newState+e.getBits(b.nbBits),l[h++]=b.symbol,c--}return e.close(),l}_decompressStream8State(t){const e=this.bitReader;e.init(this.byteReader.unread());const s=this.decTable,i=this.actualTableLog;let n=e.getBits(i),r=e.getBits(i);e.fill();let o=e.getBits(i),a=e.getBits(i);e.fill();let l=e.getBits(i),h=e.getBits(i);e.fill();let c=e.getBits(i),f=e.getBits(i);const b=new Uint16Array(t);let d=0,g=t;if(this.zeroBits)for(;e.off>=16&&g>=8;){e.fillFast();const t=s[n],i=s[r],m=e.getBits(t.nbBits),u=e.getBits(i.nbBits);n=t.newState+m,r=i.newState+u,e.fillFast();const w=s[o],B=s[a],y=e.getBits(w.nbBits),p=e.getBits(B.nbBits);o=w.newState+y,a=B.newState+p,e.fillFast();const F=s[l],S=s[h],I=e.getBits(F.nbBits),C=e.getBits(S.nbBits);l=F.newState+I,h=S.newState+C,e.fillFast();const E=s[c],R=s[f],M=e.getBits(E.nbBits),U=e.getBits(R.nbBits);c=E.newState+M,f=R.newState+U,b[d++]=t.symbol,b[d++]=i.symbol,b[d++]=w.symbol,b[d++]=B.symbol,b[d++]=F.symbol,b[d++]=S.symbol,b[d++]=E.symbol,b[d++]=R.symbol,g-=8}else for(;e.off>=16&&g>=8;){e.fillFast();const t=s[n],i=s[r],m=e.getBitsFast(t.nbBits),u=e.getBitsFast(i.nbBits);n=t.newState+m,r=i.newState+u,e.fillFast();const w=s[o],B=s[a],y=e.getBitsFast(w.nbBits),p=e.getBitsFast(B.nbBits);o=w.newState+y,a=B.newState+p,e.fillFast();const F=s[l],S=s[h],I=e.getBitsFast(F.nbBits),C=e.getBitsFast(S.nbBits);l=F.newState+I,h=S.newState+C,e.fillFast();const E=s[c],R=s[f],M=e.getBitsFast(E.nbBits),U=e.getBitsFast(R.nbBits);c=E.newState+M,f=R.newState+U,b[d++]=t.symbol,b[d++]=i.symbol,b[d++]=w.symbol,b[d++]=B.symbol,b[d++]=F.symbol,b[d++]=S.symbol,b[d++]=E.symbol,b[d++]=R.symbol,g-=8}const m=[n,r,o,a,l,h,c,f];for(;g>0;)for(let t=0;t<8&&0!==g;t++){e.fill();const i=s[m[t]];m[t]=i.newState+e.getBits(i.nbBits),b[d++]=i.symbol,g--}return e.close(),b}_readNCount(){const t=this.byteReader;if(t.remain()<4)throw new Error("input too small");let e=t.uint32(),s=5+(15&e);if(s>17)throw new Error("tableLog too large");e>>>=4;let i=4;this.actualTableLog=s;let n=1+(1<<s),r=1<<s,o=0;s++;let a=0,l=!1;const h=t.remain();for(;n>1;){if(l){let s=a;for(;!(65535&~e);)s+=24,t.off<h-5?(t.advance(2),e=t.uint32()>>>i):(e>>>=16,i+=16);for(;!(3&~e);)s+=3,e>>>=2,i+=2;if(s+=3&e,i+=2,s>65535)throw new Error("maxSymbolValue too small");for(;a<s;)this.norm[a]=0,a++;t.off<=h-7||t.off+(i>>>3)<=h-4?(t.advance(i>>>3),i&=7,e=t.uint32()>>>i):e>>>=2}const c=2*r-1-n;let f;for((e&r-1)<c?(f=e&r-1,i+=s-1):(f=e&2*r-1,f>=r&&(f-=c),i+=s),f--,f<0?(n+=f,o-=f):(n-=f,o+=f),this.norm[a]=f,a++,l=0===f;n<r;)s--,r>>=1;t.off<=h-7||t.off+(i>>>3)<=h-4?(t.advance(i>>>3),i&=7):(i-=8*(t.b.length-4-t.off),t.off=t.b.length-4),e=t.uint32()>>>(31&i)}if(this.symbolLen=a,this.symbolLen<=1)throw new Error(`symbolLen (${this.symbolLen}) too small`);if(this.symbolLen>65536)throw new Error("symbolLen too big");if(1!==n)throw new Error(`corruption detected (remaining ${n} != 1)`);if(i>32)throw new Error(`corruption detected (bitCount ${i} > 32)`);if(o!==1<<this.actualTableLog)throw new Error(`corruption detected (total ${o} != ${1<<this.actualTableLog})`);t.advance(i+7>>>3)}_buildDtable(){const t=1<<this.actualTableLog;let e=t-1;this.decTable=new Array(t);for(let e=0;e<t;e++)this.decTable[e]={newState:0,symbol:0,nbBits:0};const s=new Uint32Array(this.symbolLen);this.zeroBits=!1;const i=1<<this.actualTableLog-1;for(let t=0;t<this.symbolLen;t++){const n=this.norm[t];-1===n?(this.decTable[e].symbol=t,e--,s[t]=1):(n>=i&&(this.zeroBits=!0),s[t]=n)}const n=t-1,r=tableStep(t);let o=0;for(let t=0;t<this.symbolLen;t++){const s=this.norm[t];for(let i=0;i<s;i++)for(this.decTable[o].symbol=t,o=o+r&n;o>e;)o=o+r&n}if(0!==o)throw new Error("corrupted input (position != 0)");for(let e=0;e<t;e++){const i=this.decTable[e].symbol,n=s[i];s[i]=n+1;const r=this.actualTableLog-highBits(n);this.decTable[e].nbBits=r;const o=(n<<r)-t;if(o>=t)throw new Error(`newState (${o}) outside table size (${t})`);this.decTable[e].newState=o}}_decompressStream(){const t=this.bitReader;t.init(this.byteReader.unread());const e=this.decTable;let s=t.getBits(this.actualTableLog),i=new Uint16Array(65536),n=0;function r(t){if(n+t>i.length){const e=new Uint16Array(Math.max(2*i.length,n+t));e.set(i),i=e}}if(this.zeroBits)for(;t.off>=8;){t.fillFast();const o=e[s],a=t.getBits(o.nbBits);s=o.newState+a;const l=e[s],h=t.getBits(l.nbBits);s=l.newState+h,t.fillFast();const c=e[s],f=t.getBits(c.nbBits);s=c.newState+f;const b=e[s],d=t.getBits(b.nbBits);s=b.newState+d,r(4),i[n++]=o.symbol,i[n++]=l.symbol,i[n++]=c.symbol,i[n++]=b.symbol}else for(;t.off>=8;){t.fillFast();const o=e[s],a=t.getBitsFast(o.nbBits);s=o.newState+a;const l=e[s],h=t.getBitsFast(l.nbBits);s=l.newState+h,t.fillFast();const c=e[s],f=t.getBitsFast(c.nbBits);s=c.newState+f;const b=e[s],d=t.getBitsFast(b.nbBits);s=b.newState+d,r(4),i[n++]=o.symbol,i[n++]=l.symbol,i[n++]=c.symbol,i[n++]=b.symbol}for(;;){if(t.finished()&&e[s].nbBits>0){0!==s&&(r(1),i[n++]=e[s].symbol);break}t.fill();const o=e[s],a=t.getBits(o.nbBits);s=o.newState+a,r(1),i[n++]=o.symbol}return t.close(),i.subarray(0,n)}}class RLEDecompressor{constructor(t,e){this.in=t,this.i=e,this.c=0,this.midCount=0,this.recurringValue=0}initFromMaxValue(t){const e=bitsLen16(t);this.midCount=(1<<e-1)-1}decodeNext(){if(this.c>0&&this.c<this.midCount)return this.c--,this.recurringValue;if((0===this.c||this.c===this.midCount)&&(this.c=this.in[this.i++],this.c<=this.midCount))return this.recurringValue=this.in[this.i++],this.c--,this.recurringValue;const t=this.in[this.i++];return this.c--,t}}function deltaRleDecompress(t,e,s){const i=t[0],n=new RLEDecompressor(t,1);n.initFromMaxValue(i);const r=bitsLen16(n.decodeNext()),o=(1<<r-1)-1,a=(1<<r)-1,l=new Uint16Array(e*s);function h(t,e){const s=n.decodeNext();if(s===a)l[t]=n.decodeNext();else{const i=s-o;l[t]=e+i&65535}}{const t=n.decodeNext();l[0]=t===a?n.decodeNext():t-o&65535}for(let t=1;t<e;t++)h(t,l[t-1]);for(let t=1;t<s;t++){const s=t*e;h(s,l[s-e]);for(let t=1;t<e;t++){const i=s+t;h(i,l[i-1]+l[i-e]>>1)}}return l}function deltaDecompress(t,e,s){const i=bitsLen16(t[0]),n=(1<<i-1)-1,r=(1<<i)-1,o=new Uint16Array(e*s);let a=1;{const e=t[a++];o[0]=e===r?t[a++]:e-n&65535}for(let s=1;s<e;s++){const e=t[a++];o[s]=e===r?t[a++]:o[s-1]+e-n&65535}for(let i=1;i<s;i++){const s=i*e,l=t[a++];o[s]=l===r?t[a++]:o[s-e]+l-n&65535;for(let i=1;i<e;i++){const l=s+i,h=t[a++];if(h===r)o[l]=t[a++];else{const t=o[l-1]+o[l-e]>>1;o[l]=t+h-n&65535}}}return o}function rleDecompress(t){const e=(1<<bitsLen16(t[0])-1)-1;let s=1;const i=(t[s]<<16)+t[s+1];s+=2;const n=new RLEDecompressor(t,s);n.midCount=e;const r=new Uint16Array(i);for(let t=0;t<i;t++)r[t]=n.decodeNext();return r}const MIC_MAGIC=826493261,MIC2_MAGIC=843270477,MIC3_MAGIC=860047693,MICR_MAGIC=1380141389,PICS_MAGIC=1396918608,MIC2_HEADER_SIZE=20,MIC2_ENTRY_SIZE=8,PICS_HEADER_BASE=20,PIPELINE_TEMPORAL=2,SINGLE_FRAME_HEADER_SIZE=24,SINGLE_FRAME_VERSIONED=128,SINGLE_FRAME_VERSION=1,MIC3_HEADER_SIZE=48,MIC3_LEVEL_SIZE=20,MIC3_TILE_ENTRY_SIZE=16,PLANE_CONSTANT_ZERO=0,PLANE_CONSTANT=1,PLANE_COMPRESSED=2,PLANE_RAW=3;function parseSingleFrameHeader(t,e){const s="MICR"===e?12:20;if(t.length<s)throw new Error(`${e}: file too small`);const i=new DataView(t.buffer,t.byteOffset,t.byteLength),n=i.getUint32(4,!0),r=i.getUint32(8,!0);if(t.length<24||!(128&t[15])){if("MICR"===e)return{width:n,height:r,pipeline:1,bitsStored:8,signed:!1,maxValue:255,version:0,payload:t.subarray(12)};const s=i.getUint32(16,!0);return{width:n,height:r,pipeline:i.getUint32(12,!0),bitsStored:0,signed:!1,maxValue:0,version:0,payload:t.subarray(20,20+s)}}const o=-129&t[15];if(o>1)throw new Error(`${e}: unsupported header version ${o}`);const a=i.getUint32(16,!0);if(a>t.length-24)throw new Error(`${e}: payload extends beyond file`);return{width:n,height:r,pipeline:t[12],bitsStored:t[13],signed:!!(1&t[14]),maxValue:i.getUint16(20,!0),version:o,payload:t.subarray(24,24+a)}}function parsePICSHeader(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength);if(t.length<20)throw new Error("PICS: file too small");if(1396918608!==e.getUint32(0,!0))throw new Error("PICS: bad magic");const s=e.getUint32(4,!0),i=e.getUint32(8,!0),n=e.getUint32(12,!0),r=e.getUint32(16,!0),o=20+8*n;if(t.length<o)throw new Error("PICS: truncated offset table");const a=[];for(let t=0;t<n;t++){const s=20+8*t;a.push({offset:e.getUint32(s,!0),length:e.getUint32(s+4,!0)})}return{width:s,height:i,numStrips:n,stripH:r,strips:a,dataOffset:o}}function decodePICS(t){const e=parsePICSHeader(t),{width:s,height:i,numStrips:n,stripH:r,strips:o,dataOffset:a}=e,l=new Uint16Array(s*i);for(let e=0;e<n;e++){const n=e*r,h=Math.min(n+r,i)-n,c=a+o[e].offset,f=t.subarray(c,c+o[e].length),b=deltaRleDecompress((new FSEDecompressor).decompress(f),s,h);l.set(b,n*s)}return{pixels:l,width:s,height:i,isPICS:!0,numStrips:n}}function parseMIC2Header(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength);if(t.length<20)throw new Error("MIC2: file too small");if(843270477!==e.getUint32(0,!0))throw new Error("MIC2: invalid magic");const s=e.getUint32(4,!0),i=e.getUint32(8,!0),n=e.getUint32(12,!0),r=!!(2&t[16]),o=20+8*n;if(t.length<o)throw new Error("MIC2: file truncated in frame table");const a=[];for(let t=0;t<n;t++){const s=20+8*t;a.push({offset:e.getUint32(s,!0),length:e.getUint32(s+4,!0)})}return{width:s,height:i,frameCount:n,temporal:r,frameTable:a,dataOffset:o}}function temporalDeltaDecode(t,e){const s=new Uint16Array(t.length);for(let i=0;i<t.length;i++){const n=t[i],r=n>>>1^-(1&n);s[i]=e[i]+r&65535}return s}function decompressResidualFrame(t){return rleDecompress((new FSEDecompressor).decompress(t))}function parseMIC3Header(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength);if(t.length<48)throw new Error("MIC3: file too small");if(860047693!==e.getUint32(0,!0))throw new Error("MIC3: invalid magic");const s=e.getUint32(4,!0);if(1!==s)throw new Error(`MIC3: unsupported version ${s}`);const i=e.getUint32(8,!0),n=e.getUint32(12,!0),r=e.getUint32(16,!0),o=e.getUint32(20,!0),a=e.getUint16(24,!0),l=t[26],h=!!(2&t[27]),c=e.getUint16(28,!0),f=e.getUint32(32,!0);let b=48;const d=[];for(let t=0;t<c;t++)d.push({width:e.getUint32(b,!0),height:e.getUint32(b+4,!0),tilesX:e.getUint32(b+8,!0),tilesY:e.getUint32(b+12,!0),firstTileIdx:e.getUint32(b+16,!0)}),b+=20;const g=[];for(let t=0;t<f;t++)g.push({offset:e.getUint32(b,!0),length:e.getUint32(b+8,!0)}),b+=16;return{width:i,height:n,tileWidth:r,tileHeight:o,channels:a,bitsPerSample:l,colorTransform:h,levels:d,tileTable:g,dataOffset:b,totalTiles:f,isMIC3:!0}}function yCoCgRInverse(t,e,s,i,n){const r=i*n,o=new Uint8Array(3*r);for(let i=0;i<r;i++){const n=t[i],r=e[i]>>>1^-(1&e[i]),a=s[i]>>>1^-(1&s[i]),l=n-(a>>1),h=a+l,c=l-(r>>1),f=r+c;o[3*i]=255&f,o[3*i+1]=255&h,o[3*i+2]=255&c}return o}function decompressWSIPlane(t,e,s){if(0===t.length)throw new Error("empty plane data");const i=t[0],n=e*s;switch(i){case 0:return new Uint16Array(n);case 1:{if(t.length<3)throw new Error("constant plane truncated");const e=t[1]|t[2]<<8,s=new Uint16Array(n);return s.fill(e),s}case 2:{const i=t.subarray(1);return deltaRleDecompress((new FSEDecompressor).decompress(i),e,s)}case 3:{if(t.length<1+2*n)throw new Error("raw plane truncated");const e=new Uint16Array(n);for(let s=0;s<n;s++)e[s]=t[1+2*s]|t[2+2*s]<<8;return e}default:throw new Error(`unknown plane mode ${i}`)}}function decompressRGBTileBlob(t,e,s,i){if(t.length<12)throw new Error("MIC3: RGB tile blob too small");const n=new DataView(t.buffer,t.byteOffset,t.byteLength),r=n.getUint32(0,!0),o=n.getUint32(4,!0),a=n.getUint32(8,!0);let l=12;const h=decompressWSIPlane(t.subarray(l,l+r),e,s);l+=r;const c=decompressWSIPlane(t.subarray(l,l+o),e,s);l+=o;const f=decompressWSIPlane(t.subarray(l,l+a),e,s);if(i)return yCoCgRInverse(h,c,f,e,s);const b=e*s,d=new Uint8Array(3*b);for(let t=0;t<b;t++)d[3*t]=255&h[t],d[3*t+1]=255&c[t],d[3*t+2]=255&f[t];return d}function decompressMIC3Level(t,e,s){const i=e.levels[s],{tileWidth:n,tileHeight:r,channels:o,bitsPerSample:a,colorTransform:l}=e,h=o,c=new Uint8Array(i.width*i.height*h);for(let s=0;s<i.tilesY;s++)for(let f=0;f<i.tilesX;f++){const b=i.firstTileIdx+s*i.tilesX+f,d=e.tileTable[b],g=e.dataOffset+d.offset,m=t.subarray(g,g+d.length);let u;if(3!==o||8!==a)throw new Error("MIC3: only 8-bit RGB supported in browser decoder");u=decompressRGBTileBlob(m,n,r,l);const w=f*n,B=s*r,y=Math.min(n,i.width-w),p=Math.min(r,i.height-B);for(let t=0;t<p;t++){const e=t*n*h,s=((B+t)*i.width+w)*h,r=y*h;c.set(u.subarray(e,e+r),s)}}return c}export const MICDecoder={decode:(t,e,s)=>deltaRleDecompress((new FSEDecompressor).decompress(t),e,s),decodeFile(t){const e=new DataView(t.buffer,t.byteOffset,t.byteLength),s=e.getUint32(0,!0);if(1396918608===s)return decodePICS(t);if(1380141389===s){const e=parseSingleFrameHeader(t,"MICR");if(1!==e.pipeline)throw new Error(`Unsupported pipeline type: ${e.pipeline} (expected 1 = Delta+RLE+FSE)`);return{rgb:decompressRGBTileBlob(e.payload,e.width,e.height,!0),width:e.width,height:e.height,channels:3,isMICR:!0}}if(860047693===s){const e=parseMIC3Header(t);return{rgb:decompressMIC3Level(t,e,0),width:e.levels[0].width,height:e.levels[0].height,channels:e.channels,isMIC3:!0,mic3Header:e}}if(843270477===s){const e=parseMIC2Header(t);return{pixels:this.decodeMIC2Frame(t,0,null,e),width:e.width,height:e.height,isMIC2:!0,frameCount:e.frameCount,temporal:e.temporal}}if(826493261!==s)throw new Error(`Invalid .mic file (bad magic: 0x${s.toString(16)})`);const i=parseSingleFrameHeader(t,"MIC1");if(1!==i.pipeline)throw new Error(`Unsupported pipeline type: ${i.pipeline} (expected 1 = Delta+RLE+FSE)`);return{pixels:this.decode(i.payload,i.width,i.height),width:i.width,height:i.height,isMIC2:!1}},parseMIC2Header:t=>parseMIC2Header(t),parsePICSHeader:t=>parsePICSHeader(t),decodeMIC2Frame(t,e,s,i){i||(i=parseMIC2Header(t));const n=i.frameTable[e],r=i.dataOffset+n.offset,o=t.subarray(r,r+n.length);if(i.temporal&&e>0){const t=decompressResidualFrame(o);if(!s)throw new Error(`MIC2 temporal: prevPixels required for frame ${e}`);return temporalDeltaDecode(t,s)}return this.decode(o,i.width,i.height)},fseDecompress:t=>(new FSEDecompressor).decompress(t),rleDecompress:t=>rleDecompress(t),deltaDecompress:(t,e,s)=>deltaDecompress(t,e,s),deltaRleDecompress:(t,e,s)=>deltaRleDecompress(t,e,s),parseMIC3Header:t=>parseMIC3Header(t),decodeMIC3Level(t,e){const s=parseMIC3Header(t);if(e<0||e>=s.levels.length)throw new Error(`MIC3: level ${e} out of range [0, ${s.levels.length})`);return{rgb:decompressMIC3Level(t,s,e),width:s.levels[e].width,height:s.levels[e].height}},decodeRGBPlane:(t,e,s)=>decompressWSIPlane(t,e,s),applyYCoCgRInverse:(t,e,s,i,n)=>yCoCgRInverse(t,e,s,i,n),parseMICRPlanes(t){const{width:s,height:i,payload:n}=parseSingleFrameHeader(t,"MICR");if(n.length<12)throw new Error("MICR: blob too small");const r=new DataView(n.buffer,n.byteOffset,n.byteLength),o=r.getUint32(0,!0),a=r.getUint32(4,!0),l=r.getUint32(8,!0);let h=12;const c=n.subarray(h,h+o);h+=o;const f=n.subarray(h,h+a);h+=a;return{width:s,height:i,yBlob:c,coBlob:f,cgBlob:n.subarray(h,h+l)}}};export default MICDecoder;
\ No newline at end of file
@@ -362,7 +362,7 @@ func TestWSITileCompressWhite(t *testing.T) {
 	w, h := 256, 256
 	rgb := makeWhiteTile(w, h)
 
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, true)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -377,7 +377,7 @@ func TestWSITileCompressWhite(t *testing.T) {
 	}
 
 	// Roundtrip
-	got, err := decompressTileBlob(blob, w, h, 3, 8, true)
+	got, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, true, PredictorAvg, streamRefs{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -388,7 +388,7 @@ func TestWSITileCompressTissue(t *testing.T) {
 	w, h := 256, 256
 	rgb := makeTissueTile(w, h, 42)
 
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, true)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -404,7 +404,7 @@ func TestWSITileCompressTissue(t *testing.T) {
 	}
 
 	// Roundtrip
-	got, err := decompressTileBlob(blob, w, h, 3, 8, true)
+	got, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, true, PredictorAvg, streamRefs{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -415,7 +415,7 @@ func TestWSITileCompressGradient(t *testing.T) {
 	w, h := 256, 256
 	rgb := makeGradientTile(w, h)
 
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, true)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -424,7 +424,7 @@ func TestWSITileCompressGradient(t *testing.T) {
 	ratio := float64(rawSize) / float64(len(blob))
 	t.Logf("Gradient tile: %d bytes -> %d bytes (%.1f:1)", rawSize, len(blob), ratio)
 
-	got, err := decompressTileBlob(blob, w, h, 3, 8, true)
+	got, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, true, PredictorAvg, streamRefs{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -435,7 +435,7 @@ func TestWSITileCompressBlack(t *testing.T) {
 	w, h := 256, 256
 	rgb := makeConstantRGB(w, h, 0, 0, 0)
 
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, true)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -444,7 +444,7 @@ func TestWSITileCompressBlack(t *testing.T) {
 	ratio := float64(rawSize) / float64(len(blob))
 	t.Logf("Black tile: %d bytes -> %d bytes (%.1f:1)", rawSize, len(blob), ratio)
 
-	got, err := decompressTileBlob(blob, w, h, 3, 8, true)
+	got, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, true, PredictorAvg, streamRefs{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -455,12 +455,12 @@ func TestWSITileCompressNoColorTransform(t *testing.T) {
 	w, h := 256, 256
 	rgb := makeTissueTile(w, h, 99)
 
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, false)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, false, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	got, err := decompressTileBlob(blob, w, h, 3, 8, false)
+	got, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, false, PredictorAvg, streamRefs{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -476,12 +476,12 @@ func TestWSITileCompressRandomPixels(t *testing.T) {
 		rgb[i] = byte(rng.Intn(256))
 	}
 
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, true)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	got, err := decompressTileBlob(blob, w, h, 3, 8, true)
+	got, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, true, PredictorAvg, streamRefs{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -891,7 +891,7 @@ func BenchmarkWSITileCompressTissue(b *testing.B) {
 	b.SetBytes(int64(len(rgb)))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := compressTileBlob(rgb, w, h, 3, 8, true)
+		_, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -901,7 +901,7 @@ func BenchmarkWSITileCompressTissue(b *testing.B) {
 func BenchmarkWSITileDecompressTissue(b *testing.B) {
 	w, h := 256, 256
 	rgb := makeTissueTile(w, h, 42)
-	blob, err := compressTileBlob(rgb, w, h, 3, 8, true)
+	blob, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -909,7 +909,7 @@ func BenchmarkWSITileDecompressTissue(b *testing.B) {
 	b.SetBytes(int64(len(rgb)))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := decompressTileBlob(blob, w, h, 3, 8, true)
+		_, err := new(Decoder).decompressTileBlob(blob, w, h, 3, 8, true, PredictorAvg, streamRefs{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -923,7 +923,7 @@ func BenchmarkWSITileCompressWhite(b *testing.B) {
 	b.SetBytes(int64(len(rgb)))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := compressTileBlob(rgb, w, h, 3, 8, true)
+		_, err := compressTileBlob(rgb, w, h, 3, 8, true, PredictorAvg, CoderFSE2, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
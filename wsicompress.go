@@ -26,6 +26,15 @@ const (
 // raw bytes (1 byte per pixel for 8-bit, 2 bytes LE per pixel for 16-bit).
 func CompressWSI(pixels []byte, width, height, channels, bitsPerSample int, opts WSIOptions) ([]byte, error) {
 	opts.defaults(channels)
+	if _, ok := LookupPredictor(opts.Predictor); !ok {
+		return nil, fmt.Errorf("MIC3: unknown predictor %d", opts.Predictor)
+	}
+	if err := checkStreamCoder(opts.Coder); err != nil {
+		return nil, fmt.Errorf("MIC3: %w", err)
+	}
+	if opts.EmbedDictionary && opts.Dictionary == nil {
+		return nil, errors.New("MIC3: EmbedDictionary without a Dictionary")
+	}
 
 	numLevels := opts.PyramidLevels
 	if numLevels <= 0 {
@@ -117,7 +126,7 @@ func CompressWSI(pixels []byte, width, height, channels, bitsPerSample int, opts
 	if workers <= 1 || len(jobs) <= 1 {
 		// Sequential
 		for _, job := range jobs {
-			blob, err := compressTileBlob(job.pixels, job.width, job.height, channels, bitsPerSample, opts.ColorTransform)
+			blob, err := compressTileBlob(job.pixels, job.width, job.height, channels, bitsPerSample, opts.ColorTransform, opts.Predictor, opts.Coder, opts.Dictionary)
 			if err != nil {
 				return nil, fmt.Errorf("tile %d: %w", job.globalIdx, err)
 			}
@@ -134,7 +143,7 @@ func CompressWSI(pixels []byte, width, height, channels, bitsPerSample int, opts
 			sem <- struct{}{}
 			go func(j tileJob) {
 				defer func() { <-sem; wg.Done() }()
-				blob, err := compressTileBlob(j.pixels, j.width, j.height, channels, bitsPerSample, opts.ColorTransform)
+				blob, err := compressTileBlob(j.pixels, j.width, j.height, channels, bitsPerSample, opts.ColorTransform, opts.Predictor, opts.Coder, opts.Dictionary)
 				if err != nil {
 					errs[j.globalIdx] = err
 					return
@@ -151,6 +160,12 @@ func CompressWSI(pixels []byte, width, height, channels, bitsPerSample int, opts
 		}
 	}
 
+	if opts.ReuseTables {
+		if err := reuseTileTables(tileBlobs, levels, channels == 3 && bitsPerSample == 8, opts.TileWidth*opts.TileHeight, opts.Dictionary); err != nil {
+			return nil, err
+		}
+	}
+
 	// Write MIC3
 	hdr := WSIHeader{
 		Width:          width,
@@ -160,8 +175,13 @@ func CompressWSI(pixels []byte, width, height, channels, bitsPerSample int, opts
 		Channels:       channels,
 		BitsPerSample:  bitsPerSample,
 		ColorTransform: opts.ColorTransform,
+		Checksums:      opts.Checksums,
+		Predictor:      opts.Predictor,
 		Levels:         levels,
 	}
+	if opts.EmbedDictionary {
+		hdr.Dictionary = opts.Dictionary
+	}
 
 	var buf bytes.Buffer
 	if err := WriteMIC3(&buf, hdr, tileBlobs); err != nil {
@@ -173,7 +193,13 @@ func CompressWSI(pixels []byte, width, height, channels, bitsPerSample int, opts
 // DecompressWSITile decompresses a single tile at the given pyramid level.
 // Returns channel-interleaved pixel data.
 func DecompressWSITile(data []byte, level, tileX, tileY int) ([]byte, error) {
-	hdr, entries, dataOffset, err := ReadMIC3Header(data)
+	var d Decoder
+	return d.DecompressWSITile(data, level, tileX, tileY)
+}
+
+// DecompressWSITile is DecompressWSITile under d's limits.
+func (d *Decoder) DecompressWSITile(data []byte, level, tileX, tileY int) ([]byte, error) {
+	hdr, entries, dataOffset, err := d.readMIC3Header(data)
 	if err != nil {
 		return nil, err
 	}
@@ -192,7 +218,8 @@ func DecompressWSITile(data []byte, level, tileX, tileY int) ([]byte, error) {
 		return nil, err
 	}
 
-	tile, err := decompressTileBlob(blob, hdr.TileWidth, hdr.TileHeight, hdr.Channels, hdr.BitsPerSample, hdr.ColorTransform)
+	refs := streamRefs{dict: hdr.Dictionary, unit: tileUnits(data, entries, dataOffset, globalIdx)}
+	tile, err := d.decompressTileBlob(blob, hdr.TileWidth, hdr.TileHeight, hdr.Channels, hdr.BitsPerSample, hdr.ColorTransform, hdr.Predictor, refs)
 	if err != nil {
 		return nil, fmt.Errorf("tile (%d,%d) level %d: %w", tileX, tileY, level, err)
 	}
@@ -218,7 +245,14 @@ func DecompressWSITile(data []byte, level, tileX, tileY int) ([]byte, error) {
 
 // DecompressWSIRegion decompresses a rectangular region at a specific pyramid level.
 func DecompressWSIRegion(data []byte, level, x, y, w, h int) ([]byte, error) {
-	hdr, entries, dataOffset, err := ReadMIC3Header(data)
+	var d Decoder
+	return d.DecompressWSIRegion(data, level, x, y, w, h)
+}
+
+// DecompressWSIRegion is DecompressWSIRegion under d's limits; the clamped
+// region counts towards MaxPixels and MaxOutputBytes.
+func (d *Decoder) DecompressWSIRegion(data []byte, level, x, y, w, h int) ([]byte, error) {
+	hdr, entries, dataOffset, err := d.readMIC3Header(data)
 	if err != nil {
 		return nil, err
 	}
@@ -239,9 +273,9 @@ func DecompressWSIRegion(data []byte, level, x, y, w, h int) ([]byte, error) {
 		return nil, fmt.Errorf("MIC3: empty region")
 	}
 
-	bytesPerPixel := hdr.Channels
-	if hdr.BitsPerSample == 16 {
-		bytesPerPixel *= 2
+	bytesPerPixel := wsiBytesPerPixel(&hdr)
+	if err := d.checkImage(w, h, 1, bytesPerPixel); err != nil {
+		return nil, fmt.Errorf("MIC3: %w", err)
 	}
 
 	// Determine which tiles overlap the region
@@ -259,7 +293,8 @@ func DecompressWSIRegion(data []byte, level, x, y, w, h int) ([]byte, error) {
 			if err != nil {
 				return nil, err
 			}
-			tile, err := decompressTileBlob(blob, hdr.TileWidth, hdr.TileHeight, hdr.Channels, hdr.BitsPerSample, hdr.ColorTransform)
+			refs := streamRefs{dict: hdr.Dictionary, unit: tileUnits(data, entries, dataOffset, globalIdx)}
+			tile, err := d.decompressTileBlob(blob, hdr.TileWidth, hdr.TileHeight, hdr.Channels, hdr.BitsPerSample, hdr.ColorTransform, hdr.Predictor, refs)
 			if err != nil {
 				return nil, err
 			}
@@ -295,6 +330,30 @@ func DecompressWSIRegion(data []byte, level, x, y, w, h int) ([]byte, error) {
 	return result, nil
 }
 
+// readMIC3Header parses a MIC3 header and checks its tile count and tile
+// size against d's limits.
+func (d *Decoder) readMIC3Header(data []byte) (WSIHeader, []WSITileEntry, int, error) {
+	hdr, entries, dataOffset, err := ReadMIC3Header(data)
+	if err != nil {
+		return WSIHeader{}, nil, 0, err
+	}
+	if err := d.checkTiles(len(entries)); err != nil {
+		return WSIHeader{}, nil, 0, fmt.Errorf("MIC3: %w", err)
+	}
+	if err := d.checkImage(hdr.TileWidth, hdr.TileHeight, 1, wsiBytesPerPixel(&hdr)); err != nil {
+		return WSIHeader{}, nil, 0, fmt.Errorf("MIC3: tile size: %w", err)
+	}
+	return hdr, entries, dataOffset, nil
+}
+
+// wsiBytesPerPixel returns the decoded size of one pixel of hdr's image.
+func wsiBytesPerPixel(hdr *WSIHeader) int {
+	if hdr.BitsPerSample == 16 {
+		return hdr.Channels * 2
+	}
+	return hdr.Channels
+}
+
 // ReadWSIHeader parses only the MIC3 header without decompressing tiles.
 func ReadWSIHeader(data []byte) (*WSIHeader, error) {
 	hdr, _, _, err := ReadMIC3Header(data)
@@ -308,83 +367,129 @@ func ReadWSIHeader(data []byte) (*WSIHeader, error) {
 
 // compressTileBlob compresses a single tile's pixel data into a tile blob.
 // For RGB: applies YCoCg-R color transform, then compresses Y/Co/Cg planes.
-// For greyscale: compresses the single plane.
-func compressTileBlob(tilePixels []byte, tileWidth, tileHeight, channels, bitsPerSample int, colorTransform bool) ([]byte, error) {
+// For greyscale: compresses the single plane. Every plane uses predictor,
+// coder and, if not nil, dict.
+func compressTileBlob(tilePixels []byte, tileWidth, tileHeight, channels, bitsPerSample int, colorTransform bool, predictor PredictorID, coder Coder, dict *Dictionary) ([]byte, error) {
 	if channels == 3 && bitsPerSample == 8 {
-		return compressRGBTileBlob(tilePixels, tileWidth, tileHeight, colorTransform)
+		return compressRGBTileBlob(tilePixels, tileWidth, tileHeight, colorTransform, predictor, coder, dict)
+	}
+	return compressGreyTileBlob(tilePixels, tileWidth, tileHeight, bitsPerSample, predictor, coder, dict)
+}
+
+// reuseTileTables recodes, tile by tile in order within each level, every
+// plane whose symbols code smaller with the table of the same plane of an
+// earlier tile. rgb tells whether the blobs hold three planes of n samples.
+func reuseTileTables(blobs [][]byte, levels []WSILevel, rgb bool, n int, dict *Dictionary) error {
+	planes := 1
+	if rgb {
+		planes = 3
+	}
+	s := ScratchU16{Dictionary: dict}
+	for _, lv := range levels {
+		reuse := make([]tableReuse, planes)
+		for p := range reuse {
+			reuse[p].window = lv.TilesX
+		}
+		for i := lv.FirstTileIdx; i < lv.FirstTileIdx+lv.TilesX*lv.TilesY; i++ {
+			var near []int
+			if i-lv.FirstTileIdx >= lv.TilesX {
+				near = append(near, i-lv.TilesX)
+			}
+			streams := make([][]byte, planes)
+			changed := false
+			for p := range streams {
+				plane, err := wsiTilePlane(blobs[i], p, rgb)
+				if err != nil {
+					return fmt.Errorf("tile %d: %w", i, err)
+				}
+				streams[p] = plane
+				if plane[0] != planeCompressed {
+					continue
+				}
+				s.DecompressLimit = symbolLimit(n)
+				symbols, err := FSEDecompressU16Auto(plane[1:], &s)
+				if err != nil {
+					return fmt.Errorf("tile %d: %w", i, err)
+				}
+				if ref, ok := reuse[p].code(i, symbols, plane[1:], near...); ok {
+					streams[p] = append([]byte{planeCompressed}, ref...)
+					changed = true
+				}
+			}
+			if changed {
+				blobs[i] = packTilePlanes(streams)
+			}
+		}
 	}
-	return compressGreyTileBlob(tilePixels, tileWidth, tileHeight, bitsPerSample)
+	return nil
 }
 
-func compressRGBTileBlob(rgb []byte, width, height int, colorTransform bool) ([]byte, error) {
-	var yPlane, coPlane, cgPlane []uint16
+// packTilePlanes packs the planes of a tile: a single plane as is, three
+// planes after their lengths.
+func packTilePlanes(planes [][]byte) []byte {
+	if len(planes) == 1 {
+		return planes[0]
+	}
+	// Pack: [Y_len(u32)][Co_len(u32)][Cg_len(u32)][Y_data][Co_data][Cg_data]
+	out := make([]byte, 12, 12+len(planes[0])+len(planes[1])+len(planes[2]))
+	for i, p := range planes {
+		binary.LittleEndian.PutUint32(out[4*i:], uint32(len(p)))
+	}
+	for _, p := range planes {
+		out = append(out, p...)
+	}
+	return out
+}
 
+// rgbPlanes splits an RGB tile into the three planes compressRGBTileBlob
+// codes: Y, Co and Cg, or R, G and B without the color transform.
+func rgbPlanes(rgb []byte, width, height int, colorTransform bool) (yPlane, coPlane, cgPlane []uint16) {
 	if colorTransform {
-		yPlane, coPlane, cgPlane = YCoCgRForward(rgb, width, height)
-	} else {
-		// Planar separation without color transform
-		n := width * height
-		yPlane = make([]uint16, n)  // R channel
-		coPlane = make([]uint16, n) // G channel
-		cgPlane = make([]uint16, n) // B channel
-		for i := 0; i < n; i++ {
-			yPlane[i] = uint16(rgb[i*3])
-			coPlane[i] = uint16(rgb[i*3+1])
-			cgPlane[i] = uint16(rgb[i*3+2])
-		}
+		return YCoCgRForward(rgb, width, height)
+	}
+	// Planar separation without color transform
+	n := width * height
+	yPlane = make([]uint16, n)  // R channel
+	coPlane = make([]uint16, n) // G channel
+	cgPlane = make([]uint16, n) // B channel
+	for i := 0; i < n; i++ {
+		yPlane[i] = uint16(rgb[i*3])
+		coPlane[i] = uint16(rgb[i*3+1])
+		cgPlane[i] = uint16(rgb[i*3+2])
 	}
+	return yPlane, coPlane, cgPlane
+}
+
+func compressRGBTileBlob(rgb []byte, width, height int, colorTransform bool, predictor PredictorID, coder Coder, dict *Dictionary) ([]byte, error) {
+	yPlane, coPlane, cgPlane := rgbPlanes(rgb, width, height, colorTransform)
 
-	yBlob, err := compressWSIPlane(yPlane, width, height)
+	yBlob, err := compressWSIPlane(yPlane, width, height, predictor, coder, dict)
 	if err != nil {
 		return nil, fmt.Errorf("Y plane: %w", err)
 	}
-	coBlob, err := compressWSIPlane(coPlane, width, height)
+	coBlob, err := compressWSIPlane(coPlane, width, height, predictor, coder, dict)
 	if err != nil {
 		return nil, fmt.Errorf("Co plane: %w", err)
 	}
-	cgBlob, err := compressWSIPlane(cgPlane, width, height)
+	cgBlob, err := compressWSIPlane(cgPlane, width, height, predictor, coder, dict)
 	if err != nil {
 		return nil, fmt.Errorf("Cg plane: %w", err)
 	}
 
-	// Pack: [Y_len(u32)][Co_len(u32)][Cg_len(u32)][Y_data][Co_data][Cg_data]
-	totalLen := 12 + len(yBlob) + len(coBlob) + len(cgBlob)
-	out := make([]byte, totalLen)
-	binary.LittleEndian.PutUint32(out[0:4], uint32(len(yBlob)))
-	binary.LittleEndian.PutUint32(out[4:8], uint32(len(coBlob)))
-	binary.LittleEndian.PutUint32(out[8:12], uint32(len(cgBlob)))
-	off := 12
-	copy(out[off:], yBlob)
-	off += len(yBlob)
-	copy(out[off:], coBlob)
-	off += len(coBlob)
-	copy(out[off:], cgBlob)
-
-	return out, nil
+	return packTilePlanes([][]byte{yBlob, coBlob, cgBlob}), nil
 }
 
-func compressGreyTileBlob(pixelBytes []byte, width, height, bitsPerSample int) ([]byte, error) {
+func compressGreyTileBlob(pixelBytes []byte, width, height, bitsPerSample int, predictor PredictorID, coder Coder, dict *Dictionary) ([]byte, error) {
 	plane := bytesToUint16Slice(pixelBytes, bitsPerSample)
-	return compressWSIPlane(plane, width, height)
+	return compressWSIPlane(plane, width, height, predictor, coder, dict)
 }
 
 // compressWSIPlane compresses a single plane of uint16 data.
 // Handles constant planes specially for efficiency.
-func compressWSIPlane(plane []uint16, width, height int) ([]byte, error) {
-	// Check for constant plane
-	isConstant := true
-	val := plane[0]
-	maxVal := val
-	for _, v := range plane[1:] {
-		if v != val {
-			isConstant = false
-		}
-		if v > maxVal {
-			maxVal = v
-		}
-	}
-
+func compressWSIPlane(plane []uint16, width, height int, predictor PredictorID, coder Coder, dict *Dictionary) ([]byte, error) {
+	maxVal, isConstant := wsiPlaneMaxValue(plane)
 	if isConstant {
+		val := plane[0]
 		if val == 0 {
 			return []byte{planeConstantZero}, nil
 		}
@@ -394,12 +499,7 @@ func compressWSIPlane(plane []uint16, width, height int) ([]byte, error) {
 		return out, nil
 	}
 
-	// Ensure maxVal is large enough for reasonable RLE midCount
-	if maxVal < 255 {
-		maxVal = 255
-	}
-
-	compressed, err := CompressSingleFrame(plane, width, height, maxVal)
+	compressed, err := compressSingleFrameCoder(plane, width, height, maxVal, predictor, coder, dict)
 	if err != nil {
 		// Fallback: check if it's a known error that we can handle
 		if errors.Is(err, ErrUseRLE) || errors.Is(err, ErrIncompressible) {
@@ -420,15 +520,121 @@ func compressWSIPlane(plane []uint16, width, height int) ([]byte, error) {
 	return out, nil
 }
 
-// decompressTileBlob decompresses a tile blob back to pixel data.
-func decompressTileBlob(blob []byte, tileWidth, tileHeight, channels, bitsPerSample int, colorTransform bool) ([]byte, error) {
+// wsiPlaneMaxValue returns the max value compressWSIPlane codes plane with,
+// and whether plane is constant, in which case it is stored without coding.
+func wsiPlaneMaxValue(plane []uint16) (uint16, bool) {
+	isConstant := true
+	val := plane[0]
+	maxVal := val
+	for _, v := range plane[1:] {
+		if v != val {
+			isConstant = false
+		}
+		if v > maxVal {
+			maxVal = v
+		}
+	}
+	// Ensure maxVal is large enough for reasonable RLE midCount
+	if maxVal < 255 {
+		maxVal = 255
+	}
+	return maxVal, isConstant
+}
+
+// WSIDictionarySamples returns the full-resolution tile planes CompressWSI
+// would code for an image with opts, as training samples for
+// TrainDictionary. Constant planes, which are stored without a table, are
+// left out.
+func WSIDictionarySamples(pixels []byte, width, height, channels, bitsPerSample int, opts WSIOptions) []DictionarySample {
+	opts.defaults(channels)
+	tw, th := opts.TileWidth, opts.TileHeight
+	var samples []DictionarySample
+	for ty := 0; ty*th < height; ty++ {
+		for tx := 0; tx*tw < width; tx++ {
+			tile := extractTileRGB(pixels, width, height, tw, th, tx, ty, channels, bitsPerSample)
+			planes := [][]uint16{nil}
+			if channels == 3 && bitsPerSample == 8 {
+				y, co, cg := rgbPlanes(tile, tw, th, opts.ColorTransform)
+				planes = [][]uint16{y, co, cg}
+			} else {
+				planes[0] = bytesToUint16Slice(tile, bitsPerSample)
+			}
+			for _, p := range planes {
+				if maxVal, constant := wsiPlaneMaxValue(p); !constant {
+					samples = append(samples, DictionarySample{Pixels: p, Width: tw, Height: th, MaxValue: maxVal})
+				}
+			}
+		}
+	}
+	return samples
+}
+
+// decompressTileBlob decompresses a tile blob back to pixel data. refs holds
+// the dictionary embedded in the file, which takes precedence over
+// registered ones, and resolves units to the blobs of other tiles.
+func (d *Decoder) decompressTileBlob(blob []byte, tileWidth, tileHeight, channels, bitsPerSample int, colorTransform bool, predictor PredictorID, refs streamRefs) ([]byte, error) {
 	if channels == 3 && bitsPerSample == 8 {
-		return decompressRGBTileBlob(blob, tileWidth, tileHeight, colorTransform)
+		return d.decompressRGBTileBlob(blob, tileWidth, tileHeight, colorTransform, predictor, refs)
+	}
+	return d.decompressGreyTileBlob(blob, tileWidth, tileHeight, bitsPerSample, predictor, refs)
+}
+
+// tileUnits resolves the units named by the streams of tile idx to tile
+// blobs.
+func tileUnits(data []byte, entries []WSITileEntry, dataOffset, idx int) func(uint32) ([]byte, error) {
+	return func(n uint32) ([]byte, error) {
+		if n == RefPrevious {
+			n = uint32(idx - 1)
+		}
+		return ExtractTileBlob(data, entries, dataOffset, int(n))
+	}
+}
+
+// wsiTilePlane returns plane p of a tile blob; rgb tells whether the blob
+// holds three planes after their lengths or a single plane.
+func wsiTilePlane(blob []byte, p int, rgb bool) ([]byte, error) {
+	if !rgb {
+		return blob, nil
+	}
+	if len(blob) < 12 {
+		return nil, errors.New("MIC3: RGB tile blob too small")
+	}
+	off := 12
+	for i := range p {
+		off += int(binary.LittleEndian.Uint32(blob[4*i:]))
+	}
+	end := off + int(binary.LittleEndian.Uint32(blob[4*p:]))
+	if off < 12 || end < off || end > len(blob) {
+		return nil, errors.New("MIC3: RGB tile blob truncated")
+	}
+	return blob[off:end], nil
+}
+
+// planeRefs narrows tile-level refs to plane p: a unit is the entropy
+// stream of the same plane of another tile.
+func planeRefs(refs streamRefs, p int, rgb bool) streamRefs {
+	if refs.unit == nil {
+		return refs
+	}
+	tile := refs.unit
+	refs.unit = func(n uint32) ([]byte, error) {
+		blob, err := tile(n)
+		if err != nil {
+			return nil, err
+		}
+		plane, err := wsiTilePlane(blob, p, rgb)
+		if err != nil {
+			return nil, err
+		}
+		if len(plane) == 0 || plane[0] != planeCompressed {
+			return nil, errors.New("plane carries no table")
+		}
+		return plane[1:], nil
 	}
-	return decompressGreyTileBlob(blob, tileWidth, tileHeight, bitsPerSample)
+	return refs
 }
 
-func decompressRGBTileBlob(blob []byte, width, height int, colorTransform bool) ([]byte, error) {
+func (d *Decoder) decompressRGBTileBlob(blob []byte, width, height int, colorTransform bool, predictor PredictorID, refs streamRefs) ([]byte, error) {
 	if len(blob) < 12 {
 		return nil, errors.New("MIC3: RGB tile blob too small")
 	}
@@ -443,19 +649,19 @@ func decompressRGBTileBlob(blob []byte, width, height int, colorTransform bool)
 	}
 
 	n := width * height
-	yPlane, err := decompressWSIPlane(blob[off:off+yLen], width, height, n)
+	yPlane, err := d.decompressWSIPlane(blob[off:off+yLen], width, height, n, predictor, planeRefs(refs, 0, true))
 	if err != nil {
 		return nil, fmt.Errorf("Y plane: %w", err)
 	}
 	off += yLen
 
-	coPlane, err := decompressWSIPlane(blob[off:off+coLen], width, height, n)
+	coPlane, err := d.decompressWSIPlane(blob[off:off+coLen], width, height, n, predictor, planeRefs(refs, 1, true))
 	if err != nil {
 		return nil, fmt.Errorf("Co plane: %w", err)
 	}
 	off += coLen
 
-	cgPlane, err := decompressWSIPlane(blob[off:off+cgLen], width, height, n)
+	cgPlane, err := d.decompressWSIPlane(blob[off:off+cgLen], width, height, n, predictor, planeRefs(refs, 2, true))
 	if err != nil {
 		return nil, fmt.Errorf("Cg plane: %w", err)
 	}
@@ -474,9 +680,9 @@ func decompressRGBTileBlob(blob []byte, width, height int, colorTransform bool)
 	return rgb, nil
 }
 
-func decompressGreyTileBlob(blob []byte, width, height, bitsPerSample int) ([]byte, error) {
+func (d *Decoder) decompressGreyTileBlob(blob []byte, width, height, bitsPerSample int, predictor PredictorID, refs streamRefs) ([]byte, error) {
 	n := width * height
-	plane, err := decompressWSIPlane(blob, width, height, n)
+	plane, err := d.decompressWSIPlane(blob, width, height, n, predictor, planeRefs(refs, 0, false))
 	if err != nil {
 		return nil, err
 	}
@@ -484,7 +690,7 @@ func decompressGreyTileBlob(blob []byte, width, height, bitsPerSample int) ([]by
 }
 
 // decompressWSIPlane decompresses a single plane from its blob.
-func decompressWSIPlane(data []byte, width, height, n int) ([]uint16, error) {
+func (d *Decoder) decompressWSIPlane(data []byte, width, height, n int, predictor PredictorID, refs streamRefs) ([]uint16, error) {
 	if len(data) == 0 {
 		return nil, errors.New("empty plane data")
 	}
@@ -506,7 +712,7 @@ func decompressWSIPlane(data []byte, width, height, n int) ([]uint16, error) {
 		return out, nil
 
 	case planeCompressed:
-		return DecompressSingleFrame(data[1:], width, height)
+		return d.decompressSingleFramePredictor(data[1:], width, height, predictor, refs)
 
 	case planeRaw:
 		if len(data) < 1+n*2 {
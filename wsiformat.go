@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 )
 
@@ -15,24 +16,30 @@ import (
 //
 //	HEADER (48 bytes)
 //	  Bytes  0-3:   Magic "MIC3"
-//	  Bytes  4-7:   Format version (uint32 LE) = 1
+//	  Bytes  4-7:   Format version (uint32 LE) = 1, or 2 with checksums
 //	  Bytes  8-11:  Full-res width (uint32 LE)
 //	  Bytes 12-15:  Full-res height (uint32 LE)
 //	  Bytes 16-19:  Tile width (uint32 LE)
 //	  Bytes 20-23:  Tile height (uint32 LE)
 //	  Bytes 24-25:  Channels (uint16 LE): 1=grey, 3=RGB
 //	  Byte  26:     Bits per sample (uint8): 8 or 16
-//	  Byte  27:     Flags (bit0=spatial, bit1=color_transform)
+//	  Byte  27:     Flags (bit0=spatial, bit1=color_transform, bit2=dictionary)
 //	  Bytes 28-29:  Pyramid level count (uint16 LE)
-//	  Bytes 30-31:  Reserved
+//	  Byte  30:     PredictorID of every tile plane (0 = avg)
+//	  Byte  31:     Reserved
 //	  Bytes 32-39:  Total tile count (uint64 LE)
-//	  Bytes 40-47:  Reserved
+//	  Bytes 40-43:  Version 2: file CRC32C (uint32 LE, computed with this field zeroed)
+//	  Bytes 44-47:  Reserved
 //
 //	LEVEL DESCRIPTORS (N × 20 bytes)
 //	  Per level: width(u32) + height(u32) + tilesX(u32) + tilesY(u32) + firstTileIdx(u32)
 //
-//	TILE OFFSET TABLE (M × 16 bytes)
+//	DICTIONARY (only with flag bit2)
+//	  length(u32) + Dictionary.MarshalBinary output
+//
+//	TILE OFFSET TABLE (M × 16 bytes; M × 24 bytes in version 2)
 //	  Per tile: offset(u64) + length(u64)
+//	  Version 2 appends: crc32c(u32) + reserved(u32)
 //
 //	DATA SECTION: concatenated compressed tile blobs
 
@@ -43,8 +50,13 @@ const (
 	mic3LevelSize   = 20
 	mic3TileEntSize = 16
 
+	mic3CRCVersion     = 2  // checksummed revision (see checksum.go)
+	mic3CRCTileEntSize = 24 // offset + length + crc32c + reserved
+	mic3CRCFileOff     = 40 // whole-file CRC32C
+
 	FlagSpatial        = 0x01 // spatial delta prediction (always set)
 	FlagColorTransform = 0x02 // YCoCg-R was applied
+	FlagDictionary     = 0x04 // a Dictionary follows the level descriptors
 )
 
 // WSIHeader holds metadata for a MIC3 WSI file.
@@ -56,7 +68,13 @@ type WSIHeader struct {
 	Channels       int  // 1 (greyscale) or 3 (RGB)
 	BitsPerSample  int  // 8 or 16
 	ColorTransform bool // true if YCoCg-R was applied
+	Checksums      bool // true = version 2 with per-tile CRC32C
+	Predictor      PredictorID
 	Levels         []WSILevel
+
+	// Dictionary is the dictionary embedded in the file, or nil. Tiles
+	// coded with it decode without it being registered.
+	Dictionary *Dictionary
 }
 
 // WSILevel describes one pyramid level.
@@ -72,6 +90,8 @@ type WSILevel struct {
 type WSITileEntry struct {
 	Offset uint64
 	Length uint64
+	CRC    uint32 // CRC32C of the tile blob (version 2 only)
+	HasCRC bool   // true when CRC is present and must be verified
 }
 
 // WSIOptions configures WSI compression.
@@ -81,6 +101,30 @@ type WSIOptions struct {
 	PyramidLevels  int  // 0 = auto
 	ColorTransform bool // Default: true for RGB
 	Workers        int  // 0 = runtime.GOMAXPROCS
+	Checksums      bool // write per-tile and whole-file CRC32C (MIC3 version 2)
+
+	// Predictor is the spatial predictor of every tile plane. Any registered
+	// ID is accepted (see RegisterPredictor); the zero value is avg.
+	Predictor PredictorID
+
+	// Coder is the entropy coder of every tile plane; the zero value is
+	// two-state FSE. The coder is not recorded: the decoders detect it from
+	// each plane's stream.
+	Coder Coder
+
+	// Dictionary, if set, codes each tile plane with its best pre-trained
+	// table when that beats the plane's own table (see TrainDictionary and
+	// WSIDictionarySamples). EmbedDictionary stores it once in the file;
+	// otherwise readers must register it.
+	Dictionary      *Dictionary
+	EmbedDictionary bool
+
+	// ReuseTables lets a tile plane reuse the FSE table of the same plane
+	// of the previous tile, the tile above or a recent tile of its level
+	// that carries its own, when that codes it in fewer bytes (see
+	// FSECompressU16Ref). It adds a sequential pass over the tiles; random
+	// access to any tile is kept.
+	ReuseTables bool
 }
 
 func (o *WSIOptions) defaults(channels int) {
@@ -95,7 +139,8 @@ func (o *WSIOptions) defaults(channels int) {
 	}
 }
 
-// WriteMIC3 writes a complete MIC3 container.
+// WriteMIC3 writes a complete MIC3 container. When hdr.Checksums is set the
+// checksummed version 2 layout is written.
 func WriteMIC3(w io.Writer, hdr WSIHeader, tileBlobs [][]byte) error {
 	totalTiles := 0
 	for _, lv := range hdr.Levels {
@@ -105,10 +150,15 @@ func WriteMIC3(w io.Writer, hdr WSIHeader, tileBlobs [][]byte) error {
 		return fmt.Errorf("MIC3: tile count mismatch: header implies %d, got %d", totalTiles, len(tileBlobs))
 	}
 
-	// Write header
+	version, entSize := uint32(mic3Version), mic3TileEntSize
+	if hdr.Checksums {
+		version, entSize = mic3CRCVersion, mic3CRCTileEntSize
+	}
+
+	// Build header
 	header := make([]byte, mic3HeaderSize)
 	copy(header[0:4], mic3Magic)
-	binary.LittleEndian.PutUint32(header[4:8], mic3Version)
+	binary.LittleEndian.PutUint32(header[4:8], version)
 	binary.LittleEndian.PutUint32(header[8:12], uint32(hdr.Width))
 	binary.LittleEndian.PutUint32(header[12:16], uint32(hdr.Height))
 	binary.LittleEndian.PutUint32(header[16:20], uint32(hdr.TileWidth))
@@ -119,41 +169,65 @@ func WriteMIC3(w io.Writer, hdr WSIHeader, tileBlobs [][]byte) error {
 	if hdr.ColorTransform {
 		flags |= FlagColorTransform
 	}
+	var dict []byte
+	if hdr.Dictionary != nil {
+		flags |= FlagDictionary
+		b, err := hdr.Dictionary.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("MIC3: %w", err)
+		}
+		dict = binary.LittleEndian.AppendUint32(nil, uint32(len(b)))
+		dict = append(dict, b...)
+	}
 	header[27] = flags
 	binary.LittleEndian.PutUint16(header[28:30], uint16(len(hdr.Levels)))
-	// 30-31 reserved
+	header[30] = byte(hdr.Predictor)
+	// 31 reserved
 	binary.LittleEndian.PutUint64(header[32:40], uint64(totalTiles))
-	// 40-47 reserved
-
-	if _, err := w.Write(header); err != nil {
-		return err
-	}
+	// 40-47 reserved (40-43 file CRC32C in version 2, filled in below)
 
-	// Write level descriptors
-	for _, lv := range hdr.Levels {
-		ld := make([]byte, mic3LevelSize)
+	// Build level descriptors, followed by the embedded dictionary
+	levels := make([]byte, len(hdr.Levels)*mic3LevelSize)
+	for i, lv := range hdr.Levels {
+		ld := levels[i*mic3LevelSize:]
 		binary.LittleEndian.PutUint32(ld[0:4], uint32(lv.Width))
 		binary.LittleEndian.PutUint32(ld[4:8], uint32(lv.Height))
 		binary.LittleEndian.PutUint32(ld[8:12], uint32(lv.TilesX))
 		binary.LittleEndian.PutUint32(ld[12:16], uint32(lv.TilesY))
 		binary.LittleEndian.PutUint32(ld[16:20], uint32(lv.FirstTileIdx))
-		if _, err := w.Write(ld); err != nil {
-			return err
-		}
 	}
+	levels = append(levels, dict...)
 
-	// Write tile offset table
+	// Build tile offset table
+	table := make([]byte, len(tileBlobs)*entSize)
 	offset := uint64(0)
-	for _, blob := range tileBlobs {
-		entry := make([]byte, mic3TileEntSize)
+	for i, blob := range tileBlobs {
+		entry := table[i*entSize:]
 		binary.LittleEndian.PutUint64(entry[0:8], offset)
 		binary.LittleEndian.PutUint64(entry[8:16], uint64(len(blob)))
-		if _, err := w.Write(entry); err != nil {
-			return err
+		if hdr.Checksums {
+			binary.LittleEndian.PutUint32(entry[16:20], crc32c(blob))
 		}
 		offset += uint64(len(blob))
 	}
 
+	if hdr.Checksums {
+		// Whole-file CRC32C, with its own field still zero.
+		crc := crc32.Update(0, crc32cTable, header)
+		crc = crc32.Update(crc, crc32cTable, levels)
+		crc = crc32.Update(crc, crc32cTable, table)
+		for _, blob := range tileBlobs {
+			crc = crc32.Update(crc, crc32cTable, blob)
+		}
+		binary.LittleEndian.PutUint32(header[mic3CRCFileOff:], crc)
+	}
+
+	for _, b := range [][]byte{header, levels, table} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
 	// Write tile data
 	for _, blob := range tileBlobs {
 		if _, err := w.Write(blob); err != nil {
@@ -174,7 +248,12 @@ func ReadMIC3Header(data []byte) (WSIHeader, []WSITileEntry, int, error) {
 		return WSIHeader{}, nil, 0, fmt.Errorf("MIC3: invalid magic %q", string(data[0:4]))
 	}
 	version := binary.LittleEndian.Uint32(data[4:8])
-	if version != mic3Version {
+	entSize := mic3TileEntSize
+	switch version {
+	case mic3Version:
+	case mic3CRCVersion:
+		entSize = mic3CRCTileEntSize
+	default:
 		return WSIHeader{}, nil, 0, fmt.Errorf("MIC3: unsupported version %d", version)
 	}
 
@@ -186,6 +265,11 @@ func ReadMIC3Header(data []byte) (WSIHeader, []WSITileEntry, int, error) {
 		Channels:       int(binary.LittleEndian.Uint16(data[24:26])),
 		BitsPerSample:  int(data[26]),
 		ColorTransform: data[27]&FlagColorTransform != 0,
+		Checksums:      version == mic3CRCVersion,
+		Predictor:      PredictorID(data[30]),
+	}
+	if _, ok := LookupPredictor(hdr.Predictor); !ok {
+		return WSIHeader{}, nil, 0, fmt.Errorf("MIC3: unknown predictor %d", hdr.Predictor)
 	}
 
 	levelCount := int(binary.LittleEndian.Uint16(data[28:30]))
@@ -208,34 +292,62 @@ func ReadMIC3Header(data []byte) (WSIHeader, []WSITileEntry, int, error) {
 		}
 	}
 
-	// Read tile offset table
 	tileTableOffset := lvOffset + levelCount*mic3LevelSize
-	if len(data) < tileTableOffset+totalTiles*mic3TileEntSize {
+	if data[27]&FlagDictionary != 0 {
+		if len(data)-tileTableOffset < 4 {
+			return WSIHeader{}, nil, 0, errors.New("MIC3: truncated dictionary")
+		}
+		n := binary.LittleEndian.Uint32(data[tileTableOffset:])
+		tileTableOffset += 4
+		if uint64(n) > uint64(len(data)-tileTableOffset) {
+			return WSIHeader{}, nil, 0, errors.New("MIC3: truncated dictionary")
+		}
+		d, err := ParseDictionary(data[tileTableOffset : tileTableOffset+int(n)])
+		if err != nil {
+			return WSIHeader{}, nil, 0, fmt.Errorf("MIC3: %w", err)
+		}
+		hdr.Dictionary = d
+		tileTableOffset += int(n)
+	}
+
+	// Read tile offset table
+	if totalTiles < 0 || uint64(totalTiles) > uint64(len(data)-tileTableOffset)/uint64(entSize) {
 		return WSIHeader{}, nil, 0, errors.New("MIC3: truncated tile offset table")
 	}
 	entries := make([]WSITileEntry, totalTiles)
 	for i := 0; i < totalTiles; i++ {
-		base := tileTableOffset + i*mic3TileEntSize
+		base := tileTableOffset + i*entSize
 		entries[i] = WSITileEntry{
 			Offset: binary.LittleEndian.Uint64(data[base:]),
 			Length: binary.LittleEndian.Uint64(data[base+8:]),
 		}
+		if hdr.Checksums {
+			entries[i].CRC = binary.LittleEndian.Uint32(data[base+16:])
+			entries[i].HasCRC = true
+		}
 	}
 
-	dataOffset := tileTableOffset + totalTiles*mic3TileEntSize
+	dataOffset := tileTableOffset + totalTiles*entSize
 	return hdr, entries, dataOffset, nil
 }
 
 // ExtractTileBlob returns the compressed bytes for a specific tile from MIC3 data.
+// When the entry carries a checksum it is verified, and an *ErrChecksumMismatch
+// is returned on mismatch.
 func ExtractTileBlob(data []byte, entries []WSITileEntry, dataOffset int, tileIdx int) ([]byte, error) {
 	if tileIdx < 0 || tileIdx >= len(entries) {
 		return nil, fmt.Errorf("MIC3: tile index %d out of range [0, %d)", tileIdx, len(entries))
 	}
 	e := entries[tileIdx]
+	if dataOffset < 0 || dataOffset > len(data) || e.Offset > uint64(len(data)-dataOffset) || e.Length > uint64(len(data)-dataOffset)-e.Offset {
+		return nil, fmt.Errorf("MIC3: tile %d data extends beyond file", tileIdx)
+	}
 	start := dataOffset + int(e.Offset)
 	end := start + int(e.Length)
-	if end > len(data) {
-		return nil, fmt.Errorf("MIC3: tile %d data extends beyond file", tileIdx)
+	if e.HasCRC {
+		if err := verifyUnit(mic3Magic, "tile", tileIdx, data[start:end], e.CRC); err != nil {
+			return nil, err
+		}
 	}
 	return data[start:end], nil
 }